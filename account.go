@@ -0,0 +1,205 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// --- 帳號：密碼、顯示名稱與刪除帳號 ---
+//
+// 改密碼要先驗證目前的密碼，改完後其他裝置一律登出，只保留目前這個瀏覽器。
+// 刪除帳號同樣要輸入密碼：個人任務（含垃圾桶）全部刪除，負責的專案任務改回未認領留給其他成員，
+// 再把帳號從專案、學生名單與別人分享的任務中移除，最後登出所有裝置
+
+const maxDisplayNameLength = 40
+
+// Name 是畫面上顯示的名稱，沒有設定顯示名稱時用使用者名稱
+func (u User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
+}
+
+// displayName 依使用者名稱取顯示名稱，讀不到使用者時直接用使用者名稱
+func (a *App) displayName(username string) string {
+	if u, err := a.store.GetUser(username); err == nil {
+		return u.Name()
+	}
+	return username
+}
+
+// updateDisplayName 是設定頁的 action=displayname，空白表示改回使用者名稱
+func (a *App) updateDisplayName(r *http.Request, username string) {
+	name := strings.TrimSpace(r.FormValue("display_name"))
+	if utf8.RuneCountInString(name) > maxDisplayNameLength {
+		a.flashError(r, invalidInput("顯示名稱最多 %d 個字", maxDisplayNameLength), "")
+		return
+	}
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		u.DisplayName = name
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "更新顯示名稱失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "顯示名稱已更新")
+}
+
+// changePassword 是設定頁的 action=password；成功後登出其他裝置，目前的瀏覽器換發新的 session。
+// 只用第三方登入的帳號還沒有密碼，不需要輸入目前的密碼
+func (a *App) changePassword(w http.ResponseWriter, r *http.Request, username string) {
+	current, password := r.FormValue("current"), r.FormValue("password")
+	user, err := a.store.GetUser(username)
+	if err == nil && user.PasswordHash != "" {
+		user, err = a.auth.Verify(r, "change-password", username, current)
+	}
+	switch {
+	case err == ErrBadCredentials:
+		a.flashError(r, invalidInput("目前的密碼不正確"), "")
+		return
+	case err != nil:
+		a.flashError(r, err, "驗證失敗，請稍後再試")
+		return
+	case password == "" || password != r.FormValue("confirm"):
+		a.flashError(r, invalidInput("兩次輸入的新密碼不一致"), "")
+		return
+	case password == current:
+		a.flashError(r, invalidInput("新密碼不能和目前的密碼相同"), "")
+		return
+	}
+	hash := hashPassword(password)
+	if _, err := a.store.ModifyUser(username, func(u *User) error {
+		u.PasswordHash = hash
+		return nil
+	}); err != nil {
+		a.flashError(r, err, "變更密碼失敗，請稍後再試")
+		return
+	}
+	a.sessions.EndUser(username)
+	a.startSession(w, r, username)
+	a.flashSuccess(r, "密碼已變更，其他裝置都已登出")
+}
+
+// deleteAccount 是設定頁的 action=delete-account，成功時回傳 true，由呼叫端導回登入頁
+func (a *App) deleteAccount(w http.ResponseWriter, r *http.Request, username string) bool {
+	user, err := a.store.GetUser(username)
+	if err == nil && user.PasswordHash != "" {
+		_, err = a.auth.Verify(r, "delete-account", username, r.FormValue("password"))
+	}
+	if err == ErrBadCredentials {
+		a.flashError(r, invalidInput("密碼不正確"), "")
+		return false
+	} else if err != nil {
+		a.flashError(r, err, "驗證失敗，請稍後再試")
+		return false
+	}
+	if r.FormValue("confirm") != username {
+		a.flashError(r, invalidInput("請輸入自己的使用者名稱確認刪除"), "")
+		return false
+	}
+	if a.isAdmin(username) {
+		users, err := a.store.ListUsers()
+		if err != nil {
+			a.flashError(r, err, "刪除帳號失敗，請稍後再試")
+			return false
+		}
+		admins := 0
+		for _, u := range users {
+			if u.Role == RoleAdmin && !u.Disabled {
+				admins++
+			}
+		}
+		if admins <= 1 {
+			a.flashError(r, invalidInput("你是唯一的管理員，請先指定其他管理員再刪除帳號"), "")
+			return false
+		}
+	}
+	if err := a.removeAccount(username); err != nil {
+		a.flashError(r, err, "刪除帳號途中失敗，請稍後再試一次")
+		return false
+	}
+	recordAudit(username, "delete-account", username)
+	a.sessions.EndUser(username)
+	a.endSession(w, r)
+	return true
+}
+
+// removeAccount 刪除帳號與個人資料；中途失敗時可以重試，已處理的部分不會重複處理
+func (a *App) removeAccount(username string) error {
+	all, err := a.store.AllTasks()
+	if err != nil {
+		return err
+	}
+	for _, t := range all {
+		switch {
+		case t.Username == username && t.ProjectID == 0:
+			if err := a.store.DeleteTask(t.ID); err != nil && err != ErrNotFound {
+				return err
+			}
+		case t.Username == username || t.isSharedWith(username):
+			_, err := a.store.ModifyTask(t.ID, func(t *Task) error {
+				if t.Username == username {
+					t.Username = "" // 專案任務留給其他成員認領
+					t.stopTimer(time.Now())
+				}
+				t.SharedWith = removeString(t.SharedWith, username)
+				return nil
+			})
+			if err != nil && err != ErrNotFound {
+				return err
+			}
+		}
+	}
+
+	trash, err := a.store.ListTrash(username)
+	if err != nil {
+		return err
+	}
+	for _, t := range trash {
+		if err := a.store.DeleteTask(t.ID); err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+
+	projects, err := a.store.ListProjects(username)
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		_, err := a.store.ModifyProject(p.ID, func(p *Project) error {
+			p.Members = removeString(p.Members, username)
+			if p.Owner == username && len(p.Members) > 0 {
+				p.Owner = p.Members[0]
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		a.notifyProject(p.ID, "%s 已刪除帳號並離開專案", username)
+	}
+
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if u.Username != username && containsString(u.Roster, username) {
+			_, err := a.store.ModifyUser(u.Username, func(t *User) error {
+				t.Roster = removeString(t.Roster, username)
+				return nil
+			})
+			if err != nil && err != ErrNotFound {
+				return err
+			}
+		}
+	}
+	if err := a.store.DeleteUser(username); err != nil && err != ErrNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// --- 一鍵操作連結 ---
+//
+// 信件裡的連結帶著簽章過的 token，不必登入就能對指定任務做指定的操作。
+// token 是 base64url(JSON 內容) + "." + base64url(HMAC-SHA256)，金鑰存在 -link-key 指定的檔案。
+// 信箱的防毒掃描會預先打開信裡的連結，所以 GET 只顯示確認頁，按下按鈕（POST）才真的執行。
+// token 記著簽發時任務的 LinkSeq，任何一個連結用過後 LinkSeq 加一，
+// 同一封信裡的其他連結也跟著失效，每封信只能操作一次
+
+// linkKey 是簽章金鑰，nil 表示停用一鍵連結
+var linkKey []byte
+
+// publicBaseURL 是對外的網址，排程寄出的信件沒有請求可以推算網址，用這個組連結
+var publicBaseURL string
+
+var (
+	errInvalidActionLink = &DomainError{Code: "invalid_link", Message: "連結無效或已過期", Status: http.StatusNotFound}
+	errActionLinkUsed    = &DomainError{Code: "link_used", Message: "這封信裡的連結已經用過了，請登入後再操作", Status: http.StatusGone}
+)
+
+// actionClaim 是 token 的內容
+type actionClaim struct {
+	Action  string `json:"a"`
+	TaskID  int    `json:"t"`
+	User    string `json:"u"`
+	Seq     int    `json:"s"`
+	Expires int64  `json:"e"`
+}
+
+// check 確認 token 仍適用於目前的任務：負責人沒變，而且還沒有用過
+func (c actionClaim) check(t Task) error {
+	if t.Username != c.User {
+		return errInvalidActionLink // 任務已轉給別人，原本的連結不再有效
+	}
+	if t.LinkSeq != c.Seq {
+		return errActionLinkUsed
+	}
+	return nil
+}
+
+// loadLinkKey 讀取簽章金鑰，檔案不存在時產生一把新的
+func loadLinkKey(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key)), 0600); err != nil {
+			return nil, err
+		}
+		log.Printf("已產生新的連結簽章金鑰：%s", path)
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil || len(key) < 32 {
+		return nil, errors.New(path + " 不是有效的金鑰（至少 32 位元組的 hex）")
+	}
+	return key, nil
+}
+
+func signAction(c actionClaim) string {
+	payload, _ := json.Marshal(c)
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, linkKey)
+	mac.Write([]byte(body))
+	return body + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// actionURL 產生一鍵操作的完整網址；停用時回傳空字串
+func actionURL(action string, task Task, ttl time.Duration) string {
+	if linkKey == nil {
+		return ""
+	}
+	token := signAction(actionClaim{
+		Action:  action,
+		TaskID:  task.ID,
+		User:    task.Username,
+		Seq:     task.LinkSeq,
+		Expires: time.Now().Add(ttl).Unix(),
+	})
+	return publicBaseURL + "/act?t=" + token
+}
+
+// verifyAction 檢查簽章與期限，回傳 token 的內容
+func verifyAction(token string, now time.Time) (actionClaim, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if linkKey == nil || !ok {
+		return actionClaim{}, errInvalidActionLink
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return actionClaim{}, errInvalidActionLink
+	}
+	mac := hmac.New(sha256.New, linkKey)
+	mac.Write([]byte(body))
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return actionClaim{}, errInvalidActionLink
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return actionClaim{}, errInvalidActionLink
+	}
+	var c actionClaim
+	if err := json.Unmarshal(payload, &c); err != nil || now.Unix() > c.Expires {
+		return actionClaim{}, errInvalidActionLink
+	}
+	return c, nil
+}
+
+// linkAction 是一種可以透過連結執行的操作
+type linkAction struct {
+	Label string // 確認頁按鈕上的文字
+	Done  string // 執行後顯示的結果
+	Apply func(t *Task, now time.Time)
+}
+
+// linkActions 列出所有可以透過連結執行的操作，新增操作時加在這裡
+var linkActions = map[string]linkAction{
+	"complete":         {"標記為完成", "已完成", completeFromLink},
+	"snooze":           {"延後一天", "已延後一天", snoozeFromLink},
+	"someday-schedule": {"排入下週", "已排入下週，並移除「有空再做」標籤", scheduleSomeday},
+	"someday-keep":     {"繼續保留", "已保留，兩個月後再問你", keepSomeday},
+	"someday-drop":     {"不做了", "已移到垃圾桶，30 天內可以從垃圾桶還原", dropSomeday},
+}
+
+func completeFromLink(t *Task, now time.Time) {
+	t.setCompleted(true, now)
+}
+
+func snoozeFromLink(t *Task, now time.Time) {
+	t.DueAt = t.DueAt.AddDate(0, 0, 1)
+}
+
+func (a *App) actionLinkHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	claim, err := verifyAction(r.FormValue("t"), now)
+	action, known := linkActions[claim.Action]
+	if err == nil && !known {
+		err = errInvalidActionLink
+	}
+	var task Task
+	if err == nil {
+		task, err = a.store.GetTask(claim.TaskID)
+		if err == ErrNotFound {
+			err = errInvalidActionLink // 已刪除的任務
+		}
+	}
+	if err == nil {
+		err = claim.check(task)
+	}
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		a.renderActionPage(w, r, map[string]interface{}{"Error": localMessage(a.requestLocale(r), err, "讀取任務失敗，請稍後再試")})
+		return
+	}
+
+	data := map[string]interface{}{
+		"Task":      task,
+		"Action":    action,
+		"Token":     r.FormValue("t"),
+		"CSRFToken": a.sessions.CSRFToken(r),
+	}
+	if r.Method == "POST" {
+		var wasCompleted bool
+		task, err = a.store.ModifyTask(task.ID, func(t *Task) error {
+			if err := claim.check(*t); err != nil {
+				return err
+			}
+			wasCompleted = t.Completed
+			before := *t
+			action.Apply(t, now)
+			t.recordEdit(claim.User, before, now)
+			t.LinkSeq++
+			return nil
+		})
+		if err != nil {
+			data["Error"] = localMessage(a.requestLocale(r), err, "操作失敗，請稍後再試")
+		} else {
+			data["Task"] = task
+			data["Done"] = true
+		}
+		// 與勾選完成相同，重複任務完成後排定下一次
+		if err == nil && task.Completed && !wasCompleted && task.Recurrence != RecurNone {
+			if err := a.spawnNextOccurrence(task.ID); err != nil {
+				log.Printf("產生下一次重複任務失敗：%v", err)
+			}
+		}
+	}
+	a.renderActionPage(w, r, data)
+}
+
+func (a *App) renderActionPage(w http.ResponseWriter, r *http.Request, data map[string]interface{}) {
+	t := a.localize(r, a.pages.page("action"))
+	t.Execute(w, data)
+}
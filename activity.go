@@ -0,0 +1,182 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 任務動態與留言 ---
+//
+// 每個任務保存自己的動態：修改了哪些欄位、完成與改回未完成，以及自由留言，顯示在任務頁（/task/{id}）。
+// 建立不另外記錄，任務頁以 CreatedAt 與 CreatedBy 顯示第一筆。
+// 看得到任務的人都可以留言（專案成員也可以），最多保留 maxTaskActivity 筆，超過時丟掉最舊的
+
+const (
+	maxTaskActivity  = 200
+	maxCommentLength = 2000
+)
+
+const (
+	ActivityEdited    = "edited"
+	ActivityCompleted = "completed"
+	ActivityReopened  = "reopened"
+	ActivityComment   = "comment"
+)
+
+// TaskActivity 是任務動態的一筆；Text 在 edited 時是變更摘要，comment 時是留言內容
+type TaskActivity struct {
+	Time time.Time `json:"time"`
+	User string    `json:"user,omitempty"`
+	Kind string    `json:"kind"`
+	Text string    `json:"text,omitempty"`
+}
+
+func (t *Task) addActivity(now time.Time, user, kind, text string) {
+	t.Activity = append(t.Activity, TaskActivity{Time: now, User: user, Kind: kind, Text: text})
+	if len(t.Activity) > maxTaskActivity {
+		t.Activity = t.Activity[len(t.Activity)-maxTaskActivity:]
+	}
+}
+
+func (t Task) hasActivityBy(user string) bool {
+	for _, a := range t.Activity {
+		if a.User == user {
+			return true
+		}
+	}
+	return false
+}
+
+// activityTime 是動態裡的時間格式，記錄下來之後不再隨使用者的設定改變
+const activityTime = "2006-01-02 15:04"
+
+// activityDue 是動態裡的到期時間，全天任務只記日期
+func activityDue(t Task) string {
+	if t.AllDay {
+		return t.DueAt.Format(dateInputFormat) + " 全天"
+	}
+	return t.DueAt.Format(activityTime)
+}
+
+// describeChanges 列出 before 到 after 之間看得到的變更，沒有變更時回傳空字串；
+// 完成狀態另外記成 completed／reopened，這裡不重複列出
+func describeChanges(before, after Task) string {
+	var changes []string
+	if before.Description != after.Description {
+		changes = append(changes, "內容："+clipText(before.Description, clipLine)+" → "+clipText(after.Description, clipLine))
+	}
+	if !before.DueAt.Equal(after.DueAt) || before.AllDay != after.AllDay {
+		changes = append(changes, "到期時間："+activityDue(before)+" → "+activityDue(after))
+	}
+	if effectivePriority(before.Priority) != effectivePriority(after.Priority) {
+		changes = append(changes, "優先順序："+priorityLabel(before.Priority)+" → "+priorityLabel(after.Priority))
+	}
+	if before.Recurrence != after.Recurrence {
+		changes = append(changes, "重複："+recurrenceLabel(before.Recurrence)+" → "+recurrenceLabel(after.Recurrence))
+	}
+	if blockerList(before.BlockedBy) != blockerList(after.BlockedBy) {
+		changes = append(changes, "等待："+blockerList(before.BlockedBy)+" → "+blockerList(after.BlockedBy))
+	}
+	if before.RemindersSet != after.RemindersSet || !slices.Equal(before.Reminders, after.Reminders) {
+		changes = append(changes, "提醒："+taskReminderList(LocaleZhTW, before)+" → "+taskReminderList(LocaleZhTW, after))
+	}
+	if strings.Join(before.Tags, ",") != strings.Join(after.Tags, ",") {
+		changes = append(changes, "標籤："+tagList(before.Tags)+" → "+tagList(after.Tags))
+	}
+	if before.Completed == after.Completed && before.EffectiveStatus() != after.EffectiveStatus() {
+		changes = append(changes, "看板："+statusLabel(before.EffectiveStatus())+" → "+statusLabel(after.EffectiveStatus()))
+	}
+	if strings.Join(before.SharedWith, ",") != strings.Join(after.SharedWith, ",") {
+		changes = append(changes, "分享對象："+sharedWithLabel(before.SharedWith)+" → "+sharedWithLabel(after.SharedWith))
+	}
+	if before.Username != after.Username {
+		changes = append(changes, "負責人："+assigneeLabel(before.Username)+" → "+assigneeLabel(after.Username))
+	}
+	if before.EncryptedNote != after.EncryptedNote {
+		changes = append(changes, "更新了加密筆記")
+	}
+	return strings.Join(changes, "；")
+}
+
+func tagList(tags []string) string {
+	if len(tags) == 0 {
+		return "（無）"
+	}
+	return "#" + strings.Join(tags, " #")
+}
+
+func blockerList(ids []int) string {
+	if len(ids) == 0 {
+		return "（無）"
+	}
+	labels := make([]string, len(ids))
+	for i, id := range ids {
+		labels[i] = "#" + strconv.Itoa(id)
+	}
+	return strings.Join(labels, " ")
+}
+
+func assigneeLabel(username string) string {
+	if username == "" {
+		return "（未認領）"
+	}
+	return username
+}
+
+// recordEdit 在 ModifyTask 的 fn 結尾呼叫，把 before 之後的變更記成一筆動態
+func (t *Task) recordEdit(user string, before Task, now time.Time) {
+	if text := describeChanges(before, *t); text != "" {
+		t.addActivity(now, user, ActivityEdited, text)
+	}
+}
+
+// activityLabel 是任務頁上每種動態的說明
+func activityLabel(kind string) string {
+	switch kind {
+	case ActivityEdited:
+		return "修改了任務"
+	case ActivityCompleted:
+		return "把任務標記為完成"
+	case ActivityReopened:
+		return "把任務改回未完成"
+	case ActivityComment:
+		return "留言了"
+	}
+	return kind
+}
+
+// commentHandler 新增留言，看得到任務的人都可以留言
+func (a *App) commentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	username := a.getUsername(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	text := strings.TrimSpace(r.FormValue("text"))
+	switch {
+	case text == "":
+		a.flashError(r, invalidInput("留言不可為空白"), "")
+	case len([]rune(text)) > maxCommentLength:
+		a.flashError(r, invalidInput("留言最多 %d 個字", maxCommentLength), "")
+	default:
+		if _, err := a.viewableTask(id, username); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		_, err := a.store.ModifyTask(id, func(t *Task) error {
+			t.addActivity(time.Now(), username, ActivityComment, text)
+			return nil
+		})
+		if err != nil {
+			a.flashError(r, err, "留言失敗，請稍後再試")
+		}
+	}
+	http.Redirect(w, r, taskPath(id)+"#activity", http.StatusSeeOther)
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// --- 使用者管理（管理員） ---
+//
+// 匯入的 CSV 每列為「使用者名稱,Email」（第一列可以是標題）。
+// 匯入的帳號沒有密碼，系統寄出邀請連結，使用者點開後自行設定密碼才能登入。
+// 重設密碼沿用同一套邀請流程：清掉舊密碼、寄出設定密碼的連結，帳號沒有 Email 時把連結顯示給管理員轉交。
+// 停用的帳號不能登入，既有的 session 立即失效，任務與設定都保留，隨時可以重新啟用
+
+const (
+	inviteTTL     = 7 * 24 * time.Hour
+	maxImportRows = 500
+)
+
+// csvBOM 讓 Excel 以 UTF-8 開啟匯出的中文內容
+const csvBOM = "\ufeff"
+
+// IsInvited 表示帳號由管理員建立、尚未完成設定密碼
+func (u User) IsInvited() bool {
+	return u.PasswordHash == ""
+}
+
+// requestBaseURL 從請求推回網站的根網址，用來組出信件中的連結
+func (a *App) requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || a.sessions.secure {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// issueInvite 產生新的邀請 token 存進使用者資料，並寄出邀請信
+func (a *App) issueInvite(user User, baseURL string) error {
+	token := randomToken(32)
+	user, err := a.store.ModifyUser(user.Username, func(u *User) error {
+		u.InviteHash = hashSessionToken(token)
+		u.InviteExpires = time.Now().Add(inviteTTL)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("%s 您好：\n\n管理員已為您建立待辦清單帳號，請在 %s 前開啟以下連結設定密碼：\n\n%s/invite?token=%s\n\n若您沒有預期收到這封信，可以直接忽略。\n",
+		user.Username, user.DatePrefs().DateTime(user.InviteExpires), baseURL, token)
+	return mailer.Send(user.Email, "待辦清單帳號邀請", body)
+}
+
+// findInvite 依 token 找出邀請中的帳號
+func (a *App) findInvite(token string) (User, error) {
+	if token == "" {
+		return User{}, ErrNotFound
+	}
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return User{}, err
+	}
+	hash := hashSessionToken(token)
+	for _, u := range users {
+		if u.InviteHash == hash && time.Now().Before(u.InviteExpires) && !u.Disabled {
+			return u, nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+type importRow struct {
+	Line     int
+	Username string
+	Email    string
+}
+
+// parseUserCSV 讀出要匯入的列；格式錯誤的列記在 problems，不中斷整批匯入
+func parseUserCSV(r io.Reader) (rows []importRow, problems []error, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	line := 0
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, invalidInput("CSV 格式錯誤：%v", err)
+		}
+		line++
+		if line == 1 && len(record) > 0 {
+			record[0] = strings.TrimPrefix(record[0], csvBOM)
+			if strings.EqualFold(strings.TrimSpace(record[0]), "username") {
+				continue
+			}
+		}
+		if len(record) < 2 {
+			problems = append(problems, invalidInput("第 %d 列：需要使用者名稱與 Email 兩個欄位", line))
+			continue
+		}
+		username := strings.TrimSpace(record[0])
+		addr, err := mail.ParseAddress(strings.TrimSpace(record[1]))
+		if username == "" || err != nil {
+			problems = append(problems, invalidInput("第 %d 列：使用者名稱或 Email 不正確", line))
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, nil, invalidInput("一次最多匯入 %d 位使用者", maxImportRows)
+		}
+		rows = append(rows, importRow{Line: line, Username: username, Email: addr.Address})
+	}
+	return rows, problems, nil
+}
+
+func (a *App) adminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+
+	if r.Method == "POST" {
+		switch r.FormValue("action") {
+		case "import":
+			a.importUsers(r)
+		case "reinvite":
+			a.reinviteUser(r)
+		case "role":
+			a.changeRole(r, username)
+		case "merge":
+			a.adminMergeUsers(r, username)
+		case "reset-password":
+			a.resetPassword(r, username)
+		case "disable":
+			a.setDisabled(r, username, true)
+		case "enable":
+			a.setDisabled(r, username, false)
+		}
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+
+	users, err := a.store.ListUsers()
+	if err != nil {
+		http.Error(w, "讀取使用者失敗", http.StatusInternalServerError)
+		return
+	}
+	counts, totals, err := a.userTaskCounts()
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	data := map[string]interface{}{
+		"Username":  username,
+		"Users":     users,
+		"Counts":    counts,
+		"Totals":    totals,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("admin-users"))
+	t.Execute(w, data)
+}
+
+func (a *App) importUsers(r *http.Request) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		a.flashError(r, invalidInput("請選擇要匯入的 CSV 檔"), "")
+		return
+	}
+	defer file.Close()
+
+	rows, problems, err := parseUserCSV(file)
+	if err != nil {
+		a.flashError(r, err, "讀取 CSV 失敗")
+		return
+	}
+
+	baseURL := a.requestBaseURL(r)
+	var invited []User
+	for _, row := range rows {
+		user := User{Username: row.Username, Email: row.Email, CreatedAt: time.Now()}
+		if err := a.store.CreateUser(user); err != nil {
+			problems = append(problems, lineProblem("列", row.Line, err, "建立帳號失敗"))
+			continue
+		}
+		invited = append(invited, user)
+	}
+
+	// 邀請信在背景寄出，整班匯入時不必等 SMTP；寄送失敗可在列表上重寄
+	go func() {
+		for _, user := range invited {
+			if err := a.issueInvite(user, baseURL); err != nil {
+				log.Printf("寄送邀請給 %s 失敗：%v", user.Username, err)
+			}
+		}
+	}()
+
+	a.flashSuccess(r, "已建立 %d 個帳號並寄出邀請信", len(invited))
+	for _, p := range problems {
+		a.flashError(r, p, "")
+	}
+}
+
+func (a *App) reinviteUser(r *http.Request) {
+	user, err := a.store.GetUser(r.FormValue("username"))
+	if err != nil || !user.IsInvited() || user.Email == "" {
+		a.flashError(r, invalidInput("這個帳號不需要邀請"), "")
+		return
+	}
+	if err := a.issueInvite(user, a.requestBaseURL(r)); err != nil {
+		log.Printf("寄送邀請給 %s 失敗：%v", user.Username, err)
+		a.flashError(r, err, "寄送邀請信失敗，請確認 SMTP 設定")
+		return
+	}
+	a.flashSuccess(r, "已重新寄出邀請信給 %s", user.Username)
+}
+
+// changeRole 調整其他使用者的角色；不能改自己的，避免系統裡沒有管理員
+func (a *App) changeRole(r *http.Request, admin string) {
+	role := r.FormValue("role")
+	if role != "" && role != RoleTeacher && role != RoleAdmin {
+		a.flashError(r, invalidInput("不支援的角色"), "")
+		return
+	}
+	user, err := a.store.GetUser(r.FormValue("username"))
+	if err != nil || user.Username == admin {
+		a.flashError(r, invalidInput("不能變更這個帳號的角色"), "")
+		return
+	}
+	if _, err := a.store.ModifyUser(user.Username, func(u *User) error {
+		u.Role = role
+		return nil
+	}); err != nil {
+		a.flashError(r, err, "變更角色失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "%s 的角色已變更為%s", user.Username, a.tr(r, roleLabel(role)))
+}
+
+// taskCounts 是使用者管理頁上每個帳號的任務統計
+type taskCounts struct {
+	Total, Completed, Overdue int
+}
+
+// userTaskCounts 依負責人統計任務數，totals 是全站合計（含未認領的專案任務）
+func (a *App) userTaskCounts() (map[string]taskCounts, taskCounts, error) {
+	tasks, err := a.store.AllTasks()
+	if err != nil {
+		return nil, taskCounts{}, err
+	}
+	counts := make(map[string]taskCounts)
+	var totals taskCounts
+	now := time.Now()
+	for _, t := range tasks {
+		c := counts[t.Username]
+		c.Total++
+		totals.Total++
+		if t.Completed {
+			c.Completed++
+			totals.Completed++
+		} else if t.OverdueAt(now) {
+			c.Overdue++
+			totals.Overdue++
+		}
+		counts[t.Username] = c
+	}
+	return counts, totals, nil
+}
+
+// resetPassword 清掉使用者的密碼並登出所有裝置，再以邀請連結讓他重新設定
+func (a *App) resetPassword(r *http.Request, admin string) {
+	user, err := a.store.GetUser(r.FormValue("username"))
+	if err != nil || user.Username == admin {
+		a.flashError(r, invalidInput("不能重設這個帳號的密碼，要改自己的密碼請到設定頁"), "")
+		return
+	}
+	token := randomToken(32)
+	user, err = a.store.ModifyUser(user.Username, func(u *User) error {
+		u.PasswordHash = ""
+		u.InviteHash = hashSessionToken(token)
+		u.InviteExpires = time.Now().Add(inviteTTL)
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "重設密碼失敗，請稍後再試")
+		return
+	}
+	a.sessions.EndUser(user.Username)
+	recordAudit(admin, "reset-password", user.Username)
+
+	link := a.requestBaseURL(r) + "/invite?token=" + token
+	if user.Email == "" {
+		a.flashSuccess(r, "已重設 %s 的密碼。這個帳號沒有 Email，請把連結轉交給他（%s 前有效）：%s",
+			user.Username, a.requestDatePrefs(r).DateTime(user.InviteExpires), link)
+		return
+	}
+	body := fmt.Sprintf("%s 您好：\n\n管理員已重設您的待辦清單密碼，所有裝置都已登出。請在 %s 前開啟以下連結設定新密碼：\n\n%s\n\n若您沒有要求重設密碼，請聯絡管理員。\n",
+		user.Username, user.DatePrefs().DateTime(user.InviteExpires), link)
+	if err := mailer.Send(user.Email, "待辦清單密碼重設", body); err != nil {
+		log.Printf("寄送密碼重設信給 %s 失敗：%v", user.Username, err)
+		a.flashError(r, err, "密碼已重設，但寄信失敗，請確認 SMTP 設定後按「重寄」")
+		return
+	}
+	a.flashSuccess(r, "已重設 %s 的密碼，設定新密碼的連結已寄到 %s", user.Username, user.Email)
+}
+
+// setDisabled 停用或重新啟用帳號；不能停用自己，避免系統裡沒有能登入的管理員
+func (a *App) setDisabled(r *http.Request, admin string, disabled bool) {
+	user, err := a.store.GetUser(r.FormValue("username"))
+	if err != nil || user.Username == admin {
+		a.flashError(r, invalidInput("不能變更這個帳號的狀態"), "")
+		return
+	}
+	if _, err := a.store.ModifyUser(user.Username, func(u *User) error {
+		u.Disabled = disabled
+		return nil
+	}); err != nil {
+		a.flashError(r, err, "變更帳號狀態失敗，請稍後再試")
+		return
+	}
+	if disabled {
+		a.sessions.EndUser(user.Username)
+		recordAudit(admin, "disable-account", user.Username)
+		a.flashSuccess(r, "%s 已停用，所有裝置都已登出", user.Username)
+		return
+	}
+	recordAudit(admin, "enable-account", user.Username)
+	a.flashSuccess(r, "%s 已重新啟用", user.Username)
+}
+
+func roleLabel(role string) string {
+	switch role {
+	case RoleAdmin:
+		return "管理員"
+	case RoleTeacher:
+		return "老師"
+	}
+	return "一般"
+}
+
+// adminUsersExportHandler 下載所有使用者的 CSV，不含密碼雜湊
+func (a *App) adminUsersExportHandler(w http.ResponseWriter, r *http.Request) {
+	users, err := a.store.ListUsers()
+	if err != nil {
+		http.Error(w, "讀取使用者失敗", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="users-`+time.Now().Format("20060102")+`.csv"`)
+	io.WriteString(w, csvBOM)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"username", "email", "role", "status", "created_at"})
+	for _, u := range users {
+		status := "active"
+		if u.IsInvited() {
+			status = "invited"
+		}
+		created := ""
+		if !u.CreatedAt.IsZero() {
+			created = u.CreatedAt.Format(time.RFC3339)
+		}
+		cw.Write([]string{csvSafe(u.Username), csvSafe(u.Email), u.Role, status, created})
+	}
+	cw.Flush()
+}
+
+// csvSafe 避免 = + - @ 開頭的內容在試算表中被當成公式執行
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// inviteHandler 讓受邀的使用者設定密碼，完成後直接登入
+func (a *App) inviteHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	user, err := a.findInvite(token)
+	if err != nil {
+		a.renderInvite(w, r, "", token, a.tr(r, "邀請連結無效或已過期，請聯絡管理員重新寄送"))
+		return
+	}
+
+	if r.Method == "POST" {
+		password := r.FormValue("password")
+		if password == "" || password != r.FormValue("confirm") {
+			a.renderInvite(w, r, user.Username, token, a.tr(r, "兩次輸入的密碼不一致"))
+			return
+		}
+		hash := hashPassword(password)
+		_, err := a.store.ModifyUser(user.Username, func(u *User) error {
+			if u.InviteHash != user.InviteHash {
+				return ErrNotFound // 邀請已經用過或重新寄送過
+			}
+			u.PasswordHash = hash
+			u.InviteHash = ""
+			u.InviteExpires = time.Time{}
+			return nil
+		})
+		if err == ErrNotFound {
+			a.renderInvite(w, r, "", token, a.tr(r, "邀請連結無效或已過期，請聯絡管理員重新寄送"))
+			return
+		}
+		if err != nil {
+			a.renderInvite(w, r, user.Username, token, a.tr(r, "設定密碼失敗，請稍後再試"))
+			return
+		}
+		a.startSession(w, r, user.Username)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	a.renderInvite(w, r, user.Username, token, "")
+}
+
+func (a *App) renderInvite(w http.ResponseWriter, r *http.Request, username, token, errMsg string) {
+	data := map[string]interface{}{
+		"Username": username,
+		"Token":    token,
+		"Error":    errMsg,
+	}
+	t := a.localize(r, a.pages.page("invite"))
+	t.Execute(w, data)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"time"
+)
+
+// --- 全天任務 ---
+//
+// 很多待辦只有日期、沒有特定時間。AllDay 的任務 DueAt 是當天零點，畫面上只顯示日期，
+// 到當天結束（隔天零點）才算逾期；月曆上排在每一格最上面的全天列。
+// 表單勾選「全天」時到期欄位換成日期輸入，也接受 datetime-local 的值（時間部分捨去），
+// 沒有 JavaScript 時照樣可以送出。匯出時全天任務的到期只寫日期，匯入時只有日期的就是全天任務
+
+const dateInputFormat = "2006-01-02"
+
+// Deadline 是任務真正到期的時間：全天任務是隔天零點，其他任務就是 DueAt
+func (t Task) Deadline() time.Time {
+	if t.AllDay {
+		return t.DueAt.AddDate(0, 0, 1)
+	}
+	return t.DueAt
+}
+
+// OverdueAt 回報任務在 now 是否已逾期且尚未完成
+func (t Task) OverdueAt(now time.Time) bool {
+	return !t.Completed && t.Deadline().Before(now)
+}
+
+// parseDueInput 解析表單的到期欄位；allDay 時接受日期或 datetime-local，回傳當天零點。
+// 格式錯誤回傳 ErrInvalidDueDate，超出合理範圍的回傳 checkDueAt 的錯誤
+func parseDueInput(s string, allDay bool) (time.Time, error) {
+	if !allDay {
+		return parseDueTime(s)
+	}
+	if len(s) > len(dateInputFormat) {
+		s = s[:len(dateInputFormat)]
+	}
+	t, err := time.ParseInLocation(dateInputFormat, s, time.Local)
+	if err != nil {
+		return time.Time{}, ErrInvalidDueDate
+	}
+	return t, checkDueAt(t)
+}
+
+// startOfDay 是 t 那一天的零點，轉成全天任務時捨去時間
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Due 是到期時間的完整寫法，全天任務只有日期
+func (p DatePrefs) Due(t Task) string {
+	if t.AllDay {
+		return p.Date(t.DueAt)
+	}
+	return p.DateTime(t.DueAt)
+}
+
+// ShortDue 是 Due 省略年份的寫法
+func (p DatePrefs) ShortDue(t Task) string {
+	if t.AllDay {
+		return p.monthDay(t.DueAt)
+	}
+	return p.Short(t.DueAt)
+}
+
+// remainingDueIn 是任務的剩餘時間；全天任務以日為單位，當天顯示「今天到期」
+func remainingDueIn(locale string, t Task, now time.Time) string {
+	if !t.AllDay {
+		return remainingTimeIn(locale, t.DueAt)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, t.DueAt.Location())
+	days := int(t.DueAt.Sub(today).Hours() / 24)
+	switch {
+	case days == 0:
+		return tr(locale, "今天到期")
+	case days > 0:
+		return tr(locale, "剩 %.0f 天", float64(days))
+	}
+	return tr(locale, "已逾期 %.0f 天", float64(-days))
+}
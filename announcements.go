@@ -0,0 +1,168 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- 公告任務 ---
+
+// announcementStatus 是某則公告在某位成員身上的完成情形
+type announcementStatus struct {
+	Username  string
+	Completed bool
+	Deleted   bool // 成員已刪除自己的副本
+}
+
+type announcementView struct {
+	Announcement
+	Members   []announcementStatus
+	Completed int
+}
+
+// publishAnnouncement 建立公告，並為發布者以外的每位成員各新增一份任務副本
+func (a *App) publishAnnouncement(ann Announcement) (Announcement, error) {
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return ann, err
+	}
+	for _, user := range users {
+		if user.Username != ann.CreatedBy {
+			ann.Recipients = append(ann.Recipients, user.Username)
+		}
+	}
+	return a.distributeAnnouncement(ann)
+}
+
+// distributeAnnouncement 儲存公告並為 a.Recipients 的每個人新增一份任務副本
+func (a *App) distributeAnnouncement(ann Announcement) (Announcement, error) {
+	ann, err := a.store.CreateAnnouncement(ann)
+	if err != nil {
+		return ann, err
+	}
+	for _, username := range ann.Recipients {
+		task := Task{
+			Description:    ann.Description,
+			CreatedAt:      ann.CreatedAt,
+			DueAt:          ann.DueAt,
+			Username:       username,
+			AnnouncementID: ann.ID,
+		}
+		if _, err := a.store.CreateTask(task); err != nil {
+			return ann, err
+		}
+	}
+	return ann, nil
+}
+
+// announcementCopies 依公告 ID、收件人整理出所有仍存在的任務副本
+func (a *App) announcementCopies() (map[int]map[string]Task, error) {
+	tasks, err := a.store.AllTasks()
+	if err != nil {
+		return nil, err
+	}
+	copies := make(map[int]map[string]Task)
+	for _, task := range tasks {
+		if task.AnnouncementID == 0 {
+			continue
+		}
+		if copies[task.AnnouncementID] == nil {
+			copies[task.AnnouncementID] = make(map[string]Task)
+		}
+		copies[task.AnnouncementID][task.Username] = task
+	}
+	return copies, nil
+}
+
+// buildAnnouncementViews 彙整每則公告在各成員的完成狀態，最新的公告排在前面；
+// 老師派發的作業在老師頁面另外顯示，不列在這裡
+func (a *App) buildAnnouncementViews() ([]announcementView, error) {
+	list, err := a.store.ListAnnouncements()
+	if err != nil {
+		return nil, err
+	}
+	copies, err := a.announcementCopies()
+	if err != nil {
+		return nil, err
+	}
+
+	var views []announcementView
+	for _, ann := range list {
+		if ann.Kind != "" {
+			continue
+		}
+		view := announcementView{Announcement: ann}
+		for _, username := range ann.Recipients {
+			task, ok := copies[ann.ID][username]
+			status := announcementStatus{
+				Username:  username,
+				Completed: ok && task.Completed,
+				Deleted:   !ok,
+			}
+			if status.Completed {
+				view.Completed++
+			}
+			view.Members = append(view.Members, status)
+		}
+		views = append(views, view)
+	}
+
+	sort.Slice(views, func(i, j int) bool { return views[i].ID > views[j].ID })
+	return views, nil
+}
+
+func (a *App) announcementsHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+
+	if r.Method == "POST" {
+		desc := strings.TrimSpace(r.FormValue("description"))
+		dueAt, err := parseDueTime(r.FormValue("due_at"))
+		if desc == "" || err == ErrInvalidDueDate {
+			a.flashError(r, invalidInput("請填寫公告內容與到期時間"), "")
+			http.Redirect(w, r, "/announcements", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			a.flashError(r, err, "")
+			http.Redirect(w, r, "/announcements", http.StatusSeeOther)
+			return
+		}
+		if err := checkDescription(desc); err != nil {
+			a.flashError(r, err, "")
+			http.Redirect(w, r, "/announcements", http.StatusSeeOther)
+			return
+		}
+
+		ann := Announcement{
+			Description: desc,
+			DueAt:       dueAt,
+			CreatedAt:   time.Now(),
+			CreatedBy:   username,
+		}
+		if _, err := a.publishAnnouncement(ann); err != nil {
+			a.flashError(r, err, "發布公告失敗，請稍後再試")
+		} else {
+			a.flashSuccess(r, "公告已發布")
+		}
+		http.Redirect(w, r, "/announcements", http.StatusSeeOther)
+		return
+	}
+
+	views, err := a.buildAnnouncementViews()
+	if err != nil {
+		http.Error(w, "讀取公告失敗", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Username":      username,
+		"Announcements": views,
+		"Nonce":         newNonce(username),
+		"CSRFToken":     a.sessions.CSRFToken(r),
+		"Flashes":       a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("announcements"))
+	t.Execute(w, data)
+}
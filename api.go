@@ -0,0 +1,460 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- JSON API (/api/v1) ---
+
+type apiError struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+// taskInput 是新增/更新任務的請求內容，欄位為 nil 代表不修改
+type taskInput struct {
+	Description *string    `json:"description"`
+	DueAt       *time.Time `json:"due_at"`
+	AllDay      *bool      `json:"all_day"` // true 時只取 due_at 的日期
+	Completed   *bool      `json:"completed"`
+	Status      *string    `json:"status"` // todo、doing 或 done，與 completed 同時給時以 status 為準
+	Recurrence  *string    `json:"recurrence"`
+	Priority    *string    `json:"priority"`
+	Tags        *[]string  `json:"tags"`
+	BlockedBy   *[]int     `json:"blocked_by"` // 要先完成的任務編號，見 deps.go
+	Reminders   *[]int     `json:"reminders"`  // 到期前幾分鐘提醒，給了就不再使用預設提醒，見 reminders.go
+
+	// EncryptedNote 必須是瀏覽器端加密後的密文，伺服器不接受明文
+	EncryptedNote *string `json:"encrypted_note"`
+
+	// AllowDuplicate 為 true 時新增任務不檢查重複（見 duplicates.go），更新時忽略
+	AllowDuplicate bool `json:"allow_duplicate"`
+}
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apiError{Error: msg})
+}
+
+// writeDomainError 依錯誤決定狀態碼；DomainError 會帶上 code，其他錯誤只回 fallback
+func writeDomainError(w http.ResponseWriter, err error, fallback string) {
+	var de *DomainError
+	if errors.As(err, &de) {
+		writeJSON(w, de.Status, apiError{Error: de.Message, Code: de.Code})
+		return
+	}
+	writeAPIError(w, http.StatusInternalServerError, fallback)
+}
+
+// decodeJSON 解析請求內容，不接受未知欄位
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// readJSON 解析請求內容，失敗時寫出 400（或超過大小上限時 413）並回傳 false
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := decodeJSON(r, v)
+	if isTooLarge(err) {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, "請求內容過大")
+		return false
+	}
+	if err != nil {
+		writeDomainError(w, jsonInputError(err), "")
+		return false
+	}
+	return true
+}
+
+// requireAPIAuth 與 requireAuth 共用 session，但未登入時回 401 而非導向登入頁；
+// 帶著 Bearer token 時一律以 token 驗證（見 apitoken.go），不退回 session，csrfProtect 才能放心略過這類請求
+func (a *App) requireAPIAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if bearerToken(r) != "" || a.getUsername(r) == "" {
+			var ok bool
+			if r, ok = a.withAPITokenUser(r); !ok {
+				writeAPIError(w, http.StatusUnauthorized, "尚未登入")
+				return
+			}
+			next(w, r)
+			return
+		}
+		a.sessions.Touch(w, r)
+		next(w, r)
+	}
+}
+
+// loadOwnTask 取出路徑 /api/v1/tasks/{id} 指定、且屬於目前使用者的任務；失敗時已寫出錯誤回應
+func (a *App) loadOwnTask(w http.ResponseWriter, r *http.Request) (Task, bool) {
+	id, err := parseID(strings.TrimPrefix(r.URL.Path, "/api/v1/tasks/"))
+	if err != nil {
+		writeDomainError(w, err, "任務 ID 格式錯誤")
+		return Task{}, false
+	}
+	task, err := a.store.GetTask(id)
+	if err == ErrNotFound || (err == nil && task.Username != a.getUsername(r)) {
+		writeAPIError(w, http.StatusNotFound, "找不到任務")
+		return Task{}, false
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "讀取任務失敗")
+		return Task{}, false
+	}
+	return task, true
+}
+
+func (a *App) apiGetSession(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	if username == "" {
+		writeAPIError(w, http.StatusUnauthorized, "尚未登入")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"username": username})
+}
+
+func (a *App) apiCreateSession(w http.ResponseWriter, r *http.Request) {
+	var c credentials
+	if !readJSON(w, r, &c) {
+		return
+	}
+	user, err := a.auth.Verify(r, "api-login", c.Username, c.Password)
+	if err != nil {
+		writeDomainError(w, err, "登入失敗，請稍後再試")
+		return
+	}
+	a.startSession(w, r, user.Username)
+	writeJSON(w, http.StatusCreated, map[string]string{"username": user.Username})
+}
+
+func (a *App) apiDeleteSession(w http.ResponseWriter, r *http.Request) {
+	a.endSession(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) apiListTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := a.store.ListTasks(a.getUsername(r))
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "讀取任務失敗")
+		return
+	}
+	now := time.Now()
+	tasks = filterTasks(tasks, r.URL.Query().Get("filter"), now, a.datePrefsFor(a.getUsername(r)).WeekStart)
+	smartSort(tasks, now)
+	if tasks == nil {
+		tasks = []Task{}
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func (a *App) apiCreateTask(w http.ResponseWriter, r *http.Request) {
+	var in taskInput
+	if !readJSON(w, r, &in) {
+		return
+	}
+	if in.Description == nil || strings.TrimSpace(*in.Description) == "" {
+		writeAPIError(w, http.StatusBadRequest, "description 為必填")
+		return
+	}
+	if err := checkDescription(strings.TrimSpace(*in.Description)); err != nil {
+		writeDomainError(w, err, "")
+		return
+	}
+	if in.DueAt == nil {
+		writeAPIError(w, http.StatusBadRequest, "due_at 為必填")
+		return
+	}
+	if err := checkDueAt(*in.DueAt); err != nil {
+		writeDomainError(w, err, "")
+		return
+	}
+	if in.Recurrence != nil && !validRecurrence(*in.Recurrence) {
+		writeAPIError(w, http.StatusBadRequest, "recurrence 不正確")
+		return
+	}
+	if in.Priority != nil && !validPriority(*in.Priority) {
+		writeAPIError(w, http.StatusBadRequest, "priority 必須是 high、medium 或 low")
+		return
+	}
+	if in.Status != nil && !validStatus(*in.Status) {
+		writeAPIError(w, http.StatusBadRequest, "status 必須是 todo、doing 或 done")
+		return
+	}
+	if in.EncryptedNote != nil && !validEncryptedNote(*in.EncryptedNote) {
+		writeDomainError(w, ErrInvalidNote, "")
+		return
+	}
+	if !in.AllowDuplicate && !a.rejectDuplicate(w, a.getUsername(r), strings.TrimSpace(*in.Description)) {
+		return
+	}
+
+	task := Task{
+		Description: *in.Description,
+		CreatedAt:   time.Now(),
+		DueAt:       *in.DueAt,
+		Username:    a.getUsername(r),
+		Priority:    PriorityMedium,
+	}
+	if !a.applyBlockersInput(w, &task, in) || !applyRemindersInput(w, &task, in) {
+		return
+	}
+	if in.AllDay != nil && *in.AllDay {
+		task.AllDay = true
+		task.DueAt = startOfDay(task.DueAt)
+	}
+	if in.Completed != nil {
+		task.setCompleted(*in.Completed, time.Now())
+	}
+	if in.Status != nil {
+		task.setStatus(*in.Status, time.Now())
+	}
+	if in.Recurrence != nil {
+		task.Recurrence = *in.Recurrence
+	}
+	if in.Priority != nil {
+		task.Priority = *in.Priority
+	}
+	if in.Tags != nil {
+		task.Tags = normalizeTags(*in.Tags)
+	}
+	if in.EncryptedNote != nil {
+		task.EncryptedNote = *in.EncryptedNote
+	}
+
+	task, err := a.store.CreateTask(task)
+	if err != nil {
+		writeDomainError(w, err, "新增任務失敗")
+		return
+	}
+	w.Header().Set("Location", "/api/v1/tasks/"+strconv.Itoa(task.ID))
+	writeJSON(w, http.StatusCreated, task)
+}
+
+// completes 回報這次請求是否要把任務標記為完成；同時給 status 時以 status 為準
+func (in taskInput) completes() bool {
+	if in.Status != nil {
+		return *in.Status == StatusDone
+	}
+	return in.Completed != nil && *in.Completed
+}
+
+// applyBlockersInput 檢查並套用 blocked_by，要完成任務時確認等待的任務都已完成；
+// 有錯時寫出錯誤回應並回傳 false
+func (a *App) applyBlockersInput(w http.ResponseWriter, task *Task, in taskInput) bool {
+	if in.BlockedBy != nil {
+		ids, err := a.validateBlockers(task.ID, *in.BlockedBy, task.Username)
+		if err != nil {
+			writeDomainError(w, err, "")
+			return false
+		}
+		task.BlockedBy = ids
+	}
+	if in.completes() {
+		if err := a.checkUnblocked(*task); err != nil {
+			writeDomainError(w, err, "")
+			return false
+		}
+	}
+	return true
+}
+
+// applyRemindersInput 檢查並套用 reminders；有錯時寫出錯誤回應並回傳 false
+func applyRemindersInput(w http.ResponseWriter, task *Task, in taskInput) bool {
+	if in.Reminders == nil {
+		return true
+	}
+	offsets, err := normalizeReminders(*in.Reminders)
+	if err != nil {
+		writeDomainError(w, err, "")
+		return false
+	}
+	task.Reminders, task.RemindersSet = offsets, true
+	return true
+}
+
+func (a *App) apiGetTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := a.loadOwnTask(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (a *App) apiUpdateTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := a.loadOwnTask(w, r)
+	if !ok {
+		return
+	}
+
+	var in taskInput
+	if !readJSON(w, r, &in) {
+		return
+	}
+	if in.Description != nil && strings.TrimSpace(*in.Description) == "" {
+		writeAPIError(w, http.StatusBadRequest, "description 不可為空")
+		return
+	}
+	if in.Description != nil {
+		if err := checkDescription(strings.TrimSpace(*in.Description)); err != nil {
+			writeDomainError(w, err, "")
+			return
+		}
+	}
+	if in.DueAt != nil {
+		if err := checkDueAt(*in.DueAt); err != nil {
+			writeDomainError(w, err, "")
+			return
+		}
+	}
+	if in.Recurrence != nil && !validRecurrence(*in.Recurrence) {
+		writeAPIError(w, http.StatusBadRequest, "recurrence 不正確")
+		return
+	}
+	if in.Priority != nil && !validPriority(*in.Priority) {
+		writeAPIError(w, http.StatusBadRequest, "priority 必須是 high、medium 或 low")
+		return
+	}
+	if in.Status != nil && !validStatus(*in.Status) {
+		writeAPIError(w, http.StatusBadRequest, "status 必須是 todo、doing 或 done")
+		return
+	}
+	if in.EncryptedNote != nil && !validEncryptedNote(*in.EncryptedNote) {
+		writeDomainError(w, ErrInvalidNote, "")
+		return
+	}
+
+	if !a.applyBlockersInput(w, &task, in) || !applyRemindersInput(w, &task, in) {
+		return
+	}
+	task, err := a.store.ModifyTask(task.ID, func(t *Task) error {
+		before := *t
+		if in.Description != nil {
+			t.Description = *in.Description
+		}
+		if in.DueAt != nil {
+			t.DueAt = *in.DueAt
+		}
+		if in.AllDay != nil {
+			t.AllDay = *in.AllDay
+		}
+		if t.AllDay {
+			t.DueAt = startOfDay(t.DueAt)
+		}
+		if in.Completed != nil {
+			t.setCompleted(*in.Completed, time.Now())
+		}
+		if in.Status != nil {
+			t.setStatus(*in.Status, time.Now())
+		}
+		if in.Recurrence != nil {
+			t.Recurrence = *in.Recurrence
+		}
+		if in.Priority != nil {
+			t.Priority = *in.Priority
+		}
+		if in.Tags != nil {
+			t.Tags = normalizeTags(*in.Tags)
+		}
+		if in.EncryptedNote != nil {
+			t.EncryptedNote = *in.EncryptedNote
+		}
+		if in.BlockedBy != nil {
+			t.BlockedBy = task.BlockedBy
+		}
+		if in.Reminders != nil {
+			t.Reminders, t.RemindersSet = task.Reminders, true
+		}
+		t.recordEdit(t.Username, before, time.Now())
+		return nil
+	})
+	if err != nil {
+		writeDomainError(w, err, "更新任務失敗")
+		return
+	}
+	if task.Completed && task.Recurrence != RecurNone {
+		if err := a.spawnNextOccurrence(task.ID); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "產生下一次重複任務失敗")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (a *App) apiDeleteTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := a.loadOwnTask(w, r)
+	if !ok {
+		return
+	}
+	if err := a.trashTask(task.ID, time.Now()); err != nil {
+		writeDomainError(w, err, "刪除任務失敗")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) apiSessionHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		a.requireAPIAuth(a.apiGetSession)(w, r)
+	case "POST":
+		a.apiCreateSession(w, r)
+	case "DELETE":
+		a.apiDeleteSession(w, r)
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+	}
+}
+
+func (a *App) apiTasksHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		a.apiListTasks(w, r)
+	case "POST":
+		a.apiCreateTask(w, r)
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+	}
+}
+
+func (a *App) apiTaskHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		a.apiGetTask(w, r)
+	case "PUT":
+		a.apiUpdateTask(w, r)
+	case "DELETE":
+		a.apiDeleteTask(w, r)
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+	}
+}
+
+func (a *App) registerAPIRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/session", a.apiSessionHandler)
+	mux.HandleFunc("/api/v1/tasks", a.requireAPIAuth(a.apiTasksHandler))
+	mux.HandleFunc("/api/v1/tasks/", a.requireAPIAuth(a.apiTaskHandler))
+	mux.HandleFunc("/api/v1/tasks/diff", a.requireAPIAuth(a.apiTaskDiff))
+	mux.HandleFunc("/api/v1/tasks/quick", a.requireAPIAuth(a.apiQuickAdd))
+	mux.HandleFunc("/api/v1/export", a.requireAPIAuth(a.exportHandler))
+	mux.HandleFunc("/api/v1/stats", a.requireAPIAuth(a.apiStatsHandler))
+	mux.HandleFunc("/api/v1/focus", a.requireAPIAuth(a.apiFocusHandler))
+	mux.HandleFunc("/api/v1/suggest", a.requireAPIAuth(a.apiSuggestHandler))
+	mux.HandleFunc("/api/v1/calendar", a.requireAPIAuth(a.apiCalendarHandler))
+	mux.HandleFunc("/api/v1/poll", a.requireAPIAuth(a.apiPollHandler))
+	mux.HandleFunc("/api/v1/maintenance/purge-completed", a.requireAPIAuth(a.apiPurgeCompleted))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// --- API token ---
+//
+// 命令列工具（cmd/todo）與其他程式以 Authorization: Bearer <token> 呼叫 /api/，不必先登入取得 session。
+// token 在設定頁建立，只在建立當下顯示一次；和 session 一樣伺服器只存 SHA-256，資料檔外流也不能拿來用，
+// 外流時在設定頁撤銷。token 只在 /api/ 底下有效（見 requireAPIAuth），網頁一律要登入；帳號停用後跟著失效
+
+const (
+	maxAPITokens       = 10
+	maxAPITokenName    = 40
+	apiTokenPrefix     = "todo_"
+	apiTokenAuthScheme = "Bearer "
+)
+
+// APIToken 是一組 API token；ID 用來在設定頁撤銷，不能拿來呼叫 API
+type APIToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// apiUserKey 是以 token 通過驗證的請求在 context 裡的使用者名稱
+type apiUserKey struct{}
+
+// bearerToken 取出 Authorization header 的 token，沒有時回傳空字串
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(apiTokenAuthScheme) || !strings.EqualFold(auth[:len(apiTokenAuthScheme)], apiTokenAuthScheme) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(apiTokenAuthScheme):])
+}
+
+// findAPITokenUser 依 token 找出使用者
+func (a *App) findAPITokenUser(token string) (User, bool) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return User{}, false
+	}
+	hash := hashSessionToken(token)
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return User{}, false
+	}
+	for _, u := range users {
+		if u.Disabled {
+			continue
+		}
+		for _, t := range u.APITokens {
+			if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) == 1 {
+				return u, true
+			}
+		}
+	}
+	return User{}, false
+}
+
+// withAPITokenUser 以 Bearer token 驗證請求，成功時回傳帶著使用者名稱的請求
+func (a *App) withAPITokenUser(r *http.Request) (*http.Request, bool) {
+	user, ok := a.findAPITokenUser(bearerToken(r))
+	if !ok {
+		return r, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), apiUserKey{}, user.Username)), true
+}
+
+// createAPIToken 是設定頁的 action=apitoken；新的 token 只在這次的 flash 訊息裡出現
+func (a *App) createAPIToken(r *http.Request, username string) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		name = "命令列"
+	}
+	token := apiTokenPrefix + randomToken(24)
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		switch {
+		case utf8.RuneCountInString(name) > maxAPITokenName:
+			return invalidInput("名稱最多 %d 個字", maxAPITokenName)
+		case len(u.APITokens) >= maxAPITokens:
+			return invalidInput("最多只能建立 %d 組 API token，請先撤銷用不到的", maxAPITokens)
+		}
+		u.APITokens = append(u.APITokens, APIToken{
+			ID:        randomToken(8),
+			Name:      name,
+			Hash:      hashSessionToken(token),
+			CreatedAt: time.Now(),
+		})
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "建立 API token 失敗，請稍後再試")
+		return
+	}
+	a.sessions.pushFlash(r, Flash{Kind: FlashSuccess, Message: a.tr(r, "已建立 API token（只會顯示這一次，請馬上複製）：%s", token)})
+}
+
+// revokeAPIToken 是設定頁的 action=apitoken-revoke
+func (a *App) revokeAPIToken(r *http.Request, username string) {
+	id := r.FormValue("id")
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		var kept []APIToken
+		for _, t := range u.APITokens {
+			if t.ID != id {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) == len(u.APITokens) {
+			return ErrNotFound
+		}
+		u.APITokens = kept
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "撤銷 API token 失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "API token 已撤銷，使用它的程式需要換一組新的")
+}
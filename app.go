@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+)
+
+// --- App ---
+//
+// App 收齊 handler 需要的依賴：儲存層、session、頁面模板、登入檢查與搜尋索引。所有頁面與 API 都是 App 的方法，
+// 只透過 a.store、a.sessions 等欄位存取，測試可以用 newApp 建一個只有記憶體儲存層的實例，把 Handler() 交給
+// httptest，多個 App 可以同時服務請求（測試可以用 t.Parallel）。
+// 整個行程共用的只剩排程器、事件推播、修訂紀錄、任務快取、表單 nonce 與監控指標
+
+type App struct {
+	store     Store
+	sessions  *sessionManager
+	pages     *templateSet
+	auth      *authService
+	taskIndex *searchIndex // nil 表示沒有建立索引
+
+	metricsToken string // 抓取 /metrics 要帶的 Bearer token（METRICS_TOKEN），空白表示只開放本機
+}
+
+func newApp(store Store, sessions *sessionManager, pages *templateSet) *App {
+	return &App{store: store, sessions: sessions, pages: pages, auth: newAuthService(store)}
+}
+
+// Handler 回傳完整的路由，由外而內包上請求紀錄、監控指標、處理時間上限、請求大小上限、panic 復原與 CSRF 檢查
+func (a *App) Handler() http.Handler {
+	mux := http.NewServeMux()
+	a.routes(mux)
+	return a.withRequestLog(withMetrics(mux, a.withTimeouts(a.limitRequestBody(a.withRecovery(a.csrfProtect(mux))))))
+}
+
+func (a *App) routes(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", a.healthzHandler)
+	mux.HandleFunc("/metrics", a.metricsHandler)
+	mux.HandleFunc("/login", requireSameOrigin(a.login))
+	mux.HandleFunc("/register", requireSameOrigin(a.register))
+	mux.HandleFunc("/login/email", requireSameOrigin(a.magicLinkRequestHandler))
+	mux.HandleFunc("/login/magic", requireSameOrigin(a.magicLinkLoginHandler))
+	mux.HandleFunc("/logout", requireSameOrigin(a.logout))
+	mux.HandleFunc("/oauth/", a.oauthHandler)
+	mux.HandleFunc("/", a.requireAuth(a.index))
+	mux.HandleFunc("/calendar", a.requireAuth(a.calendarHandler))
+	mux.HandleFunc("/print/week", a.requireAuth(a.printWeekHandler))
+	mux.HandleFunc("/reschedule", a.requireAuth(a.preventDoubleSubmit(a.rescheduleHandler)))
+	mux.HandleFunc("/add/batch", a.requireAuth(a.preventDoubleSubmit(a.batchAddHandler)))
+	mux.HandleFunc("/add/markdown", a.requireAuth(a.preventDoubleSubmit(a.markdownImportHandler)))
+	mux.HandleFunc("/board", a.requireAuth(a.preventDoubleSubmit(a.boardHandler)))
+	mux.HandleFunc("/calendar/feed", a.requireAuth(a.preventDoubleSubmit(a.calendarFeedResetHandler)))
+	mux.HandleFunc("/calendar.ics", a.calendarFeedHandler)
+	mux.HandleFunc("/shared/", a.sharedListHandler)
+	mux.HandleFunc("/export", a.requireAuth(a.exportHandler))
+	mux.HandleFunc("/import", a.requireAuth(a.preventDoubleSubmit(a.importHandler)))
+	mux.HandleFunc("/import/review", a.requireAuth(a.preventDoubleSubmit(a.importReviewHandler)))
+	mux.HandleFunc("/import/service", a.requireAuth(a.preventDoubleSubmit(a.serviceImportHandler)))
+	mux.HandleFunc("/events", a.requireAuth(a.eventsHandler))
+	mux.HandleFunc("/notifications", a.requireAuth(a.preventDoubleSubmit(a.desktopNotifyHandler)))
+	mux.HandleFunc("/push/subscribe", a.requireAPIAuth(a.pushSubscribeHandler))
+	mux.HandleFunc("/push/unsubscribe", a.requireAPIAuth(a.pushUnsubscribeHandler))
+	mux.HandleFunc("/sw.js", serviceWorkerHandler)
+	mux.HandleFunc("/settings", a.requireAuth(a.preventDoubleSubmit(a.settingsHandler)))
+	mux.HandleFunc("/settings/digest", a.requireAuth(a.digestPreviewHandler))
+	mux.HandleFunc("/settings/data", a.requireAuth(a.preventDoubleSubmit(a.dataUsageHandler)))
+	mux.HandleFunc("/search", a.requireAuth(a.searchHandler))
+	mux.HandleFunc("/sort", a.requireAuth(a.preventDoubleSubmit(a.sortPreferenceHandler)))
+	mux.HandleFunc("/reorder", a.requireAuth(a.preventDoubleSubmit(a.reorderHandler)))
+	mux.HandleFunc("/add", a.requireAuth(a.preventDoubleSubmit(a.addTask)))
+	mux.HandleFunc("/toggle", a.requireAuth(a.preventDoubleSubmit(a.toggleTask)))
+	mux.HandleFunc("/task/", a.requireAuth(a.taskPageHandler))
+	mux.HandleFunc("/share", a.requireAuth(a.preventDoubleSubmit(a.shareHandler)))
+	mux.HandleFunc("/task/comment", a.requireAuth(a.preventDoubleSubmit(a.commentHandler)))
+	mux.HandleFunc("/edit", a.requireAuth(a.preventDoubleSubmit(a.editTask)))
+	mux.HandleFunc("/delete", a.requireAuth(a.deleteTask))
+	mux.HandleFunc("/undo", a.requireAuth(a.preventDoubleSubmit(a.undoHandler)))
+	mux.HandleFunc("/redo", a.requireAuth(a.preventDoubleSubmit(a.undoHandler)))
+	mux.HandleFunc("/trash", a.requireAuth(a.preventDoubleSubmit(a.trashHandler)))
+	mux.HandleFunc("/archive", a.requireAuth(a.preventDoubleSubmit(a.archiveHandler)))
+	mux.HandleFunc("/countdown", a.requireAuth(a.preventDoubleSubmit(a.countdownHandler)))
+	mux.HandleFunc("/bulk", a.requireAuth(a.preventDoubleSubmit(a.bulkHandler)))
+	mux.HandleFunc("/review", a.requireAuth(a.preventDoubleSubmit(a.reviewHandler)))
+	mux.HandleFunc("/act", a.actionLinkHandler)
+	mux.HandleFunc("/timer/start", a.requireAuth(a.preventDoubleSubmit(a.timerStartHandler)))
+	mux.HandleFunc("/timer/stop", a.requireAuth(a.preventDoubleSubmit(a.timerStopHandler)))
+	mux.HandleFunc("/stats", a.requireAuth(a.statsPageHandler))
+	mux.HandleFunc("/focus", a.requireAuth(a.preventDoubleSubmit(a.focusHandler)))
+	mux.HandleFunc("/achievements", a.requireAuth(a.achievementsHandler))
+	mux.HandleFunc("/checklist/add", a.requireAuth(a.preventDoubleSubmit(a.checklistAddHandler)))
+	mux.HandleFunc("/checklist/toggle", a.requireAuth(a.preventDoubleSubmit(a.checklistToggleHandler)))
+	mux.HandleFunc("/checklist/delete", a.requireAuth(a.preventDoubleSubmit(a.checklistDeleteHandler)))
+	mux.HandleFunc("/announcements", a.requireAdmin(a.preventDoubleSubmit(a.announcementsHandler)))
+	mux.HandleFunc("/invite", requireSameOrigin(a.inviteHandler))
+	mux.HandleFunc("/admin/users", a.requireAdmin(a.preventDoubleSubmit(a.adminUsersHandler)))
+	mux.HandleFunc("/admin/users/export", a.requireAdmin(a.adminUsersExportHandler))
+	mux.HandleFunc("/admin/console", a.requireAdmin(a.preventDoubleSubmit(a.adminConsoleHandler)))
+	mux.HandleFunc("/teacher", a.requireTeacher(a.preventDoubleSubmit(a.teacherHandler)))
+	mux.HandleFunc("/teacher/export", a.requireTeacher(a.teacherExportHandler))
+	mux.HandleFunc("/projects", a.requireAuth(a.preventDoubleSubmit(a.projectsHandler)))
+	mux.HandleFunc("/project", a.requireAuth(a.projectHandler))
+	mux.HandleFunc("/project/add", a.requireAuth(a.preventDoubleSubmit(a.projectAddHandler)))
+	mux.HandleFunc("/project/claim", a.requireAuth(a.preventDoubleSubmit(a.projectClaimHandler)))
+	mux.HandleFunc("/project/reassign", a.requireAuth(a.preventDoubleSubmit(a.projectReassignHandler)))
+	mux.HandleFunc("/project/members", a.requireAuth(a.preventDoubleSubmit(a.projectMembersHandler)))
+	mux.HandleFunc("/project/private", a.requireAuth(a.preventDoubleSubmit(a.projectPrivateHandler)))
+	a.registerAPIRoutes(mux)
+}
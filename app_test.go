@@ -0,0 +1,1692 @@
+package main
+
+import (
+	"bytes"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClient 是一個帶 cookie 的瀏覽器：不自動跟隨轉址，方便檢查 303 導去哪裡
+type testClient struct {
+	t      *testing.T
+	srv    *httptest.Server
+	app    *App
+	client *http.Client
+}
+
+// newTestApp 建立只有記憶體儲存層的 App，每個測試各自一份
+func newTestApp(t *testing.T) *testClient {
+	t.Helper()
+	pages, err := newTemplateSet("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := newMemoryStore()
+	app := newApp(st, newSessionManager(st, time.Hour, false, false), pages)
+	srv := httptest.NewServer(app.Handler())
+	t.Cleanup(srv.Close)
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar:           jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return &testClient{t: t, srv: srv, app: app, client: client}
+}
+
+func (c *testClient) get(path string) (*http.Response, string) {
+	c.t.Helper()
+	resp, err := c.client.Get(c.srv.URL + path)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return resp, readBody(c.t, resp)
+}
+
+// post 送出表單，Referer 設成首頁，和從本站頁面送出一樣
+func (c *testClient) post(path string, form url.Values) (*http.Response, string) {
+	c.t.Helper()
+	req, _ := http.NewRequest("POST", c.srv.URL+path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", c.srv.URL+"/")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return resp, readBody(c.t, resp)
+}
+
+// postForm 先打開首頁取得 nonce 與 csrf_token，再送出表單
+func (c *testClient) postForm(path string, form url.Values) (*http.Response, string) {
+	c.t.Helper()
+	_, body := c.get("/")
+	form.Set("nonce", hiddenField(c.t, body, "nonce"))
+	form.Set("csrf_token", hiddenField(c.t, body, "csrf_token"))
+	return c.post(path, form)
+}
+
+func (c *testClient) signup(username, password string) {
+	c.t.Helper()
+	form := url.Values{"username": {username}, "password": {password}}
+	if resp, _ := c.post("/register", form); resp.StatusCode != http.StatusSeeOther {
+		c.t.Fatalf("註冊 %s：狀態 %d", username, resp.StatusCode)
+	}
+	if resp, _ := c.post("/login", form); resp.StatusCode != http.StatusSeeOther {
+		c.t.Fatalf("登入 %s：狀態 %d", username, resp.StatusCode)
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func hiddenField(t *testing.T, body, name string) string {
+	t.Helper()
+	m := regexp.MustCompile(`name="` + name + `" value="([^"]*)"`).FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("頁面上找不到 %s", name)
+	}
+	return m[1]
+}
+
+func expectRedirect(t *testing.T, resp *http.Response, location string) {
+	t.Helper()
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != location {
+		t.Fatalf("預期 303 導到 %s，得到 %d %s", location, resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	c := newTestApp(t)
+	form := url.Values{"username": {"amy"}, "password": {"secret"}}
+	resp, _ := c.post("/register", form)
+	expectRedirect(t, resp, "/login")
+
+	user, err := c.app.store.GetUser("amy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != RoleAdmin {
+		t.Errorf("第一位註冊的使用者應該是管理員，得到 %q", user.Role)
+	}
+
+	resp, _ = c.post("/register", form)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("重複註冊應該留在註冊頁，得到 %d", resp.StatusCode)
+	}
+
+	resp, _ = c.post("/login?next=/calendar", form)
+	expectRedirect(t, resp, "/calendar")
+	if _, body := c.get("/"); !strings.Contains(body, "amy") {
+		t.Error("登入後的清單頁沒有顯示使用者名稱")
+	}
+}
+
+func TestFirstUserIsOnlyAdmin(t *testing.T) {
+	c := newTestApp(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.app.store.RegisterUser(User{Username: fmt.Sprintf("user%d", i), CreatedAt: time.Now()})
+		}()
+	}
+	wg.Wait()
+	users, _ := c.app.store.ListUsers()
+	admins := 0
+	for _, u := range users {
+		if u.Role == RoleAdmin {
+			admins++
+		}
+	}
+	if len(users) != 8 || admins != 1 {
+		t.Errorf("同時註冊時應該只有一位管理員，得到 %d 位使用者、%d 位管理員", len(users), admins)
+	}
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	c := newTestApp(t)
+	c.post("/register", url.Values{"username": {"amy"}, "password": {"secret"}})
+
+	resp, body := c.post("/login", url.Values{"username": {"amy"}, "password": {"wrong"}})
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `class="error"`) {
+		t.Fatalf("密碼錯誤應該顯示錯誤訊息，得到 %d", resp.StatusCode)
+	}
+	resp, _ = c.get("/")
+	expectRedirect(t, resp, "/login")
+}
+
+func TestPasswordHashUpgrade(t *testing.T) {
+	hash := hashPassword("secret")
+	if !strings.HasPrefix(hash, passwordPrefix) {
+		t.Fatalf("新的雜湊應該是 bcrypt，得到 %q", hash)
+	}
+	if ok, rehash := verifyPassword(hash, "secret"); !ok || rehash {
+		t.Errorf("bcrypt 雜湊應該通過且不必重算，得到 %v %v", ok, rehash)
+	}
+	if ok, _ := verifyPassword(hash, "wrong"); ok {
+		t.Error("密碼錯誤不應該通過")
+	}
+	long := strings.Repeat("x", 80)
+	if ok, _ := verifyPassword(hashPassword(long+"1"), long+"2"); ok {
+		t.Error("超過 72 bytes 的密碼不應該只比對前段")
+	}
+
+	salt := []byte("0123456789abcdef")
+	key, _ := pbkdf2.Key(sha256.New, "secret", salt, 1000, 32)
+	old := map[string]string{
+		"sha256": legacyHashPassword("secret"),
+		"pbkdf2": fmt.Sprintf("%s$1000$%s$%s", pbkdf2Scheme, hex.EncodeToString(salt), hex.EncodeToString(key)),
+	}
+	for name, stored := range old {
+		if ok, rehash := verifyPassword(stored, "secret"); !ok || !rehash {
+			t.Errorf("%s 的舊雜湊應該通過並要求重算，得到 %v %v", name, ok, rehash)
+		}
+
+		// 登入成功後改存成 bcrypt
+		c := newTestApp(t)
+		c.app.store.CreateUser(User{Username: "amy", PasswordHash: stored})
+		resp, _ := c.post("/login", url.Values{"username": {"amy"}, "password": {"secret"}})
+		expectRedirect(t, resp, "/")
+		if user, _ := c.app.store.GetUser("amy"); !strings.HasPrefix(user.PasswordHash, passwordPrefix) {
+			t.Errorf("%s 的舊雜湊登入後應該升級，得到 %q", name, user.PasswordHash)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	st := newMemoryStore()
+	st.CreateUser(User{Username: "amy", PasswordHash: hashPassword("secret")})
+	auth := newAuthService(st)
+	from := func(ip string) *http.Request {
+		r := httptest.NewRequest("POST", "/login", nil)
+		r.RemoteAddr = ip + ":41234"
+		return r
+	}
+
+	for i := 0; i < authMaxFailures; i++ {
+		auth.Verify(from("10.0.0.1"), "login", "amy", "wrong")
+	}
+	if _, err := auth.Verify(from("10.0.0.1"), "login", "amy", "secret"); err != ErrAuthLocked {
+		t.Fatalf("同一個 IP 失敗太多次應該鎖定，得到 %v", err)
+	}
+	if !strings.Contains(logs.String(), "rejected, locked from 10.0.0.1") {
+		t.Error("鎖定中被拒絕的嘗試也應該寫進稽核紀錄")
+	}
+	if _, err := auth.Verify(from("10.0.0.2"), "login", "amy", "secret"); err != nil {
+		t.Errorf("其他 IP 不應該被鎖在門外，得到 %v", err)
+	}
+	for i := 0; i < authMaxFailures; i++ {
+		auth.Verify(from("10.0.0.4"), "login", "AMY", "wrong")
+	}
+	if _, err := auth.Verify(from("10.0.0.4"), "login", "amy", "secret"); err != nil {
+		t.Errorf("使用者名稱大小寫有別，試 AMY 不應該鎖住 amy，得到 %v", err)
+	}
+
+	// 換 IP 分散猜測：不分 IP 累計達上限時整個帳號鎖定
+	for i := 0; i < authMaxUserFailures; i++ {
+		auth.Verify(from(fmt.Sprintf("10.1.0.%d", i+1)), "login", "amy", "wrong")
+	}
+	if _, err := auth.Verify(from("10.0.0.3"), "login", "amy", "secret"); err != ErrAuthLocked {
+		t.Errorf("不分 IP 失敗太多次應該鎖定整個帳號，得到 %v", err)
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	c := newTestApp(t)
+	resp, _ := c.get("/calendar?month=5")
+	expectRedirect(t, resp, "/login?next="+url.QueryEscape("/calendar?month=5"))
+
+	// 未登入的 POST 也不能新增任務
+	resp, _ = c.post("/add", url.Values{"description": {"x"}, "due_at": {"2030-01-01T10:00"}})
+	expectRedirect(t, resp, "/login")
+}
+
+func TestLogout(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+
+	if resp, _ := c.get("/logout"); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /logout 應該回 405，得到 %d", resp.StatusCode)
+	}
+	if resp, _ := c.post("/logout", url.Values{}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("沒有 csrf_token 的登出應該被擋下，得到 %d", resp.StatusCode)
+	}
+	resp, _ := c.postForm("/logout", url.Values{})
+	expectRedirect(t, resp, "/login")
+	resp, _ = c.get("/")
+	expectRedirect(t, resp, "/login")
+}
+
+func TestTaskCRUD(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+
+	resp, _ := c.postForm("/add", url.Values{
+		"description": {"寫期末報告 #課業"},
+		"due_at":      {"2030-01-02T15:04"},
+		"priority":    {PriorityHigh},
+	})
+	expectRedirect(t, resp, "/")
+	tasks, err := c.app.store.ListTasks("amy")
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("預期一個任務，得到 %d（%v）", len(tasks), err)
+	}
+	task := tasks[0]
+	if task.Description != "寫期末報告" || !task.HasTag("課業") || task.Priority != PriorityHigh {
+		t.Errorf("新增的任務內容不對：%+v", task)
+	}
+	if _, body := c.get("/"); !strings.Contains(body, "寫期末報告") {
+		t.Error("清單頁沒有顯示新增的任務")
+	}
+	id := strconv.Itoa(task.ID)
+
+	c.postForm("/toggle", url.Values{"id": {id}})
+	if task, _ = c.app.store.GetTask(task.ID); !task.Completed {
+		t.Error("勾選後任務應該是已完成")
+	}
+
+	resp, _ = c.postForm("/edit", url.Values{
+		"id":          {id},
+		"description": {"寫期末報告初稿"},
+		"due_at":      {"2030-01-03T09:00"},
+		"recurrence":  {RecurNone},
+		"priority":    {PriorityLow},
+	})
+	expectRedirect(t, resp, "/")
+	task, _ = c.app.store.GetTask(task.ID)
+	if task.Description != "寫期末報告初稿" || task.Priority != PriorityLow || task.DueAt.Day() != 3 {
+		t.Errorf("修改後的任務不對：%+v", task)
+	}
+
+	c.postForm("/delete", url.Values{"id": {id}})
+	if tasks, _ = c.app.store.ListTasks("amy"); len(tasks) != 0 {
+		t.Errorf("刪除後清單應該是空的，還有 %d 個", len(tasks))
+	}
+	if trash, _ := c.app.store.ListTrash("amy"); len(trash) != 1 {
+		t.Errorf("刪除的任務應該在垃圾桶，得到 %d 個", len(trash))
+	}
+}
+
+func TestTaskInvalidInput(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+
+	c.postForm("/add", url.Values{"description": {"沒有到期時間"}, "due_at": {"明天"}})
+	if tasks, _ := c.app.store.ListTasks("amy"); len(tasks) != 0 {
+		t.Errorf("到期時間錯誤時不應該新增任務")
+	}
+	if _, body := c.get("/"); !strings.Contains(body, "flash-error") {
+		t.Error("到期時間錯誤時應該顯示錯誤訊息")
+	}
+
+	c.postForm("/add", url.Values{"description": {"少打一位數"}, "due_at": {"0202-01-01T10:00"}})
+	if tasks, _ := c.app.store.ListTasks("amy"); len(tasks) != 0 {
+		t.Errorf("年份不合理時不應該新增任務")
+	}
+	if _, body := c.get("/"); !strings.Contains(body, "到期時間必須在") {
+		t.Error("年份不合理時應該說明範圍")
+	}
+	c.postForm("/toggle", url.Values{"id": {"abc"}})
+	if _, body := c.get("/"); !strings.Contains(body, ErrInvalidID.Message) {
+		t.Error("編號錯誤時應該顯示錯誤訊息，而不是當成編號 0")
+	}
+	resp, _ := c.get("/edit?id=abc")
+	expectRedirect(t, resp, "/")
+	if _, body := c.get("/"); !strings.Contains(body, ErrInvalidID.Message) {
+		t.Error("編輯頁的編號錯誤時應該顯示錯誤訊息")
+	}
+	resp, body := c.get("/api/v1/tasks/abc")
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, `"code":"invalid_id"`) {
+		t.Errorf("API 的編號錯誤應該回 400 invalid_id，得到 %d %s", resp.StatusCode, body)
+	}
+
+	for _, s := range []string{"", "0", "-3", "1x"} {
+		if _, err := parseID(s); err != ErrInvalidID {
+			t.Errorf("%q 不是合法的編號", s)
+		}
+	}
+	var in taskInput
+	err := decodeJSON(httptest.NewRequest("POST", "/api/v1/tasks", strings.NewReader(`{"due_at":"明天"}`)), &in)
+	if jsonInputError(err) != ErrInvalidDueDate {
+		t.Errorf("API 的到期時間格式錯誤應該回 invalid_due_date，得到 %v", err)
+	}
+	if err := checkDueAt(time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("零值的到期時間不應該通過")
+	}
+}
+
+func TestOtherUsersTasks(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"amy 的任務"}, "due_at": {"2030-01-02T15:04"}})
+	tasks, _ := c.app.store.ListTasks("amy")
+	id := strconv.Itoa(tasks[0].ID)
+
+	// 換 bob 登入，改不到也刪不掉 amy 的任務
+	c.postForm("/logout", url.Values{})
+	c.signup("bob", "secret")
+	if resp, _ := c.get("/edit?id=" + id); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("編輯別人的任務應該回 404，得到 %d", resp.StatusCode)
+	}
+	c.postForm("/toggle", url.Values{"id": {id}})
+	c.postForm("/delete", url.Values{"id": {id}})
+	task, err := c.app.store.GetTask(tasks[0].ID)
+	if err != nil || task.Completed || !task.DeletedAt.IsZero() {
+		t.Errorf("bob 不應該改動 amy 的任務：%+v（%v）", task, err)
+	}
+}
+
+func TestCSRFRequired(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	_, body := c.get("/")
+
+	resp, _ := c.post("/add", url.Values{
+		"description": {"偽造的任務"},
+		"due_at":      {"2030-01-02T15:04"},
+		"nonce":       {hiddenField(t, body, "nonce")},
+	})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("沒有 csrf_token 應該回 403，得到 %d", resp.StatusCode)
+	}
+	if tasks, _ := c.app.store.ListTasks("amy"); len(tasks) != 0 {
+		t.Error("沒有 csrf_token 的請求不應該新增任務")
+	}
+}
+
+func TestFormNonce(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	_, body := c.get("/")
+	csrf := hiddenField(t, body, "csrf_token")
+	add := func(nonce, desc string) string {
+		c.post("/add", url.Values{"description": {desc}, "due_at": {"2030-01-02T15:04"}, "nonce": {nonce}, "csrf_token": {csrf}})
+		_, body := c.get("/")
+		return body
+	}
+
+	// 重送：用過的 nonce 直接導回，不新增也不顯示錯誤
+	nonce := newNonce("amy")
+	add(nonce, "第一次")
+	if body := add(nonce, "重送"); strings.Contains(body, ErrFormExpired.Message) {
+		t.Error("重送不應該顯示表單過期")
+	}
+	if tasks, _ := c.app.store.ListTasks("amy"); len(tasks) != 1 {
+		t.Errorf("重送不應該新增任務，得到 %d 個", len(tasks))
+	}
+
+	// 不認得的 nonce（例如伺服器重新啟動過）與過期的 nonce 都要提示重新送出
+	for _, expired := range []bool{false, true} {
+		nonce, name := randomToken(16), "不認得"
+		if expired {
+			// 直接放進過期的 nonce：打開頁面時的 newNonce 會清掉過期的項目
+			nonce, name = newNonce("amy"), "過期"
+			formNoncesMu.Lock()
+			formNonces[nonce] = nonceEntry{username: "amy", expires: time.Now().Add(-time.Minute)}
+			formNoncesMu.Unlock()
+		}
+		if body := add(nonce, name); !strings.Contains(body, ErrFormExpired.Message) {
+			t.Errorf("%s的 nonce 應該顯示表單已過期", name)
+		}
+	}
+	if tasks, _ := c.app.store.ListTasks("amy"); len(tasks) != 1 {
+		t.Errorf("nonce 無效時不應該新增任務，得到 %d 個", len(tasks))
+	}
+}
+
+func TestAppsAreIsolated(t *testing.T) {
+	// 每個 App 同時註冊同名的使用者、新增任務並瀏覽各頁，只應該看到自己的資料
+	for _, name := range []string{"甲", "乙", "丙", "丁"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c := newTestApp(t)
+			c.signup("amy", "secret")
+			for i := 0; i < 3; i++ {
+				c.postForm("/add", url.Values{"description": {fmt.Sprintf("%s 的任務 %d", name, i)}, "due_at": {"2030-01-02T15:04"}})
+			}
+			tasks, _ := c.app.store.ListTasks("amy")
+			if len(tasks) != 3 {
+				t.Fatalf("應該只有自己的 3 個任務，得到 %d 個", len(tasks))
+			}
+			for _, path := range []string{"/", "/board", "/calendar", "/search"} {
+				resp, body := c.get(path)
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("%s 應該回 200，得到 %d", path, resp.StatusCode)
+				}
+				for _, other := range []string{"甲", "乙", "丙", "丁"} {
+					if other != name && strings.Contains(body, other+" 的任務") {
+						t.Errorf("%s 不應該出現其他 App 的任務 %q", path, other)
+					}
+				}
+			}
+			if _, body := c.get("/"); !strings.Contains(body, name+" 的任務 0") {
+				t.Error("清單應該有自己的任務")
+			}
+		})
+	}
+}
+
+func TestCalendarHostileDescription(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	hostile := []string{
+		`');alert(1);//`,
+		`"><script>alert(2)</script>`,
+		`\'); alert(3); (\'`,
+		`</div><img src=x onerror=alert(4)>`,
+	}
+	due := time.Now().Format("2006-01-02T15:04")
+	for _, desc := range hostile {
+		c.postForm("/add", url.Values{"description": {desc}, "due_at": {due}})
+	}
+	if tasks, _ := c.app.store.ListTasks("amy"); len(tasks) != len(hostile) {
+		t.Fatalf("預期 %d 個任務，得到 %d 個", len(hostile), len(tasks))
+	}
+
+	resp, body := c.get("/calendar")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("月曆頁回 %d", resp.StatusCode)
+	}
+	for _, bad := range []string{"<script>alert", "<img src=x", "alert(1);//'"} {
+		if strings.Contains(body, bad) {
+			t.Errorf("月曆頁含有未跳脫的 %q", bad)
+		}
+	}
+	if strings.Contains(body, `onclick="showTask`) || regexp.MustCompile(`onclick="[^"]*alert`).MatchString(body) {
+		t.Error("任務內容出現在 onclick 裡")
+	}
+	for _, want := range []string{
+		`data-description="&#39;);alert(1);//"`,
+		`data-description="&#34;&gt;&lt;script&gt;alert(2)&lt;/script&gt;"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("月曆頁沒有以 data 屬性輸出任務內容 %s", want)
+		}
+	}
+}
+
+func TestAllDayTask(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	today := time.Now().Format("2006-01-02")
+
+	// 勾選全天時 datetime-local 的時間部分捨去
+	c.postForm("/add", url.Values{"description": {"交報告"}, "due_at": {today + "T13:00"}, "all_day": {"1"}})
+	tasks, _ := c.app.store.ListTasks("amy")
+	if len(tasks) != 1 {
+		t.Fatalf("預期一個任務，得到 %d 個", len(tasks))
+	}
+	task := tasks[0]
+	if !task.AllDay || task.DueAt.Format("2006-01-02 15:04") != today+" 00:00" {
+		t.Fatalf("全天任務應該是當天零點：%+v", task)
+	}
+	if task.OverdueAt(time.Now()) {
+		t.Error("今天的全天任務在今天結束前不算逾期")
+	}
+	if !task.OverdueAt(task.DueAt.AddDate(0, 0, 1).Add(time.Minute)) {
+		t.Error("隔天之後全天任務應該算逾期")
+	}
+	if _, body := c.get("/"); strings.Contains(body, "你有 1 個逾期任務") || !strings.Contains(body, "今天到期") {
+		t.Error("清單頁應該顯示今天到期且不算逾期")
+	}
+
+	// 取消全天後改回一般任務
+	c.postForm("/edit", url.Values{
+		"id":          {strconv.Itoa(task.ID)},
+		"description": {"交報告"},
+		"due_at":      {today + "T18:30"},
+		"recurrence":  {RecurNone},
+		"priority":    {PriorityMedium},
+	})
+	if task, _ = c.app.store.GetTask(task.ID); task.AllDay || task.DueAt.Format("15:04") != "18:30" {
+		t.Errorf("取消全天後應該有到期時間：%+v", task)
+	}
+}
+
+func TestTaskDependencies(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"寫初稿"}, "due_at": {"2030-01-02T10:00"}})
+	c.postForm("/add", url.Values{"description": {"交報告"}, "due_at": {"2030-01-03T10:00"}})
+	tasks, _ := c.app.store.ListTasks("amy")
+	var draft, report Task
+	for _, task := range tasks {
+		if task.Description == "寫初稿" {
+			draft = task
+		} else {
+			report = task
+		}
+	}
+	edit := func(task Task, blockedBy ...int) (*http.Response, string) {
+		form := url.Values{
+			"id":          {strconv.Itoa(task.ID)},
+			"description": {task.Description},
+			"due_at":      {task.DueAt.Format("2006-01-02T15:04")},
+			"recurrence":  {RecurNone},
+			"priority":    {PriorityMedium},
+		}
+		for _, id := range blockedBy {
+			form.Add("blocked_by", strconv.Itoa(id))
+		}
+		return c.postForm("/edit", form)
+	}
+
+	resp, _ := edit(report, draft.ID)
+	expectRedirect(t, resp, "/")
+	if _, body := c.get("/"); !strings.Contains(body, `badge-blocked"`) {
+		t.Error("清單頁應該顯示被擋住的標記")
+	}
+
+	// 初稿還沒完成，報告不能完成
+	c.postForm("/toggle", url.Values{"id": {strconv.Itoa(report.ID)}})
+	if report, _ = c.app.store.GetTask(report.ID); report.Completed {
+		t.Fatal("等待的任務還沒完成時不應該能完成")
+	}
+	if _, body := c.get("/"); !strings.Contains(body, "還要先完成") {
+		t.Error("應該說明為什麼不能完成")
+	}
+
+	// 初稿改成等待報告會形成循環
+	if resp, body := edit(draft, report.ID); resp.StatusCode != http.StatusOK || !strings.Contains(body, ErrDependencyCycle.Message) {
+		t.Errorf("循環相依應該留在編輯頁並顯示錯誤，得到 %d", resp.StatusCode)
+	}
+	if draft, _ = c.app.store.GetTask(draft.ID); len(draft.BlockedBy) != 0 {
+		t.Errorf("循環相依不應該存檔：%v", draft.BlockedBy)
+	}
+
+	// 完成初稿後提醒報告可以開始，報告也就能完成了
+	c.postForm("/toggle", url.Values{"id": {strconv.Itoa(draft.ID)}})
+	if _, body := c.get("/"); !strings.Contains(body, "可以開始了") || strings.Contains(body, `badge-blocked"`) {
+		t.Error("完成等待的任務後應該提醒可以開始，且不再顯示被擋住")
+	}
+	c.postForm("/toggle", url.Values{"id": {strconv.Itoa(report.ID)}})
+	if report, _ = c.app.store.GetTask(report.ID); !report.Completed {
+		t.Error("等待的任務完成後應該能完成")
+	}
+
+	// 動態記在實際操作的人名下；等待的任務被改回未完成後，再完成會被擋下且不寫入
+	setDone := func(done bool) (Task, error) {
+		return c.app.modifyCompletion(report.ID, func(t *Task) error {
+			t.setCompletedBy(done, time.Now(), "ben")
+			return nil
+		})
+	}
+	if report, err := setDone(false); err != nil || report.Activity[len(report.Activity)-1].User != "ben" {
+		t.Errorf("動態應該記在 ben 名下，得到 %v %+v", err, report.Activity)
+	}
+	c.app.store.ModifyTask(draft.ID, func(t *Task) error { t.setCompleted(false, time.Now()); return nil })
+	if _, err := setDone(true); err == nil || !strings.Contains(err.Error(), "還要先完成") {
+		t.Errorf("等待的任務未完成時應該回報被擋住，得到 %v", err)
+	}
+	if report, _ = c.app.store.GetTask(report.ID); report.Completed {
+		t.Error("被擋住時不應該寫入完成狀態")
+	}
+}
+
+func TestManualSort(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	for i, desc := range []string{"一", "二", "三"} {
+		c.postForm("/add", url.Values{"description": {desc}, "due_at": {"2030-01-0" + strconv.Itoa(i+1) + "T10:00"}})
+	}
+	c.postForm("/sort", url.Values{"sort": {SortManual}})
+	if user, _ := c.app.store.GetUser("amy"); user.SortBy != SortManual {
+		t.Fatalf("排序方式應該存起來，得到 %q", user.SortBy)
+	}
+	order := func() string {
+		_, body := c.get("/")
+		var ids []string
+		for _, m := range regexp.MustCompile(`data-id="(\d+)"`).FindAllStringSubmatch(body, -1) {
+			ids = append(ids, m[1])
+		}
+		return strings.Join(ids, ",")
+	}
+	if got := order(); got != "1,2,3" {
+		t.Fatalf("還沒調整時依到期時間排列，得到 %s", got)
+	}
+	c.postForm("/reorder", url.Values{"id": {"3"}, "direction": {"up"}})
+	if got := order(); got != "1,3,2" {
+		t.Errorf("上移後得到 %s", got)
+	}
+	c.postForm("/reorder", url.Values{"id": {"1"}, "before": {""}})
+	if got := order(); got != "3,2,1" {
+		t.Errorf("拖曳到最後後得到 %s", got)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"買牛奶"}, "due_at": {"2030-01-01T10:00"}})
+	tasks, _ := c.app.store.ListTasks("amy")
+	if len(tasks) != 1 {
+		t.Fatalf("應該有 1 個任務，得到 %d 個", len(tasks))
+	}
+	id := tasks[0].ID
+
+	c.postForm("/undo", url.Values{})
+	if _, err := c.app.store.GetTask(id); err != ErrNotFound {
+		t.Fatal("復原新增後任務應該在垃圾桶裡")
+	}
+	c.postForm("/redo", url.Values{})
+	if _, err := c.app.store.GetTask(id); err != nil {
+		t.Fatal("重做後任務應該回來")
+	}
+
+	c.postForm("/edit", url.Values{
+		"id":          {strconv.Itoa(id)},
+		"description": {"買豆漿"},
+		"due_at":      {"2030-01-01T10:00"},
+		"recurrence":  {RecurNone},
+		"priority":    {PriorityMedium},
+	})
+	c.postForm("/undo", url.Values{})
+	if task, _ := c.app.store.GetTask(id); task.Description != "買牛奶" {
+		t.Errorf("復原編輯後應該改回原本的內容，得到 %q", task.Description)
+	}
+
+	// 之後在別處又改過的任務不復原
+	c.postForm("/redo", url.Values{})
+	c.app.store.ModifyTask(id, func(task *Task) error {
+		task.Description = "別處改的"
+		return nil
+	})
+	c.postForm("/undo", url.Values{})
+	if task, _ := c.app.store.GetTask(id); task.Description != "別處改的" {
+		t.Errorf("任務被改過時不應該復原，得到 %q", task.Description)
+	}
+	if _, body := c.get("/"); !strings.Contains(body, "無法復原") {
+		t.Error("應該說明為什麼不能復原")
+	}
+}
+
+func TestThirdPartyImport(t *testing.T) {
+	due := time.Date(2030, 1, 1, 23, 59, 0, 0, time.Local)
+	todoist := "TYPE,CONTENT,PRIORITY,INDENT,DATE\n" +
+		"section,課業,,,\n" +
+		"task,期末報告 @school,1,1,2030-06-01\n" +
+		"task,找資料,4,2,\n" +
+		"task,運動,4,1,every day\n"
+	records, problems, err := parseTodoistCSV(strings.NewReader(todoist), due)
+	if err != nil || len(records) != 2 || len(problems) != 1 {
+		t.Fatalf("Todoist：得到 %d 筆、問題 %v、錯誤 %v", len(records), problems, err)
+	}
+	report, _ := records[0].toTask("amy", due)
+	if report.Priority != PriorityHigh || !report.AllDay || len(report.Tags) != 1 || len(report.Checklist) != 1 {
+		t.Errorf("期末報告對應錯誤：%+v", report)
+	}
+	if records[1].Recurrence != RecurDaily {
+		t.Errorf("every day 應該是每天重複，得到 %q", records[1].Recurrence)
+	}
+
+	trello := `{"lists":[{"id":"L1"},{"id":"L2","closed":true}],"cards":[
+		{"id":"C1","name":"設計首頁","idList":"L1","dueComplete":true,"labels":[{"name":"Front End"}]},
+		{"id":"C2","name":"舊卡片","idList":"L2"}],
+		"checklists":[{"idCard":"C1","checkItems":[{"name":"畫草圖","state":"complete"}]}]}`
+	records, problems, err = parseTrelloJSON(strings.NewReader(trello), due)
+	if err != nil || len(records) != 1 || len(problems) != 1 {
+		t.Fatalf("Trello：得到 %d 筆、問題 %v、錯誤 %v", len(records), problems, err)
+	}
+	card, _ := records[0].toTask("amy", due)
+	if !card.Completed || card.Tags[0] != "Front-End" || !card.Checklist[0].Done || !card.DueAt.Equal(due) {
+		t.Errorf("卡片對應錯誤：%+v", card)
+	}
+
+	if _, _, err := parseTrelloJSON(strings.NewReader(`{"foo":1}`), due); err == nil {
+		t.Error("不是 Trello 匯出檔時應該回報錯誤")
+	}
+}
+
+func TestAPIToken(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/settings", url.Values{"action": {"apitoken"}, "name": {"筆電"}})
+	_, body := c.get("/settings")
+	token := regexp.MustCompile(`todo_[0-9a-f]+`).FindString(body)
+	if token == "" {
+		t.Fatal("建立後應該顯示一次 token")
+	}
+
+	call := func(path string) int {
+		req, _ := http.NewRequest("GET", c.srv.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		// 不帶 cookie，只靠 token
+		client := &http.Client{CheckRedirect: c.client.CheckRedirect}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := call("/api/v1/tasks"); code != http.StatusOK {
+		t.Fatalf("帶 token 呼叫 API 應該成功，得到 %d", code)
+	}
+	if code := call("/settings"); code != http.StatusSeeOther {
+		t.Error("token 不能用來打開網頁")
+	}
+
+	user, _ := c.app.store.GetUser("amy")
+	c.postForm("/settings", url.Values{"action": {"apitoken-revoke"}, "id": {user.APITokens[0].ID}})
+	if code := call("/api/v1/tasks"); code != http.StatusUnauthorized {
+		t.Errorf("撤銷後應該回 401，得到 %d", code)
+	}
+}
+
+// TestAPICSRFHeader 檢查靠 cookie 登入的 API 請求沒有內容時也要帶 X-CSRF-Token
+func TestAPICSRFHeader(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	task, err := c.app.store.CreateTask(Task{Username: "amy", Description: "寫作業", DueAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	del := func(csrf string) int {
+		req, _ := http.NewRequest("DELETE", c.srv.URL+"/api/v1/tasks/"+strconv.Itoa(task.ID), nil)
+		if csrf != "" {
+			req.Header.Set(csrfHeaderName, csrf)
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := del(""); code != http.StatusForbidden {
+		t.Fatalf("沒有 %s 的 DELETE 應該回 403，得到 %d", csrfHeaderName, code)
+	}
+	if _, err := c.app.store.GetTask(task.ID); err != nil {
+		t.Fatal("被擋下的請求不應該刪掉任務")
+	}
+	_, page := c.get("/")
+	if code := del(hiddenField(t, page, "csrf_token")); code != http.StatusNoContent {
+		t.Errorf("帶正確的 token 應該刪除成功，得到 %d", code)
+	}
+}
+
+func TestDevices(t *testing.T) {
+	laptop := newTestApp(t)
+	laptop.signup("amy", "secret")
+	jar, _ := cookiejar.New(nil)
+	phone := *laptop
+	phone.client = &http.Client{Jar: jar, CheckRedirect: laptop.client.CheckRedirect}
+	phone.post("/login", url.Values{"username": {"amy"}, "password": {"secret"}})
+
+	_, body := laptop.get("/settings")
+	if strings.Count(body, `value="session-revoke"`) != 1 || !strings.Contains(body, "目前的瀏覽器") {
+		t.Fatal("應該列出兩個裝置，目前的瀏覽器不能從清單登出")
+	}
+	id := hiddenField(t, body[strings.Index(body, `value="session-revoke"`):], "id")
+	laptop.postForm("/settings", url.Values{"action": {"session-revoke"}, "id": {id}})
+	if resp, _ := phone.get("/"); resp.StatusCode != http.StatusSeeOther {
+		t.Fatal("被登出的裝置應該要重新登入")
+	}
+	if resp, _ := laptop.get("/"); resp.StatusCode != http.StatusOK {
+		t.Fatal("目前的瀏覽器應該維持登入")
+	}
+
+	resp, _ := laptop.postForm("/settings", url.Values{"action": {"sessions-end-all"}})
+	expectRedirect(t, resp, "/login?signedout=1")
+	if resp, _ := laptop.get("/"); resp.StatusCode != http.StatusSeeOther {
+		t.Fatal("登出所有裝置後目前的瀏覽器也要重新登入")
+	}
+}
+
+func TestReminderOffsets(t *testing.T) {
+	now := time.Date(2030, 1, 1, 9, 0, 0, 0, time.Local)
+	task := Task{ID: 1, Username: "amy", DueAt: now.Add(20 * time.Hour)}
+	offsets := []int{24 * 60, 30}
+	if o, ok := dueReminder(task, offsets, now); !ok || o != 24*60 {
+		t.Fatalf("到期前 20 小時應該送 1 天前的提醒，得到 %d %v", o, ok)
+	}
+	task.RemindedDue, task.RemindedOffset = task.DueAt, 24*60
+	if _, ok := dueReminder(task, offsets, now); ok {
+		t.Error("同一個提醒不應該重複送")
+	}
+	if o, ok := dueReminder(task, offsets, task.DueAt.Add(-10*time.Minute)); !ok || o != 30 {
+		t.Errorf("到期前 10 分鐘應該送 30 分鐘前的提醒，得到 %d %v", o, ok)
+	}
+	if _, ok := dueReminder(task, offsets, task.DueAt.Add(time.Minute)); ok {
+		t.Error("逾期後不應該再送即將到期的提醒")
+	}
+	task.RemindedDue = time.Time{}
+	if o, ok := dueReminder(task, offsets, task.DueAt.Add(-time.Minute)); !ok || o != 30 {
+		t.Errorf("排程停過時只送最接近到期的提醒，得到 %d %v", o, ok)
+	}
+
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"繳報告"}, "due_at": {"2030-01-01T10:00"}})
+	tasks, _ := c.app.store.ListTasks("amy")
+	id := strconv.Itoa(tasks[0].ID)
+	c.postForm("/edit", url.Values{"id": {id}, "description": {"繳報告"}, "due_at": {"2030-01-01T10:00"},
+		"recurrence": {RecurNone}, "priority": {PriorityMedium}, "reminder": {"30", "1440", "30"}})
+	saved, _ := c.app.store.GetTask(tasks[0].ID)
+	if !saved.RemindersSet || len(saved.Reminders) != 2 || saved.Reminders[0] != 1440 {
+		t.Errorf("應該存下自訂的提醒，得到 %v %v", saved.RemindersSet, saved.Reminders)
+	}
+	c.postForm("/settings", url.Values{"action": {"reminders"}})
+	user, _ := c.app.store.GetUser("amy")
+	if !user.RemindersSet || len(user.defaultReminders()) != 0 {
+		t.Errorf("預設提醒應該改成不提醒，得到 %v", user.Reminders)
+	}
+}
+
+func TestPomodoro(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"寫報告"}, "due_at": {"2030-01-01T10:00"}})
+	c.postForm("/focus", url.Values{"action": {"start"}, "work": {"25"}, "break": {"5"}})
+	user, _ := c.app.store.GetUser("amy")
+	if user.Focus == nil || user.Focus.Phase != FocusWork {
+		t.Fatal("沒有指定任務時應該從清單最上面的任務開始")
+	}
+
+	// 工作時間到了：記一顆番茄並進入休息，重複讀取不會多記
+	end := user.Focus.EndsAt
+	later := end.Add(time.Minute)
+	c.app.advanceFocus("amy", later)
+	user, _ = c.app.advanceFocus("amy", later)
+	task, _ := c.app.store.GetTask(user.Focus.TaskID)
+	if user.Focus.Phase != FocusBreak || len(task.Pomodoros) != 1 || !task.Pomodoros[0].Equal(end) {
+		t.Fatalf("應該記下一顆番茄並休息，得到 %+v %v", user.Focus, task.Pomodoros)
+	}
+	if user, _ = c.app.advanceFocus("amy", end.Add(6*time.Minute)); user.Focus != nil {
+		t.Error("休息結束後應該回到待命")
+	}
+	if totals := summarize([]Task{task}, end.Add(-time.Hour), end.Add(time.Hour), later); totals.Pomodoros != 1 {
+		t.Errorf("統計應該算到 1 顆番茄，得到 %d", totals.Pomodoros)
+	}
+	if _, err := c.app.startFocus("amy", task.ID, 0, 5, later); err == nil {
+		t.Error("工作時間不正確時應該拒絕")
+	}
+}
+
+func TestGamification(t *testing.T) {
+	now := time.Date(2030, 1, 10, 20, 0, 0, 0, time.Local)
+	var tasks []Task
+	for i := 0; i < 7; i++ {
+		done := now.AddDate(0, 0, -i-1)
+		tasks = append(tasks, Task{Username: "amy", Completed: true, CompletedAt: done, DueAt: done.Add(time.Hour), Priority: PriorityMedium})
+	}
+	late := now.Add(-time.Hour)
+	tasks = append(tasks, Task{Username: "amy", Completed: true, CompletedAt: late, DueAt: late.Add(-time.Hour), Priority: PriorityHigh})
+	tasks = append(tasks, Task{Username: "bob", Completed: true, CompletedAt: late, DueAt: now})
+
+	game := computeGame(User{Username: "amy"}, tasks, now)
+	if game.Streak != 8 || game.Completed != 8 || game.OnTime != 7 || game.Points != 7*onTimePoints {
+		t.Fatalf("連續天數、完成數或點數不對：%+v", game.gameCounts)
+	}
+	unlocked := map[string]bool{}
+	for _, a := range game.Achievements {
+		unlocked[a.ID] = a.Unlocked()
+	}
+	if !unlocked["first-task"] || !unlocked["streak-7"] || unlocked["complete-100"] {
+		t.Errorf("解鎖的成就不對：%v", unlocked)
+	}
+
+	// 隔兩天沒完成任務就斷了；已解鎖的成就在任務被清掉後仍保留
+	if game := computeGame(User{Username: "amy"}, tasks, now.AddDate(0, 0, 2)); game.Streak != 0 || game.BestStreak != 8 {
+		t.Errorf("斷掉後目前連續應為 0、最長為 8，得到 %d／%d", game.Streak, game.BestStreak)
+	}
+	kept := User{Username: "amy", Achievements: map[string]time.Time{"streak-7": now}}
+	if game := computeGame(kept, nil, now); game.Unlocked != 1 {
+		t.Errorf("記在使用者資料的成就應該保留，得到 %d 個", game.Unlocked)
+	}
+}
+
+// TestSyncAchievementsOnce 同時完成任務的請求一起同步成就，每個成就只會有一個請求通知；請以 go test -race 執行
+func TestSyncAchievementsOnce(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	now := time.Now()
+	if _, err := c.app.store.CreateTask(Task{Username: "amy", Description: "寫作業", DueAt: now.Add(time.Hour), Completed: true, CompletedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	announced := map[string]int{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fresh, _ := c.app.syncAchievements("amy", now)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, ach := range fresh {
+				announced[ach.ID]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	user, _ := c.app.store.GetUser("amy")
+	if announced["first-task"] != 1 || len(announced) != len(user.Achievements) {
+		t.Errorf("每個成就應該只通知一次，得到 %v，記下 %v", announced, user.Achievements)
+	}
+}
+
+func TestOverduePolicy(t *testing.T) {
+	now := time.Date(2030, 1, 10, 0, 0, 30, 0, time.Local)
+	late := Task{Username: "amy", DueAt: time.Date(2030, 1, 8, 15, 0, 0, 0, time.Local), Priority: PriorityLow}
+
+	task := late
+	if !applyOverduePolicy(&task, OverdueRollover, now) || !task.DueAt.Equal(time.Date(2030, 1, 10, 15, 0, 0, 0, time.Local)) {
+		t.Errorf("應該移到今天並保留時間，得到 %v", task.DueAt)
+	}
+	task = late
+	applyOverduePolicy(&task, OverdueEscalate, now)
+	applyOverduePolicy(&task, OverdueEscalate, now)
+	if task.Priority != PriorityHigh || applyOverduePolicy(&task, OverdueEscalate, now) {
+		t.Errorf("每晚提高一級，到高為止，得到 %s", task.Priority)
+	}
+	task = late
+	if !applyOverduePolicy(&task, OverdueMissed, now) || !task.HasTag(missedTag) || applyOverduePolicy(&task, OverdueMissed, now) {
+		t.Errorf("應該只加一次 missed 標籤，得到 %v", task.Tags)
+	}
+	for _, skip := range []Task{
+		{Username: "amy", DueAt: now.Add(time.Hour)},
+		{Username: "amy", DueAt: late.DueAt, Completed: true},
+		{Username: "amy", DueAt: late.DueAt, Recurrence: RecurDaily},
+		{Username: "amy", DueAt: time.Date(2030, 1, 9, 0, 0, 0, 0, time.Local), AllDay: true, Completed: true},
+	} {
+		if applyOverduePolicy(&skip, OverdueRollover, now) {
+			t.Errorf("不應該處理 %+v", skip)
+		}
+	}
+
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"繳報告"}, "due_at": {"2020-01-01T10:00"}})
+	c.postForm("/settings", url.Values{"action": {"overdue"}, "policy": {OverdueMissed}})
+	if err := c.app.applyOverduePolicies(); err != nil {
+		t.Fatal(err)
+	}
+	tasks, _ := c.app.store.ListTasks("amy")
+	if len(tasks) != 1 || !tasks[0].HasTag(missedTag) {
+		t.Errorf("排程應該依使用者的設定處理逾期任務，得到 %+v", tasks)
+	}
+}
+
+func TestPrintWeek(t *testing.T) {
+	from := time.Date(2030, 1, 6, 0, 0, 0, 0, time.Local) // 週日
+	tasks := []Task{
+		{ID: 1, Description: "開會", DueAt: from.AddDate(0, 0, 1).Add(15 * time.Hour)},
+		{ID: 2, Description: "寫週報", DueAt: from.AddDate(0, 0, 1), AllDay: true},
+		{ID: 3, Description: "出差", StartAt: from.AddDate(0, 0, -1), DueAt: from.AddDate(0, 0, 2).Add(9 * time.Hour)},
+		{ID: 4, Description: "下週的事", DueAt: from.AddDate(0, 0, 7)},
+	}
+	days := buildWeekAgenda(tasks, from, from)
+	if !days[0].Today || len(days[0].Items) != 1 || !days[0].Items[0].Span {
+		t.Fatalf("跨日任務在週首應該是進行中，得到 %+v", days[0].Items)
+	}
+	monday := days[1].Items
+	if len(monday) != 3 || monday[0].ID != 2 || !monday[1].Span || monday[2].ID != 1 {
+		t.Errorf("應該依全天、進行中、時間排列，得到 %+v", monday)
+	}
+	if len(days[2].Items) != 1 || days[2].Items[0].Span {
+		t.Errorf("到期日那天是任務本體，得到 %+v", days[2].Items)
+	}
+
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"繳報告"}, "due_at": {"2030-01-08T10:00"}})
+	resp, body := c.get("/print/week?week=2030-W02")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "繳報告") || !strings.Contains(body, "2030-W03") {
+		t.Errorf("週曆應該列出這週的任務與下一週的連結，狀態 %d", resp.StatusCode)
+	}
+	if resp, _ := c.get("/print/week?week=2030-W99"); resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("週次不正確時應該導回月曆，狀態 %d", resp.StatusCode)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	idx := newSearchIndex()
+	idx.put(Task{ID: 1, Username: "amy", Description: "週會報告", Tags: []string{"work"}, Completed: true})
+	idx.put(Task{ID: 2, Username: "amy", Description: "準備週會", Tags: []string{"Work", "workshop"}})
+	idx.put(Task{ID: 3, Username: "amy", Description: "準備週會", Tags: []string{"work"}})
+	idx.put(Task{ID: 4, Username: "bob", Description: "週會", Tags: []string{"work"}})
+
+	tasks, _ := idx.Suggest("amy", "週會", maxSuggestions)
+	if len(tasks) != 2 || tasks[0].ID != 3 || tasks[1].ID != 1 {
+		t.Errorf("未完成的排前面、同樣描述只留最新的一個、不含別人的任務，得到 %+v", tasks)
+	}
+	_, tags := idx.Suggest("amy", "#WOR", maxSuggestions)
+	if len(tags) != 3 || tags[0] != "work" || tags[2] != "workshop" {
+		t.Errorf("標籤應該不分大小寫比對並依使用次數排列，得到 %v", tags)
+	}
+	if tasks, tags := idx.Suggest("amy", "  ", maxSuggestions); tasks != nil || tags != nil {
+		t.Error("空白查詢不應該有建議")
+	}
+}
+
+func TestDuplicateDetection(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Username: "amy", Description: "Buy milk & eggs"},
+		{ID: 2, Username: "amy", Description: "繳交期末報告", Completed: true},
+		{ID: 3, Username: "bob", Description: "寫週報"},
+	}
+	for desc, want := range map[string]int{
+		"ＢＵＹ　ＭＩＬＫ ＆ ＥＧＧＳ":   1, // 全形、大小寫與標點不影響
+		"buy milk and eggs": 0,
+		"Buy milk & egg":    1,
+		"繳交期末報告":            0, // 已完成的不算
+		"寫週報":               0, // 別人的不算
+	} {
+		got, ok := findDuplicate(tasks, "amy", desc)
+		if (want == 0) == ok || (ok && got.ID != want) {
+			t.Errorf("%q：預期 %d，得到 %d %v", desc, want, got.ID, ok)
+		}
+	}
+	if _, ok := duplicateDistance([]rune("abc"), []rune("abd")); ok {
+		t.Error("太短的描述要完全相同才算重複")
+	}
+
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"繳交期末報告"}, "due_at": {"2030-01-01T10:00"}, "priority": {PriorityLow}})
+	form := url.Values{"description": {"繳交期末報告！"}, "due_at": {"2030-01-05T10:00"}, "priority": {PriorityHigh}, "tags": {"school"}}
+	resp, body := c.postForm("/add", form)
+	if resp.StatusCode != http.StatusConflict || !strings.Contains(body, "可能重複") {
+		t.Fatalf("相似的任務應該先確認，狀態 %d", resp.StatusCode)
+	}
+	if tasks, _ := c.app.store.ListTasks("amy"); len(tasks) != 1 {
+		t.Fatalf("確認前不應該新增，得到 %d 個任務", len(tasks))
+	}
+	merge := url.Values{"duplicate": {"merge"}, "existing": {hiddenField(t, body, "existing")}, "return": {"/"}}
+	for k, v := range form {
+		merge[k] = v
+	}
+	merge.Set("nonce", hiddenField(t, body, "nonce"))
+	merge.Set("csrf_token", hiddenField(t, body, "csrf_token"))
+	resp, _ = c.post("/add", merge)
+	expectRedirect(t, resp, "/")
+	saved, _ := c.app.store.ListTasks("amy")
+	if len(saved) != 1 || saved[0].Priority != PriorityHigh || !saved[0].HasTag("school") || saved[0].DueAt.Day() != 5 {
+		t.Errorf("應該更新原本的任務，得到 %+v", saved)
+	}
+	form.Set("duplicate", "add")
+	c.postForm("/add", form)
+	if tasks, _ := c.app.store.ListTasks("amy"); len(tasks) != 2 {
+		t.Errorf("選擇仍要新增時應該新增，得到 %d 個任務", len(tasks))
+	}
+}
+
+func TestCompletionReporting(t *testing.T) {
+	due := time.Date(2030, 1, 10, 12, 0, 0, 0, time.Local)
+	task := Task{ID: 1, Username: "amy", DueAt: due, Checklist: []ChecklistItem{{ID: 1, Text: "草稿"}, {ID: 2, Text: "校對"}}}
+	task.toggleChecklistItem(1)
+	if task.Checklist[0].DoneAt.IsZero() || task.ChecklistProgress() != "1/2" {
+		t.Fatalf("勾選子項目應該記下時間，得到 %+v %s", task.Checklist[0], task.ChecklistProgress())
+	}
+	task.toggleChecklistItem(1)
+	if !task.Checklist[0].DoneAt.IsZero() {
+		t.Error("取消勾選應該清掉時間")
+	}
+
+	task.setCompleted(true, due.Add(time.Hour))
+	if rec := toRecord(task); rec.OnTime == nil || *rec.OnTime || rec.CompletedAt == "" || rec.Checklist != "0/2" {
+		t.Errorf("匯出應該標示逾期完成與子項目進度，得到 %+v", rec)
+	}
+	task.setCompleted(false, due)
+	if rec := toRecord(task); rec.OnTime != nil || rec.CompletedAt != "" {
+		t.Errorf("改回未完成應該清掉完成時間，得到 %+v", rec)
+	}
+
+	onTime := Task{Username: "amy", DueAt: due, Completed: true, CompletedAt: due.Add(-time.Hour)}
+	allDay := Task{Username: "amy", DueAt: startOfDay(due), AllDay: true, Completed: true, CompletedAt: due.Add(8 * time.Hour)}
+	late := Task{Username: "amy", DueAt: due, Completed: true, CompletedAt: due.Add(24 * time.Hour)}
+	unknown := Task{Username: "amy", DueAt: due, Completed: true}
+	totals := summarize([]Task{onTime, allDay, late, unknown}, due.AddDate(0, 0, -7), due.AddDate(0, 0, 7), due)
+	if totals.OnTime != 2 || totals.Late != 1 || totals.Percent() != 67 {
+		t.Errorf("全天任務當天完成算準時，不知道完成時間的不算，得到 %+v", totals.completionRate)
+	}
+}
+
+func TestFlashMessages(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"寫報告"}, "due_at": {"2030-01-02T15:04"}})
+	if _, body := c.get("/search"); !strings.Contains(body, "任務已新增") {
+		t.Fatal("轉址後的頁面應該顯示任務已新增")
+	}
+	if _, body := c.get("/"); strings.Contains(body, "任務已新增") {
+		t.Error("flash 訊息只顯示一次")
+	}
+
+	_, page := c.get("/import")
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("nonce", hiddenField(t, page, "nonce"))
+	mw.WriteField("csrf_token", hiddenField(t, page, "csrf_token"))
+	fw, _ := mw.CreateFormFile("file", "tasks.csv")
+	csv := "description,due_at\n讀第一章,2030-01-03 10:00\n,2030-01-03 10:00\n讀第二章,明天\n讀第三章,0202-01-01\n"
+	for i := 0; i < 2*maxFlashDetails; i++ {
+		csv += "讀附錄,壞掉的日期\n"
+	}
+	io.WriteString(fw, csv)
+	mw.Close()
+	req, _ := http.NewRequest("POST", c.srv.URL+"/import", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Referer", c.srv.URL+"/import")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	expectRedirect(t, resp, "/import")
+
+	_, body := c.get("/import")
+	failed := 3 + 2*maxFlashDetails
+	for _, want := range []string{"已匯入 1 個任務", fmt.Sprintf("%d 列匯入失敗", failed), "第 3 列", fmt.Sprintf("還有 %d 則未列出", failed-maxFlashDetails)} {
+		if !strings.Contains(body, want) {
+			t.Errorf("匯入結果應該顯示「%s」", want)
+		}
+	}
+	if n := strings.Count(body, `class="flash flash-error"`); n != maxFlashDetails+2 {
+		t.Errorf("失敗明細最多列出 %d 則，得到 %d 則錯誤訊息", maxFlashDetails, n)
+	}
+}
+
+// TestConcurrentTaskChanges 同時新增、勾選、刪除並讀取任務；請以 go test -race 執行
+func TestConcurrentTaskChanges(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	_, page := c.get("/")
+	csrf := hiddenField(t, page, "csrf_token")
+	send := func(path string, form url.Values) {
+		form.Set("nonce", newNonce("amy"))
+		form.Set("csrf_token", csrf)
+		c.post(path, form)
+	}
+
+	const n = 8
+	var ids []int
+	for i := 0; i < n; i++ {
+		task, err := c.app.store.CreateTask(Task{Username: "amy", Description: fmt.Sprintf("任務 %d", i), DueAt: time.Now().Add(time.Hour),
+			Checklist: []ChecklistItem{{ID: 1, Text: "一"}, {ID: 2, Text: "二"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		id := strconv.Itoa(id)
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			send("/add", url.Values{"description": {fmt.Sprintf("新的第 %d 件事", i)}, "due_at": {"2030-01-02T15:04"}, "duplicate": {"add"}})
+		}()
+		go func() {
+			defer wg.Done()
+			send("/checklist/toggle", url.Values{"id": {id}, "item": {"1"}})
+			send("/toggle", url.Values{"id": {id}})
+		}()
+		go func() {
+			defer wg.Done()
+			send("/checklist/delete", url.Values{"id": {id}, "item": {"2"}})
+			if i%2 == 0 {
+				send("/delete", url.Values{"id": {id}})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if task, err := c.app.store.GetTask(ids[i]); err == nil {
+					task.ChecklistDone()
+				}
+				c.get("/")
+			}
+		}()
+	}
+	wg.Wait()
+
+	tasks, _ := c.app.store.ListTasks("amy")
+	trash, _ := c.app.store.ListTrash("amy")
+	if len(tasks) != n+n/2 || len(trash) != n/2 {
+		t.Fatalf("預期 %d 個任務、%d 個在垃圾桶，得到 %d、%d", n+n/2, n/2, len(tasks), len(trash))
+	}
+	for _, task := range tasks {
+		if task.Checklist == nil {
+			continue // 新增的任務
+		}
+		if len(task.Checklist) != 1 || !task.Checklist[0].Done {
+			t.Errorf("#%d 的子項目應該只剩勾選過的第一項，得到 %+v", task.ID, task.Checklist)
+		}
+	}
+
+	// fn 回傳錯誤時，改到一半的內容不能留在資料裡
+	c.app.store.ModifyTask(ids[1], func(t *Task) error {
+		t.Checklist[0].Text = "改壞了"
+		t.Tags = append(t.Tags, "壞")
+		return ErrForbidden
+	})
+	if task, _ := c.app.store.GetTask(ids[1]); task.Checklist[0].Text != "一" || len(task.Tags) != 0 {
+		t.Errorf("fn 失敗時不應該寫入，得到 %+v", task)
+	}
+}
+
+// TestConcurrentUserChanges 同時從好幾個請求改同一個使用者的設定，每一筆都要留下來；請以 go test -race 執行
+func TestConcurrentUserChanges(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	_, page := c.get("/")
+	csrf := hiddenField(t, page, "csrf_token")
+	send := func(form url.Values) {
+		form.Set("nonce", newNonce("amy"))
+		form.Set("csrf_token", csrf)
+		c.post("/settings", form)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < maxAPITokens+4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			send(url.Values{"action": {"apitoken"}, "name": {fmt.Sprintf("token %d", i)}})
+		}()
+		go func() {
+			defer wg.Done()
+			send(url.Values{"action": {"taskids"}, "show": {"on"}})
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		send(url.Values{"action": {"email"}, "email": {"amy@example.com"}})
+	}()
+	wg.Wait()
+
+	user, err := c.app.store.GetUser("amy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.APITokens) != maxAPITokens || user.Email != "amy@example.com" || !user.ShowTaskIDs {
+		t.Errorf("預期 %d 組 token 且 Email、任務編號設定都留下來，得到 %d 組、%q、%v", maxAPITokens, len(user.APITokens), user.Email, user.ShowTaskIDs)
+	}
+
+	// 讀出來的使用者是複本，改它不會動到 store 裡的資料
+	user.APITokens[0].Name = "改壞了"
+	if again, _ := c.app.store.GetUser("amy"); again.APITokens[0].Name == "改壞了" {
+		t.Errorf("GetUser 應該回傳複本，得到 %+v", again)
+	}
+}
+
+// TestTaskLinkIndexes 檢查分享與相依的索引跟著新增、修改、丟進垃圾桶與還原更新
+func TestTaskLinkIndexes(t *testing.T) {
+	st := newMemoryStore()
+	shared := func(username string) []int {
+		tasks, err := st.ListSharedWith(username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+	a, _ := st.CreateTask(Task{Username: "amy", Description: "一", SharedWith: []string{"bob"}})
+	b, _ := st.CreateTask(Task{Username: "amy", Description: "二"})
+	st.ModifyTask(b.ID, func(t *Task) error {
+		t.SharedWith = []string{"bob", "cat"}
+		return nil
+	})
+	if got := shared("bob"); !slices.Equal(got, []int{a.ID, b.ID}) {
+		t.Fatalf("bob 應該看到 #%d、#%d，得到 %v", a.ID, b.ID, got)
+	}
+
+	st.ModifyTask(a.ID, func(t *Task) error {
+		t.SharedWith = nil
+		return nil
+	})
+	st.ModifyTask(b.ID, func(t *Task) error {
+		t.DeletedAt = time.Now()
+		return nil
+	})
+	if got := shared("bob"); len(got) != 0 {
+		t.Errorf("取消分享、丟進垃圾桶的任務不應該出現，得到 %v", got)
+	}
+	st.RestoreTask(b.ID)
+	if got := shared("cat"); !slices.Equal(got, []int{b.ID}) {
+		t.Errorf("還原後 cat 應該看到 #%d，得到 %v", b.ID, got)
+	}
+	st.DeleteTask(b.ID)
+	if got := shared("cat"); len(got) != 0 {
+		t.Errorf("刪除的任務不應該出現，得到 %v", got)
+	}
+
+	c, _ := st.CreateTask(Task{Username: "amy", Description: "三", BlockedBy: []int{a.ID}})
+	st.ModifyTask(c.ID, func(t *Task) error {
+		t.BlockedBy = []int{b.ID}
+		return nil
+	})
+	if waiting, _ := st.ListBlockedBy(a.ID); len(waiting) != 0 {
+		t.Errorf("改掉相依後 #%d 不應該還在等 #%d", c.ID, a.ID)
+	}
+	if waiting, _ := st.ListBlockedBy(b.ID); len(waiting) != 1 || waiting[0].ID != c.ID {
+		t.Errorf("#%d 應該在等 #%d，得到 %v", c.ID, b.ID, waiting)
+	}
+}
+
+// TestTaskCacheCopies 檢查快取交出去的是複本，寫入後下次讀取拿到新的資料
+func TestTaskCacheCopies(t *testing.T) {
+	t.Cleanup(func() { taskCache = nil })
+	st := withTaskCache(newMemoryStore(), 1)
+	task, err := st.CreateTask(Task{Username: "amy", Description: "寫作業", Tags: []string{"學校"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, _ := st.ListTasks("amy")
+	tasks[0].Tags[0] = "改壞了"
+	got, _ := st.GetTask(task.ID)
+	got.Tags[0] = "也改壞了"
+	if tasks, _ = st.ListTasks("amy"); tasks[0].Tags[0] != "學校" {
+		t.Fatalf("改讀出來的任務不應該動到快取，得到 %v", tasks[0].Tags)
+	}
+
+	task.Description = "交作業"
+	if err := st.UpdateTask(task); err != nil {
+		t.Fatal(err)
+	}
+	if tasks, _ = st.ListTasks("amy"); tasks[0].Description != "交作業" {
+		t.Errorf("UpdateTask 之後應該讀到新的內容，得到 %q", tasks[0].Description)
+	}
+}
+
+// TestMetricsAccess 檢查 /metrics 沒有 token 時只給本機直接連線，設定 token 後要帶對的 Bearer token
+func TestMetricsAccess(t *testing.T) {
+	c := newTestApp(t)
+	scrape := func(remote string, header map[string]string) int {
+		r := httptest.NewRequest("GET", "/metrics", nil)
+		r.RemoteAddr = remote
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		c.app.metricsHandler(w, r)
+		return w.Code
+	}
+
+	if code := scrape("127.0.0.1:5000", nil); code != http.StatusOK {
+		t.Errorf("本機直接抓取應該回 200，得到 %d", code)
+	}
+	if code := scrape("203.0.113.9:5000", nil); code != http.StatusUnauthorized {
+		t.Errorf("外部來源應該回 401，得到 %d", code)
+	}
+	if code := scrape("127.0.0.1:5000", map[string]string{"X-Forwarded-For": "203.0.113.9"}); code != http.StatusUnauthorized {
+		t.Errorf("經過反向代理的請求應該回 401，得到 %d", code)
+	}
+
+	c.app.metricsToken = "s3cret"
+	if code := scrape("127.0.0.1:5000", nil); code != http.StatusUnauthorized {
+		t.Errorf("設定 token 後沒帶 token 應該回 401，得到 %d", code)
+	}
+	if code := scrape("203.0.113.9:5000", map[string]string{"Authorization": "Bearer s3cret"}); code != http.StatusOK {
+		t.Errorf("帶對的 token 應該回 200，得到 %d", code)
+	}
+}
+
+func TestRestartFlushesStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app_data.json")
+	s, err := openJSONStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.startFlusher(time.Hour) // 測試期間 flusher 不會自己寫回
+	defer s.Close()
+	sched := &jobScheduler{}
+	sched.Add("test", func(now time.Time) time.Time { return now.Add(time.Hour) }, func() error { return nil })
+
+	if _, err := s.CreateTask(Task{Username: "amy", Description: "重新啟動前一刻新增", DueAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := suspendForExec(s, sched); err != nil {
+		t.Fatal(err)
+	}
+	onDisk, err := openJSONStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks, _ := onDisk.ListTasks("amy"); len(tasks) != 1 {
+		t.Fatalf("exec 之前應該已寫回資料檔，得到 %d 個任務", len(tasks))
+	}
+	if err := sched.jobs[0].execute(); err != ErrSchedulerStopped {
+		t.Errorf("exec 之前應該停止排程，得到 %v", err)
+	}
+
+	resumeAfterExec(s, sched)
+	if s.stop == nil {
+		t.Error("exec 失敗後應該恢復延後寫入")
+	}
+	if err := sched.jobs[0].execute(); err != nil {
+		t.Errorf("exec 失敗後應該恢復排程，得到 %v", err)
+	}
+
+	// 暫停、恢復的同時還有請求在寫入；請以 go test -race 執行
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			s.CreateTask(Task{Username: "amy", Description: fmt.Sprintf("第 %d 個", i), DueAt: time.Now()})
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if err := s.Suspend(); err != nil {
+			t.Fatal(err)
+		}
+		s.Resume()
+	}
+	wg.Wait()
+}
+
+func TestFormTimesUseLocalZone(t *testing.T) {
+	// 在測試伺服器關閉之後才還原，避免和還在處理的連線同時存取 time.Local
+	loc := time.Local
+	t.Cleanup(func() { time.Local = loc })
+	time.Local = time.FixedZone("UTC+8", 8*60*60) // 和 -timezone Asia/Taipei 一樣
+
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/add", url.Values{"description": {"早上的會議"}, "due_at": {"2030-01-02T10:00"}})
+	tasks, _ := c.app.store.ListTasks("amy")
+	if len(tasks) != 1 {
+		t.Fatalf("應該新增一個任務，得到 %d 個", len(tasks))
+	}
+	due := tasks[0].DueAt.In(time.Local)
+	if due.Hour() != 10 {
+		t.Errorf("表單的 10:00 應該是設定時區的 10:00，得到 %v", due)
+	}
+	if noon := time.Date(2030, 1, 2, 12, 0, 0, 0, time.Local); !tasks[0].OverdueAt(noon) {
+		t.Error("當地中午時早上十點到期的任務應該已逾期")
+	}
+
+	day, err := parseDueInput("2030-01-02", true)
+	if err != nil || day.Location() != time.Local || day.Hour() != 0 {
+		t.Errorf("全天任務應該是設定時區的零點，得到 %v %v", day, err)
+	}
+	if rec, _ := parseImportTime("2030-01-02 10:00"); rec.In(time.Local).Hour() != 10 {
+		t.Errorf("匯入的時間也應該以設定時區解讀，得到 %v", rec)
+	}
+}
+
+// TestEnglishCatalog 確認程式與模板裡每段要翻譯的中文在英文目錄裡都有，且格式動詞一致
+func TestEnglishCatalog(t *testing.T) {
+	keys := map[string]string{} // 中文 → 出處
+	formatArg := map[string]int{
+		"tr": 1, "translate": 1, "invalidInput": 0, "newMessage": 0, "displayError": 1, "localMessage": 2,
+		"flashSuccess": 1, "flashError": 2, "flashUndo": 2, "flashUndoable": 2, "lineProblem": 3,
+	}
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool { return !strings.HasSuffix(fi.Name(), "_test.go") }, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	literal := func(e ast.Expr) {
+		if lit, ok := e.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			s, _ := strconv.Unquote(lit.Value)
+			keys[s] = fset.Position(lit.Pos()).String()
+		}
+	}
+	for _, f := range pkgs["main"].Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CompositeLit:
+				if id, ok := n.Type.(*ast.Ident); ok && id.Name == "DomainError" {
+					for _, e := range n.Elts {
+						if kv, ok := e.(*ast.KeyValueExpr); ok && kv.Key.(*ast.Ident).Name == "Message" {
+							literal(kv.Value)
+						}
+					}
+				}
+			case *ast.CallExpr:
+				var name string
+				switch fn := n.Fun.(type) {
+				case *ast.Ident:
+					name = fn.Name
+				case *ast.SelectorExpr:
+					name = fn.Sel.Name
+				}
+				if i, ok := formatArg[name]; ok && i < len(n.Args) {
+					literal(n.Args[i])
+				}
+			}
+			return true
+		})
+	}
+	inTemplate := regexp.MustCompile(`(?:\{\{-?|\()\s*T\s+("(?:[^"\\]|\\.)*")`)
+	files, _ := filepath.Glob("templates/*.html")
+	partials, _ := filepath.Glob("templates/partials/*.html")
+	for _, name := range append(files, partials...) {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range inTemplate.FindAllStringSubmatch(string(b), -1) {
+			s, _ := strconv.Unquote(m[1])
+			keys[s] = name
+		}
+	}
+
+	// 模板以 {{T .Label}} 翻譯、程式裡以變數傳給 tr 的選項名稱
+	for _, opt := range statusOptions {
+		keys[opt.Label] = "statusOptions"
+	}
+	for _, opt := range priorityOptions {
+		keys[opt.Label] = "priorityOptions"
+	}
+	for _, opt := range recurrenceOptions {
+		keys[opt.Label] = "recurrenceOptions"
+	}
+	for _, opt := range overdueOptions {
+		keys[opt.Label] = "overdueOptions"
+	}
+	for _, opt := range sortOptions {
+		keys[opt.Label] = "sortOptions"
+	}
+	for _, action := range linkActions {
+		keys[action.Label], keys[action.Done] = "linkActions", "linkActions"
+	}
+	for _, ach := range achievements {
+		keys[ach.Name], keys[ach.Description] = "achievements", "achievements"
+	}
+	for _, q := range consoleQueries {
+		keys[q.Label] = "consoleQueries"
+	}
+	for _, role := range []string{"", RoleTeacher, RoleAdmin} {
+		keys[roleLabel(role)] = "roleLabel"
+	}
+	for _, kind := range []string{ActivityEdited, ActivityCompleted, ActivityReopened, ActivityComment} {
+		keys[activityLabel(kind)] = "activityLabel"
+	}
+
+	verbs := regexp.MustCompile(`%(?:\[(\d+)\])?[-+# 0]*[\d.]*([a-zA-Z%])`)
+	signature := func(s string) string {
+		var sig []string
+		n := 0
+		for _, m := range verbs.FindAllStringSubmatch(s, -1) {
+			if m[2] == "%" {
+				continue
+			}
+			if m[1] != "" {
+				n, _ = strconv.Atoi(m[1])
+			} else {
+				n++
+			}
+			sig = append(sig, fmt.Sprintf("%d%s", n, m[2]))
+		}
+		slices.Sort(sig)
+		return strings.Join(sig, " ")
+	}
+	delete(keys, "") // 沒有 fallback 的 flashError 等
+	for key, where := range keys {
+		en, ok := catalogEn[key]
+		if !ok {
+			t.Errorf("%s：英文目錄缺少 %q", where, key)
+		} else if signature(en) != signature(key) {
+			t.Errorf("%q 的英文 %q 格式動詞不一致", key, en)
+		}
+	}
+}
+
+func TestEnglishPagesAndMessages(t *testing.T) {
+	c := newTestApp(t)
+	c.signup("amy", "secret")
+	c.postForm("/settings", url.Values{"action": {"datefmt"}, "locale": {LocaleEn}})
+
+	c.postForm("/add", url.Values{"description": {"寫期末報告"}, "due_at": {"2030-01-02T15:04"}})
+	_, body := c.get("/")
+	if !strings.Contains(body, "Task added") || strings.Contains(body, "任務已新增") {
+		t.Error("選了英文後，新增任務的訊息應該是英文")
+	}
+	if !strings.Contains(body, "寫期末報告") {
+		t.Error("任務內容不應該被翻譯")
+	}
+
+	c.postForm("/add", url.Values{"description": {"沒有到期時間"}, "due_at": {"明天"}})
+	if _, body = c.get("/"); !strings.Contains(body, "Invalid date") {
+		t.Error("選了英文後，錯誤訊息應該是英文")
+	}
+	if _, body = c.get("/trash"); !strings.Contains(body, "The trash is empty") {
+		t.Error("選了英文後，垃圾桶頁應該是英文")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+)
+
+// --- 封存 ---
+//
+// 已完成的任務可以一次封存，封存後不出現在清單與月曆，改在 /archive 瀏覽；
+// 搜尋、匯出與 API 仍看得到。從封存頁還原會回到清單，完成狀態不變
+
+// withoutArchived 過濾掉已封存的任務，給清單與月曆用
+func withoutArchived(tasks []Task) []Task {
+	var result []Task
+	for _, t := range tasks {
+		if !t.Archived {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+func (a *App) archiveHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+
+	if r.Method == "POST" {
+		switch r.FormValue("action") {
+		case "archive-completed":
+			a.archiveCompleted(r, username)
+		case "restore":
+			a.unarchiveTask(r, username)
+		}
+		redirectBack(w, r)
+		return
+	}
+
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	var archived []Task
+	for _, t := range tasks {
+		if t.Archived {
+			archived = append(archived, t)
+		}
+	}
+	sort.Slice(archived, func(i, j int) bool { return archived[i].CompletedAt.After(archived[j].CompletedAt) })
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Tasks":     archived,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("archive"))
+	t.Execute(w, data)
+}
+
+// archiveCompleted 封存使用者所有已完成的任務
+func (a *App) archiveCompleted(r *http.Request, username string) {
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		a.flashError(r, err, "封存失敗，請稍後再試")
+		return
+	}
+	count := 0
+	for _, t := range tasks {
+		if !t.Completed || t.Archived {
+			continue
+		}
+		_, err := a.store.ModifyTask(t.ID, func(task *Task) error {
+			if !task.Completed || task.Username != username {
+				return ErrNotFound // 讀取後被改回未完成或轉派，就不封存
+			}
+			task.Archived = true
+			return nil
+		})
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			a.flashError(r, err, "封存失敗，請稍後再試")
+			return
+		}
+		count++
+	}
+	if count == 0 {
+		a.flashSuccess(r, "沒有可以封存的已完成任務")
+		return
+	}
+	a.flashSuccess(r, "已封存 %d 個已完成任務", count)
+}
+
+func (a *App) unarchiveTask(r *http.Request, username string) {
+	id, err := formID(r, "id")
+	if err != nil {
+		a.flashError(r, err, "")
+		return
+	}
+	task, err := a.store.ModifyTask(id, func(task *Task) error {
+		if task.Username != username || !task.Archived {
+			return ErrNotFound
+		}
+		task.Archived = false
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "還原任務失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "已將「%s」還原到清單", brief(task.Description))
+}
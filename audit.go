@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// --- 稽核紀錄 ---
+//
+// 合併帳號這類會動到其他帳號資料的操作，每筆以一行 JSON 附加到 -audit-log 指定的檔案，
+// 同時寫進 log；檔案路徑空白時只寫 log
+
+var (
+	auditLogPath string
+	auditMu      sync.Mutex
+)
+
+type auditEntry struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	Detail string    `json:"detail"`
+}
+
+// recordAudit 記下 actor 做了 action；寫檔失敗只記 log，不影響已完成的操作
+func recordAudit(actor, action, detail string) {
+	entry := auditEntry{Time: time.Now(), Actor: actor, Action: action, Detail: detail}
+	log.Printf("稽核：%s %s %s", actor, action, detail)
+	if auditLogPath == "" {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("寫入稽核紀錄失敗：%v", err)
+		return
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, err := os.OpenFile(auditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("寫入稽核紀錄失敗：%v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("寫入稽核紀錄失敗：%v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// --- 帳號密碼驗證 ---
+//
+// 所有需要密碼的地方（登入、API、改密碼、刪除與合併帳號）都透過 auth.Verify：
+// 找不到使用者、帳號停用或還沒設定密碼時，仍拿 dummyHash 跑一次同樣成本的 bcrypt，
+// 回應時間與回傳的錯誤都和密碼錯誤一樣，無法藉此猜出帳號是否存在。
+// 同一個使用者名稱從同一個 IP 連續失敗 authMaxFailures 次，就鎖定這個 IP 對這個帳號的嘗試一段時間，
+// 別人從其他地方亂試不會把本人鎖在門外；另外不分 IP 累計失敗 authMaxUserFailures 次時整個帳號也鎖定，
+// 擋下換 IP 的分散猜測。每多鎖一輪時間加倍，鎖定期間連正確的密碼也不接受；計數只存在記憶體。
+// 每次驗證的結果都寫進稽核紀錄，鎖定中被拒絕的嘗試也是
+
+const (
+	authMaxFailures     = 5  // 同一個 IP 對同一個帳號
+	authMaxUserFailures = 50 // 同一個帳號，不分 IP
+	authBaseLockout     = time.Minute
+	authMaxLockout      = 30 * time.Minute
+	authMaxTracked      = 10000 // 超過時清掉沒有鎖定中的紀錄，避免隨機帳號名稱把記憶體塞滿
+)
+
+var (
+	ErrBadCredentials = &DomainError{Code: "bad_credentials", Message: "使用者名稱或密碼錯誤", Status: http.StatusUnauthorized}
+	ErrAuthLocked     = &DomainError{Code: "too_many_attempts", Message: "嘗試次數過多，請稍後再試", Status: http.StatusTooManyRequests}
+)
+
+// authKey 是失敗計數的對象：使用者名稱與來源 IP；ip 為空字串時是不分 IP 的累計。
+// 使用者名稱和 GetUser 一樣大小寫有別，試 "AMY" 不會把 "amy" 鎖在門外
+type authKey struct {
+	username string
+	ip       string
+}
+
+// authFailures 是一個 authKey 的失敗紀錄；rounds 是已經鎖定過幾輪，決定下一次鎖多久
+type authFailures struct {
+	count       int
+	rounds      int
+	lockedUntil time.Time
+}
+
+type authService struct {
+	store    Store
+	mu       sync.Mutex
+	failures map[authKey]*authFailures
+
+	// dummyHash 是沒有人知道密碼的雜湊，啟動時就算好，第一次比對不會比較慢
+	dummyHash string
+}
+
+func newAuthService(store Store) *authService {
+	return &authService{
+		store:     store,
+		failures:  make(map[authKey]*authFailures),
+		dummyHash: hashPassword(randomToken(16)),
+	}
+}
+
+// Verify 驗證帳號密碼；purpose 記在稽核紀錄裡（login、api-login、change-password 等）。
+// 驗證失敗一律回傳 ErrBadCredentials，鎖定中回傳 ErrAuthLocked，其他錯誤是儲存層的問題
+func (a *authService) Verify(r *http.Request, purpose, username, password string) (User, error) {
+	now := time.Now()
+	fromIP, anyIP := authKey{username, remoteHost(r)}, authKey{username, ""}
+	if a.isLocked(fromIP, now) || a.isLocked(anyIP, now) {
+		a.audit(r, purpose, username, "rejected, locked")
+		return User{}, ErrAuthLocked
+	}
+
+	user, err := a.store.GetUser(username)
+	if err != nil && err != ErrNotFound {
+		return User{}, err
+	}
+	outcome := "ok"
+	stored := user.PasswordHash
+	switch {
+	case err == ErrNotFound:
+		outcome, stored = "no-such-user", a.dummyHash
+	case user.Disabled:
+		outcome = "disabled"
+	case stored == "":
+		outcome, stored = "no-password", a.dummyHash
+	}
+	if !strings.HasPrefix(stored, passwordPrefix) {
+		verifyPassword(a.dummyHash, password) // 舊格式的雜湊和 bcrypt 的成本不同，補上一次讓回應時間差不多
+	}
+	ok, needsRehash := verifyPassword(stored, password)
+	if outcome == "ok" && !ok {
+		outcome = "wrong-password"
+	}
+
+	if outcome != "ok" {
+		lockedIP := a.fail(fromIP, authMaxFailures, now)
+		lockedUser := a.fail(anyIP, authMaxUserFailures, now)
+		switch {
+		case lockedUser:
+			outcome += ", locked for all addresses"
+		case lockedIP:
+			outcome += ", locked"
+		}
+		a.audit(r, purpose, username, outcome)
+		return User{}, ErrBadCredentials
+	}
+	a.succeed(fromIP, anyIP)
+	if needsRehash {
+		old, hash := user.PasswordHash, hashPassword(password)
+		_, err := a.store.ModifyUser(user.Username, func(u *User) error {
+			if u.PasswordHash == old { // 期間改過密碼就不要蓋回舊密碼
+				u.PasswordHash = hash
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("升級 %s 的密碼雜湊失敗：%v", username, err)
+		}
+	}
+	a.audit(r, purpose, username, outcome)
+	return user, nil
+}
+
+func (a *authService) isLocked(key authKey, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, ok := a.failures[key]
+	return ok && now.Before(f.lockedUntil)
+}
+
+// fail 記下 key 的一次失敗，累計達到 limit 時開始鎖定並回傳 true
+func (a *authService) fail(key authKey, limit int, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.failures) >= authMaxTracked {
+		for k, f := range a.failures {
+			if !now.Before(f.lockedUntil) {
+				delete(a.failures, k)
+			}
+		}
+	}
+	f, ok := a.failures[key]
+	if !ok {
+		f = &authFailures{}
+		a.failures[key] = f
+	}
+	f.count++
+	if f.count < limit {
+		return false
+	}
+	lockout := authBaseLockout << uint(f.rounds)
+	if f.rounds > 10 || lockout > authMaxLockout {
+		lockout = authMaxLockout
+	}
+	f.count = 0
+	f.rounds++
+	f.lockedUntil = now.Add(lockout)
+	return true
+}
+
+func (a *authService) succeed(keys ...authKey) {
+	a.mu.Lock()
+	for _, key := range keys {
+		delete(a.failures, key)
+	}
+	a.mu.Unlock()
+}
+
+// audit 記錄驗證結果；使用者名稱是使用者輸入的，去掉控制字元並截短再寫進紀錄
+func (a *authService) audit(r *http.Request, purpose, username, outcome string) {
+	actor := strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, username)
+	for utf8.RuneCountInString(actor) > 64 {
+		_, size := utf8.DecodeLastRuneInString(actor)
+		actor = actor[:len(actor)-size]
+	}
+	recordAudit(actor, "auth-"+purpose, outcome+" from "+remoteHost(r))
+}
+
+// remoteHost 是請求來源的 IP，不含連接埠
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,24 @@
+//go:build autocert
+
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// 以 go build -tags autocert 編譯時才引入 Let's Encrypt 的自動憑證，
+// 預設建置不包含 ACME 用戶端
+func init() {
+	autocertManager = func(hosts []string, cacheDir, email string) (*tls.Config, func(http.Handler) http.Handler) {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(hosts...),
+			Cache:      autocert.DirCache(cacheDir),
+			Email:      email,
+		}
+		return m.TLSConfig(), m.HTTPHandler
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- 備份驗證 ---
+//
+// JSON 資料檔每次存檔都會輪替出舊版本（app_data.json.1、.2…，見 store_json.go），但備份壞了通常要到
+// 需要還原時才發現。backup-verify 每天清晨把最新的備份複製到暫存目錄、當成資料檔開起來，
+// 再跑一次完整性檢查：孤兒資料（與管理主控台的查詢相同）、重複的編號、已被用掉的下一個編號。
+// 結果顯示在管理主控台；失敗時通知所有管理員，之後恢復正常時再通知一次，平常成功不寄信。
+// 只有 -store json 且 -backups 大於 0 時才有這個工作
+
+// backupReportRows 是通知信裡最多列出的問題數，其餘請到管理主控台看
+const backupReportRows = 20
+
+// backupCheck 是一次備份驗證的結果
+type backupCheck struct {
+	CheckedAt  time.Time
+	Backup     string    // 檢查的備份檔
+	BackupTime time.Time // 備份檔的修改時間
+	Users      int
+	Tasks      int
+	Problems   consoleResult // 完整性檢查發現的問題，沒有問題時 Rows 為空
+	Err        string        // 找不到備份或打不開時的錯誤
+}
+
+// OK 表示備份可以還原且沒有發現問題
+func (c backupCheck) OK() bool {
+	return c.Err == "" && len(c.Problems.Rows) == 0
+}
+
+var lastBackupCheck struct {
+	sync.Mutex
+	result *backupCheck // 尚未檢查過為 nil
+}
+
+// latestBackupCheck 回傳上一次備份驗證的結果，給管理主控台顯示
+func latestBackupCheck() *backupCheck {
+	lastBackupCheck.Lock()
+	defer lastBackupCheck.Unlock()
+	return lastBackupCheck.result
+}
+
+// nextBackupCheck 是每天清晨 4 點，避開半夜的排程工作
+func nextBackupCheck(now time.Time) time.Time {
+	return nextMidnight(now.Add(-4 * time.Hour)).Add(4 * time.Hour)
+}
+
+// verifyBackupJob 回傳驗證 dataPath 最新備份的排程工作
+func (a *App) verifyBackupJob(dataPath string) func() error {
+	return func() error {
+		if _, err := os.Stat(dataPath); os.IsNotExist(err) {
+			return nil // 還沒存過檔，也就還沒有備份
+		}
+		check := verifyBackup(dataPath+".1", time.Now())
+		lastBackupCheck.Lock()
+		previous := lastBackupCheck.result
+		lastBackupCheck.result = &check
+		lastBackupCheck.Unlock()
+
+		if !check.OK() || (previous != nil && !previous.OK()) {
+			a.reportBackupCheck(check)
+		}
+		if !check.OK() {
+			return fmt.Errorf("備份 %s 驗證失敗：%s", check.Backup, check.summary())
+		}
+		return nil
+	}
+}
+
+// verifyBackup 把 backup 複製到暫存目錄還原後檢查，原本的備份檔不會被動到
+func verifyBackup(backup string, now time.Time) backupCheck {
+	check := backupCheck{CheckedAt: now, Backup: backup}
+	info, err := os.Stat(backup)
+	if err != nil {
+		check.Err = "找不到備份檔：" + err.Error()
+		return check
+	}
+	check.BackupTime = info.ModTime()
+
+	dir, err := os.MkdirTemp("", "todo-backup-check-")
+	if err != nil {
+		check.Err = "無法建立暫存目錄：" + err.Error()
+		return check
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := os.ReadFile(backup)
+	if err == nil {
+		err = writeFileSync(filepath.Join(dir, "app_data.json"), data)
+	}
+	if err != nil {
+		check.Err = "無法複製備份檔：" + err.Error()
+		return check
+	}
+	restored, err := openJSONStore(filepath.Join(dir, "app_data.json"))
+	if err != nil {
+		check.Err = "備份檔無法還原：" + err.Error()
+		return check
+	}
+
+	check.Users = len(restored.data.Users)
+	check.Tasks = len(restored.data.Tasks)
+	if check.Problems, err = checkIntegrity(restored); err != nil {
+		check.Err = "完整性檢查失敗：" + err.Error()
+	}
+	return check
+}
+
+// checkIntegrity 是還原後的完整性檢查：孤兒資料，加上任務、專案、公告的編號是否重複，
+// 以及下一個編號是否已經被用掉（用這份備份還原後新增的資料會撞號）
+func checkIntegrity(s *jsonStore) (consoleResult, error) {
+	result, err := findOrphans(s)
+	if err != nil {
+		return result, err
+	}
+	add := func(kind string, id int, format string, args ...interface{}) {
+		result.Rows = append(result.Rows, []string{kind, strconv.Itoa(id), fmt.Sprintf(format, args...)})
+	}
+	if len(s.data.Users) == 0 {
+		result.Rows = append(result.Rows, []string{"使用者", "", "備份裡沒有任何使用者"})
+	}
+
+	checkIDs := func(kind string, ids []int, next int) {
+		seen := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			if seen[id] {
+				add(kind, id, "編號重複")
+			}
+			seen[id] = true
+			if id >= next {
+				add(kind, id, "編號不小於下一個編號 %d", next)
+			}
+		}
+	}
+	var ids []int
+	for _, t := range s.data.Tasks {
+		ids = append(ids, t.ID)
+	}
+	checkIDs("任務", ids, s.data.NextID)
+	ids = nil
+	for _, p := range s.data.Projects {
+		ids = append(ids, p.ID)
+	}
+	checkIDs("專案", ids, max(s.data.NextProjectID, 1))
+	ids = nil
+	for _, a := range s.data.Announcements {
+		ids = append(ids, a.ID)
+	}
+	checkIDs("公告", ids, max(s.data.NextAnnouncementID, 1))
+	return result, nil
+}
+
+// summary 是一行的結果說明，用在信件主旨與排程工作的錯誤
+func (c backupCheck) summary() string {
+	switch {
+	case c.Err != "":
+		return c.Err
+	case len(c.Problems.Rows) > 0:
+		return fmt.Sprintf("發現 %d 個問題", len(c.Problems.Rows))
+	}
+	return fmt.Sprintf("可以還原（%d 位使用者、%d 個任務）", c.Users, c.Tasks)
+}
+
+// reportBackupCheck 把驗證結果通知管理員
+func (a *App) reportBackupCheck(c backupCheck) {
+	subject := "備份驗證失敗：" + c.summary()
+	if c.OK() {
+		subject = "備份驗證恢復正常：" + c.summary()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n備份檔：%s\n", subject, c.Backup)
+	if !c.BackupTime.IsZero() {
+		fmt.Fprintf(&b, "備份時間：%s\n", c.BackupTime.Format("2006-01-02 15:04:05"))
+	}
+	for i, row := range c.Problems.Rows {
+		if i == backupReportRows {
+			fmt.Fprintf(&b, "…還有 %d 個問題\n", len(c.Problems.Rows)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- %s\n", strings.Join(row, " "))
+	}
+	b.WriteString("\n詳細結果請見管理主控台。\n")
+	a.notifyAdmins(subject, b.String(), pushMessage{Title: "🗄️ " + subject, Body: "請到管理主控台查看備份驗證結果", Tag: "backup-verify", URL: "/admin/console"})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- 多日新增 ---
+//
+// 月曆上拖曳（或按住 Ctrl／⌘ 點選）選取多天後送到 /add/batch：
+// mode=each 在每一天各新增一個相同的任務，mode=span 新增一個從第一天開始、最後一天到期的任務。
+// 所有任務透過 CreateTasks 一起寫入
+
+const maxBatchDays = 62
+
+// parseBatchDates 讀取表單中選取的日期，排序並去掉重複
+func parseBatchDates(r *http.Request) ([]time.Time, error) {
+	values := r.Form["dates"]
+	if len(values) == 0 {
+		return nil, invalidInput("請先在月曆上選取日期")
+	}
+	seen := make(map[string]bool)
+	var days []time.Time
+	for _, v := range values {
+		day, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return nil, ErrInvalidDueDate
+		}
+		if err := checkDueAt(day); err != nil {
+			return nil, err
+		}
+		if !seen[v] {
+			seen[v] = true
+			days = append(days, day)
+		}
+	}
+	if len(days) > maxBatchDays {
+		return nil, invalidInput("一次最多選取 %d 天", maxBatchDays)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+func (a *App) batchAddHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	username := a.getUsername(r)
+	now := time.Now()
+
+	desc := strings.TrimSpace(r.FormValue("description"))
+	if err := checkDescription(desc); err != nil {
+		a.flashError(r, err, "")
+		redirectBack(w, r)
+		return
+	}
+	clock, err := time.ParseInLocation("15:04", r.FormValue("time"), time.Local)
+	if err != nil {
+		a.flashError(r, invalidInput("請選擇到期時間"), "")
+		redirectBack(w, r)
+		return
+	}
+	days, err := parseBatchDates(r)
+	if err != nil {
+		a.flashError(r, err, "")
+		redirectBack(w, r)
+		return
+	}
+	priority := r.FormValue("priority")
+	if !validPriority(priority) {
+		priority = PriorityMedium
+	}
+	base := Task{
+		Description: desc,
+		CreatedAt:   now,
+		Username:    username,
+		Priority:    priority,
+		Tags:        parseTags(r.FormValue("tags")),
+	}
+
+	var tasks []Task
+	switch r.FormValue("mode") {
+	case "span":
+		if len(days) < 2 {
+			a.flashError(r, invalidInput("跨日任務至少要選取兩天"), "")
+			redirectBack(w, r)
+			return
+		}
+		task := base
+		task.StartAt = days[0]
+		task.DueAt = onDay(clock, days[len(days)-1])
+		tasks = []Task{task}
+	default:
+		for _, day := range days {
+			task := base
+			task.Tags = append([]string(nil), base.Tags...)
+			task.DueAt = onDay(clock, day)
+			tasks = append(tasks, task)
+		}
+	}
+
+	created, err := a.store.CreateTasks(tasks)
+	if err != nil {
+		a.flashError(r, err, "新增任務失敗，請稍後再試")
+		redirectBack(w, r)
+		return
+	}
+	if len(created) == 1 {
+		a.flashSuccess(r, "任務已新增")
+	} else {
+		a.flashSuccess(r, "已在 %d 天各新增一個「%s」", len(created), brief(desc))
+	}
+	for _, task := range created {
+		if a.warnConflicts(r, username, task) {
+			break // 只提醒第一個排得太滿的日子，避免一次跳出一大串
+		}
+	}
+	redirectBack(w, r)
+}
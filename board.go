@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// --- 看板 ---
+//
+// /board 把任務分成待辦、進行中、已完成三欄，可以拖曳卡片或按按鈕移動。
+// 完成與否仍以 Completed 為準，Status 只多記了「進行中」；移到已完成欄等同勾選完成
+
+const (
+	StatusTodo  = "todo"
+	StatusDoing = "doing"
+	StatusDone  = "done"
+)
+
+// statusOptions 依看板欄位的順序排列
+var statusOptions = []struct {
+	Value string
+	Label string
+}{
+	{StatusTodo, "待辦"},
+	{StatusDoing, "進行中"},
+	{StatusDone, "已完成"},
+}
+
+func validStatus(s string) bool {
+	for _, opt := range statusOptions {
+		if opt.Value == s {
+			return true
+		}
+	}
+	return false
+}
+
+func statusLabel(s string) string {
+	for _, opt := range statusOptions {
+		if opt.Value == s {
+			return opt.Label
+		}
+	}
+	return s
+}
+
+// EffectiveStatus 回傳任務所在的欄位；舊資料沒有 Status，依 Completed 判斷
+func (t Task) EffectiveStatus() string {
+	switch {
+	case t.Completed:
+		return StatusDone
+	case t.Status == StatusDoing:
+		return StatusDoing
+	default:
+		return StatusTodo
+	}
+}
+
+// setStatus 移動到指定欄位，同步完成狀態
+func (t *Task) setStatus(status string, now time.Time) {
+	t.setCompleted(status == StatusDone, now)
+	t.Status = status
+}
+
+// boardColumn 是看板上的一欄
+type boardColumn struct {
+	Status string
+	Label  string
+	Tasks  []Task
+}
+
+func (a *App) boardHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	now := time.Now()
+
+	if r.Method == "POST" {
+		a.moveTask(r, username, now)
+		redirectBack(w, r)
+		return
+	}
+
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	columns := make([]boardColumn, len(statusOptions))
+	index := make(map[string]int)
+	for i, opt := range statusOptions {
+		columns[i] = boardColumn{Status: opt.Value, Label: opt.Label}
+		index[opt.Value] = i
+	}
+	for _, t := range withoutArchived(tasks) {
+		i := index[t.EffectiveStatus()]
+		columns[i].Tasks = append(columns[i].Tasks, t)
+	}
+	for i := range columns {
+		list := columns[i].Tasks
+		if columns[i].Status == StatusDone {
+			sort.Slice(list, func(i, j int) bool { return list[i].CompletedAt.After(list[j].CompletedAt) })
+		} else {
+			smartSort(list, now)
+		}
+	}
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Columns":   columns,
+		"Statuses":  statusOptions,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("board"))
+	t.Execute(w, data)
+}
+
+// moveTask 把任務移到表單指定的欄位；移到已完成的重複任務與勾選完成一樣排定下一次
+func (a *App) moveTask(r *http.Request, username string, now time.Time) {
+	id, err := formID(r, "id")
+	if err != nil {
+		a.flashError(r, err, "")
+		return
+	}
+	status := r.FormValue("status")
+	if !validStatus(status) {
+		a.flashError(r, invalidInput("不支援的看板欄位"), "")
+		return
+	}
+	if current, err := a.store.GetTask(id); err == nil && current.Username == username && status == StatusDone {
+		if err := a.checkUnblocked(current); err != nil {
+			a.flashError(r, err, "")
+			return
+		}
+	}
+	var wasCompleted bool
+	task, err := a.store.ModifyTask(id, func(t *Task) error {
+		if t.Username != username {
+			return ErrNotFound
+		}
+		wasCompleted = t.Completed
+		before := *t
+		t.setStatus(status, now)
+		t.recordEdit(username, before, now)
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "移動任務失敗，請稍後再試")
+		return
+	}
+	if task.Completed && !wasCompleted && task.Recurrence != RecurNone {
+		if err := a.spawnNextOccurrence(task.ID); err != nil {
+			a.flashError(r, err, "產生下一次重複任務失敗")
+		} else {
+			a.flashSuccess(r, "已排定下一次「%s」", brief(task.Description))
+		}
+	}
+	if task.Completed && !wasCompleted {
+		a.announceUnblocked(r, username, task)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 批次操作 ---
+//
+// 清單頁勾選多個任務後一次完成、刪除、改期或改標籤。所有變更透過 ModifyTasks
+// 一起寫入，其中一個任務不屬於自己或已不存在時整批都不套用
+
+const maxBulkTasks = 500
+
+// parseBulkIDs 讀取表單中勾選的任務 ID，去掉重複
+func parseBulkIDs(r *http.Request) ([]int, error) {
+	values := r.Form["ids"]
+	if len(values) == 0 {
+		return nil, invalidInput("請先勾選任務")
+	}
+	if len(values) > maxBulkTasks {
+		return nil, invalidInput("一次最多只能處理 %d 個任務", maxBulkTasks)
+	}
+	seen := make(map[int]bool)
+	var ids []int
+	for _, v := range values {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, invalidInput("任務編號不正確")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// bulkChange 依表單的 action 產生要套用到每個任務的修改
+func bulkChange(r *http.Request, now time.Time) (func(*Task) error, message, error) {
+	switch r.FormValue("action") {
+	case "complete":
+		return func(t *Task) error {
+			t.setCompleted(true, now)
+			return nil
+		}, newMessage("已完成"), nil
+	case "delete":
+		return func(t *Task) error {
+			t.DeletedAt = now
+			return nil
+		}, newMessage("已移到垃圾桶"), nil
+	case "reschedule":
+		// 改到指定的日期，保留各任務原本的時間
+		day, err := time.ParseInLocation("2006-01-02", r.FormValue("due_date"), time.Local)
+		if err != nil {
+			return nil, message{}, invalidInput("請選擇新的到期日")
+		}
+		if err := checkDueAt(day); err != nil {
+			return nil, message{}, err
+		}
+		return func(t *Task) error {
+			t.DueAt = onDay(t.DueAt, day)
+			return nil
+		}, newMessage("已改期到 %s", r.FormValue("due_date")), nil
+	case "retag":
+		add, remove := parseTags(r.FormValue("add_tags")), parseTags(r.FormValue("remove_tags"))
+		if len(add) == 0 && len(remove) == 0 {
+			return nil, message{}, invalidInput("請輸入要加上或移除的標籤")
+		}
+		removed := make(map[string]bool)
+		for _, tag := range remove {
+			removed[strings.ToLower(tag)] = true
+		}
+		return func(t *Task) error {
+			var tags []string
+			for _, tag := range t.Tags {
+				if !removed[strings.ToLower(tag)] {
+					tags = append(tags, tag)
+				}
+			}
+			t.Tags = normalizeTags(append(tags, add...))
+			return nil
+		}, newMessage("已更新標籤"), nil
+	}
+	return nil, message{}, invalidInput("不支援的批次操作")
+}
+
+func (a *App) bulkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	username := a.getUsername(r)
+	now := time.Now()
+
+	ids, err := parseBulkIDs(r)
+	if err != nil {
+		a.flashError(r, err, "")
+		redirectBack(w, r)
+		return
+	}
+	change, done, err := bulkChange(r, now)
+	if err != nil {
+		a.flashError(r, err, "")
+		redirectBack(w, r)
+		return
+	}
+
+	if r.FormValue("action") == "complete" {
+		if err := a.checkBatchUnblocked(ids); err != nil {
+			a.flashError(r, err, "")
+			redirectBack(w, r)
+			return
+		}
+	}
+
+	var wasCompleted = make(map[int]bool)
+	tasks, err := a.store.ModifyTasks(ids, func(t *Task) error {
+		if t.Username != username {
+			return ErrNotFound
+		}
+		wasCompleted[t.ID] = t.Completed
+		before := *t
+		if err := change(t); err != nil {
+			return err
+		}
+		t.recordEdit(username, before, now)
+		return nil
+	})
+	if err == ErrNotFound {
+		a.flashError(r, invalidInput("部分任務已不存在，請重新整理後再試，這次沒有套用任何變更"), "")
+		redirectBack(w, r)
+		return
+	}
+	if err != nil {
+		a.flashError(r, err, "批次操作失敗，請稍後再試，這次沒有套用任何變更")
+		redirectBack(w, r)
+		return
+	}
+	a.flashSuccess(r, "%d 個任務%s", len(tasks), done)
+
+	// 剛完成的重複任務各自排定下一次，與單筆勾選完成時相同
+	var completed []Task
+	for _, t := range tasks {
+		if t.Completed && !wasCompleted[t.ID] {
+			completed = append(completed, t)
+		}
+		if t.Completed && !wasCompleted[t.ID] && t.Recurrence != RecurNone {
+			if err := a.spawnNextOccurrence(t.ID); err != nil {
+				a.flashError(r, err, "產生下一次重複任務失敗")
+			}
+		}
+	}
+	a.announceUnblocked(r, username, completed...)
+	redirectBack(w, r)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"html/template"
+	"net/url"
+)
+
+// --- 月曆篩選 ---
+//
+// 月曆上方的工具列可以只顯示某些優先順序或某個標籤的任務（?priority=high&priority=low&tag=work），
+// 換月、跳週時帶著同樣的條件。篩選只影響顯示，拖曳改期與批次新增照常運作
+
+// calendarFilter 的 Priorities 為空表示不限優先順序，Tag 為空表示不限標籤
+type calendarFilter struct {
+	Priorities map[string]bool
+	Tag        string
+}
+
+func parseCalendarFilter(q url.Values) calendarFilter {
+	f := calendarFilter{Priorities: make(map[string]bool)}
+	for _, p := range q["priority"] {
+		if validPriority(p) {
+			f.Priorities[p] = true
+		}
+	}
+	if len(f.Priorities) == len(priorityOptions) {
+		f.Priorities = map[string]bool{} // 全選等於不篩選
+	}
+	if tags := normalizeTags([]string{q.Get("tag")}); len(tags) == 1 {
+		f.Tag = tags[0]
+	}
+	return f
+}
+
+func (f calendarFilter) Active() bool {
+	return len(f.Priorities) > 0 || f.Tag != ""
+}
+
+func (f calendarFilter) Match(t Task) bool {
+	if len(f.Priorities) > 0 && !f.Priorities[effectivePriority(t.Priority)] {
+		return false
+	}
+	return f.Tag == "" || t.HasTag(f.Tag)
+}
+
+// Query 是接在月曆連結後面的篩選條件（以 & 開頭），沒有篩選時是空字串
+func (f calendarFilter) Query() template.URL {
+	q := url.Values{}
+	for _, opt := range priorityOptions {
+		if f.Priorities[opt.Value] {
+			q.Add("priority", opt.Value)
+		}
+	}
+	if f.Tag != "" {
+		q.Set("tag", f.Tag)
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return template.URL("&" + q.Encode())
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 月曆格子 ---
+//
+// buildCalendarMonth 把任務排進月曆的 42 格（6 週 × 7 天，從使用者設定的週首開始），
+// 月曆頁的模板與 /api/v1/calendar 共用同一份結構，其他前端或 PWA 可以直接照著畫出同樣的月曆。
+// 跨日任務在開始日到到期前一天各有一格 Span 的延伸，到期日那格才是本體
+
+const calendarGridDays = 42
+
+// calendarMonth 是一個月的月曆；Hidden 是被篩選條件藏起來的任務數
+type calendarMonth struct {
+	Year      int            `json:"year"`
+	Month     int            `json:"month"`
+	Weekdays  []string       `json:"weekdays"`
+	Weeks     []calendarWeek `json:"weeks"`
+	PrevYear  int            `json:"prev_year"`
+	PrevMonth int            `json:"prev_month"`
+	NextYear  int            `json:"next_year"`
+	NextMonth int            `json:"next_month"`
+	Hidden    int            `json:"hidden"`
+}
+
+// calendarWeek 的 Label 是 ISO 週次（2024-W19），Focus 是以 ?week= 跳過來時要標示的那一週
+type calendarWeek struct {
+	Label  string        `json:"label"`
+	Number string        `json:"number"`
+	Focus  bool          `json:"focus"`
+	Days   []calendarDay `json:"days"`
+}
+
+// calendarDay 的 Class 是 other-month（不在這個月）、today 或空字串；
+// AllDay 是排在格子最上面的全天任務，Tasks 是跨日任務的延伸與有時間的任務
+type calendarDay struct {
+	Day    int            `json:"day"`
+	Date   string         `json:"date"`
+	Class  string         `json:"class"`
+	AllDay []calendarTask `json:"all_day"`
+	Tasks  []calendarTask `json:"tasks"`
+}
+
+// calendarTask 是格子裡的一個任務；Title 是滑過時顯示的內容加標籤
+type calendarTask struct {
+	ID          int       `json:"id"`
+	Description string    `json:"description"`
+	Title       string    `json:"title"`
+	Completed   bool      `json:"completed"`
+	DueAt       time.Time `json:"due_at"`
+	AllDay      bool      `json:"all_day"`
+	IsOverdue   bool      `json:"overdue"`
+	Priority    string    `json:"priority"`
+	Tags        []string  `json:"tags"`
+	Span        bool      `json:"span"`
+}
+
+func calendarChip(task Task, span bool, now time.Time) calendarTask {
+	title := task.Description
+	if len(task.Tags) > 0 {
+		title += " #" + strings.Join(task.Tags, " #")
+	}
+	tags := task.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return calendarTask{
+		ID:          task.ID,
+		Description: task.Description,
+		Title:       title,
+		Completed:   task.Completed,
+		DueAt:       task.DueAt,
+		AllDay:      task.AllDay,
+		IsOverdue:   !span && task.OverdueAt(now),
+		Priority:    effectivePriority(task.Priority),
+		Tags:        tags,
+		Span:        span,
+	}
+}
+
+// buildCalendarMonth 排出 year 年 month 月的月曆；tasks 應已排除封存的任務
+func buildCalendarMonth(tasks []Task, prefs DatePrefs, year, month int, focusWeek string, filter calendarFilter, now time.Time) calendarMonth {
+	cal := calendarMonth{Year: year, Month: month, Weekdays: prefs.Weekdays()}
+	if filter.Active() {
+		shown := tasks[:0:0]
+		for _, task := range tasks {
+			if filter.Match(task) {
+				shown = append(shown, task)
+			} else {
+				cal.Hidden++
+			}
+		}
+		tasks = shown
+	}
+
+	firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	startDate := prefs.StartOfWeek(firstDay)
+
+	// 先依到期日分組，42 格各自查表，不必每格都掃一次全部任務
+	byDate := make(map[string][]Task)
+	for _, task := range tasks {
+		key := task.DueAt.Format("2006-01-02")
+		byDate[key] = append(byDate[key], task)
+	}
+	// 跨日任務在開始日到到期前一天也各顯示一格，到期日那格才是可以拖曳改期的本體
+	spanning := make(map[string][]Task)
+	gridEnd := startDate.AddDate(0, 0, calendarGridDays).Format("2006-01-02")
+	for _, task := range tasks {
+		if task.StartAt.IsZero() {
+			continue
+		}
+		due := task.DueAt.Format("2006-01-02")
+		d := task.StartAt
+		if d.Before(startDate) {
+			d = startDate
+		}
+		for key := d.Format("2006-01-02"); key < due && key < gridEnd; key = d.Format("2006-01-02") {
+			spanning[key] = append(spanning[key], task)
+			d = d.AddDate(0, 0, 1)
+		}
+	}
+
+	var days []calendarDay
+	currentDate := startDate
+	for i := 0; i < calendarGridDays; i++ {
+		key := currentDate.Format("2006-01-02")
+		day := calendarDay{Day: currentDate.Day(), Date: key, AllDay: []calendarTask{}, Tasks: []calendarTask{}}
+		for _, task := range spanning[key] {
+			day.Tasks = append(day.Tasks, calendarChip(task, true, now))
+		}
+		for _, task := range byDate[key] {
+			if task.AllDay {
+				day.AllDay = append(day.AllDay, calendarChip(task, false, now))
+			} else {
+				day.Tasks = append(day.Tasks, calendarChip(task, false, now))
+			}
+		}
+		if currentDate.Year() != year || int(currentDate.Month()) != month {
+			day.Class = "other-month"
+		}
+		if key == now.Format("2006-01-02") {
+			day.Class = "today"
+		}
+		days = append(days, day)
+
+		// 一列七天；週次取該列的星期一，週日開始時星期一是第二格
+		if len(days) == 7 {
+			label := isoWeekLabel(currentDate.AddDate(0, 0, -6+(int(time.Monday)-int(prefs.WeekStart)+7)%7))
+			cal.Weeks = append(cal.Weeks, calendarWeek{
+				Label:  label,
+				Number: label[len(label)-2:],
+				Focus:  label == focusWeek,
+				Days:   days,
+			})
+			days = nil
+		}
+		currentDate = currentDate.AddDate(0, 0, 1)
+	}
+
+	prev := firstDay.AddDate(0, -1, 0)
+	next := firstDay.AddDate(0, 1, 0)
+	cal.PrevYear, cal.PrevMonth = prev.Year(), int(prev.Month())
+	cal.NextYear, cal.NextMonth = next.Year(), int(next.Month())
+	return cal
+}
+
+// apiCalendarHandler：GET /api/v1/calendar?year=&month= 回傳月曆頁同樣的 42 格，
+// 也接受 week=2024-W19 與月曆頁的 priority、tag 篩選；沒有指定年月時是這個月
+func (a *App) apiCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	username := a.getUsername(r)
+	q := r.URL.Query()
+	now := time.Now()
+	year, month := now.Year(), int(now.Month())
+	focusWeek := ""
+
+	if q.Get("year") != "" || q.Get("month") != "" {
+		y, errY := strconv.Atoi(q.Get("year"))
+		m, errM := strconv.Atoi(q.Get("month"))
+		if errY != nil || errM != nil || y < 1 || y > 9999 || m < 1 || m > 12 {
+			writeAPIError(w, http.StatusBadRequest, "year 與 month 必須一起指定，month 為 1 到 12")
+			return
+		}
+		year, month = y, m
+	}
+	if week := q.Get("week"); week != "" {
+		monday, err := parseISOWeek(week, time.Local)
+		if err != nil {
+			writeDomainError(w, err, "")
+			return
+		}
+		thursday := monday.AddDate(0, 0, 3)
+		year, month = thursday.Year(), int(thursday.Month())
+		focusWeek = isoWeekLabel(monday)
+	}
+
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "讀取任務失敗")
+		return
+	}
+	cal := buildCalendarMonth(withoutArchived(tasks), a.datePrefsFor(username), year, month, focusWeek, parseCalendarFilter(q), now)
+	writeJSON(w, http.StatusOK, cal)
+}
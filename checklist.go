@@ -0,0 +1,187 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 子任務（檢查清單） ---
+//
+// 子項目直接存在任務裡，不是獨立的任務；全部勾選時母任務自動完成，
+// 已完成的母任務有子項目被取消勾選時會重新打開。勾選時記下 DoneAt，取消勾選時清掉
+
+const maxChecklistItems = 50
+
+type ChecklistItem struct {
+	ID     int       `json:"id"`
+	Text   string    `json:"text"`
+	Done   bool      `json:"done"`
+	DoneAt time.Time `json:"done_at"` // 未勾選或匯入時不知道勾選時間為零值
+}
+
+var errChecklistFull = invalidInput("每個任務最多 %d 個子項目", maxChecklistItems)
+
+// ChecklistDone 回傳已完成的子項目數，給列表顯示進度
+func (t Task) ChecklistDone() int {
+	n := 0
+	for _, item := range t.Checklist {
+		if item.Done {
+			n++
+		}
+	}
+	return n
+}
+
+// ChecklistProgress 是子項目進度，例如 2/5；沒有子項目時是空字串
+func (t Task) ChecklistProgress() string {
+	if len(t.Checklist) == 0 {
+		return ""
+	}
+	return strconv.Itoa(t.ChecklistDone()) + "/" + strconv.Itoa(len(t.Checklist))
+}
+
+func (t *Task) addChecklistItem(text string) error {
+	if len(t.Checklist) >= maxChecklistItems {
+		return errChecklistFull
+	}
+	next := 1
+	for _, item := range t.Checklist {
+		if item.ID >= next {
+			next = item.ID + 1
+		}
+	}
+	t.Checklist = append(t.Checklist, ChecklistItem{ID: next, Text: text})
+	return nil
+}
+
+// toggleChecklistItem 切換子項目並同步母任務的完成狀態，
+// autoCompleted 表示這次切換讓母任務變成完成
+func (t *Task) toggleChecklistItem(itemID int) (autoCompleted bool, err error) {
+	for i := range t.Checklist {
+		if t.Checklist[i].ID != itemID {
+			continue
+		}
+		now := time.Now()
+		t.Checklist[i].Done = !t.Checklist[i].Done
+		t.Checklist[i].DoneAt = time.Time{}
+		if t.Checklist[i].Done {
+			t.Checklist[i].DoneAt = now
+		}
+		switch {
+		case !t.Checklist[i].Done:
+			t.setCompleted(false, now)
+		case !t.Completed && t.ChecklistDone() == len(t.Checklist):
+			t.setCompleted(true, now)
+			autoCompleted = true
+		}
+		return autoCompleted, nil
+	}
+	return false, ErrNotFound
+}
+
+func (t *Task) deleteChecklistItem(itemID int) error {
+	for i, item := range t.Checklist {
+		if item.ID == itemID {
+			t.Checklist = append(t.Checklist[:i], t.Checklist[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// resetChecklist 複製一份全部未勾選的清單，給下一次的重複任務使用
+func resetChecklist(items []ChecklistItem) []ChecklistItem {
+	if len(items) == 0 {
+		return nil
+	}
+	result := make([]ChecklistItem, len(items))
+	for i, item := range items {
+		result[i] = ChecklistItem{ID: item.ID, Text: item.Text}
+	}
+	return result
+}
+
+// modifyOwnTask 是子項目操作共用的 ModifyTask 包裝，只允許修改自己的或分享給自己的任務
+func (a *App) modifyOwnTask(id int, username string, fn func(*Task) error) (Task, error) {
+	return a.store.ModifyTask(id, func(t *Task) error {
+		if !t.canEdit(username) {
+			return ErrNotFound
+		}
+		return fn(t)
+	})
+}
+
+func (a *App) checklistAddHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	text := strings.TrimSpace(r.FormValue("text"))
+	if text == "" {
+		a.flashError(r, invalidInput("子項目內容不可為空白"), "")
+		redirectBack(w, r)
+		return
+	}
+
+	_, err := a.modifyOwnTask(id, username, func(t *Task) error {
+		return t.addChecklistItem(text)
+	})
+	if err != nil && err != ErrNotFound {
+		a.flashError(r, err, "新增子項目失敗，請稍後再試")
+	}
+	redirectBack(w, r)
+}
+
+func (a *App) checklistToggleHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := a.requireFormID(w, r, "item")
+	if !ok {
+		return
+	}
+
+	var autoCompleted bool
+	task, err := a.modifyOwnTask(id, username, func(t *Task) error {
+		var err error
+		autoCompleted, err = t.toggleChecklistItem(itemID)
+		return err
+	})
+	if err != nil && err != ErrNotFound {
+		a.flashError(r, err, "更新子項目失敗，請稍後再試")
+	}
+	if err == nil && autoCompleted {
+		a.flashSuccess(r, "子項目全部完成，「%s」已標記為完成", brief(task.Description))
+		if task.Recurrence != RecurNone {
+			if err := a.spawnNextOccurrence(task.ID); err != nil {
+				a.flashError(r, err, "產生下一次重複任務失敗")
+			}
+		}
+	}
+	redirectBack(w, r)
+}
+
+func (a *App) checklistDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := a.requireFormID(w, r, "item")
+	if !ok {
+		return
+	}
+
+	_, err := a.modifyOwnTask(id, username, func(t *Task) error {
+		return t.deleteChecklistItem(itemID)
+	})
+	if err != nil && err != ErrNotFound {
+		a.flashError(r, err, "刪除子項目失敗，請稍後再試")
+	}
+	redirectBack(w, r)
+}
@@ -0,0 +1,315 @@
+// todo 是待辦清單的命令列工具，透過伺服器的 JSON API（/api/v1）新增、列出、完成與匯出任務。
+//
+// 先在網頁的「設定 → API token」建立一組 token，再設定環境變數：
+//
+//	export TODO_SERVER=https://todo.example.com   # 預設 http://localhost:8080
+//	export TODO_TOKEN=todo_...
+//
+// 用法：
+//
+//	todo add [-due "2024-06-01 14:00"] [-force] 期末報告 #課業 !high
+//	todo list [-today | -week | -all]
+//	todo done <編號>...
+//	todo export [-format csv|json|obsidian] [-o 檔名]
+//
+// 新增的文字支援與網頁相同的 #標籤、!high／!medium／!low 與 @專案 寫法；沒有 -due 時是今天 23:59。
+// 已有描述相似的未完成任務時伺服器會拒絕新增，加上 -force 仍要新增
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usage = `用法：todo [-server 網址] [-token token] <指令> [參數]
+
+指令：
+  add [-due 時間] [-force] <內容>
+                              新增任務，時間格式 2024-06-01 14:00 或 2024-06-01；
+                              -force 在已有相似的未完成任務時仍要新增
+  list [-today|-week|-all]    列出任務，預設只列未完成的
+  done <編號>...              把任務標記為完成
+  export [-format csv|json|obsidian] [-o 檔名]
+                              匯出所有任務，預設 CSV 輸出到標準輸出
+
+伺服器與 token 也可以用環境變數 TODO_SERVER、TODO_TOKEN 設定
+`
+
+// task 是 API 回傳的任務裡用得到的欄位
+type task struct {
+	ID          int       `json:"id"`
+	Description string    `json:"description"`
+	Completed   bool      `json:"completed"`
+	DueAt       time.Time `json:"due_at"`
+	AllDay      bool      `json:"all_day"`
+	Priority    string    `json:"priority"`
+	Tags        []string  `json:"tags"`
+}
+
+type client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// apiError 是 API 的錯誤回應
+type apiError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+func main() {
+	global := flag.NewFlagSet("todo", flag.ExitOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	server := global.String("server", envOr("TODO_SERVER", "http://localhost:8080"), "伺服器網址")
+	token := global.String("token", os.Getenv("TODO_TOKEN"), "API token")
+	global.Parse(os.Args[1:])
+	if global.NArg() == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+	if *token == "" {
+		fail(errors.New("請先在網頁的「設定 → API token」建立 token，並設定環境變數 TODO_TOKEN"))
+	}
+	c := &client{
+		server: strings.TrimRight(*server, "/"),
+		token:  *token,
+		http:   &http.Client{Timeout: 30 * time.Second},
+	}
+
+	args := global.Args()
+	var err error
+	switch args[0] {
+	case "add":
+		err = c.add(args[1:])
+	case "list", "ls":
+		err = c.list(args[1:])
+	case "done":
+		err = c.done(args[1:])
+	case "export":
+		err = c.export(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "不認得的指令 %q\n\n", args[0])
+		global.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "todo:", err)
+	os.Exit(1)
+}
+
+// do 送出請求；in 不為 nil 時以 JSON 送出，out 不為 nil 時解析 JSON 回應
+func (c *client) do(method, path string, in, out interface{}) error {
+	resp, err := c.send(method, path, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send 送出請求並檢查狀態碼，呼叫端負責關閉回應
+func (c *client) send(method, path string, in interface{}) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, c.server+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var e apiError
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return nil, errors.New(e.Error)
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, errors.New("token 無效或已撤銷")
+		}
+		return nil, fmt.Errorf("伺服器回應 %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *client) add(args []string) error {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	due := fs.String("due", "", "到期時間，例如 2024-06-01 14:00")
+	force := fs.Bool("force", false, "已有相似的未完成任務時仍要新增")
+	fs.Parse(args)
+	text := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(text) == "" {
+		return errors.New("請輸入任務內容，例如：todo add 期末報告 #課業")
+	}
+	in := map[string]interface{}{"text": text, "allow_duplicate": *force}
+	if *due != "" {
+		t, err := parseDue(*due)
+		if err != nil {
+			return err
+		}
+		in["due_at"] = t
+	}
+	var created task
+	if err := c.do("POST", "/api/v1/tasks/quick", in, &created); err != nil {
+		return err
+	}
+	fmt.Printf("已新增 #%d %s（到期 %s）\n", created.ID, created.Description, formatDue(created))
+	return nil
+}
+
+// parseDue 接受 2024-06-01 14:00、2024-06-01T14:00 與只有日期（當天 23:59）
+func parseDue(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.Add(23*time.Hour + 59*time.Minute), nil
+	}
+	return time.Time{}, fmt.Errorf("看不懂到期時間 %q，請用 2024-06-01 14:00 的格式", s)
+}
+
+func (c *client) list(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	today := fs.Bool("today", false, "只列出今天到期的任務")
+	week := fs.Bool("week", false, "只列出本週到期的任務")
+	all := fs.Bool("all", false, "包含已完成的任務")
+	fs.Parse(args)
+
+	filter := "incomplete"
+	switch {
+	case *today:
+		filter = "today"
+	case *week:
+		filter = "week"
+	case *all:
+		filter = ""
+	}
+	var tasks []task
+	if err := c.do("GET", "/api/v1/tasks?filter="+filter, nil, &tasks); err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		fmt.Println("沒有任務 🎉")
+		return nil
+	}
+	now := time.Now()
+	for _, t := range tasks {
+		box := "[ ]"
+		if t.Completed {
+			box = "[x]"
+		}
+		line := fmt.Sprintf("%4d %s %s  %s", t.ID, box, formatDue(t), t.Description)
+		if t.Priority == "high" {
+			line += " !"
+		}
+		for _, tag := range t.Tags {
+			line += " #" + tag
+		}
+		if !t.Completed && deadline(t).Before(now) {
+			line += "（逾期）"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func formatDue(t task) string {
+	if t.AllDay {
+		return t.DueAt.Local().Format("01-02      ")
+	}
+	return t.DueAt.Local().Format("01-02 15:04")
+}
+
+// deadline 與伺服器相同：全天任務到當天結束才算逾期
+func deadline(t task) time.Time {
+	if t.AllDay {
+		return t.DueAt.AddDate(0, 0, 1)
+	}
+	return t.DueAt
+}
+
+func (c *client) done(args []string) error {
+	if len(args) == 0 {
+		return errors.New("請指定任務編號，例如：todo done 12")
+	}
+	failed := 0
+	for _, arg := range args {
+		id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+		if err != nil {
+			return fmt.Errorf("任務編號 %q 不正確", arg)
+		}
+		var t task
+		if err := c.do("PUT", "/api/v1/tasks/"+strconv.Itoa(id), map[string]bool{"completed": true}, &t); err != nil {
+			fmt.Fprintf(os.Stderr, "#%d：%v\n", id, err)
+			failed++
+			continue
+		}
+		fmt.Printf("已完成 #%d %s\n", t.ID, t.Description)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 個任務沒有完成", failed)
+	}
+	return nil
+}
+
+func (c *client) export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "csv", "csv、json 或 obsidian（zip）")
+	output := fs.String("o", "", "輸出的檔名，預設為標準輸出")
+	fs.Parse(args)
+
+	resp, err := c.send("GET", "/api/v1/export?format="+*format, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
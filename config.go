@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// --- 啟動設定 ---
+//
+// 每個啟動參數都可以從三個地方設定，優先順序由高到低：命令列、環境變數、設定檔，都沒有時用預設值。
+// 環境變數是參數名稱轉大寫、- 換成 _ 再加上 TODO_ 前綴，例如 -session-ttl 對應 TODO_SESSION_TTL；
+// 另外為了 PaaS 慣例，沒有設定 TODO_LISTEN 時也接受 PORT（只給埠號）。
+// 設定檔由 -config 或 TODO_CONFIG 指定，每行一個「名稱 = 值」，名稱與命令列參數相同，# 開頭是註解。
+// 密碼與 client secret 不放在這裡，仍只從各自的環境變數讀取（SMTP_PASSWORD 等），避免出現在 ps 或設定檔裡
+
+const configEnvPrefix = "TODO_"
+
+// configEnvName 是參數對應的環境變數名稱
+func configEnvName(name string) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadConfig 在 flag.Parse 之後呼叫，把命令列沒有指定的參數依序以環境變數、設定檔補上
+func loadConfig(fs *flag.FlagSet, path string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	fromEnv := make(map[string]bool)
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] || f.Name == "config" {
+			return
+		}
+		value, ok := os.LookupEnv(configEnvName(f.Name))
+		if !ok && f.Name == "listen" {
+			if port := os.Getenv("PORT"); port != "" {
+				value, ok = ":"+port, true
+			}
+		}
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("環境變數 %s：%v", configEnvName(f.Name), setErr)
+			return
+		}
+		fromEnv[f.Name] = true
+	})
+	if err != nil || path == "" {
+		return err
+	}
+	return loadConfigFile(fs, path, func(name string) bool { return explicit[name] || fromEnv[name] })
+}
+
+// loadConfigFile 讀取設定檔；skip 回傳 true 的參數已由命令列或環境變數指定，檔案裡的值不採用
+func loadConfigFile(fs *flag.FlagSet, path string, skip func(name string) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(text, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return fmt.Errorf("%s 第 %d 行：格式必須是「名稱 = 值」", path, line)
+		}
+		if fs.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("%s 第 %d 行：未知的設定 %q", path, line, name)
+		}
+		if skip(name) {
+			continue
+		}
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s 第 %d 行：%s：%v", path, line, name, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// setTimezone 把伺服器的預設時區（time.Local）換成 name，例如 Asia/Taipei；空字串表示沿用系統時區。
+// 所有以 time.Local 解讀的時間（表單輸入、提醒、每日摘要的「今天」）都跟著改變
+func setTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("未知的時區 %q：%v", name, err)
+	}
+	time.Local = loc
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// --- 排程衝突提醒 ---
+//
+// 新增或編輯任務後，如果同一個小時或同一天已經有太多未完成的任務到期，
+// 用 flash 提醒並附上當天任務的連結。門檻可在設定頁調整，
+// User 欄位為 0 時用預設值，負數代表關閉
+
+const (
+	defaultConflictHour = 2
+	defaultConflictDay  = 5
+	maxConflictLimit    = 20
+
+	// dayFilterPrefix 是清單頁只看某一天任務的過濾條件，例如 day:2024-05-01
+	dayFilterPrefix = "day:"
+)
+
+// conflictLimits 回傳同一小時、同一天的門檻，0 代表不檢查
+func (u User) conflictLimits() (hour, day int) {
+	hour, day = u.ConflictHour, u.ConflictDay
+	if hour == 0 {
+		hour = defaultConflictHour
+	}
+	if day == 0 {
+		day = defaultConflictDay
+	}
+	if hour < 0 {
+		hour = 0
+	}
+	if day < 0 {
+		day = 0
+	}
+	return hour, day
+}
+
+// dueConflicts 計算除了 task 本身以外，同一小時與同一天到期的未完成任務數；全天任務沒有時間，只算同一天
+func dueConflicts(tasks []Task, task Task) (sameHour, sameDay int) {
+	hour := task.DueAt.Truncate(time.Hour)
+	day := task.DueAt.Format("2006-01-02")
+	for _, t := range tasks {
+		if t.ID == task.ID || t.Completed || t.Archived {
+			continue
+		}
+		if t.DueAt.Format("2006-01-02") != day {
+			continue
+		}
+		sameDay++
+		if !task.AllDay && !t.AllDay && t.DueAt.Truncate(time.Hour).Equal(hour) {
+			sameHour++
+		}
+	}
+	return sameHour, sameDay
+}
+
+// warnConflicts 在 task 存檔後檢查是否排得太滿，超過門檻時加上提醒並回傳 true；只提醒最嚴重的一項
+func (a *App) warnConflicts(r *http.Request, username string, task Task) bool {
+	user, err := a.store.GetUser(username)
+	if err != nil {
+		return false
+	}
+	hourLimit, dayLimit := user.conflictLimits()
+	if hourLimit == 0 && dayLimit == 0 {
+		return false
+	}
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		return false
+	}
+	sameHour, sameDay := dueConflicts(tasks, task)
+	prefs := a.requestDatePrefs(r)
+
+	var msg string
+	switch {
+	case hourLimit > 0 && sameHour >= hourLimit:
+		msg = a.tr(r, "⚠️ %s 這個小時已經有 %d 個任務到期，小心排得太滿", prefs.Short(task.DueAt.Truncate(time.Hour)), sameHour)
+	case dayLimit > 0 && sameDay >= dayLimit:
+		msg = a.tr(r, "⚠️ %s 這天已經有 %d 個任務到期，小心排得太滿", prefs.Date(task.DueAt), sameDay)
+	default:
+		return false
+	}
+	a.sessions.pushFlash(r, Flash{
+		Kind:    FlashWarning,
+		Message: msg,
+		Link:    &FlashLink{URL: "/?filter=" + dayFilterPrefix + task.DueAt.Format("2006-01-02"), Label: a.tr(r, "查看當天任務")},
+	})
+	return true
+}
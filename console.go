@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// --- 管理主控台 ---
+//
+// 只提供預先寫好的唯讀查詢，不接受任意查詢語法；
+// 另外可以手動觸發排程工作並查看各工作的狀態
+
+// consoleResult 是查詢結果的表格
+type consoleResult struct {
+	Columns []string
+	Rows    [][]string
+}
+
+type consoleQuery struct {
+	Key   string
+	Label string
+	run   func(*App) (consoleResult, error)
+}
+
+var consoleQueries = []consoleQuery{
+	{"tasks-by-user", "各使用者的任務數", (*App).queryTasksByUser},
+	{"orphans", "孤兒資料（參照已不存在的使用者、專案或公告）", (*App).queryOrphans},
+	{"task-cache", "任務快取狀態", (*App).queryTaskCache},
+}
+
+func (a *App) queryTasksByUser() (consoleResult, error) {
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return consoleResult{}, err
+	}
+	tasks, err := a.store.AllTasks()
+	if err != nil {
+		return consoleResult{}, err
+	}
+
+	type counts struct{ total, completed, overdue int }
+	byUser := make(map[string]*counts)
+	for _, u := range users {
+		byUser[u.Username] = &counts{}
+	}
+	now := time.Now()
+	for _, task := range tasks {
+		c, ok := byUser[task.Username]
+		if !ok {
+			continue // 未認領的專案任務或孤兒資料，另外查
+		}
+		c.total++
+		if task.Completed {
+			c.completed++
+		} else if task.OverdueAt(now) {
+			c.overdue++
+		}
+	}
+
+	names := make([]string, 0, len(byUser))
+	for name := range byUser {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return byUser[names[i]].total > byUser[names[j]].total })
+
+	result := consoleResult{Columns: []string{"使用者", "任務數", "已完成", "逾期"}}
+	for _, name := range names {
+		c := byUser[name]
+		result.Rows = append(result.Rows, []string{name, strconv.Itoa(c.total), strconv.Itoa(c.completed), strconv.Itoa(c.overdue)})
+	}
+	return result, nil
+}
+
+func (a *App) queryOrphans() (consoleResult, error) {
+	return findOrphans(a.store)
+}
+
+// findOrphans 找出 s 裡參照已不存在的使用者、專案或公告的資料；備份驗證也用它檢查還原出來的資料
+func findOrphans(s Store) (consoleResult, error) {
+	users, err := s.ListUsers()
+	if err != nil {
+		return consoleResult{}, err
+	}
+	exists := make(map[string]bool)
+	for _, u := range users {
+		exists[u.Username] = true
+	}
+	tasks, err := s.AllTasks()
+	if err != nil {
+		return consoleResult{}, err
+	}
+
+	result := consoleResult{Columns: []string{"類型", "ID", "問題"}}
+	add := func(kind, id, format string, args ...interface{}) {
+		result.Rows = append(result.Rows, []string{kind, id, fmt.Sprintf(format, args...)})
+	}
+
+	projectExists := make(map[int]bool)
+	announcementExists := make(map[int]bool)
+	for _, task := range tasks {
+		id := strconv.Itoa(task.ID)
+		if task.Username != "" && !exists[task.Username] {
+			add("任務", id, "擁有者 %s 不存在", task.Username)
+		}
+		if task.ProjectID != 0 {
+			ok, seen := projectExists[task.ProjectID]
+			if !seen {
+				_, err := s.GetProject(task.ProjectID)
+				ok = err == nil
+				projectExists[task.ProjectID] = ok
+			}
+			if !ok {
+				add("任務", id, "專案 #%d 不存在", task.ProjectID)
+			}
+		}
+		if task.AnnouncementID != 0 {
+			ok, seen := announcementExists[task.AnnouncementID]
+			if !seen {
+				_, err := s.GetAnnouncement(task.AnnouncementID)
+				ok = err == nil
+				announcementExists[task.AnnouncementID] = ok
+			}
+			if !ok {
+				add("任務", id, "公告 #%d 不存在", task.AnnouncementID)
+			}
+		}
+	}
+
+	sessions, err := s.ListSessions()
+	if err != nil {
+		return consoleResult{}, err
+	}
+	for _, sess := range sessions {
+		if !exists[sess.Username] {
+			add("Session", sess.ID[:8], "使用者 %s 不存在", sess.Username)
+		}
+	}
+	return result, nil
+}
+
+func (a *App) adminConsoleHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+
+	if r.Method == "POST" {
+		name := r.FormValue("job")
+		start := time.Now()
+		if err := scheduler.RunNow(name); err != nil {
+			a.flashError(r, invalidInput("工作 %s 執行失敗：%v", name, err), "") // 只有管理員看得到，直接顯示錯誤細節
+		} else {
+			a.flashSuccess(r, "工作 %s 已完成（%s）", name, time.Since(start).Round(time.Millisecond))
+		}
+		http.Redirect(w, r, "/admin/console", http.StatusSeeOther)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Queries":   consoleQueries,
+		"Jobs":      scheduler.Status(),
+		"Backup":    latestBackupCheck(),
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+
+	key := r.URL.Query().Get("q")
+	for _, q := range consoleQueries {
+		if q.Key != key {
+			continue
+		}
+		result, err := q.run(a)
+		if err != nil {
+			data["QueryError"] = a.tr(r, "查詢失敗：%v", err)
+		} else {
+			data["Result"] = result
+		}
+		data["Query"] = q
+	}
+
+	t := a.localize(r, a.pages.page("console"))
+	t.Execute(w, data)
+}
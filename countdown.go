@@ -0,0 +1,84 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+)
+
+// --- 倒數 ---
+//
+// 重要的期限可以標成「倒數」，各頁面頂端顯示即時倒數（天／小時），每人最多 maxCountdowns 個。
+// 完成、封存或移到垃圾桶的任務不再顯示，但標記保留，改回未完成時會再出現
+
+const maxCountdowns = 3
+
+// countdownTasks 回傳使用者要顯示倒數的任務，依到期時間排序
+func (a *App) countdownTasks(username string) []Task {
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		return nil
+	}
+	var result []Task
+	for _, t := range tasks {
+		if t.Countdown && !t.Completed && !t.Archived {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DueAt.Before(result[j].DueAt) })
+	if len(result) > maxCountdowns {
+		result = result[:maxCountdowns]
+	}
+	return result
+}
+
+// countdownFuncs 給倒數列（templates/partials/countdown.html）用，頁面以 {{template "countdown" .Username}} 顯示；
+// 倒數列自己讀取任務，各頁 handler 不必另外準備資料。countdowns 要讀這個 App 的任務，在 localize 才掛上
+var countdownFuncs = template.FuncMap{
+	"countdownLabel": func(s string) string { return clipText(s, clipCalendar) },
+}
+
+func (a *App) countdownHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	username := a.getUsername(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	enabled := r.FormValue("enabled") == "true"
+
+	if enabled {
+		count := 0
+		tasks, _ := a.store.ListTasks(username)
+		for _, t := range tasks {
+			if t.Countdown && t.ID != id && !t.Completed && !t.Archived {
+				count++
+			}
+		}
+		if count >= maxCountdowns {
+			a.flashError(r, invalidInput("最多只能設定 %d 個倒數，請先取消其他任務的倒數", maxCountdowns), "")
+			redirectBack(w, r)
+			return
+		}
+	}
+
+	task, err := a.store.ModifyTask(id, func(t *Task) error {
+		if t.Username != username {
+			return ErrNotFound
+		}
+		t.Countdown = enabled
+		return nil
+	})
+	switch {
+	case err != nil:
+		a.flashError(r, err, "更新倒數失敗，請稍後再試")
+	case enabled:
+		a.flashSuccess(r, "已將「%s」加入倒數", brief(task.Description))
+	default:
+		a.flashSuccess(r, "已取消「%s」的倒數", brief(task.Description))
+	}
+	redirectBack(w, r)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/subtle"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// --- CSRF 防護 ---
+//
+// 每個 session 有一組固定的 CSRF token，頁面上所有表單都以隱藏欄位 csrf_token 帶回；
+// 其他網站拿不到這個值，就無法替已登入的使用者偽造請求。
+// JSON API 要求 Content-Type 必須是 application/json；靠 session cookie 登入的 API 請求
+// 還要在 X-CSRF-Token header 帶回同一個 token，沒有內容的 POST、DELETE 也一樣。
+// 帶 Authorization: Bearer 的請求（API token，見 apitoken.go）不靠 cookie，跨站頁面也加不上這個 header，不必檢查。
+// 登入、註冊、設定邀請密碼與登出在還沒有（或即將沒有）session 時送出，沒有 token 可比對，
+// 改由 requireSameOrigin 檢查請求是否來自本站的頁面，避免其他網站替使用者登入攻擊者的帳號
+
+const (
+	csrfFieldName  = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// CSRFToken 回傳目前 session 的 CSRF token，未登入時為空字串
+func (m *sessionManager) CSRFToken(r *http.Request) string {
+	s, ok := m.lookup(r)
+	if !ok {
+		return ""
+	}
+	return s.CSRFToken
+}
+
+func isSafeMethod(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS"
+}
+
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// validCSRFToken 比對表單欄位（或 header）與 session 中的 token
+func (a *App) validCSRFToken(r *http.Request) bool {
+	want := a.sessions.CSRFToken(r)
+	got := r.PostFormValue(csrfFieldName)
+	if got == "" {
+		got = r.Header.Get(csrfHeaderName)
+	}
+	return want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// validCSRFHeader 只比對 header，給 JSON API 用；API 的內容不是表單，不從 body 讀 token
+func (a *App) validCSRFHeader(r *http.Request) bool {
+	want := a.sessions.CSRFToken(r)
+	got := r.Header.Get(csrfHeaderName)
+	return want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// sameOrigin 依 Sec-Fetch-Site，沒有時依 Origin 或 Referer 判斷請求是否來自本站；
+// 三者都沒有（舊瀏覽器、命令列工具）時放行，這類請求不是從其他網站的頁面發出的
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin" || site == "none"
+	}
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return true
+	}
+	u, err := url.Parse(source)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// requireSameOrigin 拒絕從其他網站送出的 POST，用在沒有 CSRF token 可檢查的登入相關表單
+func requireSameOrigin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && !sameOrigin(r) {
+			http.Error(w, "請從本站的頁面送出表單", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// csrfProtect 包住整個 mux，檢查所有會改變狀態的請求。
+// 尚未登入的表單（登入、註冊）沒有 session 可偽造，直接放行
+func (a *App) csrfProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			if r.ContentLength != 0 && !isJSONRequest(r) {
+				writeAPIError(w, http.StatusUnsupportedMediaType, "Content-Type 必須是 application/json")
+				return
+			}
+			if bearerToken(r) == "" && a.getUsername(r) != "" && !a.validCSRFHeader(r) {
+				writeAPIError(w, http.StatusForbidden, "缺少或錯誤的 "+csrfHeaderName+" header")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if a.getUsername(r) != "" && !a.validCSRFToken(r) {
+			http.Error(w, "表單已失效，請重新整理頁面後再試一次", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
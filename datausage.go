@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// --- 資料用量 ---
+//
+// /settings/data 列出帳號在伺服器上存了多少東西：任務（含垃圾桶）、加密筆記的位元組數、
+// 任務上的留言與登入中的 session，每一類都可以下載備份或清除。
+// 這個系統沒有附件，占空間的二進位資料只有瀏覽器加密後的筆記，所以「附件」一欄列的是筆記的大小。
+// 數量由儲存層的 DataUsage 彙總；筆記與留言只清除自己（非專案）已完成任務上的，
+// 進行中的任務與專案任務的討論不會被這裡刪掉
+
+// DataUsage 是一個帳號佔用的資料量；筆記與留言只算不在垃圾桶裡的任務，
+// Cleanable* 是自己、不屬於專案的已完成任務上的部分，也就是資料用量頁可以清除的量
+type DataUsage struct {
+	Tasks              int   `json:"tasks"`
+	CompletedTasks     int   `json:"completed_tasks"`
+	TrashedTasks       int   `json:"trashed_tasks"`
+	NoteTasks          int   `json:"note_tasks"`
+	NoteBytes          int64 `json:"note_bytes"`
+	CleanableNoteBytes int64 `json:"cleanable_note_bytes"`
+	Comments           int   `json:"comments"`
+	CleanableComments  int   `json:"cleanable_comments"`
+	Sessions           int   `json:"sessions"`
+}
+
+// cleanableFor 判斷資料用量頁能不能清除 t 上的筆記與留言
+func (t Task) cleanableFor(username string) bool {
+	return t.Username == username && t.Completed && t.ProjectID == 0
+}
+
+// add 把一個任務算進用量，沒有自己查詢語言的儲存後端（jsonStore）用這個逐一加總
+func (u *DataUsage) add(username string, t Task) {
+	if t.Trashed() {
+		u.TrashedTasks++
+		return
+	}
+	u.Tasks++
+	if t.Completed {
+		u.CompletedTasks++
+	}
+	cleanable := t.cleanableFor(username)
+	if t.EncryptedNote != "" {
+		u.NoteTasks++
+		u.NoteBytes += int64(len(t.EncryptedNote))
+		if cleanable {
+			u.CleanableNoteBytes += int64(len(t.EncryptedNote))
+		}
+	}
+	for _, a := range t.Activity {
+		if a.Kind != ActivityComment {
+			continue
+		}
+		u.Comments++
+		if cleanable {
+			u.CleanableComments++
+		}
+	}
+}
+
+// formatBytes 以 B、KB、MB 顯示大小
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+func (a *App) dataUsageHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+
+	if r.Method == "POST" {
+		switch r.FormValue("action") {
+		case "clean-trash":
+			a.emptyTrash(r, username)
+		case "clean-notes":
+			a.clearCompletedNotes(r, username)
+		case "clean-comments":
+			a.clearCompletedComments(r, username)
+		case "clean-sessions":
+			n := a.sessions.EndOthers(r, username)
+			a.flashSuccess(r, "已登出其他 %d 個裝置", n)
+		}
+		http.Redirect(w, r, "/settings/data", http.StatusSeeOther)
+		return
+	}
+
+	if category := r.URL.Query().Get("export"); category != "" {
+		a.exportUsageCategory(w, r, username, category)
+		return
+	}
+
+	usage, err := a.store.DataUsage(username)
+	if err != nil {
+		http.Error(w, "讀取資料用量失敗", http.StatusInternalServerError)
+		return
+	}
+	if !a.sessions.persist {
+		usage.Sessions = a.sessions.Count(username)
+	}
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Usage":     usage,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("datausage"))
+	t.Execute(w, data)
+}
+
+// modifyCleanable 對 username 所有可清除的任務中 match 回傳 true 的套用 fn，回傳改了幾個任務
+func (a *App) modifyCleanable(username string, match func(Task) bool, fn func(*Task) error) (int, error) {
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for _, t := range tasks {
+		if t.cleanableFor(username) && match(t) {
+			ids = append(ids, t.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	_, err = a.store.ModifyTasks(ids, fn)
+	return len(ids), err
+}
+
+func countComments(t Task) int {
+	n := 0
+	for _, a := range t.Activity {
+		if a.Kind == ActivityComment {
+			n++
+		}
+	}
+	return n
+}
+
+func (a *App) clearCompletedNotes(r *http.Request, username string) {
+	n, err := a.modifyCleanable(username, func(t Task) bool {
+		return t.EncryptedNote != ""
+	}, func(t *Task) error {
+		t.EncryptedNote = ""
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "清除筆記失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "已清除 %d 個已完成任務的筆記", n)
+}
+
+// clearCompletedComments 只刪留言，修改紀錄等其他動態保留下來
+func (a *App) clearCompletedComments(r *http.Request, username string) {
+	removed := 0
+	_, err := a.modifyCleanable(username, func(t Task) bool {
+		return countComments(t) > 0
+	}, func(t *Task) error {
+		kept := t.Activity[:0]
+		for _, act := range t.Activity {
+			if act.Kind == ActivityComment {
+				removed++
+				continue
+			}
+			kept = append(kept, act)
+		}
+		t.Activity = kept
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "清除留言失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "已刪除已完成任務上的 %d 則留言", removed)
+}
+
+type noteExport struct {
+	TaskID        int    `json:"task_id"`
+	Description   string `json:"description"`
+	EncryptedNote string `json:"encrypted_note"`
+}
+
+type commentExport struct {
+	TaskID      int    `json:"task_id"`
+	Description string `json:"description"`
+	Time        string `json:"time"`
+	User        string `json:"user"`
+	Text        string `json:"text"`
+}
+
+type sessionExport struct {
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	Current   bool   `json:"current"`
+}
+
+// exportUsageCategory 下載一類資料的 JSON；任務本身由 /export 負責，這裡只處理另外三類。
+// 筆記照原樣匯出密文，要用當初的密語才能解開
+func (a *App) exportUsageCategory(w http.ResponseWriter, r *http.Request, username, category string) {
+	var v interface{}
+	switch category {
+	case "notes", "comments":
+		tasks, err := a.store.ListTasks(username)
+		if err != nil {
+			http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+			return
+		}
+		notes, comments := []noteExport{}, []commentExport{}
+		for _, t := range tasks {
+			if t.EncryptedNote != "" {
+				notes = append(notes, noteExport{t.ID, t.Description, t.EncryptedNote})
+			}
+			for _, act := range t.Activity {
+				if act.Kind == ActivityComment {
+					comments = append(comments, commentExport{t.ID, t.Description, formatExportTime(act.Time), act.User, act.Text})
+				}
+			}
+		}
+		if category == "notes" {
+			v = notes
+		} else {
+			v = comments
+		}
+	case "sessions":
+		current, _ := a.sessions.lookup(r)
+		list := []sessionExport{}
+		for _, s := range a.sessions.userSessions(username) {
+			list = append(list, sessionExport{formatExportTime(s.CreatedAt), formatExportTime(s.ExpiresAt), s.ID == current.ID})
+		}
+		v = list
+	default:
+		http.Error(w, "不支援的類別", http.StatusBadRequest)
+		return
+	}
+	filename := category + "-" + time.Now().Format("20060102") + ".json"
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
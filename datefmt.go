@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// --- 日期顯示格式 ---
+//
+// 畫面、提醒與摘要信裡的日期都透過 DatePrefs 格式化，依使用者設定切換語系、
+// 12／24 小時制與民國年。表單欄位、CSV、iCalendar 等給程式讀的格式不在此列
+
+const (
+	LocaleZhTW = "zh-TW"
+	LocaleEn   = "en"
+)
+
+var localeOptions = []struct{ Value, Label string }{
+	{LocaleZhTW, "中文"},
+	{LocaleEn, "English"},
+}
+
+// DatePrefs 是使用者的日期顯示偏好；零值即預設的中文、24 小時制、西元年、週日開始
+type DatePrefs struct {
+	Locale    string
+	Clock12   bool
+	ROCYear   bool
+	WeekStart time.Weekday
+}
+
+func (u User) DatePrefs() DatePrefs {
+	return DatePrefs{Locale: u.Locale, Clock12: u.Clock12, ROCYear: u.ROCYear, WeekStart: u.WeekStart}
+}
+
+// datePrefsFor 讀取使用者的偏好，讀不到時用預設格式
+func (a *App) datePrefsFor(username string) DatePrefs {
+	user, err := a.store.GetUser(username)
+	if err != nil {
+		return DatePrefs{}
+	}
+	return user.DatePrefs()
+}
+
+func (p DatePrefs) english() bool { return p.Locale == LocaleEn }
+
+// Date 是完整日期，例如 2024-05-01、民國113年05月01日、May 1, 2024
+func (p DatePrefs) Date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	switch {
+	case p.english():
+		return t.Format("Jan 2, 2006")
+	case p.ROCYear:
+		return fmt.Sprintf("民國%d年%02d月%02d日", rocYear(t.Year()), t.Month(), t.Day())
+	}
+	return t.Format("2006-01-02")
+}
+
+// Clock 是時間，例如 15:04、下午 3:04、3:04 PM
+func (p DatePrefs) Clock(t time.Time) string {
+	return p.clock(t, false)
+}
+
+func (p DatePrefs) clock(t time.Time, seconds bool) string {
+	if !p.Clock12 {
+		if seconds {
+			return t.Format("15:04:05")
+		}
+		return t.Format("15:04")
+	}
+	layout := "3:04"
+	if seconds {
+		layout = "3:04:05"
+	}
+	if p.english() {
+		return t.Format(layout + " PM")
+	}
+	half := "上午"
+	if t.Hour() >= 12 {
+		half = "下午"
+	}
+	return half + " " + t.Format(layout)
+}
+
+// DateTime 是完整日期加時間
+func (p DatePrefs) DateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return p.Date(t) + " " + p.Clock(t)
+}
+
+// Short 省略年份，用在清單這類空間有限的地方，例如 05-01 15:04、May 1 3:04 PM
+func (p DatePrefs) Short(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return p.monthDay(t) + " " + p.Clock(t)
+}
+
+// Stamp 是含秒數的 Short，給排程紀錄這類需要精確時間的地方
+func (p DatePrefs) Stamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return p.monthDay(t) + " " + p.clock(t, true)
+}
+
+func (p DatePrefs) monthDay(t time.Time) string {
+	if p.english() {
+		return t.Format("Jan 2")
+	}
+	return t.Format("01-02")
+}
+
+// MonthTitle 是月曆的標題，例如 2024 年 5 月、民國 113 年 5 月、May 2024
+func (p DatePrefs) MonthTitle(year, month int) string {
+	switch {
+	case p.english():
+		return time.Month(month).String() + " " + fmt.Sprint(year)
+	case p.ROCYear:
+		return fmt.Sprintf("民國 %d 年 %d 月", rocYear(year), month)
+	}
+	return fmt.Sprintf("%d 年 %d 月", year, month)
+}
+
+// StartOfWeek 回傳 t 所在那一週第一天的零點
+func (p DatePrefs) StartOfWeek(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) - int(p.WeekStart) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// Weekdays 是從一週第一天開始排列的星期簡稱，給月曆的表頭用
+func (p DatePrefs) Weekdays() []string {
+	names := []string{"日", "一", "二", "三", "四", "五", "六"}
+	if p.english() {
+		names = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+	}
+	ordered := make([]string, 7)
+	for i := range ordered {
+		ordered[i] = names[(int(p.WeekStart)+i)%7]
+	}
+	return ordered
+}
+
+// isoWeekLabel 是 ISO 8601 週次，例如 2024-W19
+func isoWeekLabel(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// parseISOWeek 解析 2024-W19 這種週次，回傳那一週星期一的零點
+func parseISOWeek(s string, loc *time.Location) (time.Time, error) {
+	var year, week int
+	if n, err := fmt.Sscanf(s, "%4d-W%2d", &year, &week); err != nil || n != 2 || len(s) != len("2006-W01") {
+		return time.Time{}, invalidInput("週次格式不正確，例如 2024-W19")
+	}
+	// 1 月 4 日一定落在第 1 週
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(week-1)*7)
+	if y, w := monday.ISOWeek(); y != year || w != week {
+		return time.Time{}, invalidInput("%d 年沒有第 %d 週", year, week)
+	}
+	return monday, nil
+}
+
+// rocYear 把西元年換成民國年；民國前的年份照樣回傳（0 以下），這個程式用不到
+func rocYear(year int) int {
+	return year - 1911
+}
+
+// Funcs 是給模板用的格式化函式
+func (p DatePrefs) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"date":       p.Date,
+		"datetime":   p.DateTime,
+		"shortdt":    p.Short,
+		"due":        p.Due,
+		"shortdue":   p.ShortDue,
+		"stamp":      p.Stamp,
+		"clock":      p.Clock,
+		"monthTitle": p.MonthTitle,
+	}
+}
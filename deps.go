@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 任務相依 ---
+//
+// 任務可以設定「等待」其他任務（BlockedBy）：等待的任務還沒全部完成前，清單上顯示「被擋住」，
+// 勾選完成、拖到看板的已完成欄或透過 API 完成都會被拒絕。等待的任務完成時，
+// 因此不再被擋住的任務以 flash 提醒。被刪除（在垃圾桶裡）或找不到的任務不算在內。
+// 只能等待自己能編輯的任務，設定時檢查是否形成循環（A 等 B、B 又等 A）
+
+// maxBlockers 是一個任務最多可以等待的任務數
+const maxBlockers = 20
+
+var ErrDependencyCycle = &DomainError{Code: "dependency_cycle", Message: "相依關係不能形成循環", Status: http.StatusBadRequest}
+
+// blockedError 是因為還有未完成的相依任務而不能完成時的錯誤
+func blockedError(open []Task) error {
+	names := make(quotedList, len(open))
+	for i, t := range open {
+		names[i] = t.Description
+	}
+	return &DomainError{Code: "task_blocked", Message: "還要先完成%s才能完成這個任務", Args: []interface{}{names}, Status: http.StatusConflict}
+}
+
+// blockersOf 回傳 t 等待的任務（含已完成的），給任務頁列出；viewer 看不到的不列
+func (a *App) blockersOf(t Task, viewer string) []Task {
+	var list []Task
+	for _, id := range t.BlockedBy {
+		b, err := a.store.GetTask(id)
+		if err == nil && !b.Trashed() && b.VisibleTo(viewer) {
+			list = append(list, b)
+		}
+	}
+	return list
+}
+
+// openBlockers 回傳 t 等待中、尚未完成的任務
+func (a *App) openBlockers(t Task) []Task {
+	var open []Task
+	for _, id := range t.BlockedBy {
+		b, err := a.store.GetTask(id)
+		if err != nil || b.Trashed() || b.Completed {
+			continue
+		}
+		open = append(open, b)
+	}
+	return open
+}
+
+// checkBatchUnblocked 是批次完成前的檢查：等待的任務在同一批裡一起完成也可以
+func (a *App) checkBatchUnblocked(ids []int) error {
+	for _, id := range ids {
+		t, err := a.store.GetTask(id)
+		if err != nil || t.Completed {
+			continue
+		}
+		var open []Task
+		for _, b := range a.openBlockers(t) {
+			if !containsInt(ids, b.ID) {
+				open = append(open, b)
+			}
+		}
+		if len(open) > 0 {
+			return blockedError(open)
+		}
+	}
+	return nil
+}
+
+// checkUnblocked 在把 t 標記為完成之前呼叫，還有未完成的相依任務時回傳錯誤
+func (a *App) checkUnblocked(t Task) error {
+	if t.Completed {
+		return nil
+	}
+	if open := a.openBlockers(t); len(open) > 0 {
+		return blockedError(open)
+	}
+	return nil
+}
+
+// errBlockersChanged 表示讀取相依任務之後 BlockedBy 又被改過，modifyCompletion 會重新讀取再試
+var errBlockersChanged = errors.New("相依任務已變更")
+
+// modifyCompletion 和 ModifyTask 一樣以 fn 修改任務 id，但把它等待的任務也放進同一次 ModifyTasks：
+// fn 把任務改成完成時，在寫入前以同一份資料檢查等待的任務都已完成，檢查與寫入之間不會被別人改回未完成
+func (a *App) modifyCompletion(id int, fn func(*Task) error) (Task, error) {
+	for attempt := 0; ; attempt++ {
+		current, err := a.store.GetTask(id)
+		if err != nil {
+			return Task{}, err
+		}
+		// 已不存在或在垃圾桶的相依任務不算數（和 openBlockers 一樣），也不放進 ModifyTasks
+		var ids []int
+		for _, b := range current.BlockedBy {
+			if t, err := a.store.GetTask(b); err == nil && !t.Trashed() {
+				ids = append(ids, b)
+			}
+		}
+		blockers := make(map[int]Task, len(ids))
+		tasks, err := a.store.ModifyTasks(append(ids, id), func(t *Task) error {
+			if t.ID != id {
+				blockers[t.ID] = *t
+				return nil
+			}
+			wasCompleted := t.Completed
+			if err := fn(t); err != nil {
+				return err
+			}
+			if wasCompleted || !t.Completed {
+				return nil
+			}
+			var open []Task
+			for _, b := range t.BlockedBy {
+				blocker, ok := blockers[b]
+				switch {
+				case !ok && !containsInt(current.BlockedBy, b):
+					return errBlockersChanged
+				case ok && !blocker.Completed:
+					open = append(open, blocker)
+				}
+			}
+			if len(open) > 0 {
+				return blockedError(open)
+			}
+			return nil
+		})
+		if err == errBlockersChanged && attempt < 3 {
+			continue
+		}
+		if err != nil {
+			return Task{}, err
+		}
+		return tasks[len(tasks)-1], nil
+	}
+}
+
+// parseBlockers 讀取表單的 blocked_by（可以有多個），略過空白
+func parseBlockers(values []string) ([]int, error) {
+	var ids []int
+	for _, v := range values {
+		if v = strings.TrimSpace(strings.TrimPrefix(v, "#")); v == "" {
+			continue
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, invalidInput("相依任務的編號「%s」不正確", v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// validateBlockers 檢查 taskID（新任務為 0）要等待的任務：必須存在、username 能編輯、不是自己，且不形成循環。
+// 回傳去掉重複之後的編號
+func (a *App) validateBlockers(taskID int, ids []int, username string) ([]int, error) {
+	var unique []int
+	for _, id := range ids {
+		if !containsInt(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > maxBlockers {
+		return nil, invalidInput("最多只能等待 %d 個任務", maxBlockers)
+	}
+	for _, id := range unique {
+		if id == taskID {
+			return nil, invalidInput("任務不能等待自己")
+		}
+		b, err := a.store.GetTask(id)
+		if err != nil || b.Trashed() || !b.canEdit(username) || !b.VisibleTo(username) {
+			return nil, invalidInput("找不到相依的任務 #%d", id)
+		}
+	}
+	if taskID != 0 && a.dependsOn(unique, taskID) {
+		return nil, ErrDependencyCycle
+	}
+	return unique, nil
+}
+
+// dependsOn 回報從 ids 沿著 BlockedBy 往下走是否會走到 target
+func (a *App) dependsOn(ids []int, target int) bool {
+	seen := make(map[int]bool)
+	stack := append([]int(nil), ids...)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == target {
+			return true
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if t, err := a.store.GetTask(id); err == nil {
+			stack = append(stack, t.BlockedBy...)
+		}
+	}
+	return false
+}
+
+// newlyUnblocked 回傳等待 done、而且 done 完成後已經沒有其他未完成相依任務的任務，只列出 username 看得到的
+func (a *App) newlyUnblocked(done Task, username string) []Task {
+	waiting, err := a.store.ListBlockedBy(done.ID)
+	if err != nil {
+		return nil
+	}
+	var result []Task
+	for _, t := range waiting {
+		if t.Completed || !t.canEdit(username) || !t.VisibleTo(username) {
+			continue
+		}
+		if len(a.openBlockers(t)) == 0 {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DueAt.Before(result[j].DueAt) })
+	return result
+}
+
+// announceUnblocked 在 done 完成後提醒因此可以開始的任務；一次完成多個時，同一個任務只提醒一次
+func (a *App) announceUnblocked(r *http.Request, username string, done ...Task) {
+	seen := make(map[int]bool)
+	for _, d := range done {
+		for _, t := range a.newlyUnblocked(d, username) {
+			if seen[t.ID] {
+				continue
+			}
+			seen[t.ID] = true
+			a.sessions.pushFlash(r, Flash{
+				Kind:    FlashSuccess,
+				Message: a.tr(r, "🔓 「%s」等待的任務都完成了，可以開始了", brief(t.Description)),
+				Link:    &FlashLink{URL: "/task/" + strconv.Itoa(t.ID), Label: a.tr(r, "查看任務")},
+			})
+		}
+	}
+}
+
+// blockedLabels 是清單上「被擋住」標記的說明，key 是任務編號，只含目前被擋住的任務
+func (a *App) blockedLabels(tasks []Task) map[int]quotedList {
+	labels := make(map[int]quotedList)
+	for _, t := range tasks {
+		if t.Completed || len(t.BlockedBy) == 0 {
+			continue
+		}
+		if open := a.openBlockers(t); len(open) > 0 {
+			names := make(quotedList, len(open))
+			for i, b := range open {
+				names[i] = b.Description
+			}
+			labels[t.ID] = names
+		}
+	}
+	return labels
+}
+
+// blockerOptions 是編輯頁「等待」的選項：username 自己未完成的任務，加上 task 目前等待的任務
+func (a *App) blockerOptions(task Task, username string) []Task {
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		return nil
+	}
+	var options []Task
+	for _, t := range tasks {
+		if t.ID == task.ID || t.Archived {
+			continue
+		}
+		if !t.Completed || containsInt(task.BlockedBy, t.ID) {
+			options = append(options, t)
+		}
+	}
+	smartSort(options, time.Now())
+	return options
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
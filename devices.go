@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- 登入中的裝置 ---
+//
+// 設定頁列出目前帳號尚未過期的 session：登入時間、最後使用時間、瀏覽器與 IP，
+// 可以個別登出某個裝置（例如忘了登出的公用電腦），或一次登出所有裝置（連同目前的瀏覽器）。
+// 只想保留目前這台可以用資料用量頁的「登出其他裝置」。API token 不是 session，要在 API token 區塊撤銷
+
+// deviceView 是設定頁上的一個裝置
+type deviceView struct {
+	ID        string
+	Device    string // 由 User-Agent 整理出的「Chrome · Windows」
+	UserAgent string
+	IP        string
+	CreatedAt time.Time
+	LastSeen  time.Time
+	Current   bool
+}
+
+// deviceViews 列出 username 登入中的裝置，最近使用的排前面，目前的瀏覽器永遠在第一個
+func (a *App) deviceViews(r *http.Request, username string) []deviceView {
+	current, _ := a.sessions.lookup(r)
+	locale := a.requestLocale(r)
+	var list []deviceView
+	for _, s := range a.sessions.userSessions(username) {
+		list = append(list, deviceView{
+			ID:        s.ID,
+			Device:    deviceLabel(locale, s.UserAgent),
+			UserAgent: s.UserAgent,
+			IP:        s.IP,
+			CreatedAt: s.CreatedAt,
+			LastSeen:  s.LastSeen,
+			Current:   s.ID == current.ID,
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Current != list[j].Current {
+			return list[i].Current
+		}
+		return list[i].LastSeen.After(list[j].LastSeen)
+	})
+	return list
+}
+
+// deviceLabel 從 User-Agent 認出常見的瀏覽器與作業系統，認不出來時回傳「未知的裝置」
+func deviceLabel(locale, ua string) string {
+	var browser, os string
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(ua, "curl/"):
+		browser = "curl"
+	}
+	switch {
+	case strings.Contains(ua, "iPhone"):
+		os = "iPhone"
+	case strings.Contains(ua, "iPad"):
+		os = "iPad"
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "CrOS"):
+		os = "ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+	switch {
+	case browser != "" && os != "":
+		return browser + " · " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+	return tr(locale, "未知的裝置")
+}
+
+// endDevice 是設定頁的 action=session-revoke
+func (a *App) endDevice(r *http.Request, username string) {
+	current, _ := a.sessions.lookup(r)
+	id := r.FormValue("id")
+	if id == current.ID {
+		a.flashError(r, invalidInput("要登出目前的瀏覽器請按右上角的登出"), "")
+		return
+	}
+	if !a.sessions.EndOne(username, id) {
+		a.flashError(r, ErrNotFound, "")
+		return
+	}
+	a.flashSuccess(r, "已登出該裝置")
+}
+
+// endAllDevices 是設定頁的 action=sessions-end-all：登出所有裝置，包括目前的瀏覽器
+func (a *App) endAllDevices(w http.ResponseWriter, r *http.Request, username string) {
+	a.sessions.EndUser(username)
+	a.endSession(w, r)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// --- 每日摘要信 ---
+//
+// 每小時檢查一次，使用者設定的時間到了就寄出當天的摘要：逾期、今天到期、接下來幾天到期的任務，
+// 以及昨天完成的數量。User.DigestSentOn 記錄最後寄出的日期，一天只寄一次；
+// 伺服器在設定的時間停機時，啟動後當天仍會補寄
+
+const (
+	defaultDigestHour = 7
+	maxDigestDays     = 7
+)
+
+type digestSection struct {
+	Title string
+	Tasks []Task
+}
+
+type digest struct {
+	Subject       string
+	Body          string
+	DoneYesterday int
+}
+
+// buildDigest 依使用者的設定整理出摘要內容，寄信與網頁預覽共用
+func buildDigest(user User, tasks []Task, now time.Time) digest {
+	prefs := user.DatePrefs()
+	today := now.Format("2006-01-02")
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+	days := user.DigestDays
+	if days < 0 || days > maxDigestDays {
+		days = 0
+	}
+	horizon := now.AddDate(0, 0, days).Format("2006-01-02")
+
+	var overdue, dueToday, upcoming []Task
+	var sections []digestSection
+	d := digest{}
+	for _, t := range tasks {
+		if t.Completed {
+			if !t.CompletedAt.IsZero() && t.CompletedAt.Format("2006-01-02") == yesterday {
+				d.DoneYesterday++
+			}
+			continue
+		}
+		day := t.DueAt.Format("2006-01-02")
+		switch {
+		case t.OverdueAt(now):
+			overdue = append(overdue, t)
+		case day == today:
+			dueToday = append(dueToday, t)
+		case day <= horizon:
+			upcoming = append(upcoming, t)
+		}
+	}
+	smartSort(overdue, now)
+	smartSort(dueToday, now)
+	smartSort(upcoming, now)
+
+	if len(overdue) > 0 {
+		sections = append(sections, digestSection{fmt.Sprintf("⚠️ 逾期（%d）", len(overdue)), overdue})
+	}
+	if len(dueToday) > 0 {
+		sections = append(sections, digestSection{fmt.Sprintf("📅 今天到期（%d）", len(dueToday)), dueToday})
+	}
+	if len(upcoming) > 0 {
+		sections = append(sections, digestSection{fmt.Sprintf("🔜 %d 天內到期（%d）", days, len(upcoming)), upcoming})
+	}
+
+	d.Subject = fmt.Sprintf("今日摘要（%s）：%d 個任務今天到期", prefs.Date(now), len(dueToday))
+	if len(overdue) > 0 {
+		d.Subject += fmt.Sprintf("，%d 個逾期", len(overdue))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s 你好，以下是 %s 的待辦摘要。\n", user.Username, prefs.Date(now))
+	for _, s := range sections {
+		fmt.Fprintf(&b, "\n%s\n", s.Title)
+		for _, t := range s.Tasks {
+			fmt.Fprintf(&b, "・%s（%s，%s）\n  %s\n", clipText(taskLabel(user, t), clipLine), prefs.ShortDue(t), priorityLabel(t.Priority)+"優先", taskURL(t.ID))
+		}
+	}
+	if len(sections) == 0 {
+		b.WriteString("\n最近沒有待辦任務，好好休息！\n")
+	}
+	if d.DoneYesterday > 0 {
+		fmt.Fprintf(&b, "\n✅ 昨天完成了 %d 個任務，繼續保持！\n", d.DoneYesterday)
+	}
+	b.WriteString("\n可以在「設定」頁調整摘要的時間與內容，或關閉摘要信。\n")
+	d.Body = b.String()
+	return d
+}
+
+// digestDue 判斷使用者今天是否該寄摘要
+func digestDue(user User, now time.Time) bool {
+	return user.DigestEnabled && user.Email != "" &&
+		now.Hour() >= user.DigestHour && user.DigestSentOn != now.Format("2006-01-02")
+}
+
+func (a *App) sendDigest(user User, now time.Time) error {
+	tasks, err := a.store.ListTasks(user.Username)
+	if err != nil {
+		return err
+	}
+	d := buildDigest(user, tasks, now)
+	return mailer.Send(user.Email, d.Subject, d.Body)
+}
+
+// sendDigests 是排程工作；單一使用者寄送失敗不影響其他人，下一個小時會再試
+func (a *App) sendDigests() error {
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var failed []string
+	for _, user := range users {
+		if !digestDue(user, now) {
+			continue
+		}
+		if err := a.sendDigest(user, now); err != nil {
+			log.Printf("寄送摘要給 %s 失敗：%v", user.Username, err)
+			failed = append(failed, user.Username)
+			continue
+		}
+		_, err := a.store.ModifyUser(user.Username, func(u *User) error {
+			u.DigestSentOn = now.Format("2006-01-02")
+			return nil
+		})
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("寄送摘要失敗：%s", strings.Join(failed, "、"))
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"net/http"
+	"time"
+	"unicode"
+)
+
+// --- 重複任務偵測 ---
+//
+// 新增任務時，如果描述和一個自己的未完成任務幾乎一樣，先不新增，讓使用者選擇仍要新增、
+// 或把這次填的到期時間、優先順序與標籤更新到原本的任務。比對前先正規化：全形英數轉半形、
+// 不分大小寫、去掉標點符號並合併空白，再以編輯距離計算相似度，達 duplicateSimilarity 才算重複；
+// 正規化後不到 minFuzzyLength 個字的描述要完全相同才算；數字不同的（第 1 章、第 2 章）一定是不同的任務。
+// 表單以 duplicate=add 或 duplicate=merge 略過檢查，API 以 allow_duplicate 略過，偵測到時回 409
+
+const (
+	duplicateSimilarity = 0.8
+	minFuzzyLength      = 4
+)
+
+// normalizeDescription 是比對用的描述：全形英數與空白轉半形、轉小寫、去掉標點與符號、合併空白
+func normalizeDescription(s string) []rune {
+	var runes []rune
+	space := true // 開頭的空白直接略過
+	for _, r := range s {
+		switch {
+		case r == '　':
+			r = ' '
+		case r >= '！' && r <= '～':
+			r -= 0xfee0
+		}
+		if unicode.IsSpace(r) {
+			if !space {
+				runes = append(runes, ' ')
+			}
+			space = true
+			continue
+		}
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		runes = append(runes, unicode.ToLower(r))
+		space = false
+	}
+	if n := len(runes); n > 0 && runes[n-1] == ' ' {
+		runes = runes[:n-1]
+	}
+	return runes
+}
+
+// editDistance 是 a 變成 b 最少要幾次插入、刪除或替換（以字元計）
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// duplicateDistance 回傳兩個正規化後描述的編輯距離，不夠相似時 ok 為 false
+func duplicateDistance(a, b []rune) (distance int, ok bool) {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 0, false
+	}
+	allowed := int(float64(longest) * (1 - duplicateSimilarity))
+	if longest < minFuzzyLength {
+		allowed = 0
+	}
+	// 長度差超過容許的距離就不可能夠相似，不必算
+	if diff := len(a) - len(b); diff > allowed || -diff > allowed {
+		return 0, false
+	}
+	if digits(a) != digits(b) {
+		return 0, false
+	}
+	d := editDistance(a, b)
+	return d, d <= allowed
+}
+
+// digits 依序取出所有數字
+func digits(runes []rune) string {
+	var b []rune
+	for _, r := range runes {
+		if unicode.IsDigit(r) {
+			b = append(b, r)
+		}
+	}
+	return string(b)
+}
+
+// findDuplicate 在 username 自己的未完成、未封存任務中找描述最接近 desc 的重複任務
+func findDuplicate(tasks []Task, username, desc string) (Task, bool) {
+	target := normalizeDescription(desc)
+	var best Task
+	bestDistance, found := 0, false
+	for _, t := range tasks {
+		if t.Username != username || t.Completed || t.Archived {
+			continue
+		}
+		d, ok := duplicateDistance(target, normalizeDescription(t.Description))
+		if ok && (!found || d < bestDistance) {
+			best, bestDistance, found = t, d, true
+		}
+	}
+	return best, found
+}
+
+// ownDuplicate 讀出 username 的任務並找出 desc 的重複任務
+func (a *App) ownDuplicate(username, desc string) (Task, bool, error) {
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		return Task{}, false, err
+	}
+	existing, ok := findDuplicate(tasks, username, desc)
+	return existing, ok, nil
+}
+
+// rejectDuplicate 給 API 用：找到重複時寫出 409 並回傳 false，讀取失敗時寫出 500
+func (a *App) rejectDuplicate(w http.ResponseWriter, username, desc string) bool {
+	existing, found, err := a.ownDuplicate(username, desc)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "讀取任務失敗")
+		return false
+	}
+	if found {
+		writeDomainError(w, duplicateTaskError(existing), "")
+		return false
+	}
+	return true
+}
+
+// duplicateTaskError 是偵測到重複時的錯誤，訊息帶上原本任務的編號與描述
+func duplicateTaskError(existing Task) error {
+	return &DomainError{Code: "duplicate_task", Message: "已有相似的未完成任務 #%d「%s」",
+		Args: []interface{}{existing.ID, brief(existing.Description)}, Status: http.StatusConflict}
+}
+
+// mergeDuplicate 把新填的到期時間、優先順序與標籤更新到原本的任務，標籤是聯集
+func (a *App) mergeDuplicate(id int, username string, incoming Task, now time.Time) (Task, error) {
+	return a.store.ModifyTask(id, func(t *Task) error {
+		if t.Username != username || t.Completed {
+			return ErrNotFound
+		}
+		before := *t
+		t.DueAt = incoming.DueAt
+		t.AllDay = incoming.AllDay
+		t.Priority = incoming.Priority
+		t.Tags = normalizeTags(append(append([]string(nil), t.Tags...), incoming.Tags...))
+		t.recordEdit(username, before, now)
+		return nil
+	})
+}
+
+// duplicateField 是重新送出新增表單時要帶回的欄位
+type duplicateField struct {
+	Name  string
+	Value string
+}
+
+// renderDuplicate 顯示重複確認頁：原本的任務與這次要新增的內容，讓使用者選擇仍要新增或更新原本的任務
+func (a *App) renderDuplicate(w http.ResponseWriter, r *http.Request, username string, existing, incoming Task) {
+	var fields []duplicateField
+	for name, values := range r.PostForm {
+		if name == "nonce" || name == "csrf_token" || name == "duplicate" || name == "existing" || name == "return" {
+			continue
+		}
+		for _, v := range values {
+			fields = append(fields, duplicateField{name, v})
+		}
+	}
+	data := map[string]interface{}{
+		"Username":  username,
+		"Existing":  existing,
+		"Incoming":  incoming,
+		"Fields":    fields,
+		"Return":    safeRedirectPath(r, r.Header.Get("Referer")),
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+	}
+	w.WriteHeader(http.StatusConflict)
+	t := a.localize(r, a.pages.page("duplicate"))
+	t.Execute(w, data)
+}
+
+// resolveDuplicate 處理重複確認頁送出的 duplicate=merge；回報是否已處理（duplicate=add 時照常新增）
+func (a *App) resolveDuplicate(w http.ResponseWriter, r *http.Request, username string, incoming Task) bool {
+	if r.FormValue("duplicate") != "merge" {
+		return false
+	}
+	id, err := formID(r, "existing")
+	var task Task
+	if err == nil {
+		task, err = a.mergeDuplicate(id, username, incoming, time.Now())
+	}
+	if err != nil {
+		a.flashError(r, err, "更新任務失敗，請稍後再試")
+	} else {
+		a.flashSuccess(r, "已更新原本的任務「%s」", brief(task.Description))
+		a.warnConflicts(r, username, task)
+	}
+	redirectAfterDuplicate(w, r)
+	return true
+}
+
+// redirectAfterDuplicate 導回重複確認頁之前的頁面；確認頁本身是 POST /add 的回應，不能用 Referer
+func redirectAfterDuplicate(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, safeRedirectPath(r, r.FormValue("return")), http.StatusSeeOther)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// --- 領域錯誤 ---
+
+// DomainError 是可以直接顯示給使用者的錯誤；Code 給 API 用戶端判斷，
+// Status 決定 HTTP 回應碼。Message 是中文原文，也是翻譯目錄的鍵（見 i18n.go），有 Args 時是格式字串，
+// 翻譯後才代入。其他錯誤（I/O、資料庫）一律視為內部錯誤，不把細節顯示出去
+type DomainError struct {
+	Code    string
+	Message string
+	Args    []interface{}
+	Status  int
+}
+
+func (e *DomainError) Error() string {
+	return e.In(LocaleZhTW)
+}
+
+// In 是翻成 locale 的訊息
+func (e *DomainError) In(locale string) string {
+	return tr(locale, e.Message, e.Args...)
+}
+
+var (
+	ErrEmptyDescription = &DomainError{Code: "empty_description", Message: "任務內容不可為空白", Status: http.StatusBadRequest}
+	ErrInvalidDueDate   = &DomainError{Code: "invalid_due_date", Message: "日期格式錯誤", Status: http.StatusBadRequest}
+	ErrForbidden        = &DomainError{Code: "forbidden", Message: "沒有權限執行此操作", Status: http.StatusForbidden}
+)
+
+// invalidInput 建立一個 400 的 DomainError，用在需要帶入細節的驗證訊息
+func invalidInput(format string, args ...interface{}) error {
+	return &DomainError{Code: "invalid_input", Message: format, Args: args, Status: http.StatusBadRequest}
+}
+
+// displayError 是 err 可以顯示的版本：DomainError 原樣回傳，其他錯誤換成以 fallback 為訊息的 500
+func displayError(err error, fallback string) *DomainError {
+	var de *DomainError
+	if errors.As(err, &de) {
+		return de
+	}
+	return &DomainError{Code: "internal", Message: fallback, Status: http.StatusInternalServerError}
+}
+
+// localMessage 取出錯誤中可顯示給使用者的訊息並翻成 locale，非 DomainError 時使用 fallback
+func localMessage(locale string, err error, fallback string) string {
+	return displayError(err, fallback).In(locale)
+}
+
+// localMessages 把一批問題（例如匯入時各列的錯誤）翻成 locale
+func localMessages(locale string, problems []error) []string {
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = localMessage(locale, p, "")
+	}
+	return msgs
+}
+
+// errorStatus 回傳錯誤對應的 HTTP 狀態碼，非 DomainError 為 500
+func errorStatus(err error) int {
+	var de *DomainError
+	if errors.As(err, &de) {
+		return de.Status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// --- 即時事件（SSE）與桌面通知 ---
+//
+// 開著的分頁以 EventSource 連到 /events，伺服器依使用者推送事件。
+// 到期提醒優先送到開著的分頁（由瀏覽器以 Notification API 顯示），沒有分頁在線時才改寄信，
+// 兩個管道共用任務的 RemindedDue，同一個提醒不會重複送出
+
+const (
+	eventBuffer    = 8
+	eventKeepAlive = 25 * time.Second // 避免代理伺服器把閒置的連線切掉
+)
+
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// eventHub 記錄每位使用者開著的事件串流
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Event]bool
+}
+
+var events = &eventHub{subs: make(map[string]map[chan Event]bool)}
+
+func (h *eventHub) Subscribe(username string) (<-chan Event, func()) {
+	ch := make(chan Event, eventBuffer)
+	h.mu.Lock()
+	if h.subs[username] == nil {
+		h.subs[username] = make(map[chan Event]bool)
+	}
+	h.subs[username][ch] = true
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.subs[username][ch] { // DisconnectAll 可能已經關閉過
+			delete(h.subs[username], ch)
+			close(ch)
+		}
+		if len(h.subs[username]) == 0 {
+			delete(h.subs, username)
+		}
+	}
+}
+
+// Publish 送出事件並回傳收到的串流數；緩衝已滿的串流（分頁卡住）直接略過
+func (h *eventHub) Publish(username string, ev Event) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delivered := 0
+	for ch := range h.subs[username] {
+		select {
+		case ch <- ev:
+			delivered++
+		default:
+		}
+	}
+	return delivered
+}
+
+// DisconnectAll 結束所有串流，讓重新啟動時的排空不必等 SSE 連線逾時；瀏覽器會自動重連
+func (h *eventHub) DisconnectAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for username, subs := range h.subs {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(h.subs, username)
+	}
+}
+
+func (a *App) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "不支援串流", http.StatusInternalServerError)
+		return
+	}
+	ch, unsubscribe := events.Subscribe(a.getUsername(r))
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprint(w, "retry: 10000\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// reminderEvent 是送給瀏覽器的提醒內容
+type reminderEvent struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+	Due         string `json:"due"`
+	Remaining   string `json:"remaining"`
+	URL         string `json:"url"`
+}
+
+func notifyDesktop(user User, tasks []Task) bool {
+	prefs := user.DatePrefs()
+	payload := make([]reminderEvent, len(tasks))
+	for i, t := range tasks {
+		payload[i] = reminderEvent{
+			ID:          t.ID,
+			Description: clipText(taskLabel(user, t), clipLine),
+			Due:         prefs.Short(t.DueAt),
+			Remaining:   remainingTimeIn(prefs.Locale, t.DueAt),
+			URL:         taskPath(t.ID),
+		}
+	}
+	return events.Publish(user.Username, Event{Type: "reminder", Data: payload}) > 0
+}
+
+// desktopNotifyHandler 切換使用者的桌面通知設定；瀏覽器端先取得通知權限才會送出開啟
+func (a *App) desktopNotifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		user, err := a.store.ModifyUser(a.getUsername(r), func(u *User) error {
+			u.DesktopNotify = r.FormValue("enabled") == "true"
+			return nil
+		})
+		switch {
+		case err != nil:
+			a.flashError(r, err, "更新通知設定失敗，請稍後再試")
+		case user.DesktopNotify:
+			a.flashSuccess(r, "已開啟桌面通知，開著這個頁面時會在任務到期前提醒你")
+		default:
+			a.flashSuccess(r, "已關閉桌面通知")
+		}
+	}
+	redirectBack(w, r)
+}
@@ -1,697 +1,1074 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"flag"
 	"fmt"
-	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // --- 資料結構定義 ---
 
 type User struct {
-	Username     string `json:"username"`
-	PasswordHash string `json:"password_hash"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	Role         string    `json:"role,omitempty"`
+	Email        string    `json:"email,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// 管理員匯入的帳號在設定密碼前 PasswordHash 為空，憑邀請 token（存雜湊）設定密碼
+	InviteHash    string    `json:"invite_hash,omitempty"`
+	InviteExpires time.Time `json:"invite_expires"`
+
+	// Roster 是老師的學生名單
+	Roster []string `json:"roster,omitempty"`
+
+	// FeedToken 是 iCalendar 訂閱網址用的 token，只能讀取任務
+	FeedToken string `json:"feed_token,omitempty"`
+
+	// DisplayName 是畫面上顯示的名稱，空白時顯示使用者名稱（見 account.go）
+	DisplayName string `json:"display_name,omitempty"`
+
+	// Disabled 的帳號由管理員停用，不能登入，資料保留（見 admin_users.go）
+	Disabled bool `json:"disabled,omitempty"`
+
+	// OAuthIdentities 是連結到帳號的 Google／GitHub 身分（見 oauth.go），
+	// 只用第三方登入建立的帳號 PasswordHash 為空
+	OAuthIdentities []OAuthIdentity `json:"oauth_identities,omitempty"`
+
+	// ShareLinks 是不需登入的唯讀分享連結（見 sharelink.go），token 與 FeedToken 一樣以明碼保存
+	ShareLinks []ShareLink `json:"share_links,omitempty"`
+
+	// APITokens 是呼叫 /api/ 用的 token（見 apitoken.go），只存雜湊
+	APITokens []APIToken `json:"api_tokens,omitempty"`
+
+	// DesktopNotify 開啟時，到期提醒優先以瀏覽器桌面通知送出
+	DesktopNotify bool `json:"desktop_notify,omitempty"`
+
+	// Reminders 是沒有自訂提醒的任務使用的預設提醒；RemindersSet 為 false 時是 -remind-window（見 reminders.go）
+	Reminders    []int `json:"reminders,omitempty"`
+	RemindersSet bool  `json:"reminders_set,omitempty"`
+
+	// FocusWork、FocusBreak 是番茄鐘的工作與休息分鐘數，0 是預設的 25／5；Focus 是進行中的番茄鐘（見 pomodoro.go）
+	FocusWork  int         `json:"focus_work,omitempty"`
+	FocusBreak int         `json:"focus_break,omitempty"`
+	Focus      *FocusState `json:"focus,omitempty"`
+
+	// Achievements 是已解鎖的成就與第一次達成的時間（見 gamification.go）
+	Achievements map[string]time.Time `json:"achievements,omitempty"`
+
+	// 每日摘要信的設定；DigestSentOn 是最後寄出的日期（2006-01-02）
+	DigestEnabled bool   `json:"digest_enabled,omitempty"`
+	DigestHour    int    `json:"digest_hour,omitempty"`
+	DigestDays    int    `json:"digest_days,omitempty"`
+	DigestSentOn  string `json:"digest_sent_on,omitempty"`
+
+	// PushSubscriptions 是各裝置瀏覽器的 Web Push 訂閱，分頁關閉時提醒改由推播送出
+	PushSubscriptions []PushSubscription `json:"push_subscriptions,omitempty"`
+
+	// 日期顯示偏好，見 datefmt.go
+	Locale  string `json:"locale,omitempty"`
+	Clock12 bool   `json:"clock12,omitempty"`
+	ROCYear bool   `json:"roc_year,omitempty"`
+
+	// 排程衝突提醒的門檻（見 conflicts.go），0 為預設值、負數為關閉
+	ConflictHour int `json:"conflict_hour,omitempty"`
+	ConflictDay  int `json:"conflict_day,omitempty"`
+
+	// ShowTaskIDs 開啟時，清單與通知裡的任務前面加上 #編號（見 tasklink.go）
+	ShowTaskIDs bool `json:"show_task_ids,omitempty"`
+
+	// WeekStart 是一週的第一天，只接受 Sunday（預設）或 Monday；
+	// WeekNumbers 開啟時月曆左側顯示 ISO 週次
+	WeekStart   time.Weekday `json:"week_start,omitempty"`
+	WeekNumbers bool         `json:"week_numbers,omitempty"`
+
+	// SortBy 是清單頁的排序方式（見 sort.go），空字串等同依到期時間
+	SortBy string `json:"sort_by,omitempty"`
+
+	// MagicSeq 是 Email 登入連結用過的次數，讓連結只能用一次；MagicSentAt 是上次寄出的時間（見 magiclink.go）
+	MagicSeq    int       `json:"magic_seq,omitempty"`
+	MagicSentAt time.Time `json:"magic_sent_at"`
+
+	// OverduePolicy 是逾期任務每天午夜的處理方式，空字串是不處理（見 overdue.go）
+	OverduePolicy string `json:"overdue_policy,omitempty"`
 }
 
+// RoleAdmin 可發布公告任務；第一位註冊的使用者自動成為管理員。
+// RoleTeacher 由管理員指定，可以對自己的學生名單派發作業
+const (
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+)
+
 type Task struct {
 	ID          int       `json:"id"`
 	Description string    `json:"description"`
 	Completed   bool      `json:"completed"`
+	CompletedAt time.Time `json:"completed_at"` // 未完成時為零值
 	CreatedAt   time.Time `json:"created_at"`
 	DueAt       time.Time `json:"due_at"`
 	Username    string    `json:"username"`
+
+	// AllDay 的任務只有日期，DueAt 是當天零點，到當天結束才算逾期（見 allday.go）
+	AllDay bool `json:"all_day,omitempty"`
+
+	// Status 是看板上的欄位（見 board.go），與 Completed 同步；
+	// 舊資料沒有這個欄位，讀取時一律透過 EffectiveStatus 依 Completed 判斷
+	Status string `json:"status,omitempty"`
+
+	// StartAt 不為零值時是跨日任務的開始日，月曆上從這天一直顯示到 DueAt（見 batchadd.go）
+	StartAt time.Time `json:"start_at"`
+
+	// AnnouncementID 不為 0 時，此任務是由公告發送給個人的副本
+	AnnouncementID int `json:"announcement_id,omitempty"`
+
+	// ProjectID 不為 0 時屬於共享專案；專案任務的 Username 是負責人，
+	// 空字串代表尚未認領
+	ProjectID int    `json:"project_id,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// Private 的專案任務只有負責人看得到，其他成員在任何地方都看不到
+	Private bool `json:"private,omitempty"`
+
+	// Recurrence 是重複規則（daily、weekly、monthly、weekdays），
+	// NextSpawned 記錄下一次的任務是否已產生
+	Recurrence  string `json:"recurrence,omitempty"`
+	NextSpawned bool   `json:"next_spawned,omitempty"`
+
+	// Priority 是 high、medium 或 low；舊資料為空字串，視為 medium
+	Priority string `json:"priority"`
+
+	Tags []string `json:"tags,omitempty"`
+
+	Checklist []ChecklistItem `json:"checklist,omitempty"`
+
+	// BlockedBy 是要先完成的任務編號，全部完成前這個任務不能完成（見 deps.go）
+	BlockedBy []int `json:"blocked_by,omitempty"`
+
+	// SortOrder 是手動排序的位置，由小到大；0 是還沒調整過的任務（見 sort.go）
+	SortOrder int `json:"sort_order,omitempty"`
+
+	// Activity 是任務的修改紀錄與留言（見 activity.go），依時間先後排列
+	Activity []TaskActivity `json:"activity,omitempty"`
+
+	// TimeEntries 是計時紀錄（見 timetrack.go），最後一筆沒有 End 代表正在計時
+	TimeEntries []TimeEntry `json:"time_entries,omitempty"`
+
+	// Pomodoros 是完成的番茄鐘，記的是每一顆工作時間結束的時間（見 pomodoro.go）
+	Pomodoros []time.Time `json:"pomodoros,omitempty"`
+
+	// EncryptedNote 是瀏覽器加密後的筆記（見 notes.go），伺服器不知道內容
+	EncryptedNote string `json:"encrypted_note,omitempty"`
+
+	// Reminders 是到期前幾分鐘提醒，由大到小；RemindersSet 為 false 時改用負責人的預設提醒（見 reminders.go）
+	Reminders    []int `json:"reminders,omitempty"`
+	RemindersSet bool  `json:"reminders_set,omitempty"`
+
+	// RemindedDue 是已寄出提醒時的到期時間，與 DueAt 不同代表還沒提醒過；
+	// RemindedOffset 是當時送出的是到期前幾分鐘的提醒
+	RemindedDue    time.Time `json:"reminded_due"`
+	RemindedOffset int       `json:"reminded_offset,omitempty"`
+
+	// DeletedAt 不為零值代表任務在垃圾桶裡（見 trash.go）
+	DeletedAt time.Time `json:"deleted_at"`
+
+	// SharedWith 是擁有者分享的對象，他們的清單裡也看得到這個任務（見 sharing.go）
+	SharedWith []string `json:"shared_with,omitempty"`
+
+	// Archived 的任務不出現在清單與月曆（見 archive.go）
+	Archived bool `json:"archived,omitempty"`
+
+	// Countdown 的任務在各頁頂端顯示倒數（見 countdown.go）
+	Countdown bool `json:"countdown,omitempty"`
+
+	// ReviewedAt 是「有空再做」的任務上次在回顧中選擇保留的時間（見 review.go）
+	ReviewedAt time.Time `json:"reviewed_at"`
+
+	// LinkSeq 是信件中一鍵操作連結被使用過的次數，用來讓連結只能用一次（見 actionlink.go）
+	LinkSeq int `json:"link_seq,omitempty"`
 }
 
-type AppData struct {
-	Users  []User `json:"users"`
-	Tasks  []Task `json:"tasks"`
-	NextID int    `json:"next_id"`
+// setCompleted 變更完成狀態並同步 CompletedAt 與看板欄位；改回未完成的任務也一併取消封存，
+// 完成時停止計時。動態記在負責人名下
+func (t *Task) setCompleted(done bool, now time.Time) {
+	t.setCompletedBy(done, now, t.Username)
+}
+
+// setCompletedBy 和 setCompleted 一樣，但動態記在實際操作的 user 名下，例如被分享的人勾選時
+func (t *Task) setCompletedBy(done bool, now time.Time, user string) {
+	if done == t.Completed {
+		return
+	}
+	t.Completed = done
+	if done {
+		t.CompletedAt = now
+		t.Status = StatusDone
+		t.stopTimer(now)
+		t.addActivity(now, user, ActivityCompleted, "")
+	} else {
+		t.CompletedAt = time.Time{}
+		t.Archived = false
+		t.Status = StatusTodo
+		t.addActivity(now, user, ActivityReopened, "")
+	}
 }
 
-// --- 全域變數 ---
+// Project 是多位成員共用的任務池
+type Project struct {
+	ID       int            `json:"id"`
+	Name     string         `json:"name"`
+	Owner    string         `json:"owner"`
+	Members  []string       `json:"members"` // 含 Owner
+	Activity []ProjectEvent `json:"activity,omitempty"`
+}
 
-var appData *AppData
-var sessions = make(map[string]string) // sessionID -> username
+// ProjectEvent 是專案動態（認領、轉派等），顯示在專案頁通知所有成員
+type ProjectEvent struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Announcement 是管理員一次發給所有成員的任務
+type Announcement struct {
+	ID          int       `json:"id"`
+	Description string    `json:"description"`
+	DueAt       time.Time `json:"due_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   string    `json:"created_by"`
+	Recipients  []string  `json:"recipients"`
+
+	// Kind 為空字串是管理員的公告，AnnouncementKindAssignment 是老師派給學生的作業
+	Kind string `json:"kind,omitempty"`
+}
+
+const AnnouncementKindAssignment = "assignment"
+
+type AppData struct {
+	Users              []User         `json:"users"`
+	Tasks              []Task         `json:"tasks"`
+	NextID             int            `json:"next_id"`
+	Announcements      []Announcement `json:"announcements,omitempty"`
+	NextAnnouncementID int            `json:"next_announcement_id,omitempty"`
+	Projects           []Project      `json:"projects,omitempty"`
+	NextProjectID      int            `json:"next_project_id,omitempty"`
+	Sessions           []Session      `json:"sessions,omitempty"`
+
+	// EventSeq 是快照包含到的最後一筆事件序號，只有事件紀錄儲存使用（見 store_events.go）
+	EventSeq int64 `json:"event_seq,omitempty"`
+}
 
 // --- 輔助函式 ---
 
-func hashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
+func (a *App) getUsername(r *http.Request) string {
+	if username, ok := r.Context().Value(apiUserKey{}).(string); ok {
+		return username // 以 API token 通過驗證的請求，見 requireAPIAuth
+	}
+	return a.sessions.Username(r)
+}
+
+// startSession 建立新 session 並寫入 cookie
+func (a *App) startSession(w http.ResponseWriter, r *http.Request, username string) {
+	a.sessions.Start(w, r, username)
 }
 
-func loadData() {
-	file, err := os.ReadFile("app_data.json")
-	if err == nil && len(file) > 0 {
-		json.Unmarshal(file, appData)
+// endSession 移除目前的 session 並清除 cookie
+func (a *App) endSession(w http.ResponseWriter, r *http.Request) {
+	a.sessions.End(w, r)
+}
+
+// safeRedirectPath 只接受站內路徑：相對路徑必須以單一 "/" 開頭，
+// 絕對網址必須與本站同 host，否則一律回到 "/"，避免被 Referer 帶去外部網站
+func safeRedirectPath(r *http.Request, target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Opaque != "" || u.User != nil {
+		return "/"
+	}
+	if u.IsAbs() || u.Host != "" {
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host != r.Host {
+			return "/"
+		}
+	}
+	path := u.EscapedPath()
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(u.Path, "\\") {
+		return "/"
 	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
 }
 
-func saveData() {
-	data, _ := json.MarshalIndent(appData, "", "  ")
-	os.WriteFile("app_data.json", data, 0644)
+// redirectBack 以 303 導回來源頁（Referer），來源不在站內時回首頁
+func redirectBack(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, safeRedirectPath(r, r.Header.Get("Referer")), http.StatusSeeOther)
 }
 
-func getUsername(r *http.Request) string {
-	cookie, err := r.Cookie("session")
-	if err != nil {
-		return ""
+// filterTasks 依清單頁的過濾條件（""、today、week、incomplete）篩選任務；
+// week 是 now 所在的那一週，依 weekStart 決定從週日或週一算起
+func filterTasks(tasks []Task, filter string, now time.Time, weekStart time.Weekday) []Task {
+	weekFrom := DatePrefs{WeekStart: weekStart}.StartOfWeek(now)
+	weekTo := weekFrom.AddDate(0, 0, 7).Format("2006-01-02")
+	var result []Task
+	for _, task := range tasks {
+		if filter == "today" {
+			if task.DueAt.Format("2006-01-02") != now.Format("2006-01-02") {
+				continue
+			}
+		} else if filter == "week" {
+			day := task.DueAt.Format("2006-01-02")
+			if day < weekFrom.Format("2006-01-02") || day >= weekTo {
+				continue
+			}
+		} else if filter == "incomplete" {
+			if task.Completed {
+				continue
+			}
+		} else if day, ok := strings.CutPrefix(filter, dayFilterPrefix); ok {
+			if task.DueAt.Format("2006-01-02") != day {
+				continue
+			}
+		} else if tag, ok := strings.CutPrefix(filter, tagFilterPrefix); ok {
+			if !task.HasTag(tag) {
+				continue
+			}
+		}
+		result = append(result, task)
 	}
-	return sessions[cookie.Value]
+	return result
 }
 
-func requireAuth(next http.HandlerFunc) http.HandlerFunc {
+// smartSort 智慧排序：逾期且未完成的優先 -> 接著按到期時間
+func smartSort(tasks []Task, now time.Time) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		iOver := tasks[i].OverdueAt(now)
+		jOver := tasks[j].OverdueAt(now)
+
+		if iOver != jOver {
+			return iOver // 如果一個逾期一個沒逾期，逾期的排前面
+		}
+		iRank, jRank := priorityRank(tasks[i].Priority), priorityRank(tasks[j].Priority)
+		if iOver && iRank != jRank {
+			return iRank < jRank // 逾期的任務中，優先順序高的排前面
+		}
+		if !tasks[i].DueAt.Equal(tasks[j].DueAt) {
+			return tasks[i].DueAt.Before(tasks[j].DueAt) // 否則按時間排
+		}
+		return iRank < jRank
+	})
+}
+
+func (a *App) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if getUsername(r) == "" {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+		if a.getUsername(r) == "" {
+			// 從信件或通知點進來的深層連結，登入後回到原本的頁面
+			target := "/login"
+			if r.Method == "GET" && r.URL.Path != "/" {
+				target += "?next=" + url.QueryEscape(r.URL.RequestURI())
+			}
+			http.Redirect(w, r, target, http.StatusSeeOther)
 			return
 		}
+		a.sessions.Touch(w, r)
 		next(w, r)
 	}
 }
 
-func remainingTime(d time.Time) string {
-	now := time.Now()
-	diff := d.Sub(now)
+func (a *App) isAdmin(username string) bool {
+	user, err := a.store.GetUser(username)
+	return err == nil && user.Role == RoleAdmin
+}
 
-	if diff > 0 {
-		if diff.Hours() >= 24 {
-			return fmt.Sprintf("剩 %.0f 天", diff.Hours()/24)
-		}
-		if diff.Hours() >= 1 {
-			return fmt.Sprintf("剩 %.0f 小時", diff.Hours())
+func (a *App) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return a.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !a.isAdmin(a.getUsername(r)) {
+			http.Error(w, "需要管理員權限", http.StatusForbidden)
+			return
 		}
-		return fmt.Sprintf("剩 %.0f 分鐘", diff.Minutes())
-	}
+		next(w, r)
+	})
+}
 
-	diff = now.Sub(d)
-	if diff.Hours() >= 24 {
-		return fmt.Sprintf("已逾期 %.0f 天", diff.Hours()/24)
+// ensureAdmin 讓舊資料也有管理員：若沒有任何管理員，就把最早註冊的使用者設為管理員
+func (a *App) ensureAdmin() error {
+	users, err := a.store.ListUsers()
+	if err != nil || len(users) == 0 {
+		return err
 	}
-	if diff.Hours() >= 1 {
-		return fmt.Sprintf("已逾期 %.0f 小時", diff.Hours())
+	for _, user := range users {
+		if user.Role == RoleAdmin {
+			return nil
+		}
 	}
-	return fmt.Sprintf("已逾期 %.0f 分鐘", diff.Minutes())
+	_, err = a.store.ModifyUser(users[0].Username, func(u *User) error {
+		u.Role = RoleAdmin
+		return nil
+	})
+	return err
 }
 
-// --- HTML 模板 ---
+// remainingTime 是中文的剩餘時間；頁面模板的 remain 由 localize 換成請求的語系（見 i18n.go）
+func remainingTime(d time.Time) string {
+	return remainingTimeIn(LocaleZhTW, d)
+}
 
-const loginTemplate = `
-<!DOCTYPE html>
-<html lang="zh-TW">
-<head>
-<meta charset="UTF-8">
-<meta name="viewport" content="width=device-width, initial-scale=1.0">
-<title>登入 - To-Do List</title>
-<style>
-body { font-family: 'Microsoft JhengHei', sans-serif; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; }
-.container { background: white; padding: 2rem; border-radius: 12px; box-shadow: 0 8px 16px rgba(0,0,0,0.2); width: 360px; }
-h1 { text-align: center; color: #333; margin-bottom: 1.5rem; }
-.form-group { margin-bottom: 1rem; }
-label { display: block; margin-bottom: 0.5rem; color: #555; font-weight: 500; }
-input[type="text"], input[type="password"] { width: 100%; padding: 10px; border: 1px solid #ddd; border-radius: 4px; box-sizing: border-box; font-size: 14px; }
-button { width: 100%; padding: 12px; background-color: #667eea; color: white; border: none; border-radius: 4px; cursor: pointer; font-size: 16px; font-weight: 500; margin-top: 1rem; }
-button:hover { background-color: #5568d3; }
-.switch { text-align: center; margin-top: 1rem; color: #666; }
-.switch a { color: #667eea; text-decoration: none; font-weight: 500; }
-.switch a:hover { text-decoration: underline; }
-.error { color: #dc3545; text-align: center; margin-bottom: 1rem; font-size: 14px; }
-</style>
-</head>
-<body>
-<div class="container">
-<h1>{{if .IsRegister}}註冊帳號{{else}}登入系統{{end}}</h1>
-{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
-
-<form method="POST">
-    <div class="form-group">
-        <label>使用者名稱</label>
-        <input type="text" name="username" required autofocus>
-    </div>
-    <div class="form-group">
-        <label>密碼</label>
-        <input type="password" name="password" required>
-    </div>
-    <button type="submit">{{if .IsRegister}}註冊{{else}}登入{{end}}</button>
-</form>
-
-<div class="switch">
-    {{if .IsRegister}}
-        已有帳號？<a href="/login">前往登入</a>
-    {{else}}
-        還沒帳號？<a href="/register">立即註冊</a>
-    {{end}}
-</div>
-</div>
-</body>
-</html>
-`
-
-const listTemplate = `
-<!DOCTYPE html>
-<html lang="zh-TW">
-<head>
-<meta charset="UTF-8">
-<meta name="viewport" content="width=device-width, initial-scale=1.0">
-<title>我的待辦清單</title>
-<style>
-body { font-family: 'Microsoft JhengHei', sans-serif; background-color: #f4f4f9; margin: 0; padding-top: 20px; }
-.header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1.5rem; margin-bottom: 20px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
-.header-content { max-width: 800px; margin: 0 auto; display: flex; justify-content: space-between; align-items: center; }
-.header h1 { margin: 0; font-size: 1.8rem; }
-.user-info { display: flex; gap: 15px; align-items: center; }
-.username { font-size: 1rem; }
-.nav-links a { color: white; text-decoration: none; padding: 8px 15px; border-radius: 4px; background: rgba(255,255,255,0.2); transition: background 0.3s; }
-.nav-links a:hover { background: rgba(255,255,255,0.3); }
-.container { max-width: 800px; margin: 0 auto; padding: 0 1rem; }
-.view-toggle { display: flex; gap: 10px; margin-bottom: 20px; justify-content: center; }
-.view-toggle a { padding: 10px 20px; background: white; color: #667eea; text-decoration: none; border-radius: 4px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); transition: all 0.3s; }
-.view-toggle a:hover, .view-toggle a.active { background: #667eea; color: white; }
-.input-group { display: flex; gap: 10px; margin-bottom: 20px; background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 6px rgba(0,0,0,0.1); }
-input[type="text"], input[type="datetime-local"] { padding: 10px; border: 1px solid #ddd; border-radius: 4px; }
-input[type="text"] { flex: 1; }
-button.add-btn { padding: 10px 20px; background-color: #28a745; color: white; border: none; border-radius: 4px; cursor: pointer; font-weight: 500; }
-button.add-btn:hover { background-color: #218838; }
-.task-list { background: white; border-radius: 8px; box-shadow: 0 2px 6px rgba(0,0,0,0.1); }
-ul { list-style: none; padding: 0; margin: 0; }
-li { border-bottom: 1px solid #eee; padding: 15px; display: flex; align-items: center; justify-content: space-between; }
-li:last-child { border-bottom: none; }
-.task-content { display: flex; align-items: center; gap: 10px; flex: 1; }
-.completed { text-decoration: line-through; color: #888; }
-.time { font-size: 0.85em; margin-left: 10px; color: #666; }
-.red { color: #dc3545; font-weight: 500; }
-.actions a { text-decoration: none; color: #dc3545; margin-left: 10px; font-size: 0.9em; }
-.actions a:hover { text-decoration: underline; }
-.empty-state { text-align: center; padding: 3rem; color: #888; font-size: 1.1rem; }
-.filter-tabs { display: flex; gap: 10px; margin-bottom: 15px; justify-content: center; }
-.filter-tabs a { padding: 5px 15px; border-radius: 15px; text-decoration: none; font-size: 0.9rem; color: #555; background: #e9ecef; }
-.filter-tabs a.active { background: #667eea; color: white; }
-</style>
-</head>
-<body>
-<div class="header">
-    <div class="header-content">
-        <h1>📝 我的待辦清單</h1>
-        <div class="user-info">
-            <span class="username">👤 {{.Username}}</span>
-            <div class="nav-links">
-                <a href="/logout">登出</a>
-            </div>
-        </div>
-    </div>
-</div>
-
-<div class="container">
-    <div style="text-align:center; margin-bottom:15px;">
-        {{if gt .OverdueCount 0}}
-            <span style="color:#dc3545; font-weight:500;">⚠️ 你有 {{.OverdueCount}} 個逾期任務</span>
-        {{end}}
-    </div>
-
-    <div class="view-toggle">
-        <a href="/" class="active">📋 清單模式</a>
-        <a href="/calendar">📅 月曆模式</a>
-    </div>
-
-    <div class="filter-tabs">
-        <a href="/?filter=" class="{{if eq .Filter ""}}active{{end}}">全部</a>
-        <a href="/?filter=today" class="{{if eq .Filter "today"}}active{{end}}">今日任務</a>
-        <a href="/?filter=incomplete" class="{{if eq .Filter "incomplete"}}active{{end}}">未完成</a>
-    </div>
-
-    <form action="/add" method="POST" class="input-group">
-        <input type="text" name="description" placeholder="輸入新的待辦事項..." required>
-        <input type="datetime-local" name="due_at" required max="9999-12-31T23:59">
-        <button type="submit" class="add-btn">新增</button>
-    </form>
-
-    <div class="task-list">
-        <ul>
-        {{range .Tasks}}
-        <li>
-            <div class="task-content">
-                <form action="/toggle" method="POST" style="margin:0;">
-                    <input type="hidden" name="id" value="{{.ID}}">
-                    <input type="checkbox" onchange="this.form.submit()" {{if .Completed}}checked{{end}}>
-                </form>
-
-                <span class="{{if .Completed}}completed{{end}}">
-                    {{.Description}}
-                    <span class="time {{if .DueAt.Before now}}red{{end}}">
-                        到期：{{.DueAt.Format "01-02 15:04"}} ｜ {{remain .DueAt}}
-                    </span>
-                </span>
-            </div>
-
-            <div class="actions">
-                <a href="/delete?id={{.ID}}">刪除</a>
-            </div>
-        </li>
-        {{else}}
-        <li class="empty-state">目前沒有任務 🎉</li>
-        {{end}}
-        </ul>
-    </div>
-</div>
-
-<script>
-setTimeout(function(){ location.reload(); }, 60000);
-</script>
-</body>
-</html>
-`
-const calendarTemplate = `
-<!DOCTYPE html>
-<html lang="zh-TW">
-<head>
-<meta charset="UTF-8">
-<meta name="viewport" content="width=device-width, initial-scale=1.0">
-<title>月曆 - 待辦清單</title>
-<style>
-body { font-family: 'Microsoft JhengHei', sans-serif; background-color: #f4f4f9; margin: 0; padding-top: 20px; }
-.header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1.5rem; margin-bottom: 20px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
-.header-content { max-width: 1200px; margin: 0 auto; display: flex; justify-content: space-between; align-items: center; }
-.header h1 { margin: 0; font-size: 1.8rem; }
-.user-info { display: flex; gap: 15px; align-items: center; }
-.username { font-size: 1rem; }
-.nav-links a { color: white; text-decoration: none; padding: 8px 15px; border-radius: 4px; background: rgba(255,255,255,0.2); transition: background 0.3s; }
-.nav-links a:hover { background: rgba(255,255,255,0.3); }
-.container { max-width: 1200px; margin: 0 auto; padding: 0 1rem; }
-.view-toggle { display: flex; gap: 10px; margin-bottom: 20px; justify-content: center; }
-.view-toggle a { padding: 10px 20px; background: white; color: #667eea; text-decoration: none; border-radius: 4px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); transition: all 0.3s; }
-.view-toggle a:hover, .view-toggle a.active { background: #667eea; color: white; }
-.calendar-nav { display: flex; justify-content: space-between; align-items: center; background: white; padding: 1rem; border-radius: 8px; margin-bottom: 20px; box-shadow: 0 2px 6px rgba(0,0,0,0.1); }
-.calendar-nav a { text-decoration: none; color: #667eea; padding: 8px 15px; border-radius: 4px; background: #f0f0f0; }
-.calendar-nav a:hover { background: #e0e0e0; }
-.calendar-nav h2 { margin: 0; color: #333; }
-.calendar { background: white; border-radius: 8px; box-shadow: 0 2px 6px rgba(0,0,0,0.1); padding: 1rem; }
-.calendar-grid { display: grid; grid-template-columns: repeat(7, 1fr); gap: 1px; background: #ddd; border: 1px solid #ddd; }
-.calendar-header { background: #667eea; color: white; padding: 10px; text-align: center; font-weight: 600; }
-.calendar-day { background: white; padding: 8px; min-height: 100px; position: relative; }
-.calendar-day.other-month { background: #f9f9f9; }
-.calendar-day.other-month .day-number { color: #bbb; }
-.calendar-day.today { background: #fff3cd; }
-.day-number { font-weight: 600; margin-bottom: 5px; color: #333; }
-.day-task { font-size: 0.75em; padding: 2px 4px; margin: 2px 0; background: #e7f3ff; border-radius: 3px; cursor: pointer; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
-.day-task.completed { background: #d4edda; text-decoration: line-through; color: #666; }
-.day-task.overdue { background: #f8d7da; color: #721c24; }
-.task-detail { position: fixed; top: 50%; left: 50%; transform: translate(-50%, -50%); background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 4px 12px rgba(0,0,0,0.3); z-index: 1000; min-width: 300px; display: none; }
-.overlay { position: fixed; top: 0; left: 0; right: 0; bottom: 0; background: rgba(0,0,0,0.5); z-index: 999; display: none; }
-.task-detail h3 { margin-top: 0; color: #333; }
-.task-detail-actions { display: flex; gap: 10px; margin-top: 1rem; }
-.task-detail-actions a, .task-detail-actions button { padding: 8px 15px; border-radius: 4px; text-decoration: none; cursor: pointer; border: none; font-size: 14px; }
-.close-btn { background: #6c757d; color: white; }
-.delete-btn { background: #dc3545; color: white; }
-</style>
-</head>
-<body>
-<div class="header">
-    <div class="header-content">
-        <h1>📅 月曆模式</h1>
-        <div class="user-info">
-            <span class="username">👤 {{.Username}}</span>
-            <div class="nav-links">
-                <a href="/logout">登出</a>
-            </div>
-        </div>
-    </div>
-</div>
-
-<div class="container">
-    <div class="view-toggle">
-        <a href="/">📋 清單模式</a>
-        <a href="/calendar" class="active">📅 月曆模式</a>
-    </div>
-
-    <div class="calendar-nav">
-        <a href="/calendar?year={{.PrevYear}}&month={{.PrevMonth}}">← 上個月</a>
-        <h2>{{printf "%d" .Year}} 年 {{printf "%d" .Month}} 月</h2>
-        <a href="/calendar?year={{.NextYear}}&month={{.NextMonth}}">下個月 →</a>
-    </div>
-
-    <div class="calendar">
-        <div class="calendar-grid">
-            <div class="calendar-header">日</div>
-            <div class="calendar-header">一</div>
-            <div class="calendar-header">二</div>
-            <div class="calendar-header">三</div>
-            <div class="calendar-header">四</div>
-            <div class="calendar-header">五</div>
-            <div class="calendar-header">六</div>
-            
-            {{range .Days}}
-            <div class="calendar-day {{.Class}}">
-                <div class="day-number">{{.Day}}</div>
-                {{range .Tasks}}
-                <div class="day-task {{if .Completed}}completed{{else if .IsOverdue}}overdue{{end}}" 
-                     onclick="showTask({{.ID}}, '{{.Description}}', '{{.DueAt.Format "2006-01-02 15:04"}}', {{.Completed}})">
-                    {{.Description}}
-                </div>
-                {{end}}
-            </div>
-            {{end}}
-        </div>
-    </div>
-</div>
-
-<div class="overlay" id="overlay" onclick="closeTask()"></div>
-<div class="task-detail" id="taskDetail">
-    <h3 id="taskTitle"></h3>
-    <p><strong>到期時間：</strong><span id="taskDue"></span></p>
-    <p><strong>狀態：</strong><span id="taskStatus"></span></p>
-    <div class="task-detail-actions">
-        <button class="close-btn" onclick="closeTask()">關閉</button>
-        <a id="deleteLink" class="delete-btn">刪除</a>
-    </div>
-</div>
-
-<script>
-function showTask(id, description, dueAt, completed) {
-    document.getElementById('taskTitle').textContent = description;
-    document.getElementById('taskDue').textContent = dueAt;
-    document.getElementById('taskStatus').textContent = completed ? '✅ 已完成' : '⏳ 待完成';
-    document.getElementById('deleteLink').href = '/delete?id=' + id;
-    document.getElementById('overlay').style.display = 'block';
-    document.getElementById('taskDetail').style.display = 'block';
-}
-
-function closeTask() {
-    document.getElementById('overlay').style.display = 'none';
-    document.getElementById('taskDetail').style.display = 'none';
-}
-</script>
-</body>
-</html>
-`
+// --- HTML 模板 ---
 
 // --- Handlers ---
 
-func loginHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) login(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
-		passwordHash := hashPassword(password)
-
-		for _, user := range appData.Users {
-			if user.Username == username && user.PasswordHash == passwordHash {
-				sessionID := fmt.Sprintf("%d", time.Now().UnixNano())
-				sessions[sessionID] = username
-				http.SetCookie(w, &http.Cookie{
-					Name:  "session",
-					Value: sessionID,
-					Path:  "/",
-				})
-				http.Redirect(w, r, "/", http.StatusSeeOther)
-				return
-			}
+
+		_, err := a.auth.Verify(r, "login", username, password)
+		if err == nil {
+			a.sessions.Start(w, r, username)
+			http.Redirect(w, r, safeRedirectPath(r, r.URL.Query().Get("next")), http.StatusSeeOther)
+			return
 		}
 
 		data := map[string]interface{}{
 			"IsRegister": false,
-			"Error":      "使用者名稱或密碼錯誤",
+			"Error":      localMessage(a.requestLocale(r), err, "登入失敗，請稍後再試"),
 		}
-		t, _ := template.New("login").Parse(loginTemplate)
-		t.Execute(w, data)
+		a.renderLogin(w, r, data)
 		return
 	}
 
-	data := map[string]interface{}{"IsRegister": false}
-	t, _ := template.New("login").Parse(loginTemplate)
+	data := map[string]interface{}{"IsRegister": false, "Next": r.URL.Query().Get("next")}
+	if r.URL.Query().Get("deleted") != "" {
+		data["Notice"] = a.tr(r, "帳號已刪除，謝謝你使用待辦清單")
+	}
+	if r.URL.Query().Get("signedout") != "" {
+		data["Notice"] = a.tr(r, "已登出所有裝置，請重新登入")
+	}
+	a.renderLogin(w, r, data)
+}
+
+// renderLogin 顯示登入／註冊頁，已啟用的第三方登入方式一併列出（見 oauth.go）
+func (a *App) renderLogin(w http.ResponseWriter, r *http.Request, data map[string]interface{}) {
+	data["Providers"] = oauthProviders
+	data["MagicLink"] = magicLinkEnabled
+	t := a.localize(r, a.pages.page("login"))
 	t.Execute(w, data)
 }
 
-func registerHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) register(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
 
-		for _, user := range appData.Users {
-			if user.Username == username {
-				data := map[string]interface{}{
-					"IsRegister": true,
-					"Error":      "使用者名稱已存在",
-				}
-				t, _ := template.New("login").Parse(loginTemplate)
-				t.Execute(w, data)
-				return
-			}
-		}
-
 		newUser := User{
 			Username:     username,
 			PasswordHash: hashPassword(password),
+			CreatedAt:    time.Now(),
+		}
+		// 第一位註冊的使用者是管理員，由儲存層在新增的同時判斷
+		if _, err := a.store.RegisterUser(newUser); err != nil {
+			msg := a.tr(r, "註冊失敗，請稍後再試")
+			if err == ErrUserExists {
+				msg = ErrUserExists.In(a.requestLocale(r))
+			}
+			a.renderLogin(w, r, map[string]interface{}{
+				"IsRegister": true,
+				"Error":      msg,
+			})
+			return
 		}
-		appData.Users = append(appData.Users, newUser)
-		saveData()
 
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
-	data := map[string]interface{}{"IsRegister": true}
-	t, _ := template.New("login").Parse(loginTemplate)
-	t.Execute(w, data)
+	a.renderLogin(w, r, map[string]interface{}{"IsRegister": true})
 }
 
-func logoutHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session")
-	if err == nil {
-		delete(sessions, cookie.Value)
+// logout 只接受 POST：GET 登出會被其他網站用 <img src="/logout"> 觸發，
+// 各頁面的登出按鈕是帶 csrf_token 的表單（見 flash.go 的 logoutTemplate）
+func (a *App) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:   "session",
-		Value:  "",
-		Path:   "/",
-		MaxAge: -1,
-	})
+	a.sessions.End(w, r)
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
-func indexHandler(w http.ResponseWriter, r *http.Request) {
-	username := getUsername(r)
+func (a *App) index(w http.ResponseWriter, r *http.Request) {
+	username := a.sessions.Username(r)
 	filter := r.URL.Query().Get("filter") // 取得過濾參數
+	rev := revisions.Of(username)         // 先取版本號再讀任務，讀取途中的變動下次輪詢仍會發現
+
+	allTasks, err := a.store.ListTasks(username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	allTasks = withoutArchived(allTasks)
+	if shared, err := a.sharedTasks(username); err == nil {
+		allTasks = append(allTasks, shared...)
+	}
 
-	var userTasks []Task
 	now := time.Now()
 
+	user, _ := a.store.GetUser(username)
+
 	// 篩選任務
-	for _, task := range appData.Tasks {
-		if task.Username == username {
-			if filter == "today" {
-				if task.DueAt.Format("2006-01-02") != now.Format("2006-01-02") {
-					continue
-				}
-			} else if filter == "incomplete" {
-				if task.Completed {
-					continue
-				}
-			}
-			userTasks = append(userTasks, task)
-		}
+	userTasks := filterTasks(allTasks, filter, now, user.WeekStart)
+	tagFilter, isTagFilter := strings.CutPrefix(filter, tagFilterPrefix)
+	if !isTagFilter {
+		tagFilter = ""
 	}
 
-	// 智慧排序：逾期且未完成的優先 -> 接著按到期時間
-	sort.SliceStable(userTasks, func(i, j int) bool {
-		iOver := userTasks[i].DueAt.Before(now) && !userTasks[i].Completed
-		jOver := userTasks[j].DueAt.Before(now) && !userTasks[j].Completed
-
-		if iOver != jOver {
-			return iOver // 如果一個逾期一個沒逾期，逾期的排前面
-		}
-		return userTasks[i].DueAt.Before(userTasks[j].DueAt) // 否則按時間排
-	})
+	sortTasks(userTasks, user.SortBy, username, now)
 
 	// 計算總逾期數（不管過濾條件，算給 Header 警告用的）
-	overdueCount := 0
-	for _, task := range appData.Tasks {
-		if task.Username == username && task.DueAt.Before(now) && !task.Completed {
+	overdueCount, completedCount := 0, 0
+	for _, task := range allTasks {
+		if task.OverdueAt(now) {
 			overdueCount++
 		}
+		if task.Completed {
+			completedCount++
+		}
 	}
 
-	funcMap := template.FuncMap{
-		"remain": remainingTime,
-		"now":    time.Now,
+	assignments := make(map[int]bool)
+	if list, err := a.store.ListAnnouncements(); err == nil {
+		for _, a := range list {
+			assignments[a.ID] = a.Kind == AnnouncementKindAssignment
+		}
 	}
 
+	projectNames := make(map[int]string)
+	if projects, err := a.store.ListProjects(username); err == nil {
+		for _, p := range projects {
+			projectNames[p.ID] = p.Name
+		}
+	}
+
+	desktopNotify := user.DesktopNotify
+
 	data := map[string]interface{}{
-		"Username":     username,
-		"Tasks":        userTasks,
-		"IsCalendar":   false,
-		"OverdueCount": overdueCount,
-		"Filter":       filter,
+		"Username":          username,
+		"DisplayName":       user.Name(),
+		"Game":              a.gameFor(user, now),
+		"ProjectNames":      projectNames,
+		"RecurrenceOptions": recurrenceOptions,
+		"PriorityOptions":   priorityOptions,
+		"Tasks":             userTasks,
+		"Blocked":           a.blockedLabels(userTasks),
+		"SortBy":            user.SortBy,
+		"SortOptions":       sortOptions,
+		"ManualSort":        user.SortBy == SortManual,
+		"IsCalendar":        false,
+		"OverdueCount":      overdueCount,
+		"CompletedCount":    completedCount,
+		"Filter":            filter,
+		"TagCloud":          collectTags(allTasks),
+		"TagFilter":         tagFilter,
+		"DayFilter":         strings.TrimPrefix(filter, dayFilterPrefix),
+		"IsDayFilter":       strings.HasPrefix(filter, dayFilterPrefix),
+		"IsAdmin":           a.isAdmin(username),
+		"IsTeacher":         a.isTeacher(username),
+		"Assignments":       assignments,
+		"DesktopNotify":     desktopNotify,
+		"ShowTaskIDs":       user.ShowTaskIDs,
+		"VAPIDKey":          vapidPublicKey(),
+		"MaxDescription":    maxDescriptionLength,
+		"Rev":               rev,
+		"Nonce":             newNonce(username),
+		"CSRFToken":         a.sessions.CSRFToken(r),
+		"Flashes":           a.sessions.PopFlashes(r),
 	}
 
-	t, _ := template.New("list").Funcs(funcMap).Parse(listTemplate)
+	t := a.localize(r, a.pages.page("list"))
 	t.Execute(w, data)
 }
 
-func calendarHandler(w http.ResponseWriter, r *http.Request) {
-	username := getUsername(r)
+func (a *App) calendarHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
 
 	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
 	month, _ := strconv.Atoi(r.URL.Query().Get("month"))
 
+	// week=2024-W19 直接跳到那一週所在的月份（以週四所在月份為準）並標示該週
+	focusWeek := ""
+	if week := r.URL.Query().Get("week"); week != "" {
+		monday, err := parseISOWeek(week, time.Local)
+		if err != nil {
+			a.flashError(r, err, "")
+			http.Redirect(w, r, "/calendar", http.StatusSeeOther)
+			return
+		}
+		thursday := monday.AddDate(0, 0, 3)
+		year, month = thursday.Year(), int(thursday.Month())
+		focusWeek = isoWeekLabel(monday)
+	}
+
 	if year == 0 {
 		now := time.Now()
 		year = now.Year()
 		month = int(now.Month())
 	}
 
-	firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
-	startWeekday := int(firstDay.Weekday())
-	startDate := firstDay.AddDate(0, 0, -startWeekday)
-
-	var days []map[string]interface{}
-	currentDate := startDate
-	now := time.Now()
-
-	for i := 0; i < 42; i++ {
-		var dayTasks []map[string]interface{}
-		for _, task := range appData.Tasks {
-			if task.Username == username {
-				taskDate := task.DueAt.Format("2006-01-02")
-				currentDateStr := currentDate.Format("2006-01-02")
-				if taskDate == currentDateStr {
-					dayTasks = append(dayTasks, map[string]interface{}{
-						"ID":          task.ID,
-						"Description": task.Description,
-						"Completed":   task.Completed,
-						"DueAt":       task.DueAt,
-						"IsOverdue":   task.DueAt.Before(now) && !task.Completed,
-					})
-				}
-			}
-		}
-
-		class := ""
-		if currentDate.Year() != year || int(currentDate.Month()) != month {
-			class = "other-month"
-		}
-		if currentDate.Format("2006-01-02") == now.Format("2006-01-02") {
-			class = "today"
-		}
-
-		days = append(days, map[string]interface{}{
-			"Day":   currentDate.Day(),
-			"Tasks": dayTasks,
-			"Class": class,
-		})
-
-		currentDate = currentDate.AddDate(0, 0, 1)
+	userTasks, err := a.store.ListTasks(username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
 	}
-
-	prevMonth := month - 1
-	prevYear := year
-	if prevMonth == 0 {
-		prevMonth = 12
-		prevYear--
+	userTasks = withoutArchived(userTasks)
+	feedToken, err := a.ensureFeedToken(username)
+	if err != nil {
+		http.Error(w, "讀取訂閱網址失敗", http.StatusInternalServerError)
+		return
 	}
 
-	nextMonth := month + 1
-	nextYear := year
-	if nextMonth == 13 {
-		nextMonth = 1
-		nextYear++
-	}
+	user, _ := a.store.GetUser(username)
+	prefs := a.requestDatePrefs(r)
+	filter := parseCalendarFilter(r.URL.Query())
+	cal := buildCalendarMonth(userTasks, prefs, year, month, focusWeek, filter, time.Now())
 
 	data := map[string]interface{}{
-		"Username":  username,
-		"Year":      year,
-		"Month":     month,
-		"Days":      days,
-		"PrevYear":  prevYear,
-		"PrevMonth": prevMonth,
-		"NextYear":  nextYear,
-		"NextMonth": nextMonth,
+		"Username":        username,
+		"Year":            cal.Year,
+		"Month":           cal.Month,
+		"Weeks":           cal.Weeks,
+		"WeekNumbers":     user.WeekNumbers || focusWeek != "",
+		"FocusWeek":       focusWeek,
+		"Weekdays":        cal.Weekdays,
+		"PrevYear":        cal.PrevYear,
+		"PrevMonth":       cal.PrevMonth,
+		"NextYear":        cal.NextYear,
+		"NextMonth":       cal.NextMonth,
+		"FeedURL":         a.requestBaseURL(r) + "/calendar.ics?token=" + feedToken,
+		"Filter":          filter,
+		"FilterQuery":     filter.Query(),
+		"Hidden":          cal.Hidden,
+		"TagCloud":        collectTags(userTasks),
+		"PriorityOptions": priorityOptions,
+		"MaxDescription":  maxDescriptionLength,
+		"Nonce":           newNonce(username),
+		"Flashes":         a.sessions.PopFlashes(r),
+		"CSRFToken":       a.sessions.CSRFToken(r),
 	}
 
-	t, _ := template.New("calendar").Parse(calendarTemplate)
+	t := a.localize(r, a.pages.page("calendar"))
 	t.Execute(w, data)
 }
 
-func addHandler(w http.ResponseWriter, r *http.Request) {
-	username := getUsername(r)
+func (a *App) addTask(w http.ResponseWriter, r *http.Request) {
+	username := a.sessions.Username(r)
 	if r.Method == "POST" {
-		desc := r.FormValue("description")
-		dueStr := r.FormValue("due_at")
-		dueAt, _ := time.Parse("2006-01-02T15:04", dueStr)
+		desc := strings.TrimSpace(r.FormValue("description"))
+		allDay := r.FormValue("all_day") != ""
+		dueAt, err := parseDueInput(r.FormValue("due_at"), allDay)
+		recurrence := r.FormValue("recurrence")
+		if !validRecurrence(recurrence) {
+			recurrence = RecurNone
+		}
+		priority := r.FormValue("priority")
+		if !validPriority(priority) {
+			priority = PriorityMedium
+		}
+		desc, markers, inlineErr := a.parseInline(desc, username)
+		if inlineErr == nil {
+			inlineErr = checkDescription(desc)
+		}
+		if inlineErr != nil {
+			a.flashError(r, inlineErr, "")
+			redirectBack(w, r)
+			return
+		}
+		if err != nil {
+			a.flashError(r, err, "")
+			redirectBack(w, r)
+			return
+		}
 
 		task := Task{
-			ID:          appData.NextID,
 			Description: desc,
 			Completed:   false,
 			CreatedAt:   time.Now(),
 			DueAt:       dueAt,
+			AllDay:      allDay,
 			Username:    username,
+			Recurrence:  recurrence,
+			Priority:    priority,
+			Tags:        parseTags(r.FormValue("tags")),
 		}
+		markers.apply(&task)
 
-		appData.Tasks = append(appData.Tasks, task)
-		appData.NextID++
-		saveData()
+		if a.resolveDuplicate(w, r, username, task) {
+			return
+		}
+		if r.FormValue("duplicate") == "" {
+			if existing, found, _ := a.ownDuplicate(username, task.Description); found {
+				a.renderDuplicate(w, r, username, existing, task)
+				return
+			}
+		}
+
+		if created, err := a.store.CreateTask(task); err != nil {
+			a.flashError(r, err, "新增任務失敗，請稍後再試")
+		} else {
+			a.announceInline(markers, created)
+			a.flashUndoable(r, undoOp{Kind: undoRestore, TaskID: created.ID, Label: a.tr(r, "新增「%s」", brief(created.Description))}, "任務已新增")
+			a.warnConflicts(r, username, created)
+		}
+		if r.FormValue("duplicate") != "" {
+			redirectAfterDuplicate(w, r)
+			return
+		}
 	}
 
-	referer := r.Header.Get("Referer")
-	if referer == "" {
-		referer = "/"
+	redirectBack(w, r)
+}
+
+func (a *App) toggleTask(w http.ResponseWriter, r *http.Request) {
+	username := a.sessions.Username(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	task, err := a.modifyCompletion(id, func(task *Task) error {
+		if !task.canEdit(username) {
+			return ErrNotFound
+		}
+		task.setCompletedBy(!task.Completed, time.Now(), username)
+		return nil
+	})
+	if err != nil && err != ErrNotFound {
+		a.flashError(r, err, "更新任務失敗，請稍後再試")
+	}
+	if err == nil {
+		op := undoOp{Kind: undoComplete, TaskID: task.ID, Completed: task.Completed}
+		if task.Completed {
+			op.Label = a.tr(r, "標記完成「%s」", brief(task.Description))
+			a.flashUndoable(r, op, "已將「%s」標記完成", brief(task.Description))
+		} else {
+			op.Label = a.tr(r, "標記未完成「%s」", brief(task.Description))
+			a.flashUndoable(r, op, "已將「%s」標記未完成", brief(task.Description))
+		}
 	}
-	http.Redirect(w, r, referer, http.StatusSeeOther)
+	if err == nil && task.Completed && task.Recurrence != RecurNone {
+		if err := a.spawnNextOccurrence(task.ID); err != nil {
+			a.flashError(r, err, "產生下一次重複任務失敗")
+		} else {
+			a.flashSuccess(r, "已排定下一次「%s」", brief(task.Description))
+		}
+	}
+	if err == nil && task.Completed {
+		a.announceUnblocked(r, username, task)
+		if task.Username == username {
+			a.announceAchievements(r, username)
+		}
+	}
+	redirectBack(w, r)
 }
 
-func toggleHandler(w http.ResponseWriter, r *http.Request) {
-	username := getUsername(r)
-	id, _ := strconv.Atoi(r.FormValue("id"))
-	for i := range appData.Tasks {
-		if appData.Tasks[i].ID == id && appData.Tasks[i].Username == username {
-			appData.Tasks[i].Completed = !appData.Tasks[i].Completed
-			saveData()
-			break
+func (a *App) editTask(w http.ResponseWriter, r *http.Request) {
+	username := a.sessions.Username(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	task, err := a.store.GetTask(id)
+	if err != nil || !task.canEdit(username) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method == "POST" {
+		desc := strings.TrimSpace(r.FormValue("description"))
+		allDay := r.FormValue("all_day") != ""
+		dueAt, err := parseDueInput(r.FormValue("due_at"), allDay)
+		recurrence := r.FormValue("recurrence")
+		priority := r.FormValue("priority")
+		note := r.FormValue("encrypted_note")
+		if desc == "" || !validRecurrence(recurrence) || !validPriority(priority) {
+			task.Description = r.FormValue("description")
+			a.renderEdit(w, r, task, invalidInput("請填寫任務內容與正確的到期時間"))
+			return
+		}
+		if err != nil {
+			task.Description = r.FormValue("description")
+			a.renderEdit(w, r, task, err)
+			return
+		}
+		if err := checkDescription(desc); err != nil {
+			task.Description = r.FormValue("description")
+			a.renderEdit(w, r, task, err)
+			return
+		}
+		if !validEncryptedNote(note) {
+			a.renderEdit(w, r, task, ErrInvalidNote)
+			return
+		}
+		blockers, err := parseBlockers(r.Form["blocked_by"])
+		if err == nil {
+			blockers, err = a.validateBlockers(task.ID, blockers, username)
+		}
+		if err != nil {
+			a.renderEdit(w, r, task, err)
+			return
+		}
+		remindersSet := r.FormValue("reminders_default") == ""
+		reminders, err := parseReminders(r.Form["reminder"])
+		if err != nil {
+			a.renderEdit(w, r, task, err)
+			return
+		}
+		if !remindersSet {
+			reminders = nil
+		}
+
+		updated, err := a.store.ModifyTask(id, func(t *Task) error {
+			if !t.canEdit(username) {
+				return ErrNotFound
+			}
+			before := *t
+			t.Description = desc
+			t.DueAt = dueAt
+			t.AllDay = allDay
+			t.Recurrence = recurrence
+			t.Priority = priority
+			t.Tags = parseTags(r.FormValue("tags"))
+			t.EncryptedNote = note
+			t.BlockedBy = blockers
+			t.Reminders = reminders
+			t.RemindersSet = remindersSet
+			t.recordEdit(username, before, time.Now())
+			return nil
+		})
+		if err != nil && err != ErrNotFound {
+			a.renderEdit(w, r, task, displayError(err, "更新任務失敗，請稍後再試"))
+			return
+		}
+		if err == nil {
+			a.flashUndoable(r, undoOp{Kind: undoEdit, TaskID: id, Label: a.tr(r, "編輯「%s」", brief(updated.Description)), From: fieldsOf(task), To: fieldsOf(updated)}, "任務已更新")
 		}
+		if err == nil && (!updated.DueAt.Equal(task.DueAt) || updated.AllDay != task.AllDay) {
+			a.warnConflicts(r, username, updated) // 只在改了到期時間時檢查，避免改個錯字也被提醒
+		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
 	}
-	http.Redirect(w, r, r.Header.Get("Referer"), http.StatusSeeOther)
+
+	a.renderEdit(w, r, task, nil)
+}
+
+// renderEdit 顯示編輯頁；err 不為 nil 時顯示在表單上方
+func (a *App) renderEdit(w http.ResponseWriter, r *http.Request, task Task, err error) {
+	errMsg := ""
+	if err != nil {
+		errMsg = localMessage(a.requestLocale(r), err, "")
+	}
+	blockers := make(map[int]bool, len(task.BlockedBy))
+	for _, id := range task.BlockedBy {
+		blockers[id] = true
+	}
+	data := map[string]interface{}{
+		"Task":              task,
+		"Error":             errMsg,
+		"Nonce":             newNonce(a.sessions.Username(r)),
+		"CSRFToken":         a.sessions.CSRFToken(r),
+		"RecurrenceOptions": recurrenceOptions,
+		"PriorityOptions":   priorityOptions,
+		"Priority":          effectivePriority(task.Priority),
+		"BlockerOptions":    a.blockerOptions(task, a.sessions.Username(r)),
+		"Blockers":          blockers,
+		"MaxDescription":    maxDescriptionLength,
+		"ReminderOptions":   reminderOptions(a.requestLocale(r), task.reminders(a.reminderOwner(task))),
+		"Flashes":           a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("edit"))
+	t.Execute(w, data)
 }
 
-func deleteHandler(w http.ResponseWriter, r *http.Request) {
-	username := getUsername(r)
-	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
-	for i, task := range appData.Tasks {
-		if task.ID == id && task.Username == username {
-			appData.Tasks = append(appData.Tasks[:i], appData.Tasks[i+1:]...)
-			saveData()
-			break
+func (a *App) deleteTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	username := a.sessions.Username(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	task, err := a.store.GetTask(id)
+	if err == nil && task.Username == username {
+		if err := a.trashTask(id, time.Now()); err != nil {
+			a.flashError(r, err, "刪除任務失敗，請稍後再試")
+		} else {
+			a.flashUndoable(r, undoOp{Kind: undoTrash, TaskID: id, Label: a.tr(r, "刪除「%s」", brief(task.Description))}, "任務已移到垃圾桶")
 		}
 	}
-	http.Redirect(w, r, r.Header.Get("Referer"), http.StatusSeeOther)
+	redirectBack(w, r)
 }
 
 // --- Main ---
 
 func main() {
-	appData = &AppData{
-		Users:  []User{},
-		Tasks:  []Task{},
-		NextID: 1,
-	}
-	loadData()
-
-	http.HandleFunc("/login", loginHandler)
-	http.HandleFunc("/register", registerHandler)
-	http.HandleFunc("/logout", logoutHandler)
-	http.HandleFunc("/", requireAuth(indexHandler))
-	http.HandleFunc("/calendar", requireAuth(calendarHandler))
-	http.HandleFunc("/add", requireAuth(addHandler))
-	http.HandleFunc("/toggle", requireAuth(toggleHandler))
-	http.HandleFunc("/delete", requireAuth(deleteHandler))
-
-	fmt.Println("Server started at http://localhost:8080")
-	fmt.Println("請先註冊帳號再登入使用")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	configPath := flag.String("config", os.Getenv(configEnvPrefix+"CONFIG"), "設定檔路徑（每行「名稱 = 值」）；命令列與 TODO_ 開頭的環境變數優先於設定檔")
+	timezone := flag.String("timezone", "", "預設時區（例如 Asia/Taipei），空白表示使用系統時區")
+	storeKind := flag.String("store", "json", "儲存後端：json、sqlite、eventlog（附加式事件紀錄，定期壓縮成快照）或 memory（不存檔，重新啟動就清空）")
+	seed := flag.Int64("seed", 0, "在沒有任何使用者的資料裡放入第 N 組範例帳號與任務（密碼皆為 "+seedPassword+"），0 表示不放；搭配 -store memory 即為展示模式")
+	dbPath := flag.String("db", "app_data.json", "資料檔路徑或 DSN（JSON 檔、SQLite 資料庫或事件紀錄的快照）")
+	sessionTTL := flag.Duration("session-ttl", 7*24*time.Hour, "登入有效期限，期間內有使用會自動延長")
+	secureCookies := flag.Bool("secure-cookies", false, "session cookie 加上 Secure（僅透過 HTTPS 傳送）")
+	persistSessions := flag.Bool("persist-sessions", true, "把 session 存進資料檔，重新啟動後不必重新登入")
+	smtpAddr := flag.String("smtp-addr", "", "SMTP 伺服器 host:port；未設定時信件只寫進 log（密碼請用環境變數 SMTP_PASSWORD）")
+	smtpFrom := flag.String("smtp-from", "todo@localhost", "寄件人地址")
+	smtpUser := flag.String("smtp-user", "", "SMTP 帳號，空白表示不需認證")
+	vapidKeyPath := flag.String("vapid-key", "vapid_key.pem", "Web Push 的 VAPID 私鑰（PEM），不存在時自動產生；空白表示停用推播")
+	flag.StringVar(&vapidSubject, "vapid-subject", "", "VAPID 聯絡資訊（mailto: 或 https: 網址），預設為 mailto: 加上 -smtp-from")
+	flag.DurationVar(&reminderWindow, "remind-window", reminderWindow, "使用者沒有設定預設提醒時，到期前多久提醒，0 表示預設不提醒")
+	linkKeyPath := flag.String("link-key", "link_key", "信件中一鍵操作連結的簽章金鑰，不存在時自動產生；空白表示停用一鍵連結")
+	flag.BoolVar(&magicLinkEnabled, "magic-link", false, "登入頁提供「用 Email 登入」：寄出 15 分鐘內有效、只能用一次的登入連結（需要 -link-key）")
+	flag.StringVar(&publicBaseURL, "base-url", "", "對外網址（例如 https://todo.example.com），用於信件中的連結；預設依監聽位址推算")
+	flag.StringVar(&auditLogPath, "audit-log", "audit.log", "稽核紀錄檔（合併帳號等管理操作），空白表示只寫進 log")
+	flag.DurationVar(&handlerTimeout, "handler-timeout", handlerTimeout, "每個請求的處理時間上限，超過時回 503；匯入等較重的頁面另有較長的上限")
+	flag.DurationVar(&slowRequest, "slow-request", slowRequest, "處理時間超過多久就記進 log 並通知管理員")
+	flag.DurationVar(&flushInterval, "flush-interval", flushInterval, "JSON 資料檔延後寫入的間隔：異動先留在記憶體，每隔這麼久整份寫回一次；0 表示每次異動都立刻寫檔")
+	flag.IntVar(&dataBackups, "backups", dataBackups, "JSON 資料檔保留幾份舊版本（app_data.json.1、.2…），每次存檔輪替一次；0 表示不保留")
+	flag.IntVar(&maxDescriptionLength, "max-description", maxDescriptionLength, "任務內容的字數上限，超過時拒絕新增或修改；各頁面顯示時另外截斷")
+	taskCacheMB := flag.Int("task-cache-mb", 64, "SQLite 後端的任務快取上限（MB）：啟動時不載入任務，用到時才依使用者讀進來，超過上限時淘汰最久沒用到的使用者；0 表示不快取")
+	googleClientID := flag.String("google-client-id", "", "Google 登入的 OAuth client ID（client secret 請用環境變數 GOOGLE_CLIENT_SECRET）；空白表示不啟用")
+	githubClientID := flag.String("github-client-id", "", "GitHub 登入的 OAuth client ID（client secret 請用環境變數 GITHUB_CLIENT_SECRET）；空白表示不啟用")
+	flag.StringVar(&requestLogFormat, "request-log", requestLogFormat, "請求紀錄的格式：text（key=value）、json（一行一個 JSON 物件）或 off")
+	flag.StringVar(&templateDir, "templates", "", "自訂頁面模板的目錄：裡面有同名檔案（例如 list.html、partials/flash.html）就取代內建的版本")
+	flag.BoolVar(&templateReload, "dev-templates", false, "開發模式：每個請求都重新讀取並解析模板（預設讀工作目錄的 templates/），改完重新整理就看得到")
+	flag.StringVar(&tlsCertFile, "tls-cert", "", "HTTPS 憑證檔（PEM，含中繼憑證）；與 -tls-key 一起指定時直接以 HTTPS 服務")
+	flag.StringVar(&tlsKeyFile, "tls-key", "", "HTTPS 私鑰檔（PEM）")
+	flag.StringVar(&autocertHosts, "autocert", "", "以 Let's Encrypt 自動取得憑證的主機名稱，多個以逗號分隔（需以 -tags autocert 編譯，-listen 通常設為 :443）")
+	flag.StringVar(&autocertCache, "autocert-cache", autocertCache, "自動取得的憑證與帳號金鑰存放的目錄")
+	flag.StringVar(&autocertEmail, "autocert-email", "", "Let's Encrypt 帳號的聯絡 Email（憑證快到期時通知），可留空")
+	flag.StringVar(&httpRedirectAddr, "http-redirect", "", "開啟 HTTPS 時另外監聽的 HTTP 位址（例如 :80），把請求轉到 HTTPS；空白表示不監聽")
+	listenAddr := flag.String("listen", ":8080", "監聽位址：TCP（:8080）或 unix:/path/to.sock；由 systemd socket activation 啟動時忽略")
+	flag.Parse()
+	if err := loadConfig(flag.CommandLine, *configPath); err != nil {
+		log.Fatal(err)
+	}
+	if err := setTimezone(*timezone); err != nil {
+		log.Fatal(err)
+	}
+	if err := setupRequestLog(requestLogFormat); err != nil {
+		log.Fatal(err)
+	}
+	if maxDescriptionLength < 1 {
+		log.Fatal("-max-description 必須大於 0")
+	}
+	pages, err := newTemplateSet(templateDir, templateReload)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tlsConfig, wrapRedirect, err := setupTLS()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := openStore(*storeKind, *dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	seeded := false
+	if *seed != 0 {
+		if seeded, err = seedIfEmpty(store, *seed); err != nil {
+			log.Fatal(err)
+		}
+	}
+	lazy := *storeKind == "sqlite"
+	if lazy {
+		store = withTaskCache(store, *taskCacheMB)
+	}
+	var index *searchIndex
+	if store, index, err = withSearchIndex(store, lazy); err != nil {
+		log.Fatal(err)
+	}
+	store = withRevisions(withPersistMetrics(store))
+	app := newApp(store, newSessionManager(store, *sessionTTL, *secureCookies || tlsConfig != nil, *persistSessions), pages)
+	app.taskIndex = index
+	app.metricsToken = os.Getenv("METRICS_TOKEN")
+	if err := app.ensureAdmin(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *smtpAddr != "" {
+		mailer = smtpMailer{addr: *smtpAddr, from: *smtpFrom, username: *smtpUser, password: os.Getenv("SMTP_PASSWORD")}
+	}
+	configureOAuth(*googleClientID, *githubClientID)
+
+	if *vapidKeyPath != "" {
+		if vapidKey, err = loadVAPIDKey(*vapidKeyPath); err != nil {
+			log.Fatal(err)
+		}
+		if vapidSubject == "" {
+			vapidSubject = "mailto:" + *smtpFrom
+		}
+	}
+
+	if *linkKeyPath != "" {
+		if linkKey, err = loadLinkKey(*linkKeyPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if magicLinkEnabled && linkKey == nil {
+		log.Fatal("-magic-link 需要 -link-key 的簽章金鑰")
+	}
+
+	if err := app.sessions.load(); err != nil {
+		log.Fatal(err)
+	}
+	handler := app.Handler()
+
+	scheduler.Add("recurrence", nextMidnight, app.materializeRecurring)
+	scheduler.Add("session-purge", every(time.Hour), app.sessions.Purge)
+	scheduler.Add("reminders", every(reminderInterval), app.sendReminders)
+	scheduler.Add("digest", nextHour, app.sendDigests)
+	scheduler.Add("trash-purge", nextMidnight, app.purgeTrash)
+	scheduler.Add("overdue", nextMidnight, app.applyOverduePolicies)
+	scheduler.Add("someday-review", nextMonth, app.sendSomedayReviews)
+	if *storeKind == "json" && dataBackups > 0 {
+		scheduler.Add("backup-verify", nextBackupCheck, app.verifyBackupJob(*dbPath))
+	}
+	scheduler.Start()
+
+	ln, err := openListener(*listenAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if httpRedirectAddr != "" {
+		if err := startHTTPRedirect(httpRedirectAddr, ln, wrapRedirect); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if publicBaseURL == "" {
+		publicBaseURL = listenerURL(ln, tlsConfig != nil)
+	}
+	publicBaseURL = strings.TrimSuffix(publicBaseURL, "/")
+	fmt.Println("Server started at " + listenerURL(ln, tlsConfig != nil))
+	fmt.Println("時區：" + time.Local.String())
+	if seeded {
+		fmt.Printf("已放入範例資料（-seed %d）：帳號 %s，密碼皆為 %s\n", *seed, strings.Join(seedUsers, "、"), seedPassword)
+	} else {
+		fmt.Println("請先註冊帳號再登入使用")
+	}
+	if err := app.serve(ln, handler, tlsConfig); err != nil {
+		log.Fatal(err)
+	}
+	scheduler.Stop()
+	if err := store.Close(); err != nil {
+		log.Fatalf("寫回資料失敗：%v", err)
+	}
+	log.Println("已寫回資料，伺服器已關閉")
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+)
+
+// --- Flash 訊息 ---
+//
+// 操作結果（成功或錯誤）先存在 session，下一個頁面渲染時顯示一次就清掉，
+// 讓 POST -> 303 -> GET 之後使用者仍看得到結果
+
+const (
+	FlashSuccess = "success"
+	FlashWarning = "warning"
+	FlashError   = "error"
+)
+
+type Flash struct {
+	Kind    string
+	Message string
+	Undo    *FlashUndo // 不為 nil 時訊息旁顯示「復原」按鈕
+	Link    *FlashLink // 不為 nil 時訊息後附上連結
+}
+
+// FlashLink 是附在訊息後的站內連結
+type FlashLink struct {
+	URL   string
+	Label string
+}
+
+// FlashUndo 是復原按鈕送出時需要的資料。有 TaskID 時從垃圾桶復原該任務（見 trash.go 的 flashUndo），
+// 否則送到 Action（/undo 或 /redo，見 undo.go）
+type FlashUndo struct {
+	TaskID    int
+	Action    string
+	Nonce     string
+	CSRFToken string
+}
+
+// AddFlash 把訊息掛在目前的 session 上；未登入時直接丟棄
+func (m *sessionManager) AddFlash(r *http.Request, kind, message string) {
+	m.pushFlash(r, Flash{Kind: kind, Message: message})
+}
+
+func (m *sessionManager) pushFlash(r *http.Request, f Flash) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[hashSessionToken(cookie.Value)]; ok {
+		s.flashes = append(s.flashes, f)
+	}
+}
+
+// PopFlashes 取出並清空目前 session 的訊息
+func (m *sessionManager) PopFlashes(r *http.Request) []Flash {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[hashSessionToken(cookie.Value)]
+	if !ok {
+		return nil
+	}
+	flashes := s.flashes
+	s.flashes = nil
+	return flashes
+}
+
+// maxFlashDetails 是一次操作最多列出幾則明細，其餘合併成一則，匯入大檔時才不會整頁都是訊息
+const maxFlashDetails = 5
+
+// flashDetails 以同一種 kind 列出多則明細（例如匯入失敗的每一列），超過 maxFlashDetails 的只顯示數量
+func (a *App) flashDetails(r *http.Request, kind string, details []error) {
+	for i, d := range localMessages(a.requestLocale(r), details) {
+		if i == maxFlashDetails {
+			a.sessions.AddFlash(r, kind, a.tr(r, "……還有 %d 則未列出", len(details)-i))
+			return
+		}
+		a.sessions.AddFlash(r, kind, d)
+	}
+}
+
+// tr 把 format 翻成這個請求的語系再代入 args。訊息在放進 session 時就翻好，
+// 所以 flash 的明細與復原紀錄裡的文字都用這裡先翻譯
+func (a *App) tr(r *http.Request, format string, args ...interface{}) string {
+	return tr(a.requestLocale(r), format, args...)
+}
+
+func (a *App) flashSuccess(r *http.Request, format string, args ...interface{}) {
+	a.sessions.AddFlash(r, FlashSuccess, a.tr(r, format, args...))
+}
+
+// flashError 顯示錯誤；非 DomainError 時以 fallback 代替，不外洩內部錯誤細節
+func (a *App) flashError(r *http.Request, err error, fallback string) {
+	a.sessions.AddFlash(r, FlashError, localMessage(a.requestLocale(r), err, fallback))
+}
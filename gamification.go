@@ -0,0 +1,206 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// --- 連續紀錄、點數與成就 ---
+//
+// 全部由自己負責、已完成的任務算出來（封存的也算，垃圾桶裡的不算），不另外記帳：
+// 連續天數是每天都有完成任務的天數，今天還沒完成時算到昨天為止；準時完成（在 Deadline 之前）得 onTimePoints 點，
+// 高優先順序再加 highPriorityBonus 點，任務改回未完成時點數跟著收回。
+// 成就第一次達成時記在 User.Achievements，之後清除舊任務也不會消失；清單頁頂端顯示連續天數、點數與成就數，
+// /achievements 列出所有成就與進度。勾選完成時剛解鎖的成就會以 flash 通知
+
+const (
+	onTimePoints      = 10
+	highPriorityBonus = 5
+)
+
+// gameCounts 是計算成就進度用的累計數字
+type gameCounts struct {
+	Completed  int
+	OnTime     int
+	Streak     int // 到目前這筆完成為止連續的天數
+	BestStreak int
+	Points     int
+}
+
+type achievement struct {
+	ID          string
+	Icon        string
+	Name        string
+	Description string
+	Goal        int
+	progress    func(gameCounts) int
+}
+
+var achievements = []achievement{
+	{"first-task", "🌱", "第一步", "完成第一個任務", 1, func(c gameCounts) int { return c.Completed }},
+	{"on-time-10", "⏰", "準時達人", "準時完成 10 個任務", 10, func(c gameCounts) int { return c.OnTime }},
+	{"streak-7", "🔥", "一週不間斷", "連續 7 天都有完成任務", 7, func(c gameCounts) int { return c.BestStreak }},
+	{"streak-30", "🌋", "一個月不間斷", "連續 30 天都有完成任務", 30, func(c gameCounts) int { return c.BestStreak }},
+	{"complete-100", "💯", "百戰百勝", "累計完成 100 個任務", 100, func(c gameCounts) int { return c.Completed }},
+}
+
+// achievementView 是成就頁上的一個成就
+type achievementView struct {
+	achievement
+	Progress   int
+	Percent    int
+	UnlockedAt time.Time
+}
+
+func (a achievementView) Unlocked() bool { return !a.UnlockedAt.IsZero() }
+
+// gameStats 是一位使用者目前的連續紀錄、點數與成就
+type gameStats struct {
+	gameCounts
+	Achievements []achievementView
+	Unlocked     int
+}
+
+// pointsFor 是完成 t 得到的點數，逾期才完成的不給點
+func pointsFor(t Task) int {
+	if t.CompletedAt.After(t.Deadline()) {
+		return 0
+	}
+	if effectivePriority(t.Priority) == PriorityHigh {
+		return onTimePoints + highPriorityBonus
+	}
+	return onTimePoints
+}
+
+// computeGame 依完成時間先後走過 username 完成的任務，算出目前的數字與每個成就第一次達成的時間
+func computeGame(user User, tasks []Task, now time.Time) gameStats {
+	var done []Task
+	for _, t := range tasks {
+		if t.Username == user.Username && t.Completed && !t.CompletedAt.IsZero() && !t.Trashed() {
+			done = append(done, t)
+		}
+	}
+	sort.Slice(done, func(i, j int) bool { return done[i].CompletedAt.Before(done[j].CompletedAt) })
+
+	var c gameCounts
+	unlocked := make(map[string]time.Time)
+	var lastDay time.Time
+	for _, t := range done {
+		c.Completed++
+		if p := pointsFor(t); p > 0 {
+			c.OnTime++
+			c.Points += p
+		}
+		day := startOfDay(t.CompletedAt.In(time.Local))
+		switch {
+		case day.Equal(lastDay):
+		case day.Equal(lastDay.AddDate(0, 0, 1)):
+			c.Streak++
+		default:
+			c.Streak = 1
+		}
+		lastDay = day
+		if c.Streak > c.BestStreak {
+			c.BestStreak = c.Streak
+		}
+		for _, a := range achievements {
+			if _, ok := unlocked[a.ID]; !ok && a.progress(c) >= a.Goal {
+				unlocked[a.ID] = t.CompletedAt
+			}
+		}
+	}
+	// 今天還沒完成任務時連續紀錄算到昨天，更早就斷了
+	if today := startOfDay(now.In(time.Local)); !lastDay.Equal(today) && !lastDay.Equal(today.AddDate(0, 0, -1)) {
+		c.Streak = 0
+	}
+
+	stats := gameStats{gameCounts: c}
+	for _, a := range achievements {
+		view := achievementView{achievement: a, Progress: a.progress(c)}
+		if view.Progress > a.Goal {
+			view.Progress = a.Goal
+		}
+		view.Percent = view.Progress * 100 / a.Goal
+		view.UnlockedAt = unlocked[a.ID]
+		if at, ok := user.Achievements[a.ID]; ok && (view.UnlockedAt.IsZero() || at.Before(view.UnlockedAt)) {
+			view.UnlockedAt = at
+		}
+		if view.Unlocked() {
+			view.Progress, view.Percent = a.Goal, 100
+			stats.Unlocked++
+		}
+		stats.Achievements = append(stats.Achievements, view)
+	}
+	return stats
+}
+
+// gameFor 讀取 username 的任務算出 gameStats；讀取失敗時回傳零值，不影響頁面顯示
+func (a *App) gameFor(user User, now time.Time) gameStats {
+	tasks, err := a.store.ListTasks(user.Username)
+	if err != nil {
+		return gameStats{}
+	}
+	return computeGame(user, tasks, now)
+}
+
+// syncAchievements 把新達成的成就記到使用者資料，回傳這次新解鎖的成就
+func (a *App) syncAchievements(username string, now time.Time) ([]achievementView, gameStats) {
+	user, err := a.store.GetUser(username)
+	if err != nil {
+		return nil, gameStats{}
+	}
+	stats := a.gameFor(user, now)
+	var unlocked []achievementView
+	for _, ach := range stats.Achievements {
+		if _, ok := user.Achievements[ach.ID]; ach.Unlocked() && !ok {
+			unlocked = append(unlocked, ach)
+		}
+	}
+	if len(unlocked) == 0 {
+		return nil, stats
+	}
+	// 在 ModifyUser 裡再確認一次，兩個請求同時完成任務時同一個成就只會通知一次
+	var fresh []achievementView
+	_, err = a.store.ModifyUser(username, func(u *User) error {
+		fresh = nil
+		for _, ach := range unlocked {
+			if _, ok := u.Achievements[ach.ID]; ok {
+				continue
+			}
+			if u.Achievements == nil {
+				u.Achievements = make(map[string]time.Time)
+			}
+			u.Achievements[ach.ID] = ach.UnlockedAt
+			fresh = append(fresh, ach)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, stats
+	}
+	return fresh, stats
+}
+
+// announceAchievements 在完成任務後通知剛解鎖的成就
+func (a *App) announceAchievements(r *http.Request, username string) {
+	fresh, _ := a.syncAchievements(username, time.Now())
+	for _, ach := range fresh {
+		a.flashSuccess(r, "🏆 解鎖成就：%s %s（%s）", ach.Icon, a.tr(r, ach.Name), a.tr(r, ach.Description))
+	}
+}
+
+func (a *App) achievementsHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	_, stats := a.syncAchievements(username, time.Now())
+	data := map[string]interface{}{
+		"Username":          username,
+		"Game":              stats,
+		"OnTimePoints":      onTimePoints,
+		"HighPriorityBonus": highPriorityBonus,
+		"CSRFToken":         a.sessions.CSRFToken(r),
+		"Flashes":           a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("achievements"))
+	t.Execute(w, data)
+}
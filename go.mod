@@ -0,0 +1,5 @@
+module finalproject
+
+go 1.24
+
+require golang.org/x/crypto v0.9.0
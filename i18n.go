@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 介面語言 ---
+//
+// 畫面上的文字照舊以繁體中文寫在模板與程式裡，這些中文同時是翻譯目錄的鍵：模板以 {{T "登出"}}、
+// {{T "你有 %d 個逾期任務" .OverdueCount}} 標示要翻譯的文字，程式裡用 tr。
+// 頁面、flash 訊息與顯示在頁面上的 DomainError 都會翻譯；目錄裡找不到的文字照原文顯示，不會出現空白。
+// 刻意維持中文的有：Email 與推播通知、存下來的文字（任務動態與專案通知的內容、稽核紀錄）、
+// 管理主控台的備份檢查與查詢結果、API 的錯誤訊息、http.Error 與 log，以及啟動時就產生好的逾時頁。
+// 語言優先用使用者在設定頁選的語系；選「自動」或還沒登入時依瀏覽器的 Accept-Language，都不支援時用中文。
+// 日期格式（DatePrefs）與剩餘時間跟著同一個語系；英文目錄在 i18n_en.go，TestEnglishCatalog 會檢查有沒有漏翻
+
+// catalogs 是各語系的翻譯目錄；中文是原文，不需要目錄
+var catalogs = map[string]map[string]string{
+	LocaleEn: catalogEn,
+}
+
+// translate 回傳 msg 在 locale 的翻譯，沒有翻譯時回傳 msg
+func translate(locale, msg string) string {
+	if s, ok := catalogs[locale][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+// localizer 是代入時才依語系決定文字的參數，例如 DomainError 與 quotedList
+type localizer interface {
+	In(locale string) string
+}
+
+// tr 翻譯 format 後再代入 args；沒有 args 時不經過 Sprintf，訊息裡的 % 照樣顯示
+func tr(locale, format string, args ...interface{}) string {
+	format = translate(locale, format)
+	if len(args) == 0 {
+		return format
+	}
+	local := make([]interface{}, len(args))
+	for i, arg := range args {
+		if l, ok := arg.(localizer); ok {
+			arg = l.In(locale)
+		}
+		local[i] = arg
+	}
+	return fmt.Sprintf(format, local...)
+}
+
+// message 是還沒翻譯的訊息，等知道語系時才由 tr 翻譯，例如批次操作回報的結果
+type message struct {
+	format string
+	args   []interface{}
+}
+
+func newMessage(format string, args ...interface{}) message {
+	return message{format, args}
+}
+
+func (m message) In(locale string) string {
+	return tr(locale, m.format, m.args...)
+}
+
+// quotedList 是以「」括起、以頓號分隔的任務清單，例如 還要先完成「買牛奶」、「繳費」
+type quotedList []string
+
+func (q quotedList) In(locale string) string {
+	items := make([]string, len(q))
+	for i, s := range q {
+		items[i] = tr(locale, "「%s」", brief(s))
+	}
+	return strings.Join(items, translate(locale, "、"))
+}
+
+// supportedLocale 把 Accept-Language 裡的語言標籤對應到支援的語系：zh 開頭都算中文（簡體也先用繁體），en 開頭算英文
+func supportedLocale(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "zh" || strings.HasPrefix(tag, "zh-"):
+		return LocaleZhTW
+	case tag == "en" || strings.HasPrefix(tag, "en-"):
+		return LocaleEn
+	}
+	return ""
+}
+
+// acceptLanguage 依 q 值由高到低挑出第一個支援的語系，例如 "en-US,en;q=0.9,zh-TW;q=0.8" 是英文
+func acceptLanguage(header string) string {
+	type choice struct {
+		locale string
+		q      float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if locale := supportedLocale(tag); locale != "" && q > 0 {
+			choices = append(choices, choice{locale, q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	if len(choices) == 0 {
+		return LocaleZhTW
+	}
+	return choices[0].locale
+}
+
+// requestLocale 是這個請求要用的語系：使用者設定優先，其次是瀏覽器語言
+func (a *App) requestLocale(r *http.Request) string {
+	if user, err := a.store.GetUser(a.getUsername(r)); err == nil && user.Locale != "" {
+		return user.Locale
+	}
+	return acceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// requestDatePrefs 是使用者的日期偏好，語系換成這個請求實際使用的語系
+func (a *App) requestDatePrefs(r *http.Request) DatePrefs {
+	prefs := a.datePrefsFor(a.getUsername(r))
+	prefs.Locale = a.requestLocale(r)
+	return prefs
+}
+
+// i18nFuncs 是模板裡的 T、lang、剩餘時間、時間長度與各種選項名稱（優先順序、重複、狀態等），
+// 都換成 locale；templateFuncs 先以中文掛上，讓模板可以解析
+func i18nFuncs(locale string) template.FuncMap {
+	label := func(name func(string) string) func(string) string {
+		return func(v string) string { return translate(locale, name(v)) }
+	}
+	return template.FuncMap{
+		"T":           func(msg string, args ...interface{}) string { return tr(locale, msg, args...) },
+		"lang":        func() string { return locale },
+		"remain":      func(d time.Time) string { return remainingTimeIn(locale, d) },
+		"remainDue":   func(t Task) string { return remainingDueIn(locale, t, time.Now()) },
+		"duration":    func(d time.Duration) string { return formatDuration(locale, d) },
+		"prioLabel":   label(priorityLabel),
+		"recurLabel":  label(recurrenceLabel),
+		"statusLabel": label(statusLabel),
+		"roleLabel":   label(roleLabel),
+		"activity":    label(activityLabel),
+	}
+}
+
+// localize 把頁面模板的文字與日期格式換成這個請求的語系，放在各頁模板的最外層，
+// 蓋過裡面以使用者偏好掛上的 date、datetime 等函式；倒數列也在這裡改成讀 a 的任務
+func (a *App) localize(r *http.Request, t *template.Template) *template.Template {
+	prefs := a.requestDatePrefs(r)
+	return t.Funcs(prefs.Funcs()).Funcs(i18nFuncs(prefs.Locale)).Funcs(template.FuncMap{"countdowns": a.countdownTasks})
+}
+
+// remainingTimeIn 是到期前的剩餘時間或逾期多久，例如「剩 3 天」、「3 days left」
+func remainingTimeIn(locale string, d time.Time) string {
+	now := time.Now()
+	diff := d.Sub(now)
+
+	if diff > 0 {
+		if diff.Hours() >= 24 {
+			return tr(locale, "剩 %.0f 天", diff.Hours()/24)
+		}
+		if diff.Hours() >= 1 {
+			return tr(locale, "剩 %.0f 小時", diff.Hours())
+		}
+		return tr(locale, "剩 %.0f 分鐘", diff.Minutes())
+	}
+
+	diff = now.Sub(d)
+	if diff.Hours() >= 24 {
+		return tr(locale, "已逾期 %.0f 天", diff.Hours()/24)
+	}
+	if diff.Hours() >= 1 {
+		return tr(locale, "已逾期 %.0f 小時", diff.Hours())
+	}
+	return tr(locale, "已逾期 %.0f 分鐘", diff.Minutes())
+}
@@ -0,0 +1,1001 @@
+package main
+
+// catalogEn 是英文目錄，依頁面分組
+var catalogEn = map[string]string{
+	// 剩餘時間
+	"剩 %.0f 天":    "%.0f days left",
+	"剩 %.0f 小時":   "%.0f hours left",
+	"剩 %.0f 分鐘":   "%.0f minutes left",
+	"已逾期 %.0f 天":  "%.0f days overdue",
+	"已逾期 %.0f 小時": "%.0f hours overdue",
+	"已逾期 %.0f 分鐘": "%.0f minutes overdue",
+	"今天到期":        "Due today",
+
+	// 共用
+	"登出":        "Log out",
+	"新增":        "Add",
+	"編輯":        "Edit",
+	"刪除":        "Delete",
+	"儲存":        "Save",
+	"取消":        "Cancel",
+	"關閉":        "Close",
+	"套用":        "Apply",
+	"分享":        "Share",
+	"詳情":        "Details",
+	"全部":        "All",
+	"使用者名稱":     "Username",
+	"密碼":        "Password",
+	"任務內容":      "Task",
+	"到期時間":      "Due",
+	"全天":        "All day",
+	"標籤":        "Tags",
+	"優先順序":      "Priority",
+	"重複":        "Repeat",
+	"高":         "High",
+	"中":         "Medium",
+	"低":         "Low",
+	"不重複":       "Does not repeat",
+	"每天":        "Daily",
+	"平日（週一至週五）": "Weekdays (Mon–Fri)",
+	"每週":        "Weekly",
+	"每月":        "Monthly",
+
+	// 導覽列
+	"專案":    "Projects",
+	"統計":    "Stats",
+	"匯入／匯出": "Import / Export",
+	"封存":    "Archive",
+	"垃圾桶":   "Trash",
+	"設定":    "Settings",
+	"老師":    "Teacher",
+	"公告":    "Announcements",
+	"使用者":   "Users",
+	"主控台":   "Console",
+	"清單模式":  "List",
+	"月曆模式":  "Calendar",
+	"看板模式":  "Board",
+
+	// 登入與註冊
+	"登入":         "Log in",
+	"註冊":         "Sign up",
+	"登入系統":       "Log in",
+	"註冊帳號":       "Create an account",
+	"或":          "or",
+	"使用 %s 登入":   "Log in with %s",
+	"使用 %s 註冊":   "Sign up with %s",
+	"已有帳號？":      "Already have an account?",
+	"前往登入":       "Log in",
+	"還沒帳號？":      "No account yet?",
+	"立即註冊":       "Sign up now",
+	"使用者名稱或密碼錯誤": "Incorrect username or password",
+	"使用者名稱已存在":   "That username is already taken",
+	"登入失敗，請稍後再試": "Login failed, please try again later",
+	"註冊失敗，請稍後再試": "Sign-up failed, please try again later",
+	"帳號已刪除，謝謝你使用待辦清單": "Your account has been deleted. Thanks for using the to-do list",
+	"忘記密碼？用 Email 登入": "Forgot your password? Log in with email",
+	"用 Email 登入":      "Log in with email",
+	"寄登入連結給我":         "Email me a login link",
+	"以 %s 的身分登入":      "Log in as %s",
+	"用密碼登入":           "Log in with a password",
+	"重新索取連結":          "Request a new link",
+	"請輸入有效的 Email":    "Please enter a valid email address",
+	"如果這個 Email 有註冊，登入連結已經寄出，請在 15 分鐘內打開信裡的連結": "If this email is registered, a login link is on its way. Open it within 15 minutes",
+	"登入連結無效或已過期，請重新索取":                         "This login link is invalid or has expired. Please request a new one",
+	"這個登入連結已經用過或已失效，請重新索取":                     "This login link has already been used or is no longer valid. Please request a new one",
+
+	// 清單
+	"我的待辦清單":       "My To-Do List",
+	"你有 %d 個逾期任務":  "You have %d overdue tasks",
+	"開啟桌面通知":       "Turn on desktop notifications",
+	"關閉桌面通知":       "Turn off desktop notifications",
+	"搜尋任務、標籤、子項目…": "Search tasks, tags, subtasks…",
+	"今日任務":         "Today",
+	"本週":           "This week",
+	"未完成":          "Incomplete",
+	"輸入新的待辦事項...":  "Add a new to-do...",
+	"標籤（以逗號分隔）":    "Tags (comma-separated)",
+	"可以直接寫 #標籤、!high／!medium／!low 與 @專案名稱": "You can type #tags, !high / !medium / !low and @project right in the text",
+	"批次操作":      "Bulk actions",
+	"全選":        "Select all",
+	"標記完成":      "Mark complete",
+	"改期":        "Reschedule",
+	"改標籤":       "Retag",
+	"到期：":       "Due: ",
+	"計時":        "Start timer",
+	"停止":        "Stop",
+	"子項目":       "Subtasks",
+	"新增子項目...":  "Add a subtask...",
+	"尚未分享給任何人":  "Not shared with anyone yet",
+	"目前沒有任務 🎉":  "No tasks yet 🎉",
+	"任務已新增":     "Task added",
+	"任務已更新":     "Task updated",
+	"被擋住":       "Blocked",
+	"排序：":       "Sort: ",
+	"名稱":        "Name",
+	"手動":        "Manual",
+	"上移":        "Move up",
+	"下移":        "Move down",
+	"建立時間（新到舊）": "Newest first",
+
+	"登入中的裝置": "Signed-in devices",
+	"預設提醒":   "Default reminders",
+	"提醒":     "Reminders",
+	"使用預設提醒": "Use my default reminders",
+
+	"已登出所有裝置，請重新登入": "Signed out of all devices. Please sign in again",
+
+	"專注":   "Focus",
+	"專注模式": "Focus mode",
+
+	"成就":         "Achievements",
+	"連續天數、點數與成就": "Streak, points and achievements",
+
+	// 月曆
+	"月曆":         "Calendar",
+	"← 上個月":      "← Previous month",
+	"下個月 →":      "Next month →",
+	"優先順序：":      "Priority:",
+	"所有標籤":       "All tags",
+	"已隱藏 %d 個任務": "%d tasks hidden",
+	"清除篩選":       "Clear filters",
+	"週":          "Wk",
+	"新增任務":       "New task",
+	"每天各一個":      "One per day",
+	"一個跨日任務（第一天開始、最後一天到期）":               "One multi-day task (starts on the first day, due on the last)",
+	"在空白處拖曳選取多天（或按住 Ctrl／⌘ 點選），就能一次新增任務": "Drag across empty days (or Ctrl/⌘-click) to add tasks to several days at once",
+	"列印週曆": "Print this week",
+
+	// 列印用週曆
+	"週曆":    "Weekly agenda",
+	"← 回月曆": "← Back to calendar",
+	"← 上一週": "← Previous week",
+	"下一週 →": "Next week →",
+	"列印":    "Print",
+	"要存成 PDF 時，在列印對話框選擇「另存為 PDF」": "To get a PDF, choose “Save as PDF” in the print dialog",
+	"進行中": "Ongoing",
+	"備註":  "Notes",
+
+	// 重複任務
+	"可能重複的任務": "Possible duplicate",
+	"你已經有一個很像的未完成任務。要把這次填的到期時間、優先順序與標籤更新到原本的任務，還是仍要新增一個？": "You already have a very similar open task. Update it with this due date, priority and tags, or add a new one anyway?",
+	"原本的任務":   "Existing task",
+	"這次要新增的":  "New task",
+	"更新原本的任務": "Update existing task",
+	"仍要新增":    "Add anyway",
+
+	// 編輯
+	"編輯任務": "Edit task",
+	"以逗號分隔，例如：工作, 學校": "Comma-separated, e.g. work, school",
+	"等待這些任務完成":        "Waiting on",
+	"按住 Ctrl／⌘ 可以選多個": "Hold Ctrl/⌘ to select several",
+
+	// 設定
+	"待辦清單":      "To-Do List",
+	"回清單":       "Back to list",
+	"顯示名稱":      "Display name",
+	"變更密碼":      "Change password",
+	"第三方登入":     "Third-party sign-in",
+	"每日摘要信":     "Daily digest email",
+	"排程衝突提醒":    "Schedule conflict alerts",
+	"逾期任務":      "Overdue tasks",
+	"任務編號":      "Task numbers",
+	"唯讀分享連結":    "Read-only share link",
+	"資料用量":      "Data usage",
+	"清除舊的已完成任務": "Clear old completed tasks",
+	"合併帳號":      "Merge accounts",
+	"刪除帳號":      "Delete account",
+	"語言與日期格式":   "Language and date format",
+	"畫面文字、清單、月曆、提醒與摘要信裡的日期都會依這裡的設定顯示。目前範例：%s": "Page text and the dates in lists, the calendar, reminders and digest emails follow these settings. Example: %s",
+	"語言":            "Language",
+	"自動（依瀏覽器語言）":    "Automatic (browser language)",
+	"24 小時制":        "24-hour clock",
+	"12 小時制（上午／下午）": "12-hour clock (AM/PM)",
+	"使用民國年（僅中文）":    "Use ROC years (Chinese only)",
+	"每週從":           "Weeks start on",
+	"星期日":           "Sunday",
+	"星期一":           "Monday",
+	"開始（月曆與「本週」篩選）":  "(calendar and the “this week” filter)",
+	"在月曆左側顯示 ISO 週次": "Show ISO week numbers on the calendar",
+	"儲存語言與日期格式":      "Save language and date format",
+	"語言與日期格式已更新":     "Language and date format updated",
+
+	// 共用的訊息與標籤
+	"「%s」":         "“%s”",
+	"、":            ", ",
+	"：":            ": ",
+	"任務":           "Tasks",
+	"狀態":           "Status",
+	"到期":           "Due",
+	"開始":           "Start",
+	"建立於":          "Created",
+	"建立於 %s":       "Created %s",
+	"到期：%s":        "Due: %s",
+	"到期 %s":        "Due %s",
+	"開始：%s":        "Start: %s",
+	"已完成":          "Completed",
+	"待完成":          "Pending",
+	"逾期":           "Overdue",
+	"預覽":           "Preview",
+	"匯入":           "Import",
+	"匯出":           "Export",
+	"匯出 CSV":       "Export CSV",
+	"匯出 JSON":      "Export JSON",
+	"回上一頁":         "Go back",
+	"設定頁":          "Settings",
+	"撤銷":           "Revoke",
+	"啟用":           "Enable",
+	"停用":           "Disable",
+	"合併":           "Merge",
+	"建立":           "Create",
+	"邀請":           "Invite",
+	"復原":           "Undo",
+	"重做":           "Redo",
+	"筆記":           "Note",
+	"加密筆記":         "Encrypted note",
+	"目前的密碼":        "Current password",
+	"新密碼":          "New password",
+	"確認密碼":         "Confirm password",
+	"我的清單":         "My list",
+	"專案：%s":        "Project: %s",
+	"未認領":          "Unclaimed",
+	"由 %s 分享":      "Shared by %s",
+	"重複，略過":        "Duplicate, skipped",
+	"（關閉）":         "(off)",
+	"查看任務":         "View task",
+	"讀取任務失敗":       "Could not load tasks",
+	"讀取任務失敗，請稍後再試": "Could not load the task. Please try again later",
+	"新增任務失敗，請稍後再試": "Could not add the task. Please try again later",
+	"任務內容最多 %d 個字": "Tasks can be at most %d characters",
+	"編號格式錯誤":       "Invalid ID",
+	"請求格式錯誤":       "Malformed request",
+	"找不到資料":        "Not found",
+	"表單已過期，請重新送出":  "This form has expired. Please submit it again",
+	"任務內容不可為空白":    "The task cannot be empty",
+	"日期格式錯誤":       "Invalid date",
+	"沒有權限執行此操作":    "You don't have permission to do that",
+	"嘗試次數過多，請稍後再試": "Too many attempts. Please try again later",
+	"到期時間必須在 %d 年到 %d 年之間":  "The due date must be between %d and %d",
+	"週次格式不正確，例如 2024-W19":   "Invalid week, e.g. 2024-W19",
+	"%d 年沒有第 %d 週":          "%d has no week %d",
+	"……還有 %d 則未列出":          "…and %d more not shown",
+	"一個任務只能放進一個專案（@%s、@%s）": "A task can only belong to one project (@%s, @%s)",
+
+	// 任務的新增、完成與刪除
+	"新增「%s」":             "Add “%s”",
+	"編輯「%s」":             "Edit “%s”",
+	"刪除「%s」":             "Delete “%s”",
+	"標記完成「%s」":           "Complete “%s”",
+	"標記未完成「%s」":          "Reopen “%s”",
+	"已將「%s」標記完成":         "Marked “%s” complete",
+	"已將「%s」標記未完成":        "Marked “%s” incomplete",
+	"產生下一次重複任務失敗":        "Could not schedule the next occurrence",
+	"已排定下一次「%s」":         "Scheduled the next “%s”",
+	"請填寫任務內容與正確的到期時間":    "Please enter a task and a valid due date",
+	"更新任務失敗，請稍後再試":       "Could not update the task. Please try again later",
+	"刪除任務失敗，請稍後再試":       "Could not delete the task. Please try again later",
+	"任務已移到垃圾桶":           "Task moved to the trash",
+	"已有相似的未完成任務 #%d「%s」": "You already have a similar open task #%d “%s”",
+	"已更新原本的任務「%s」":       "Updated the existing task “%s”",
+	"⚠️ %s 這個小時已經有 %d 個任務到期，小心排得太滿": "⚠️ %[2]d tasks are already due in the %[1]s hour. Careful not to overbook",
+	"⚠️ %s 這天已經有 %d 個任務到期，小心排得太滿":   "⚠️ %[2]d tasks are already due on %[1]s. Careful not to overbook",
+	"查看當天任務":      "See that day",
+	"改期失敗，請稍後再試":  "Could not reschedule. Please try again later",
+	"已將「%s」改到 %s": "Moved “%s” to %s",
+
+	// 復原與重做
+	"任務之後又被修改過，無法復原": "The task has changed since then and can't be restored",
+	"沒有可以重做的動作":      "Nothing to redo",
+	"沒有可以復原的動作":      "Nothing to undo",
+	"重做失敗，請稍後再試":     "Could not redo. Please try again later",
+	"已重做：%s":         "Redone: %s",
+	"復原失敗，請稍後再試":     "Could not undo. Please try again later",
+	"已復原：%s":         "Undone: %s",
+
+	// 批次操作
+	"一次最多只能處理 %d 個任務": "You can change at most %d tasks at once",
+	"任務編號不正確":         "Invalid task ID",
+	"已移到垃圾桶":          "moved to the trash",
+	"請選擇新的到期日":        "Please choose a new due date",
+	"已改期到 %s":         "rescheduled to %s",
+	"請輸入要加上或移除的標籤":    "Please enter tags to add or remove",
+	"已更新標籤":           "retagged",
+	"不支援的批次操作":        "Unsupported bulk action",
+	"部分任務已不存在，請重新整理後再試，這次沒有套用任何變更": "Some tasks no longer exist. Reload and try again; nothing was changed",
+	"批次操作失敗，請稍後再試，這次沒有套用任何變更":      "The bulk action failed; nothing was changed. Please try again later",
+	"%d 個任務%s": "%d tasks: %s",
+
+	// 子項目、相依與倒數
+	"每個任務最多 %d 個子項目":            "A task can have at most %d subtasks",
+	"子項目內容不可為空白":                "The subtask cannot be empty",
+	"新增子項目失敗，請稍後再試":             "Could not add the subtask. Please try again later",
+	"更新子項目失敗，請稍後再試":             "Could not update the subtask. Please try again later",
+	"子項目全部完成，「%s」已標記為完成":        "All subtasks done, so “%s” is marked complete",
+	"刪除子項目失敗，請稍後再試":             "Could not delete the subtask. Please try again later",
+	"相依關係不能形成循環":                "Dependencies can't form a cycle",
+	"還要先完成%s才能完成這個任務":           "Finish %s before completing this task",
+	"相依任務的編號「%s」不正確":            "Invalid dependency ID “%s”",
+	"最多只能等待 %d 個任務":             "A task can wait on at most %d tasks",
+	"任務不能等待自己":                  "A task can't wait on itself",
+	"找不到相依的任務 #%d":              "Dependency #%d not found",
+	"🔓 「%s」等待的任務都完成了，可以開始了":     "🔓 Everything “%s” was waiting on is done. You can start it now",
+	"最多只能設定 %d 個倒數，請先取消其他任務的倒數": "You can count down to at most %d tasks. Remove another countdown first",
+	"更新倒數失敗，請稍後再試":              "Could not update the countdown. Please try again later",
+	"已將「%s」加入倒數":                "Counting down to “%s”",
+	"已取消「%s」的倒數":                "Stopped counting down to “%s”",
+
+	// 月曆與看板
+	"請先在月曆上選取日期":       "Select days on the calendar first",
+	"一次最多選取 %d 天":      "You can select at most %d days",
+	"請選擇到期時間":          "Please choose a due time",
+	"跨日任務至少要選取兩天":      "A multi-day task needs at least two days",
+	"已在 %d 天各新增一個「%s」": "Added “%[2]s” to %[1]d days",
+	"不支援的看板欄位":         "Unsupported board column",
+	"移動任務失敗，請稍後再試":     "Could not move the task. Please try again later",
+	"看板":               "Board",
+	"把卡片拖到這裡":          "Drop cards here",
+	"待辦":               "To do",
+	"到期時間：":            "Due:",
+	"狀態：":              "Status:",
+	"新增任務（%s）":         "New task (%s)",
+	"在 %s 天新增任務":       "Add tasks on %s days",
+	"訂閱行事曆":            "Subscribe to calendar",
+	"把這個網址加到 Google 日曆或 Apple 行事曆的「以網址訂閱」，就能在那裡看到任務期限。網址等同密碼，請勿分享。": "Add this URL to Google Calendar or Apple Calendar with “Subscribe from URL” to see your due dates there. Treat it like a password and don't share it.",
+	"舊的訂閱網址會失效，確定要重新產生嗎？": "The old subscription URL will stop working. Generate a new one?",
+	"重新產生網址": "Generate a new URL",
+	"重新產生訂閱網址失敗，請稍後再試":  "Could not generate a new URL. Please try again later",
+	"已產生新的訂閱網址，舊的網址已失效": "New subscription URL generated. The old one no longer works",
+
+	// 清單
+	"不再在清單中顯示這個任務": "Stop showing this task in your list",
+	"任務即將到期":       "Task due soon",
+	"作業":           "Assignment",
+	"倒數":           "Countdown",
+	"取消倒數":         "Stop countdown",
+	"在頁面頂端顯示倒數":    "Show a countdown at the top of the page",
+	"分享「%s」":       "Share “%s”",
+	"刪除子項目":        "Delete subtask",
+	"加上標籤":         "Add tags",
+	"移除標籤":         "Remove tags",
+	"取消分享":         "Stop sharing",
+	"退出分享":         "Leave",
+	"只顯示 %s 到期的任務": "Showing only tasks due %s",
+	"顯示全部":         "Show all",
+	"封存 %d 個已完成任務": "Archive %d completed tasks",
+	"已分享給 %d 人":    "Shared with %d people",
+	"已選取：":         "Selected: ",
+	"選取":           "Select",
+	"從 %s 開始":      "Started %s",
+	"計時中 %s":       "Timing %s",
+	"等待 %s":        "Waiting on %s",
+	"瀏覽器封鎖了通知，請在網址列的網站設定中允許":     "Your browser is blocking notifications. Allow them in the site settings next to the address bar",
+	"確定要刪除選取的 %s 個任務嗎？可以在垃圾桶復原。": "Delete the %s selected tasks? You can restore them from the trash.",
+	"請先勾選任務":                 "Select some tasks first",
+	"貼上 Markdown 清單一次新增多個任務": "Paste a Markdown list to add several tasks at once",
+	"開啟推播":                   "Turn on push",
+	"關閉推播":                   "Turn off push",
+	"不支援的排序方式":               "Unsupported sort order",
+	"更新排序方式失敗，請稍後再試":         "Could not change the sort order. Please try again later",
+	"調整順序失敗，請稍後再試":           "Could not reorder. Please try again later",
+	"%s 天 %s 小時":             "%s d %s h",
+	"%s 小時 %s 分":             "%s h %s min",
+	"已到期":                    "Due now",
+
+	// 任務頁
+	"分享給":        "Shared with",
+	"動態":         "Activity",
+	"建立了任務":      "created the task",
+	"修改了任務":      "edited the task",
+	"把任務標記為完成":   "marked the task complete",
+	"把任務改回未完成":   "reopened the task",
+	"留言了":        "commented",
+	"留言…":        "Write a comment…",
+	"等待":         "Waiting on",
+	"系統":         "System",
+	"花費時間":       "Time spent",
+	"送出留言":       "Comment",
+	"（%s 完成）":    "(completed %s)",
+	"（計時中）":      "(timing)",
+	"，尚未認領":      ", unclaimed",
+	"，負責人 %s":    ", assigned to %s",
+	"留言不可為空白":    "The comment cannot be empty",
+	"留言最多 %d 個字": "Comments can be at most %d characters",
+	"留言失敗，請稍後再試": "Could not post the comment. Please try again later",
+
+	// 分享
+	"請輸入要分享的使用者名稱":         "Please enter a username to share with",
+	"已經分享給 %s":             "Already shared with %s",
+	"一個任務最多分享給 %d 人":       "A task can be shared with at most %d people",
+	"分享失敗，請稍後再試":           "Could not share. Please try again later",
+	"已分享給 %s":              "Shared with %s",
+	"取消分享失敗，請稍後再試":         "Could not stop sharing. Please try again later",
+	"已取消分享給 %s":            "Stopped sharing with %s",
+	"退出分享失敗，請稍後再試":         "Could not leave the shared task. Please try again later",
+	"已退出分享，這個任務不會再出現在你的清單": "You left the shared task. It won't appear in your list anymore",
+
+	// 信件連結操作
+	"任務操作":     "Task action",
+	"前往待辦清單":   "Go to your to-do list",
+	"連結無效或已過期": "This link is invalid or has expired",
+	"這封信裡的連結已經用過了，請登入後再操作": "This link has already been used. Please log in to continue",
+	"操作失敗，請稍後再試":           "Something went wrong. Please try again later",
+	"標記為完成":                "Mark complete",
+	"延後一天":                 "Snooze one day",
+	"已延後一天":                "Snoozed one day",
+	"排入下週":                 "Schedule for next week",
+	"已排入下週，並移除「有空再做」標籤":    "Scheduled for next week and removed the “someday” tag",
+	"繼續保留":                 "Keep it",
+	"已保留，兩個月後再問你":          "Kept. We'll ask again in two months",
+	"不做了":                  "Drop it",
+	"已移到垃圾桶，30 天內可以從垃圾桶還原": "Moved to the trash. You can restore it within 30 days",
+
+	// 有空再做回顧
+	"有空再做回顧": "Someday review",
+	"以下標著 #someday 或 #有空再做 的任務已經放了兩個月以上。排入下週會拿掉標籤；保留的話兩個月後再問你。": "These tasks tagged #someday have been sitting for over two months. Scheduling one removes the tag; if you keep it, we'll ask again in two months.",
+	"沒有放太久的「有空再做」任務 👍": "No someday tasks have been sitting too long 👍",
+	"放了 %d 天": "Waiting %d days",
+	"不支援的操作":  "Unsupported action",
+	"「%s」%s":  "“%s”: %s",
+
+	// 垃圾桶與封存
+	"%d 天後清除": "Purged in %d days",
+	"刪除於 %s":  "Deleted %s",
+	"刪除的任務會保留 %d 天，之後自動永久刪除。": "Deleted tasks are kept for %d days, then deleted permanently.",
+	"垃圾桶是空的": "The trash is empty",
+	"垃圾桶裡的任務都會永久刪除，無法復原，確定要清空嗎？": "Every task in the trash will be deleted permanently. Empty the trash?",
+	"永久刪除": "Delete forever",
+	"永久刪除後無法復原，確定嗎？": "This can't be undone. Delete forever?",
+	"清空垃圾桶":         "Empty trash",
+	"復原任務失敗，請稍後再試":  "Could not restore the task. Please try again later",
+	"已復原「%s」":       "Restored “%s”",
+	"已永久刪除「%s」":     "Permanently deleted “%s”",
+	"清空垃圾桶失敗，請稍後再試": "Could not empty the trash. Please try again later",
+	"已永久刪除 %d 個任務":  "Permanently deleted %d tasks",
+	"共 %d 個封存的任務，依完成時間排列。還原後會回到清單。": "%d archived tasks, by completion time. Restored tasks go back to your list.",
+	"完成於 %s": "Completed %s",
+	"還原":     "Restore",
+	"還沒有封存的任務，在清單頁可以一次封存所有已完成的任務": "No archived tasks yet. You can archive all completed tasks at once from your list",
+	"封存失敗，請稍後再試":    "Could not archive. Please try again later",
+	"沒有可以封存的已完成任務":  "There are no completed tasks to archive",
+	"已封存 %d 個已完成任務": "Archived %d completed tasks",
+	"還原任務失敗，請稍後再試":  "Could not restore the task. Please try again later",
+	"已將「%s」還原到清單":   "Restored “%s” to your list",
+
+	// 搜尋
+	"搜尋": "Search",
+	"搜尋任務內容、標籤、子項目…（多個關鍵字以空白分隔）": "Search tasks, tags, subtasks… (separate keywords with spaces)",
+	"找到 %d 個任務":           "Found %d tasks",
+	"找到 %d 個任務，只顯示前 %d 個": "Found %d tasks, showing the first %d",
+	"沒有符合「%s」的任務":         "No tasks match “%s”",
+
+	// 統計與計時
+	"%d 準時／%d 逾期": "%d on time / %d late",
+	"%d 顆":        "%d",
+	"共 %s":        "Total %s",
+	"各專案":         "By project",
+	"個人任務":        "Personal tasks",
+	"最近兩週共 %d 顆":  "%d in the last two weeks",
+	"最近兩週完成 %d 個": "%d completed in the last two weeks",
+	"最近兩週每天":      "Daily, last two weeks",
+	"最近兩週還沒有完成任務": "No tasks completed in the last two weeks",
+	"最近兩週還沒有完成番茄鐘，到專注模式開始第一顆吧":   "No pomodoros in the last two weeks. Start one in focus mode",
+	"最近兩週還沒有計時紀錄，在清單裡按「▶ 計時」開始吧": "No time tracked in the last two weeks. Press “▶ Start timer” in your list",
+	"準時 %d%%":                       "%d%% on time",
+	"準時完成":                          "On time",
+	"番茄最多的任務":                       "Tasks with the most pomodoros",
+	"番茄鐘":                           "Pomodoros",
+	"計時中":                           "Timing",
+	"（從 %s 開始，累計 %s）":               "(started %s, %s in total)",
+	"這個任務已經在計時了":                    "This task is already being timed",
+	"已完成的任務不能計時":                    "Completed tasks can't be timed",
+	"每個任務最多 %d 段計時紀錄":               "A task can have at most %d time entries",
+	"%d 分":                          "%d min",
+	"%d 小時":                         "%d h",
+	"%d 小時 %d 分":                    "%d h %d min",
+	"開始計時失敗，請稍後再試":                  "Could not start the timer. Please try again later",
+	"開始計時「%s」":                      "Timing “%s”",
+	"停止計時失敗，請稍後再試":                  "Could not stop the timer. Please try again later",
+	"「%s」這次花了 %s，累計 %s":             "“%s” took %s this time, %s in total",
+	"interval 必須是 day、week 或 month": "interval must be day, week or month",
+	"期間太長，最多 %d 段，請改用較大的 interval": "The range is too long (at most %d intervals). Use a larger interval",
+
+	// 專注模式
+	"今天完成了 %d 顆番茄": "%d pomodoros done today",
+	"任務完成":         "Task done",
+	"休息一下":         "Take a break",
+	"分鐘":           "min",
+	"分鐘，休息":        "min, break",
+	"專心工作中":        "Focusing",
+	"工作":           "Work",
+	"已完成 %d 顆番茄":   "%d pomodoros done",
+	"放棄這顆":         "Abandon this one",
+	"沒有未完成的任務，好好休息吧 🎉": "Nothing left to do. Enjoy the break 🎉",
+	"跳過休息": "Skip break",
+	"還沒到時間，這顆番茄不會記錄，確定要停止嗎？": "Time isn't up yet, so this pomodoro won't count. Stop anyway?",
+	"開始番茄鐘":             "Start pomodoro",
+	"沒有可以專心做的未完成任務":     "There are no open tasks to focus on",
+	"工作時間必須是 1 到 %d 分鐘": "Work time must be 1 to %d minutes",
+	"休息時間必須是 1 到 %d 分鐘": "Break time must be 1 to %d minutes",
+	"任務已經完成了":           "The task is already done",
+	"番茄鐘操作失敗，請稍後再試":     "The pomodoro action failed. Please try again later",
+
+	// 成就
+	"%s 解鎖": "Unlocked %s",
+	"在到期前完成任務得 %d 點，高優先順序的任務再加 %d 點；逾期才完成的不給點。": "Finishing a task before it's due earns %d points, plus %d more for high priority. Late tasks earn nothing.",
+	"完成的任務（%d 個準時）": "Tasks completed (%d on time)",
+	"已解鎖 %d / %d":   "%d / %d unlocked",
+	"最長連續天數":        "Best streak",
+	"每天至少完成一個任務就能延續連續紀錄，今天還沒完成的話算到昨天為止。": "Complete at least one task a day to keep your streak. If you haven't yet today, it counts through yesterday.",
+	"目前連續天數":           "Current streak",
+	"點數":               "Points",
+	"第一步":              "First step",
+	"完成第一個任務":          "Complete your first task",
+	"準時達人":             "Always on time",
+	"準時完成 10 個任務":      "Complete 10 tasks on time",
+	"一週不間斷":            "Full week",
+	"連續 7 天都有完成任務":     "Complete tasks 7 days in a row",
+	"一個月不間斷":           "Full month",
+	"連續 30 天都有完成任務":    "Complete tasks 30 days in a row",
+	"百戰百勝":             "Centurion",
+	"累計完成 100 個任務":     "Complete 100 tasks",
+	"🏆 解鎖成就：%s %s（%s）": "🏆 Achievement unlocked: %s %s (%s)",
+
+	// 匯入／匯出
+	"下載自己所有的任務。":                              "Download all of your tasks.",
+	"Obsidian 筆記庫（zip）":                       "Obsidian vault (zip)",
+	"每個專案一個 Markdown 檔，使用 Obsidian Tasks 的格式": "One Markdown file per project, in Obsidian Tasks format",
+	"CSV 第一列為標題，欄位：":                          "The first CSV row is a header with the columns ",
+	"，只有 description 與 due_at 必填；時間格式如":       "; only description and due_at are required. Times look like",
+	"，多個標籤以逗號分隔。":                             ", and multiple tags are separated by commas.",
+	"JSON 為同樣欄位的物件陣列。描述與到期時間都相同的任務會略過，一次最多 %d 筆。": "JSON is an array of objects with the same fields. Tasks with the same description and due date are skipped, up to %d records at a time.",
+	"選擇檢視差異或同步時，會先列出新增、更新與刪除的任務，逐項確認後才寫入。":        "With review or sync, the added, updated and deleted tasks are listed first and only written after you confirm them.",
+	"只新增，略過重複的任務":                     "Add only, skip duplicates",
+	"先檢視差異：新增並更新現有任務":                 "Review first: add and update existing tasks",
+	"同步：檔案中沒有的任務也一併刪除":                "Sync: also delete tasks missing from the file",
+	"也可以直接貼上 Markdown 核取方塊清單，預覽後再新增：": "You can also paste a Markdown checklist and preview it before adding:",
+	"從 Todoist／Trello 匯入":             "Import from Todoist / Trello",
+	"Todoist：在專案選單選「匯出為範本」下載 CSV，@標籤會轉成標籤，縮排的子任務成為子項目。":      "Todoist: choose “Export as template” in the project menu to download a CSV. @labels become tags and indented subtasks become subtasks.",
+	"Trello：在看板選單的「列印、匯出與分享」選「匯出 JSON」，標籤與檢查清單一併匯入，封存的卡片略過。": "Trello: choose “Export as JSON” under “Print, export and share” in the board menu. Labels and checklists are imported; archived cards are skipped.",
+	"沒有到期日的任務使用下面的預設時間；先預覽，確認後才新增，重複的任務會略過。":                 "Tasks without a due date use the default below. You'll see a preview first, and duplicates are skipped.",
+	"預設到期：":         "Default due date: ",
+	"從 %s 匯入":       "Import from %s",
+	"預覽：將新增 %d 個任務": "Preview: %d tasks will be added",
+	"確認新增 %d 個任務":   "Add %d tasks",
+	"第 %d 列":        "Row %d",
+	"第 %d 筆":        "Record %d",
+	"第 %d 行":        "Line %d",
+	"第 %d 列：%s":     "Row %d: %s",
+	"第 %d 筆：%s":     "Record %d: %s",
+	"第 %d 行：%s":     "Line %d: %s",
+	"%d 列匯入失敗":      "%d rows failed to import",
+	"%d 筆匯入失敗":      "%d records failed to import",
+	"優先順序「%s」不正確":   "Invalid priority “%s”",
+	"重複規則「%s」不正確":   "Invalid recurrence “%s”",
+	"狀態「%s」不正確":     "Invalid status “%s”",
+	"完成時間格式錯誤":      "Invalid completion time",
+	"第 %d 列：completed 欄必須是 true 或 false":      "Row %d: completed must be true or false",
+	"JSON 格式錯誤：需要任務陣列":                        "Invalid JSON: expected an array of tasks",
+	"一次最多匯入 %d 筆任務":                           "You can import at most %d tasks at a time",
+	"請選擇要匯入的 CSV 或 JSON 檔":                    "Please choose a CSV or JSON file to import",
+	"讀取檔案失敗":                                  "Could not read the file",
+	"資料不正確":                                   "Invalid data",
+	"新增任務失敗":                                  "Could not add the task",
+	"已匯入 %d 個任務，略過 %d 個重複任務":                  "Imported %d tasks, skipped %d duplicates",
+	"已匯入 %d 個任務":                              "Imported %d tasks",
+	"不是 Todoist 匯出的 CSV：找不到 TYPE 與 CONTENT 欄": "Not a Todoist CSV export: the TYPE and CONTENT columns are missing",
+	"第 %d 列：子任務前面沒有上層任務，已略過":                  "Row %d: subtask without a parent task, skipped",
+	"第 %d 列：看不懂到期日「%s」，改用預設的到期時間":             "Row %d: couldn't read the due date “%s”, using the default",
+	"略過 %d 列不是任務的資料（區段或留言）":                   "Skipped %d rows that aren't tasks (sections or comments)",
+	"不是 Trello 看板匯出的 JSON":                    "Not a Trello board JSON export",
+	"第 %d 筆：看不懂到期日「%s」，改用預設的到期時間":             "Record %d: couldn't read the due date “%s”, using the default",
+	"略過 %d 張封存的卡片":                            "Skipped %d archived cards",
+	"請選擇要匯入的檔案":                               "Please choose a file to import",
+	"預覽資料不正確，請重新上傳檔案":                         "The preview data is invalid. Please upload the file again",
+	"檔案中沒有任務":                                 "The file has no tasks",
+
+	// 檢視匯入差異
+	"檢視匯入差異":           "Review import",
+	"回匯入頁":             "Back to import",
+	"優先：%s":            "Priority: %s",
+	"重複：%s":            "Repeat: %s",
+	"狀態：%s":            "Status: %s",
+	"標籤：%s":            "Tags: %s",
+	"標籤：（無）":           "Tags: (none)",
+	"全部接受／略過":          "Accept / skip all",
+	"目前":               "Current",
+	"匯入後":              "After import",
+	"新增 %d、更新 %d 個任務，": "%d tasks added and %d updated;",
+	"新增 %d、更新 %d、刪除 %d 個任務，": "%d tasks added, %d updated and %d deleted;",
+	"另有 %d 個任務沒有變動。取消勾選的項目不會寫入；刪除的任務會移到垃圾桶，30 天內可以復原。": "%d tasks are unchanged. Unchecked items are not written; deleted tasks go to the trash and can be restored within 30 days.",
+	"沒有需要套用的變更":              "Nothing to apply",
+	"套用勾選的變更":                "Apply selected changes",
+	"檔案第 %d 列":               "File row %d",
+	"檔案第 %d 筆":               "File record %d",
+	"（刪除）":                   "(deleted)",
+	"（沒有這個任務）":               "(no such task)",
+	"➕ 新增":                   "➕ Added",
+	"✏️ 更新":                  "✏️ Updated",
+	"🗑 刪除（移到垃圾桶）":            "🗑 Deleted (moved to the trash)",
+	"審閱資料不正確，請重新上傳檔案":        "The review data is invalid. Please upload the file again",
+	"套用途中失敗":                 "Applying the changes failed partway",
+	"已新增 %d、更新 %d、刪除 %d 個任務": "Added %d, updated %d and deleted %d tasks",
+
+	// 貼上 Markdown 清單
+	"貼上 Markdown 清單": "Paste a Markdown list",
+	"貼上清單":           "Paste a list",
+	"修改內容":           "Edit",
+	"一次最多 %d 個任務。":   "Up to %d tasks at a time.",
+	"每個任務一行，以 %s 開頭（%s 表示已完成），縮排的核取方塊會成為上一個任務的子項目。":            "One task per line starting with %s (%s for done). Indented checkboxes become subtasks of the task above.",
+	"可以加上 %s 指定到期日、%s／%s 指定優先順序、#標籤，也能直接貼上從這裡匯出的 Obsidian 筆記。": "Add %s for a due date, %s / %s for priority and #tags. Obsidian notes exported from here work too.",
+	"- [ ] 期末報告 ⏫ 📅 2024-06-01 #課業\n    - [ ] 找資料\n- [ ] 繳電話費": "- [ ] Final report ⏫ 📅 2024-06-01 #school\n    - [ ] Research\n- [ ] Pay the phone bill",
+	"沒有 📅 的任務在": "; tasks without 📅 are due on",
+	"日期「%s」不正確": "Invalid date “%s”",
+	"子項目太多":     "Too many subtasks",
+	"一次最多新增 %d 個任務，第 %d 行以後沒有處理":  "At most %d tasks at a time; lines from %d on were not processed",
+	"內容太長，一次最多貼上 %d KB":           "Too long. You can paste at most %d KB at a time",
+	"沒有找到任務，每一行要以 - [ ] 開頭":       "No tasks found. Each line must start with - [ ]",
+	"解析清單失敗，請稍後再試":                "Could not read the list. Please try again later",
+	"已從 %s 新增 %d 個任務，略過 %d 個重複任務": "Added %[2]d tasks from %[1]s, skipped %[3]d duplicates",
+	"已從 %s 新增 %d 個任務":             "Added %[2]d tasks from %[1]s",
+
+	// 帳號
+	"顯示名稱最多 %d 個字":            "Display names can be at most %d characters",
+	"更新顯示名稱失敗，請稍後再試":          "Could not update your display name. Please try again later",
+	"顯示名稱已更新":                 "Display name updated",
+	"目前的密碼不正確":                "Your current password is incorrect",
+	"兩次輸入的新密碼不一致":             "The new passwords don't match",
+	"新密碼不能和目前的密碼相同":           "The new password must differ from the current one",
+	"變更密碼失敗，請稍後再試":            "Could not change your password. Please try again later",
+	"密碼已變更，其他裝置都已登出":          "Password changed. Your other devices have been logged out",
+	"密碼不正確":                   "Incorrect password",
+	"驗證失敗，請稍後再試":              "Verification failed. Please try again later",
+	"請輸入自己的使用者名稱確認刪除":         "Enter your username to confirm",
+	"你是唯一的管理員，請先指定其他管理員再刪除帳號": "You're the only administrator. Make someone else an administrator before deleting your account",
+	"刪除帳號失敗，請稍後再試":            "Could not delete your account. Please try again later",
+	"刪除帳號途中失敗，請稍後再試一次":        "Deleting your account failed partway. Please try again",
+	"這個帳號已停用，請聯絡管理員":          "This account is disabled. Please contact an administrator",
+
+	// 設定
+	"不支援的語系": "Unsupported language",
+	"更新語言與日期格式失敗，請稍後再試":     "Could not update the language and date format. Please try again later",
+	"Email 格式不正確":           "Invalid email address",
+	"更新 Email 失敗，請稍後再試":     "Could not update your email. Please try again later",
+	"Email 已更新":             "Email updated",
+	"任務編號設定已更新":             "Task number setting updated",
+	"更新設定失敗，請稍後再試":          "Could not update the setting. Please try again later",
+	"摘要設定不正確":               "Invalid digest settings",
+	"請先設定 Email 才能開啟摘要信":    "Set an email address before turning on the digest",
+	"更新摘要設定失敗，請稍後再試":        "Could not update the digest settings. Please try again later",
+	"摘要設定已儲存":               "Digest settings saved",
+	"請先設定 Email":            "Please set an email address first",
+	"寄送測試信失敗":               "Could not send the test email",
+	"寄送測試信失敗，請確認 SMTP 設定":   "Could not send the test email. Check the SMTP settings",
+	"測試摘要已寄到 %s":            "Test digest sent to %s",
+	"衝突提醒設定不正確":             "Invalid conflict warning settings",
+	"更新衝突提醒失敗，請稍後再試":        "Could not update the conflict warnings. Please try again later",
+	"衝突提醒設定已儲存":             "Conflict warning settings saved",
+	"提醒時間不正確":               "Invalid reminder time",
+	"提醒時間必須是到期前 0 分鐘到 30 天": "Reminders must be between 0 minutes and 30 days before the due date",
+	"每個任務最多 %d 個提醒":         "A task can have at most %d reminders",
+	"更新預設提醒失敗，請稍後再試":        "Could not update the default reminders. Please try again later",
+	"預設提醒已儲存：%s":            "Default reminders saved: %s",
+	"到期時":                   "At due time",
+	"%d 分鐘前":                "%d min before",
+	"%d 小時前":                "%d h before",
+	"%d 天前":                 "%d days before",
+	"%d 週前":                 "%d weeks before",
+	"不提醒":                   "No reminder",
+	"預設":                    "Default",
+	"逾期處理方式不正確":             "Invalid overdue handling",
+	"更新逾期處理方式失敗，請稍後再試":      "Could not update the overdue handling. Please try again later",
+	"逾期任務的處理方式：%s":          "Overdue tasks: %s",
+	"不處理，維持逾期":              "Do nothing, leave them overdue",
+	"自動移到今天":                "Move them to today",
+	"提高一級優先順序":              "Raise the priority one level",
+	"加上 missed 標籤，移到「錯過」清單": "Tag them missed and move them to the “missed” list",
+	"已開啟桌面通知，開著這個頁面時會在任務到期前提醒你": "Desktop notifications on. While this page is open, you'll be reminded before tasks are due",
+	"已關閉桌面通知":                  "Desktop notifications off",
+	"更新通知設定失敗，請稍後再試":           "Could not update the notification setting. Please try again later",
+	"加密筆記格式錯誤，伺服器只接受瀏覽器加密後的內容": "Invalid encrypted note. The server only accepts content encrypted in the browser",
+	"CSV 格式錯誤：%v":              "Invalid CSV: %v",
+
+	// 設定頁
+	"寄送時間":    "Send at",
+	"包含未來":    "including tasks due in the next",
+	"天內到期的任務": "days",
+	"儲存摘要設定":  "Save digest settings",
+	"上次寄出：%s": "Last sent: %s",
+	"預覽今日摘要":  "Preview today's digest",
+	"寄送測試信":   "Send a test email",
+	"開啟摘要信":   "Send me a daily digest",
+	"每天在指定時間寄出逾期、今天到期與接下來幾天到期的任務。":                         "Every day at the chosen time, get an email with overdue tasks, tasks due today and tasks due in the next few days.",
+	"提醒信與摘要信會寄到這個地址。":                                      "Reminders and digests are sent to this address.",
+	"沒有自訂提醒的任務會在這些時間提醒你（桌面通知、推播或 Email）。個別任務可以在編輯頁改成其他時間。": "Tasks without their own reminders remind you at these times (desktop notification, push or email). You can pick other times for a task on its edit page.",
+	"儲存預設提醒": "Save default reminders",
+	"新增或修改任務時，如果同一個時段已經有很多任務到期，會提醒你避免排得太滿。": "When you add or edit a task, you'll be warned if many tasks are already due around the same time so you don't overbook.",
+	"同一小時已有":     "Warn when an hour already has",
+	"個任務，或同一天已有": "tasks, or a day already has",
+	"個任務時提醒":     "tasks",
+	"儲存提醒設定":     "Save warning settings",
+	"每天午夜檢查你負責、已經逾期還沒完成的任務，依這裡的設定處理。重複任務不受影響。": "Every midnight, your open overdue tasks are handled as set here. Repeating tasks are not affected.",
+	"儲存處理方式": "Save",
+	"把指定日期以前完成的任務永久刪除，不會進垃圾桶，也無法復原。專案任務不會被刪除。": "Permanently delete tasks completed before a date. They skip the trash and can't be restored. Project tasks are not deleted.",
+	"以前完成的任務":                                    "(tasks completed before this date)",
+	"沒有在 %s 以前完成的任務。":                            "No tasks were completed before %s.",
+	"共有 %d 個在 %s 以前完成的任務會被永久刪除。":                 "%d tasks completed before %s will be deleted permanently.",
+	"確定要永久刪除 %d 個任務嗎？刪除後無法復原。":                   "Permanently delete %d tasks? This can't be undone.",
+	"在清單與通知裡的任務前面顯示 #編號":                         "Show #numbers before tasks in lists and notifications",
+	"每個任務都有固定的網址（/task/編號），提醒信與通知裡的連結會直接打開那個任務。": "Every task has a permanent URL (/task/number), and links in reminders and notifications open that task directly.",
+	"頁面上方會顯示這個名稱，登入時仍使用帳號 %s。空白表示直接顯示帳號。":        "This name is shown at the top of the page. You still log in as %s. Leave it blank to show your username.",
+	"變更後其他裝置都會登出，只保留目前這個瀏覽器。":                    "Changing it logs out your other devices and keeps only this browser.",
+	"再輸入一次新密碼":                                   "Repeat the new password",
+	"你目前只用第三方帳號登入，設定密碼後也可以用帳號 %s 和密碼登入。":         "You only log in with a third-party account. Set a password to also log in as %s with a password.",
+	"連結後可以直接用 Google 或 GitHub 帳號登入這個帳號。":         "Once linked, you can log in to this account with Google or GitHub.",
+	"連結 %s 帳號": "Link %s account",
+	"取消連結":     "Unlink",
+	"目前登入這個帳號的瀏覽器。看到不認得的裝置請登出它並更改密碼。": "Browsers logged in to this account. If you don't recognize one, log it out and change your password.",
+	"目前的瀏覽器":           "This browser",
+	"登入於 %s · 最後使用 %s": "Logged in %s · last used %s",
+	"登出所有裝置":           "Log out everywhere",
+	"所有裝置（包括這個瀏覽器）都會登出，確定嗎？":                     "Every device, including this browser, will be logged out. Continue?",
+	"給命令列工具 %s 或其他程式使用，設定成環境變數 %s 即可新增、列出與完成任務。": "For the %s command-line tool or other programs. Set it as the %s environment variable to add, list and complete tasks.",
+	"token 能讀寫你所有的任務，只在建立時顯示一次；不再使用或外流時請撤銷。":     "A token can read and write all of your tasks and is shown only once. Revoke it when you stop using it or if it leaks.",
+	"用途，例如：筆電的終端機":                               "Purpose, e.g. laptop terminal",
+	"建立 token":                                   "Create token",
+	"撤銷後使用這組 token 的程式就不能再連線，確定嗎？":               "Programs using this token will stop working. Revoke it?",
+	"拿到網址的人不用登入就能看到你尚未完成的任務（或某個專案的任務），但不能修改。加密筆記與私人任務不會出現。網址外流時請撤銷。": "Anyone with the URL can see your open tasks (or a project's tasks) without logging in, but can't change them. Encrypted notes and private tasks are hidden. Revoke the link if it leaks.",
+	"建立連結": "Create link",
+	"撤銷後這個網址就不能再使用，確定嗎？":                 "This URL will stop working. Revoke it?",
+	"查看任務、加密筆記、留言與登入中的裝置各佔了多少，並個別匯出或清除。": "See how much space your tasks, encrypted notes, comments and logged-in devices take, and export or clear each.",
+	"查看資料用量": "View data usage",
+	"如果你不小心註冊了兩個帳號，輸入另一個帳號的名稱與密碼，就能把它的任務與專案併進目前的帳號。目前帳號已有的設定會保留，只補上沒設定的部分；另一個帳號合併後會被刪除，無法復原。": "If you accidentally signed up twice, enter the other account's username and password to merge its tasks and projects into this one. Settings you already have are kept and only missing ones are filled in. The other account is then deleted, which can't be undone.",
+	"另一個帳號的使用者名稱":       "Other account's username",
+	"另一個帳號的密碼":          "Other account's password",
+	"合併到目前帳號":           "Merge into this account",
+	"另一個帳號會被刪除，確定要合併嗎？": "The other account will be deleted. Merge?",
+	"個人任務（含垃圾桶）會全部刪除，負責的專案任務改回未認領留給其他成員，所有裝置都會登出。刪除後無法復原，需要的話請先下載備份：": "All personal tasks, including the trash, are deleted, project tasks assigned to you go back to unclaimed, and every device is logged out. This can't be undone, so download a backup first if you need one:",
+	"輸入 %s 確認": "Type %s to confirm",
+	"確認刪除":     "Confirm",
+	"帳號與任務會永久刪除，確定嗎？": "Your account and tasks will be deleted permanently. Continue?",
+	"永久刪除帳號":          "Delete account permanently",
+
+	// 裝置與資料用量
+	"未知的裝置":  "Unknown device",
+	"已登出該裝置": "Device logged out",
+	"要登出目前的瀏覽器請按右上角的登出": "To log out this browser, use Log out at the top right",
+	"已登出其他 %d 個裝置":      "Logged out %d other devices",
+	"清除筆記失敗，請稍後再試":      "Could not clear the notes. Please try again later",
+	"已清除 %d 個已完成任務的筆記":  "Cleared the notes on %d completed tasks",
+	"清除留言失敗，請稍後再試":      "Could not clear the comments. Please try again later",
+	"已刪除已完成任務上的 %d 則留言": "Deleted %d comments on completed tasks",
+	"回設定": "Back to settings",
+	"其中 %d 個已完成，另有 %d 個在垃圾桶裡。": "%d of them are completed, and %d more are in the trash.",
+	"確定要永久刪除垃圾桶裡的 %d 個任務嗎？":    "Permanently delete the %d tasks in the trash?",
+	"舊的已完成任務可以依日期清除：":          "Old completed tasks can be cleared by date: ",
+	"附件（加密筆記）":                 "Attachments (encrypted notes)",
+	"這裡沒有檔案附件，伺服器上只有 %d 個任務的加密筆記。匯出的是密文，需要當初的密語才能解開。": "There are no file attachments here; the server only stores encrypted notes for %d tasks. Exports contain the ciphertext and need the original passphrase to open.",
+	"已完成任務上的筆記共 %s，可以清除；專案任務不受影響。":                    "Notes on completed tasks take %s and can be cleared. Project tasks are not affected.",
+	"清除已完成任務的筆記":               "Clear notes on completed tasks",
+	"確定要刪除已完成任務上的筆記嗎？刪除後無法復原。": "Delete the notes on completed tasks? This can't be undone.",
+	"留言": "Comments",
+	"你的任務上所有人留下的留言。已完成任務上有 %d 則可以清除，修改紀錄會保留；專案任務不受影響。": "Comments anyone left on your tasks. %d on completed tasks can be cleared; the change history is kept and project tasks are not affected.",
+	"清除已完成任務的留言":                  "Clear comments on completed tasks",
+	"確定要刪除已完成任務上的 %d 則留言嗎？":       "Delete the %d comments on completed tasks?",
+	"包含目前這個瀏覽器。登出其他裝置後，它們需要重新登入。": "Including this browser. Other devices will need to log in again.",
+	"登出其他裝置":                 "Log out other devices",
+	"已刪除 %d 個任務":             "Deleted %d tasks",
+	"已永久刪除 %d 個在 %s 以前完成的任務": "Permanently deleted %d tasks completed before %s",
+	"日期不可晚於明天":               "The date can't be later than tomorrow",
+	"日期格式必須是 YYYY-MM-DD":     "The date must be YYYY-MM-DD",
+	"清除途中失敗":                 "Clearing failed partway",
+
+	// 加密筆記與提醒（編輯頁）
+	"筆記密語":        "Note passphrase",
+	"請輸入筆記密語":     "Please enter the note passphrase",
+	"密語錯誤，無法解密":   "Wrong passphrase, can't decrypt",
+	"解鎖":          "Unlock",
+	"只有知道密語的人看得到": "Only people who know the passphrase can read it",
+	"筆記在瀏覽器內加密，伺服器只保存密文；密語不會送出，忘記就無法復原。清空內容並儲存即可刪除筆記。": "Notes are encrypted in your browser and the server only stores ciphertext. The passphrase is never sent, so a forgotten one can't be recovered. Clear the note and save to delete it.",
+	"取消「使用預設提醒」才會套用下面勾選的時間；都不勾就不提醒。":                   "Untick “Use default reminders” to use the times below. Tick none for no reminders.",
+	"預設提醒可以在設定頁調整：": "Change the default reminders on the ",
+
+	// API token 與分享連結
+	"名稱最多 %d 個字": "Names can be at most %d characters",
+	"最多只能建立 %d 組 API token，請先撤銷用不到的":  "You can have at most %d API tokens. Revoke one you don't use first",
+	"建立 API token 失敗，請稍後再試":           "Could not create the API token. Please try again later",
+	"已建立 API token（只會顯示這一次，請馬上複製）：%s": "API token created (shown only once, copy it now): %s",
+	"撤銷 API token 失敗，請稍後再試":           "Could not revoke the API token. Please try again later",
+	"API token 已撤銷，使用它的程式需要換一組新的":     "API token revoked. Programs using it need a new one",
+	"找不到這個專案":                         "Project not found",
+	"最多只能建立 %d 條分享連結，請先撤銷用不到的":        "You can have at most %d share links. Revoke one you don't use first",
+	"建立分享連結失敗，請稍後再試":                  "Could not create the share link. Please try again later",
+	"已建立唯讀分享連結，複製下方的網址傳給對方即可":         "Read-only share link created. Copy the URL below and send it",
+	"撤銷分享連結失敗，請稍後再試":                  "Could not revoke the share link. Please try again later",
+	"分享連結已撤銷，舊的網址不能再使用":               "Share link revoked. The old URL no longer works",
+	"（已無法存取的專案）":                      "(project no longer accessible)",
+	"%s 的待辦清單":                        "%s's to-do list",
+	"由 %s 分享的唯讀清單，只列出尚未完成的任務":         "A read-only list shared by %s, showing only open tasks",
+	"目前沒有未完成的任務 🎉":                    "No open tasks right now 🎉",
+	"這是唯讀頁面，內容會隨清單更新":                 "This page is read-only and follows the list",
+
+	// 第三方登入
+	"%s 登入逾時或連結無效，請重新再試一次": "The %s login timed out or the link is invalid. Please try again",
+	"已取消 %s 登入":               "%s login cancelled",
+	"無法向 %s 確認身分，請稍後再試":       "Could not verify your identity with %s. Please try again later",
+	"這個 %s 帳號已經連結到其他使用者":      "This %s account is already linked to another user",
+	"這個 %s 帳號已經連結過了":          "This %s account is already linked",
+	"連結失敗，請稍後再試":              "Could not link the account. Please try again later",
+	"已連結 %s 帳號 %s，之後可以用它登入":   "Linked %s account %s. You can log in with it from now on",
+	"建立帳號失敗，請稍後再試":            "Could not create the account. Please try again later",
+	"這是目前唯一的登入方式，請先設定密碼再取消連結": "This is your only way to log in. Set a password before unlinking",
+	"取消連結失敗，請稍後再試":            "Could not unlink the account. Please try again later",
+	"已取消連結 %s 帳號":             "Unlinked the %s account",
+
+	// 合併帳號
+	"請輸入另一個帳號的使用者名稱":                     "Please enter the other account's username",
+	"請選擇兩個不同的帳號":                         "Please choose two different accounts",
+	"不能把自己目前登入的帳號併到別的帳號":                 "You can't merge the account you're logged in with into another one",
+	"管理員帳號只能由管理員合併":                      "Only administrators can merge administrator accounts",
+	"合併帳號失敗，請稍後再試":                       "Could not merge the accounts. Please try again later",
+	"任務 %d 個、垃圾桶 %d 個、專案 %d 個、學生名單 %d 份": "%d tasks, %d in the trash, %d projects, %d rosters",
+	"已將 %s 合併到 %s（%s）":                   "Merged %s into %s (%s)",
+	"已將 %s 併入目前的帳號（%s）":                  "Merged %s into this account (%s)",
+
+	// 使用者管理
+	"使用者管理":     "Users",
+	"一般":        "Member",
+	"管理員":       "Administrator",
+	"角色":        "Role",
+	"建立時間":      "Created",
+	"已停用":       "Disabled",
+	"邀請中":       "Invited",
+	"重寄":        "Resend",
+	"重設密碼":      "Reset password",
+	"所有使用者（%d）": "All users (%d)",
+	"全站合計（含未認領的專案任務）":           "Site total (including unclaimed project tasks)",
+	"%s 的密碼會被清除並登出所有裝置，確定要重設嗎？": "%s's password will be cleared and all their devices logged out. Reset it?",
+	"匯入使用者": "Import users",
+	"上傳 CSV，每列為「使用者名稱,Email」，第一列可以是標題 username,email。建立的帳號會收到設定密碼的邀請信。":    "Upload a CSV with one “username,email” per row; the first row may be the header username,email. New accounts get an invitation email to set a password.",
+	"把重複的帳號併進另一個：任務、垃圾桶、專案與學生名單都會移過去，保留的帳號已有的設定不變。被併掉的帳號會刪除並登出，操作會寫進稽核紀錄。": "Merge a duplicate account into another: tasks, trash, projects and rosters move over, and the kept account's settings stay as they are. The merged account is deleted and logged out, and the action is written to the audit log.",
+	"要併掉的帳號": "Account to merge",
+	"保留的帳號":  "Account to keep",
+	"被併掉的帳號會刪除，確定要合併嗎？": "The merged account will be deleted. Merge?",
+	"不支援的角色":                        "Unsupported role",
+	"不能變更這個帳號的角色":                   "You can't change this account's role",
+	"變更角色失敗，請稍後再試":                  "Could not change the role. Please try again later",
+	"%s 的角色已變更為%s":                  "%s is now: %s",
+	"不能變更這個帳號的狀態":                   "You can't change this account's status",
+	"變更帳號狀態失敗，請稍後再試":                "Could not change the account status. Please try again later",
+	"%s 已停用，所有裝置都已登出":               "%s is disabled and logged out everywhere",
+	"%s 已重新啟用":                      "%s is enabled again",
+	"不能重設這個帳號的密碼，要改自己的密碼請到設定頁":      "You can't reset this account's password. Change your own password on the settings page",
+	"重設密碼失敗，請稍後再試":                  "Could not reset the password. Please try again later",
+	"密碼已重設，但寄信失敗，請確認 SMTP 設定後按「重寄」": "The password was reset but the email failed. Check the SMTP settings and press “Resend”",
+	"已重設 %s 的密碼，設定新密碼的連結已寄到 %s":     "Reset %s's password and sent a link to set a new one to %s",
+	"已重設 %s 的密碼。這個帳號沒有 Email，請把連結轉交給他（%s 前有效）：%s": "Reset %s's password. This account has no email, so pass on this link (valid until %s): %s",
+	"這個帳號不需要邀請":                  "This account doesn't need an invitation",
+	"寄送邀請信失敗，請確認 SMTP 設定":        "Could not send the invitation. Check the SMTP settings",
+	"已重新寄出邀請信給 %s":               "Invitation resent to %s",
+	"請選擇要匯入的 CSV 檔":              "Please choose a CSV file to import",
+	"讀取 CSV 失敗":                  "Could not read the CSV",
+	"第 %d 列：需要使用者名稱與 Email 兩個欄位": "Row %d: a username and an email are required",
+	"第 %d 列：使用者名稱或 Email 不正確":    "Row %d: invalid username or email",
+	"一次最多匯入 %d 位使用者":             "You can import at most %d users at a time",
+	"建立帳號失敗":                     "Could not create the accounts",
+	"已建立 %d 個帳號並寄出邀請信":           "Created %d accounts and sent invitations",
+	"邀請連結無效或已過期，請聯絡管理員重新寄送":      "This invitation link is invalid or has expired. Ask an administrator to resend it",
+	"兩次輸入的密碼不一致":                 "The passwords don't match",
+	"設定密碼失敗，請稍後再試":               "Could not set the password. Please try again later",
+	"歡迎，%s！請設定登入密碼。":             "Welcome, %s! Please set a password.",
+	"設定密碼":                       "Set password",
+	"完成並登入":                      "Finish and log in",
+
+	// 公告
+	"請填寫公告內容與到期時間": "Please enter an announcement and a due date",
+	"發布公告失敗，請稍後再試": "Could not publish the announcement. Please try again later",
+	"公告已發布":        "Announcement published",
+	"公告任務":         "Announcement tasks",
+	"發給所有成員的任務...": "A task for every member...",
+	"發布":           "Publish",
+	"尚未發布任何公告":     "No announcements yet",
+	"發布者：%s":       "Posted by %s",
+	"已完成 %d / %d":  "%d / %d completed",
+
+	// 共享專案
+	"共享專案":         "Shared projects",
+	"專案名稱":         "Project name",
+	"成員（以逗號分隔）":    "Members (comma separated)",
+	"成員 %d 人":      "%d members",
+	"擁有者：%s":       "Owner: %s",
+	"還沒有參與任何專案":    "You're not in any projects yet",
+	"所有專案":         "All projects",
+	"成員：":          "Members: ",
+	"邀請成員（以逗號分隔）":  "Invite members (comma separated)",
+	"新增專案任務...":    "Add a project task...",
+	"專案內還沒有任務":     "No tasks in this project yet",
+	"待認領":          "Unclaimed",
+	"認領":           "Claim",
+	"負責人：%s":       "Assignee: %s",
+	"轉派":           "Reassign",
+	"退回待認領":        "Unassign",
+	"公開":           "Public",
+	"私人":           "Private",
+	"設為私人":         "Make private",
+	"只有指派給自己時有效":   "Only for tasks assigned to you",
+	"專案動態":         "Project activity",
+	"目前沒有動態":       "No activity yet",
+	"請填寫專案名稱":      "Please enter a project name",
+	"建立專案失敗，請稍後再試": "Could not create the project. Please try again later",
+	"專案「%s」已建立":    "Project “%s” created",
+	"找不到使用者 %s":    "User %s not found",
+	"新增成員失敗，請稍後再試": "Could not add the member. Please try again later",
+	"已邀請 %s":       "Invited %s",
+	"這個任務已經被其他成員認領了":    "Another member already claimed this task",
+	"認領失敗，請稍後再試":        "Could not claim the task. Please try again later",
+	"已認領「%s」":           "Claimed “%s”",
+	"%s 不是專案成員":         "%s is not a project member",
+	"轉派失敗，請稍後再試":        "Could not reassign the task. Please try again later",
+	"只有指派給自己的任務可以設為私人":  "Only tasks assigned to you can be made private",
+	"變更可見性失敗，請稍後再試":     "Could not change the visibility. Please try again later",
+	"「%s」已設為私人，其他成員看不到": "“%s” is now private and hidden from other members",
+	"「%s」已公開給專案成員":      "“%s” is now visible to project members",
+
+	// 老師模式
+	"老師模式":         "Teacher mode",
+	"學生名單":         "Roster",
+	"每行一位學生的使用者名稱": "One student username per line",
+	"學生需要先有帳號，可請管理員從「使用者」頁面整批匯入。": "Students need accounts first. An administrator can import them in bulk from the Users page.",
+	"儲存名單":         "Save roster",
+	"派發作業":         "Assign homework",
+	"作業內容":         "Assignment",
+	"派發給名單上的學生":    "Assign to everyone on the roster",
+	"尚未派發任何作業":     "No assignments yet",
+	"截止 %s":        "Due %s",
+	"完成情形":         "Progress",
+	"學生":           "Student",
+	"完成數":          "Completed",
+	"遲交 %s":        "Late by %s",
+	"儲存名單失敗，請稍後再試": "Could not save the roster. Please try again later",
+	"學生名單已更新":      "Roster updated",
+	"請先設定學生名單":     "Please set up the roster first",
+	"派發作業失敗，請稍後再試": "Could not assign the homework. Please try again later",
+	"作業已派發給 %s":    "Homework assigned to %s",
+
+	// 管理主控台
+	"管理主控台":  "Admin console",
+	"排程工作":   "Scheduled jobs",
+	"上次執行":   "Last run",
+	"下次執行":   "Next run",
+	"尚未執行":   "Not run yet",
+	"執行中…":   "Running…",
+	"成功":     "OK",
+	"結果":     "Result",
+	"立即執行":   "Run now",
+	"備份驗證":   "Backup check",
+	"檢查於 %s": "Checked %s",
+	"%s（%s 的版本）可以還原：%d 位使用者、%d 個任務": "%s (version from %s) can be restored: %d users, %d tasks",
+	"%s（%s 的版本）發現 %d 個問題":           "%s (version from %s) has %d problems",
+	"查詢":       "Queries",
+	"沒有資料":     "No data",
+	"各使用者的任務數": "Tasks per user",
+	"任務快取狀態":   "Task cache status",
+	"孤兒資料（參照已不存在的使用者、專案或公告）": "Orphaned data (referencing deleted users, projects or announcements)",
+	"任務數":           "Tasks",
+	"類型":            "Type",
+	"問題":            "Problem",
+	"項目":            "Item",
+	"數值":            "Value",
+	"工作 %s 執行失敗：%v": "Job %s failed: %v",
+	"工作 %s 已完成（%s）": "Job %s finished (%s)",
+	"查詢失敗：%v":       "Query failed: %v",
+	"找不到排程工作":       "No such scheduled job",
+	"伺服器正在關閉，排程工作已停止": "The server is shutting down; scheduled jobs have stopped",
+
+	// 其他頁面
+	"今日摘要預覽": "Today's digest preview",
+	"收件人：%s": "To: %s",
+	"這是依目前的任務與設定產生的預覽，實際寄出的內容以寄送當下為準。": "This preview reflects your current tasks and settings; the email that is sent uses whatever they are at send time.",
+	"內容過大":     "Too large",
+	"送出的內容太大了": "What you sent is too large",
+	"單次送出的資料上限為 %d KB，請縮減內容後再試一次。": "Each submission is limited to %d KB. Please shorten it and try again.",
+	"改回未完成": "Mark incomplete",
+}
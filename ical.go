@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// --- iCalendar 訂閱 ---
+//
+// /calendar.ics?token=... 輸出使用者所有任務，讓 Google 日曆、Apple 行事曆可以訂閱。
+// 訂閱用的 token 只能讀取任務，因此以明碼存在使用者資料中方便再次顯示網址，
+// 外流時可以在月曆頁重新產生
+
+const (
+	icalTimeFormat    = "20060102T150405"
+	icalDateFormat    = "20060102" // 全天任務的 VALUE=DATE
+	icalEventDuration = 30 * time.Minute
+	icalLineLimit     = 75 // RFC 5545：每行最多 75 octets，超過要折行
+)
+
+// findFeedUser 依訂閱 token 找出使用者
+func (a *App) findFeedUser(token string) (User, bool) {
+	if token == "" {
+		return User{}, false
+	}
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return User{}, false
+	}
+	for _, u := range users {
+		if u.FeedToken != "" && !u.Disabled && subtle.ConstantTimeCompare([]byte(u.FeedToken), []byte(token)) == 1 {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// ensureFeedToken 回傳使用者的訂閱 token，第一次使用時才產生
+func (a *App) ensureFeedToken(username string) (string, error) {
+	user, err := a.store.GetUser(username)
+	if err != nil {
+		return "", err
+	}
+	if user.FeedToken != "" {
+		return user.FeedToken, nil
+	}
+	// 同時打開兩個頁面時，只有先到的那個產生 token
+	user, err = a.store.ModifyUser(username, func(u *User) error {
+		if u.FeedToken == "" {
+			u.FeedToken = randomToken(24)
+		}
+		return nil
+	})
+	return user.FeedToken, err
+}
+
+func (a *App) resetFeedToken(username string) (string, error) {
+	user, err := a.store.ModifyUser(username, func(u *User) error {
+		u.FeedToken = randomToken(24)
+		return nil
+	})
+	return user.FeedToken, err
+}
+
+// icalEscape 依 RFC 5545 跳脫 TEXT 值
+func icalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICalLine 寫出一行內容，超過 75 octets 時在字元邊界折行
+func writeICalLine(b *strings.Builder, line string) {
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icalLineLimit - 1 // 續行開頭的空白也算一個 octet
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// renderICal 產生整份行事曆。任務時間是沒有時區的「牆上時間」，
+// 所以輸出為 floating time（不加 Z），在任何時區的行事曆都顯示成使用者輸入的時間。
+// asTodo 為 true 時輸出 VTODO（給支援待辦的用戶端），否則輸出 VEVENT
+func renderICal(host string, tasks []Task, asTodo bool, now time.Time) string {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		writeICalLine(&b, fmt.Sprintf(format, args...))
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//go-FinalProject//To-Do List//ZH-TW")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:%s", icalEscape("待辦清單"))
+	stamp := now.UTC().Format(icalTimeFormat) + "Z"
+
+	for _, task := range tasks {
+		var notes []string
+		notes = append(notes, "優先順序："+priorityLabel(task.Priority))
+		if len(task.Tags) > 0 {
+			notes = append(notes, "標籤："+strings.Join(task.Tags, "、"))
+		}
+		if len(task.Checklist) > 0 {
+			notes = append(notes, fmt.Sprintf("子項目：%d/%d", task.ChecklistDone(), len(task.Checklist)))
+		}
+
+		summary := task.Description
+		if asTodo {
+			line("BEGIN:VTODO")
+		} else {
+			line("BEGIN:VEVENT")
+			if task.Completed {
+				summary = "✅ " + summary
+			}
+		}
+		line("UID:task-%d@%s", task.ID, host)
+		line("DTSTAMP:%s", stamp)
+		line("SUMMARY:%s", icalEscape(summary))
+		line("DESCRIPTION:%s", icalEscape(strings.Join(notes, "\n")))
+		line("URL:%s", taskURL(task.ID))
+		if len(task.Tags) > 0 {
+			escaped := make([]string, len(task.Tags))
+			for i, tag := range task.Tags {
+				escaped[i] = icalEscape(tag)
+			}
+			line("CATEGORIES:%s", strings.Join(escaped, ","))
+		}
+		if asTodo {
+			if task.AllDay {
+				line("DUE;VALUE=DATE:%s", task.DueAt.Format(icalDateFormat))
+			} else {
+				line("DUE:%s", task.DueAt.Format(icalTimeFormat))
+			}
+			line("PRIORITY:%d", map[int]int{0: 1, 1: 5, 2: 9}[priorityRank(task.Priority)])
+			if task.Completed {
+				line("STATUS:COMPLETED")
+				if !task.CompletedAt.IsZero() {
+					line("COMPLETED:%sZ", task.CompletedAt.UTC().Format(icalTimeFormat))
+				}
+			} else {
+				line("STATUS:NEEDS-ACTION")
+			}
+			line("END:VTODO")
+		} else {
+			if task.AllDay {
+				line("DTSTART;VALUE=DATE:%s", task.DueAt.Format(icalDateFormat))
+				line("DTEND;VALUE=DATE:%s", task.DueAt.AddDate(0, 0, 1).Format(icalDateFormat))
+			} else {
+				line("DTSTART:%s", task.DueAt.Format(icalTimeFormat))
+				line("DTEND:%s", task.DueAt.Add(icalEventDuration).Format(icalTimeFormat))
+			}
+			line("END:VEVENT")
+		}
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// calendarFeedHandler 不需要登入，以網址上的 token 辨識使用者
+func (a *App) calendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.findFeedUser(r.URL.Query().Get("token"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	tasks, err := a.store.ListTasks(user.Username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	asTodo := r.URL.Query().Get("kind") == "todo"
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="todo.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	fmt.Fprint(w, renderICal(r.Host, tasks, asTodo, time.Now()))
+}
+
+// calendarFeedResetHandler 作廢舊的訂閱網址並產生新的
+func (a *App) calendarFeedResetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		if _, err := a.resetFeedToken(a.getUsername(r)); err != nil {
+			a.flashError(r, err, "重新產生訂閱網址失敗，請稍後再試")
+		} else {
+			a.flashSuccess(r, "已產生新的訂閱網址，舊的網址已失效")
+		}
+	}
+	http.Redirect(w, r, "/calendar", http.StatusSeeOther)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 任務內容裡的標記 ---
+//
+// 新增任務時可以直接在內容裡寫標記，例如「期末報告 #課業 !high @期末專題」：
+// #標籤 加上標籤、!high／!medium／!low（或 !高／!中／!低）設定優先順序、@專案名稱 把任務放進自己參與的專案
+// （名稱有空白時去掉空白來寫）。認得的標記會從內容裡拿掉，認不得的 @ 與 ! 照原樣留著，
+// 例如「@某人」或「!!」。新增表單與 POST /api/v1/tasks/quick 都適用；編輯任務時不解析
+
+// inlineMarkers 是從內容裡取出的標記；Priority 為空、ProjectID 為 0 表示沒有寫
+type inlineMarkers struct {
+	Tags      []string
+	Priority  string
+	ProjectID int
+	Project   string
+}
+
+var inlinePriorities = map[string]string{
+	"high": PriorityHigh, "medium": PriorityMedium, "low": PriorityLow,
+	"高": PriorityHigh, "中": PriorityMedium, "低": PriorityLow,
+}
+
+// parseInline 取出 text 裡的標記，回傳去掉標記後的內容（空白合併成一格）。
+// @專案 只比對 username 參與的專案，寫了兩個不同的專案時回傳錯誤
+func (a *App) parseInline(text, username string) (string, inlineMarkers, error) {
+	var m inlineMarkers
+	var projects []Project
+	var kept []string
+	for _, word := range strings.Fields(text) {
+		switch {
+		case len(word) > 1 && word[0] == '#':
+			m.Tags = append(m.Tags, word[1:])
+			continue
+		case len(word) > 1 && word[0] == '!':
+			if p, ok := inlinePriorities[strings.ToLower(word[1:])]; ok {
+				m.Priority = p // 寫了好幾個時以最後一個為準
+				continue
+			}
+		case len(word) > 1 && word[0] == '@':
+			if projects == nil {
+				var err error
+				if projects, err = a.store.ListProjects(username); err != nil {
+					return "", m, err
+				}
+			}
+			if p, ok := findInlineProject(projects, word[1:]); ok {
+				if m.ProjectID != 0 && m.ProjectID != p.ID {
+					return "", m, invalidInput("一個任務只能放進一個專案（@%s、@%s）", m.Project, p.Name)
+				}
+				m.ProjectID, m.Project = p.ID, p.Name
+				continue
+			}
+		}
+		kept = append(kept, word)
+	}
+	m.Tags = normalizeTags(m.Tags)
+	return strings.Join(kept, " "), m, nil
+}
+
+// findInlineProject 不分大小寫比對專案名稱，名稱裡的空白可以省略
+func findInlineProject(projects []Project, name string) (Project, bool) {
+	for _, p := range projects {
+		if strings.EqualFold(p.Name, name) || strings.EqualFold(strings.Join(strings.Fields(p.Name), ""), name) {
+			return p, true
+		}
+	}
+	return Project{}, false
+}
+
+// apply 把標記寫進新任務：標籤接在原有的後面，優先順序與專案有寫才覆蓋
+func (m inlineMarkers) apply(task *Task) {
+	task.Tags = normalizeTags(append(task.Tags, m.Tags...))
+	if m.Priority != "" {
+		task.Priority = m.Priority
+	}
+	if m.ProjectID != 0 {
+		task.ProjectID = m.ProjectID
+		task.CreatedBy = task.Username
+	}
+}
+
+// announceInline 在 m 把任務放進專案時寫一筆專案動態，和在專案頁新增並指派給自己一樣
+func (a *App) announceInline(m inlineMarkers, task Task) {
+	if m.ProjectID != 0 {
+		a.notifyProject(m.ProjectID, "%s 新增了任務%s並指派給 %s", task.Username, quoted(task.Description), task.Username)
+	}
+}
+
+// quickAddInput 是快速新增的請求內容；沒有 due_at 時是今天 23:59
+type quickAddInput struct {
+	Text           string     `json:"text"`
+	DueAt          *time.Time `json:"due_at"`
+	AllowDuplicate bool       `json:"allow_duplicate"` // 為 true 時不檢查重複
+}
+
+// apiQuickAdd：POST /api/v1/tasks/quick，以一行文字新增任務，標記的寫法與新增表單相同
+func (a *App) apiQuickAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	var in quickAddInput
+	if !readJSON(w, r, &in) {
+		return
+	}
+	username := a.getUsername(r)
+	desc, markers, err := a.parseInline(in.Text, username)
+	if err == nil {
+		err = checkDescription(desc)
+	}
+	if err != nil {
+		writeDomainError(w, err, "新增任務失敗")
+		return
+	}
+	if !in.AllowDuplicate && !a.rejectDuplicate(w, username, desc) {
+		return
+	}
+	now := time.Now()
+	due := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 0, 0, time.Local)
+	if in.DueAt != nil {
+		due = *in.DueAt
+	}
+
+	task := Task{
+		Description: desc,
+		CreatedAt:   now,
+		DueAt:       due,
+		Username:    username,
+		Priority:    PriorityMedium,
+	}
+	markers.apply(&task)
+	task, err = a.store.CreateTask(task)
+	if err != nil {
+		writeDomainError(w, err, "新增任務失敗")
+		return
+	}
+	a.announceInline(markers, task)
+	w.Header().Set("Location", "/api/v1/tasks/"+strconv.Itoa(task.ID))
+	writeJSON(w, http.StatusCreated, task)
+}
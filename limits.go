@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// --- 請求大小限制 ---
+
+const (
+	maxFormBytes       = 256 << 10 // 一般表單（含貼上的大段文字）
+	maxJSONBytes       = 1 << 20   // /api/ 的 JSON 內容
+	maxUploadBytes     = 8 << 20   // multipart 檔案上傳
+	maxMultipartMemory = 1 << 20   // 超過的部分由 multipart 暫存到磁碟
+)
+
+func isMultipart(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+func isTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// limitRequestBody 包住整個 mux：所有帶內容的請求都套上 MaxBytesReader，
+// HTML 表單在這裡先解析，超過上限時回 413 友善頁面，handler 之後的 FormValue 直接讀取已解析的結果
+func (a *App) limitRequestBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "HEAD" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		isAPI := strings.HasPrefix(r.URL.Path, "/api/")
+		limit := int64(maxFormBytes)
+		switch {
+		case isAPI:
+			limit = maxJSONBytes
+		case isMultipart(r):
+			limit = maxUploadBytes
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+		if !isAPI {
+			var err error
+			if isMultipart(r) {
+				err = r.ParseMultipartForm(maxMultipartMemory)
+			} else {
+				err = r.ParseForm()
+			}
+			if isTooLarge(err) {
+				a.renderTooLarge(w, r, limit)
+				return
+			}
+			if err != nil {
+				http.Error(w, "表單格式錯誤", http.StatusBadRequest)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *App) renderTooLarge(w http.ResponseWriter, r *http.Request, limit int64) {
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	t := a.localize(r, a.pages.page("too-large"))
+	t.Execute(w, map[string]interface{}{"LimitKB": limit >> 10})
+}
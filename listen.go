@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// --- 監聽位址 ---
+//
+// -listen 可以是 TCP 位址（":8080"、"127.0.0.1:8080"）或 "unix:/run/todo/todo.sock"。
+// 由 systemd socket activation 啟動時（LISTEN_PID 等於自己且 LISTEN_FDS >= 1），
+// 或是 SIGHUP 重新啟動後（見 restart.go），一律使用繼承來的 socket，-listen 的設定會被忽略
+
+const (
+	unixSocketPrefix = "unix:"
+	unixSocketMode   = 0660 // 讓同群組的反向代理（例如 nginx）可以連線
+	systemdFirstFD   = 3    // sd_listen_fds(3)：繼承的 fd 從 3 開始
+)
+
+// systemdListener 回傳 systemd 傳進來的 listener；不是由 socket activation 啟動時回傳 nil
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	// 不讓子行程誤以為 socket 是給它的
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(uintptr(systemdFirstFD), "systemd-socket")
+	defer f.Close() // FileListener 會 dup 一份，原本的可以關掉
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("無法使用 systemd 傳入的 socket：%w", err)
+	}
+	return ln, nil
+}
+
+// listenUnix 建立 unix domain socket；上次沒清掉的 socket 檔會先移除
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s 已存在且不是 socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// openListener 依 -listen 的設定（或繼承來的 socket）建立 listener
+func openListener(addr string) (net.Listener, error) {
+	ln, err := inheritedListener()
+	if err != nil || ln != nil {
+		return ln, err
+	}
+	ln, err = systemdListener()
+	if err != nil || ln != nil {
+		return ln, err
+	}
+	if path, ok := strings.CutPrefix(addr, unixSocketPrefix); ok {
+		return listenUnix(path)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// listenerURL 是啟動訊息裡顯示給人看的位址；secure 表示以 HTTPS 服務
+func listenerURL(ln net.Listener, secure bool) string {
+	addr := ln.Addr()
+	if addr.Network() == "unix" {
+		return unixSocketPrefix + addr.String()
+	}
+	scheme := "http://"
+	if secure {
+		scheme = "https://"
+	}
+	if tcp, ok := addr.(*net.TCPAddr); ok && tcp.IP.IsUnspecified() {
+		return fmt.Sprintf("%slocalhost:%d", scheme, tcp.Port)
+	}
+	return scheme + addr.String()
+}
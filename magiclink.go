@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// --- Email 登入連結 ---
+//
+// 啟用 -magic-link 後，登入頁多一個「用 Email 登入」：輸入帳號設定過的 Email，
+// 就會收到一封 15 分鐘內有效的登入連結，給常忘記密碼的家人用。
+// 連結沿用一鍵操作連結的簽章 token（見 actionlink.go），動作是 login、Seq 是使用者的 MagicSeq，
+// 登入後 MagicSeq 加一，同一個連結與之前寄出的連結都一起失效。
+// 和一鍵操作一樣，GET 只顯示確認按鈕，按下（POST）才登入，信箱的掃描程式預先打開連結也不會用掉它。
+// 不論 Email 有沒有註冊都回覆同樣的訊息，避免被拿來查詢誰有帳號；同一個帳號一分鐘內只寄一封
+
+const (
+	magicLinkTTL      = 15 * time.Minute
+	magicLinkCooldown = time.Minute
+	magicLinkAction   = "login"
+)
+
+// magicLinkEnabled 由 -magic-link 開啟，需要 -link-key
+var magicLinkEnabled bool
+
+// usersByEmail 找出 Email 相符（不分大小寫）且可以登入的帳號；同一個 Email 可能設在好幾個帳號上
+func (a *App) usersByEmail(email string) ([]User, error) {
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	var matched []User
+	for _, user := range users {
+		if user.Email != "" && strings.EqualFold(user.Email, email) && !user.Disabled {
+			matched = append(matched, user)
+		}
+	}
+	return matched, nil
+}
+
+func magicLinkURL(user User, now time.Time) string {
+	token := signAction(actionClaim{
+		Action:  magicLinkAction,
+		User:    user.Username,
+		Seq:     user.MagicSeq,
+		Expires: now.Add(magicLinkTTL).Unix(),
+	})
+	return publicBaseURL + "/login/magic?t=" + token
+}
+
+// sendMagicLink 寄出登入連結；冷卻中的帳號略過
+func (a *App) sendMagicLink(r *http.Request, user User, now time.Time) error {
+	if now.Sub(user.MagicSentAt) < magicLinkCooldown {
+		a.auth.audit(r, "magic-link-request", user.Username, "cooldown")
+		return nil
+	}
+	user, err := a.store.ModifyUser(user.Username, func(u *User) error {
+		u.MagicSentAt = now
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("%s 你好，\n\n請在 %d 分鐘內打開下面的連結登入待辦清單（只能使用一次）：\n\n%s\n\n"+
+		"如果你沒有要求登入，請忽略這封信，你的帳號不會有任何變化。\n",
+		user.Username, int(magicLinkTTL.Minutes()), magicLinkURL(user, now))
+	a.auth.audit(r, "magic-link-request", user.Username, "sent")
+	return mailer.Send(user.Email, "待辦清單登入連結", body)
+}
+
+// magicLinkRequestHandler：/login/email 輸入 Email 索取登入連結
+func (a *App) magicLinkRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if !magicLinkEnabled {
+		http.NotFound(w, r)
+		return
+	}
+	data := map[string]interface{}{"Request": true}
+	if r.Method == "POST" {
+		email := strings.TrimSpace(r.FormValue("email"))
+		if !strings.Contains(email, "@") {
+			data["Error"] = a.tr(r, "請輸入有效的 Email")
+			a.renderMagicLink(w, r, data)
+			return
+		}
+		users, err := a.usersByEmail(email)
+		now := time.Now()
+		for _, user := range users {
+			if err == nil {
+				err = a.sendMagicLink(r, user, now)
+			}
+		}
+		if err != nil {
+			log.Printf("寄送登入連結失敗：%v", err)
+		}
+		data["Notice"] = a.tr(r, "如果這個 Email 有註冊，登入連結已經寄出，請在 15 分鐘內打開信裡的連結")
+	}
+	a.renderMagicLink(w, r, data)
+}
+
+// magicLinkLoginHandler：/login/magic?t= GET 顯示確認按鈕，POST 驗證後登入
+func (a *App) magicLinkLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if !magicLinkEnabled {
+		http.NotFound(w, r)
+		return
+	}
+	token := r.FormValue("t")
+	claim, err := verifyAction(token, time.Now())
+	if err == nil && claim.Action != magicLinkAction {
+		err = errInvalidActionLink
+	}
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		a.renderMagicLink(w, r, map[string]interface{}{"Error": a.tr(r, "登入連結無效或已過期，請重新索取")})
+		return
+	}
+	if r.Method != "POST" {
+		a.renderMagicLink(w, r, map[string]interface{}{"Token": token, "Username": claim.User})
+		return
+	}
+
+	// 「檢查 Seq -> 加一」在 ModifyUser 裡做，兩個同時送出的請求只有一個會通過
+	_, err = a.store.ModifyUser(claim.User, func(u *User) error {
+		switch {
+		case u.Disabled:
+			return errInvalidActionLink
+		case u.MagicSeq != claim.Seq:
+			return errActionLinkUsed
+		}
+		u.MagicSeq++
+		return nil
+	})
+	if err == ErrNotFound {
+		err = errInvalidActionLink
+	}
+	if err != nil {
+		outcome := "invalid"
+		if err == errActionLinkUsed {
+			outcome = "used"
+		}
+		a.auth.audit(r, "magic-link", claim.User, outcome)
+		w.WriteHeader(errorStatus(err))
+		a.renderMagicLink(w, r, map[string]interface{}{"Error": a.tr(r, "這個登入連結已經用過或已失效，請重新索取")})
+		return
+	}
+	a.auth.audit(r, "magic-link", claim.User, "ok")
+	a.startSession(w, r, claim.User)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (a *App) renderMagicLink(w http.ResponseWriter, r *http.Request, data map[string]interface{}) {
+	t := a.localize(r, a.pages.page("magic-link"))
+	t.Execute(w, data)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// --- 寄信 ---
+
+// Mailer 寄出純文字信件
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// 沒有設定 -smtp-addr 時只把信件內容寫進 log，方便本機開發
+var mailer Mailer = logMailer{}
+
+type logMailer struct{}
+
+func (logMailer) Send(to, subject, body string) error {
+	log.Printf("（未設定 SMTP，信件未寄出）收件人：%s 主旨：%s\n%s", to, subject, body)
+	return nil
+}
+
+// smtpMailer 透過 SMTP 寄信；有帳號時使用 PLAIN 認證（net/smtp 只會在 TLS 連線上送出密碼）
+type smtpMailer struct {
+	addr     string // host:port
+	from     string
+	username string
+	password string
+}
+
+func (m smtpMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		host, _, err := net.SplitHostPort(m.addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.username, m.password, host)
+	}
+	return smtp.SendMail(m.addr, auth, m.from, []string{to}, buildMessage(m.from, to, subject, body))
+}
+
+// buildMessage 組出 UTF-8 的信件，主旨以 RFC 2047 編碼
+func buildMessage(from, to, subject, body string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(body)
+	return buf.Bytes()
+}
+
+// notifyAdmins 通知所有管理員：有 Email 的寄信，沒有的改用推播 msg
+func (a *App) notifyAdmins(subject, body string, msg pushMessage) {
+	users, err := a.store.ListUsers()
+	if err != nil {
+		log.Printf("通知管理員失敗：%v", err)
+		return
+	}
+	for _, u := range users {
+		if u.Role != RoleAdmin {
+			continue
+		}
+		if u.Email != "" {
+			if err := mailer.Send(u.Email, subject, body); err != nil {
+				log.Printf("寄送警示給 %s 失敗：%v", u.Username, err)
+			}
+			continue
+		}
+		a.pushToUser(u, msg)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// --- 貼上 Markdown 清單 ---
+//
+// /add/markdown 可以貼上 Markdown 的核取方塊清單一次新增多個任務，例如
+// "- [ ] 期末報告 ⏫ 📅 2024-06-01 #課業"。先按「預覽」檢查解析結果，確認後才真正新增。
+// 支援的寫法與 Obsidian 匯出（見 obsidian.go）相同：⏫／🔼／🔽 優先順序、🔁 重複、🛫 開始日、
+// 📅 到期日、✅ 完成日與 #標籤；縮排在任務底下的核取方塊成為子項目。
+// 沒有 📅 的任務用表單上指定的預設日期，描述與到期時間都相同的任務視為重複不再新增
+
+const maxMarkdownLength = 64 << 10
+
+var (
+	markdownCheckbox = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	markdownDate     = regexp.MustCompile(`(📅|🛫|✅|➕)\s*(\d{4}-\d{2}-\d{2})`)
+	markdownRecur    = regexp.MustCompile(`🔁\s*every\s+(day|week on Monday, Tuesday, Wednesday, Thursday, Friday|weekday|week|month)\b`)
+	markdownTag      = regexp.MustCompile(`(^|\s)#([^\s#]+)`)
+)
+
+// markdownTask 是預覽頁上的一個任務；Line 是原文的行號，Duplicate 表示清單裡已經有同樣的任務
+type markdownTask struct {
+	Task
+	Line      int
+	Duplicate bool
+}
+
+// markdownRecurrence 是 obsidianRecurrence 的反向對應
+func markdownRecurrence(rule string) string {
+	switch rule {
+	case "day":
+		return RecurDaily
+	case "week":
+		return RecurWeekly
+	case "month":
+		return RecurMonthly
+	case "weekday", "week on Monday, Tuesday, Wednesday, Thursday, Friday":
+		return RecurWeekdays
+	}
+	return RecurNone
+}
+
+// parseMarkdownLine 解析一行任務文字（不含開頭的 "- [ ] "），把符號轉成任務欄位
+func parseMarkdownLine(text string, task *Task, clock time.Time) error {
+	switch {
+	case strings.Contains(text, "⏫"):
+		task.Priority = PriorityHigh
+	case strings.Contains(text, "🔽"):
+		task.Priority = PriorityLow
+	}
+	text = strings.NewReplacer("⏫", "", "🔼", "", "🔽", "").Replace(text)
+
+	if m := markdownRecur.FindStringSubmatch(text); m != nil {
+		task.Recurrence = markdownRecurrence(m[1])
+		text = strings.Replace(text, m[0], "", 1)
+	}
+
+	for _, m := range markdownDate.FindAllStringSubmatch(text, -1) {
+		day, err := time.ParseInLocation("2006-01-02", m[2], time.Local)
+		if err != nil {
+			return invalidInput("日期「%s」不正確", m[2])
+		}
+		switch m[1] {
+		case "📅":
+			task.DueAt = onDay(clock, day)
+		case "🛫":
+			task.StartAt = day
+		case "✅":
+			task.CompletedAt = day
+		}
+	}
+	text = markdownDate.ReplaceAllString(text, "")
+
+	var tags []string
+	for _, m := range markdownTag.FindAllStringSubmatch(text, -1) {
+		tags = append(tags, m[2])
+	}
+	task.Tags = normalizeTags(tags)
+	text = markdownTag.ReplaceAllString(text, "$1")
+
+	task.Description = obsidianText(text)
+	return checkDescription(task.Description)
+}
+
+// parseMarkdownTasks 解析貼上的清單；不是核取方塊的行直接略過，有問題的行回報在 problems
+func parseMarkdownTasks(text, username string, defaultDue time.Time, now time.Time) ([]markdownTask, []error) {
+	var tasks []markdownTask
+	var problems []error
+	parentIndent := -1
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		m := markdownCheckbox.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+		done := m[2] != " "
+
+		if len(tasks) > 0 && parentIndent >= 0 && indent > parentIndent {
+			parent := &tasks[len(tasks)-1]
+			item := obsidianText(m[3])
+			if item == "" {
+				continue
+			}
+			if err := parent.addChecklistItem(item); err != nil {
+				problems = append(problems, invalidInput("第 %d 行：%s", i+1, displayError(err, "子項目太多")))
+				continue
+			}
+			parent.Checklist[len(parent.Checklist)-1].Done = done
+			continue
+		}
+
+		task := Task{CreatedAt: now, DueAt: defaultDue, Username: username, Priority: PriorityMedium}
+		if err := parseMarkdownLine(m[3], &task, defaultDue); err != nil {
+			problems = append(problems, invalidInput("第 %d 行：%s", i+1, displayError(err, "資料不正確")))
+			parentIndent = -1
+			continue
+		}
+		completedAt := task.CompletedAt
+		task.CompletedAt = time.Time{}
+		if done {
+			task.setStatus(StatusDone, now)
+			if !completedAt.IsZero() {
+				task.CompletedAt = completedAt
+			}
+		}
+		if len(tasks) == maxTaskImportSize {
+			problems = append(problems, invalidInput("一次最多新增 %d 個任務，第 %d 行以後沒有處理", maxTaskImportSize, i+1))
+			break
+		}
+		tasks = append(tasks, markdownTask{Task: task, Line: i + 1})
+		parentIndent = indent
+	}
+	return tasks, problems
+}
+
+// markDuplicates 標出清單裡已有的任務，以及貼上的內容裡重複出現的任務
+func (a *App) markDuplicates(tasks []markdownTask, username string) error {
+	existing, err := a.store.ListTasks(username)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		seen[dedupKey(t.Description, t.DueAt)] = true
+	}
+	for i := range tasks {
+		key := dedupKey(tasks[i].Description, tasks[i].DueAt)
+		tasks[i].Duplicate = seen[key]
+		seen[key] = true
+	}
+	return nil
+}
+
+// markdownDefaultDue 讀取表單的預設到期日與時間，沒有填時用今天 23:59
+func markdownDefaultDue(r *http.Request, now time.Time) (time.Time, error) {
+	day := r.FormValue("default_date")
+	if day == "" {
+		day = now.Format("2006-01-02")
+	}
+	clock := r.FormValue("default_time")
+	if clock == "" {
+		clock = "23:59"
+	}
+	due, err := time.ParseInLocation("2006-01-02 15:04", day+" "+clock, time.Local)
+	if err != nil {
+		return time.Time{}, ErrInvalidDueDate
+	}
+	return due, checkDueAt(due)
+}
+
+func (a *App) markdownImportHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	now := time.Now()
+	text := r.FormValue("markdown")
+	data := map[string]interface{}{
+		"Username":    username,
+		"Markdown":    text,
+		"DefaultDate": now.Format("2006-01-02"),
+		"DefaultTime": "23:59",
+	}
+
+	if r.Method == "POST" {
+		if v := r.FormValue("default_date"); v != "" {
+			data["DefaultDate"] = v
+		}
+		if v := r.FormValue("default_time"); v != "" {
+			data["DefaultTime"] = v
+		}
+		var tasks []markdownTask
+		var problems []error
+		var err error
+		if len(text) > maxMarkdownLength {
+			err = invalidInput("內容太長，一次最多貼上 %d KB", maxMarkdownLength>>10)
+		}
+		var defaultDue time.Time
+		if err == nil {
+			defaultDue, err = markdownDefaultDue(r, now)
+		}
+		if err == nil {
+			tasks, problems = parseMarkdownTasks(text, username, defaultDue, now)
+			if len(tasks) == 0 && len(problems) == 0 {
+				err = invalidInput("沒有找到任務，每一行要以 - [ ] 開頭")
+			}
+		}
+		if err == nil {
+			err = a.markDuplicates(tasks, username)
+		}
+		if err != nil {
+			a.flashError(r, err, "解析清單失敗，請稍後再試")
+		} else if r.FormValue("action") == "create" {
+			a.createPreviewedTasks(r, username, "Markdown", tasks, problems)
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		} else if len(tasks) == 0 {
+			a.flashDetails(r, FlashError, problems)
+		} else {
+			data["Preview"] = tasks
+			data["Problems"] = localMessages(a.requestLocale(r), problems)
+			fresh := 0
+			for _, t := range tasks {
+				if !t.Duplicate {
+					fresh++
+				}
+			}
+			data["NewCount"] = fresh
+		}
+	}
+
+	data["Nonce"] = newNonce(username)
+	data["CSRFToken"] = a.sessions.CSRFToken(r)
+	data["Flashes"] = a.sessions.PopFlashes(r)
+	data["MaxTasks"] = maxTaskImportSize
+	t := a.localize(r, a.pages.page("markdown-import"))
+	t.Execute(w, data)
+}
+
+// createPreviewedTasks 新增預覽過的任務，重複的略過，結果以 flash 訊息回報；
+// source 是訊息裡的來源，例如 Markdown（Todoist、Trello 見 thirdparty.go）
+func (a *App) createPreviewedTasks(r *http.Request, username, source string, tasks []markdownTask, problems []error) {
+	var fresh []Task
+	skipped := 0
+	for _, t := range tasks {
+		if t.Duplicate {
+			skipped++
+			continue
+		}
+		fresh = append(fresh, t.Task)
+	}
+	created, err := a.store.CreateTasks(fresh)
+	if err != nil {
+		a.flashError(r, err, "新增任務失敗，請稍後再試")
+		return
+	}
+	if skipped > 0 {
+		a.flashSuccess(r, "已從 %s 新增 %d 個任務，略過 %d 個重複任務", source, len(created), skipped)
+	} else {
+		a.flashSuccess(r, "已從 %s 新增 %d 個任務", source, len(created))
+	}
+	a.flashDetails(r, FlashError, problems)
+	for _, task := range created {
+		if a.warnConflicts(r, username, task) {
+			break
+		}
+	}
+}
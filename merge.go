@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// --- 合併帳號 ---
+//
+// 同一個人註冊了兩個帳號時，把 from 的任務（含垃圾桶）、留言與專案身分移到 into，再刪除 from。
+// 管理員可以在 /admin/users 合併任意兩個帳號；一般使用者在設定頁輸入另一個帳號的密碼，
+// 證明兩個都是自己的之後，把那個帳號併進目前登入的帳號。
+// 設定以保守為原則：into 已有的設定一律保留，只補上 into 沒設定的欄位，角色也不會因此提升。
+// 公告本身的建立者與收件人名單是歷史紀錄，不跟著改；發到個人的副本是任務，會一起移過去
+
+// mergeResult 是合併後搬移的數量，用來顯示結果與寫進稽核紀錄
+type mergeResult struct {
+	Tasks    int
+	Trashed  int
+	Projects int
+	Rosters  int
+}
+
+func (m mergeResult) String() string {
+	return m.In(LocaleZhTW)
+}
+
+func (m mergeResult) In(locale string) string {
+	return tr(locale, "任務 %d 個、垃圾桶 %d 個、專案 %d 個、學生名單 %d 份", m.Tasks, m.Trashed, m.Projects, m.Rosters)
+}
+
+// replaceMember 把名單中的 from 換成 into，into 已在名單上時只移除 from
+func replaceMember(list []string, from, into string) ([]string, bool) {
+	var result []string
+	changed, seen := false, false
+	for _, name := range list {
+		if name == from {
+			name, changed = into, true
+		}
+		if name == into {
+			if seen {
+				continue
+			}
+			seen = true
+		}
+		result = append(result, name)
+	}
+	return result, changed
+}
+
+// mergeSettings 把 from 的設定補到 into 沒設定的欄位，推播訂閱則兩邊都保留
+func mergeSettings(into *User, from User) {
+	if into.Email == "" {
+		into.Email = from.Email
+	}
+	if into.Locale == "" {
+		into.Locale, into.Clock12, into.ROCYear = from.Locale, from.Clock12, from.ROCYear
+	}
+	if into.ConflictHour == 0 && into.ConflictDay == 0 {
+		into.ConflictHour, into.ConflictDay = from.ConflictHour, from.ConflictDay
+	}
+	if !into.DigestEnabled && from.DigestEnabled {
+		into.DigestEnabled, into.DigestHour, into.DigestDays = true, from.DigestHour, from.DigestDays
+	}
+	for _, sub := range from.PushSubscriptions {
+		if len(into.PushSubscriptions) >= maxPushSubscriptions {
+			break
+		}
+		if !hasSubscription(into.PushSubscriptions, sub.Endpoint) {
+			into.PushSubscriptions = append(into.PushSubscriptions, sub)
+		}
+	}
+	// from 連結的第三方帳號改登入 into
+	into.OAuthIdentities = append(into.OAuthIdentities, from.OAuthIdentities...)
+	// from 的分享連結繼續有效，改由 into 管理
+	for _, link := range from.ShareLinks {
+		if len(into.ShareLinks) >= maxShareLinks {
+			break
+		}
+		into.ShareLinks = append(into.ShareLinks, link)
+	}
+	for _, student := range from.Roster {
+		if student != into.Username && !containsString(into.Roster, student) {
+			into.Roster = append(into.Roster, student)
+		}
+	}
+}
+
+func hasSubscription(list []PushSubscription, endpoint string) bool {
+	for _, sub := range list {
+		if sub.Endpoint == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeAccounts 把 from 併進 into 並刪除 from；actor 是執行合併的人，寫進稽核紀錄
+func (a *App) mergeAccounts(from, into, actor string) (mergeResult, error) {
+	var result mergeResult
+	if from == "" || into == "" || from == into {
+		return result, invalidInput("請選擇兩個不同的帳號")
+	}
+	fromUser, err := a.store.GetUser(from)
+	if err != nil {
+		return result, err
+	}
+	if _, err := a.store.GetUser(into); err != nil {
+		return result, err
+	}
+
+	// 先搬任務：即使後面的步驟失敗，任務也已經在 into 名下，不會跟著帳號一起消失
+	all, err := a.store.AllTasks()
+	if err != nil {
+		return result, err
+	}
+	var ids []int
+	for _, t := range all {
+		if t.Username == from || t.CreatedBy == from || t.isSharedWith(from) || t.hasActivityBy(from) {
+			ids = append(ids, t.ID)
+		}
+	}
+	if len(ids) > 0 {
+		moved, err := a.store.ModifyTasks(ids, func(t *Task) error {
+			if t.Username == from {
+				t.Username = into
+			}
+			if t.CreatedBy == from {
+				t.CreatedBy = into
+			}
+			t.SharedWith, _ = replaceMember(t.SharedWith, from, into)
+			t.SharedWith = removeString(t.SharedWith, t.Username)
+			for i := range t.Activity {
+				if t.Activity[i].User == from {
+					t.Activity[i].User = into
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Tasks = len(moved)
+	}
+
+	// 垃圾桶裡的任務對 ModifyTask 不可見，先還原、改名再放回去，保留原本的刪除時間
+	trash, err := a.store.ListTrash(from)
+	if err != nil {
+		return result, err
+	}
+	for _, t := range trash {
+		deletedAt := t.DeletedAt
+		if _, err := a.store.RestoreTask(t.ID); err != nil {
+			return result, err
+		}
+		if _, err := a.store.ModifyTask(t.ID, func(t *Task) error {
+			t.Username = into
+			t.DeletedAt = deletedAt
+			return nil
+		}); err != nil {
+			return result, err
+		}
+		result.Trashed++
+	}
+
+	projects, err := a.store.ListProjects(from)
+	if err != nil {
+		return result, err
+	}
+	for _, p := range projects {
+		_, err := a.store.ModifyProject(p.ID, func(p *Project) error {
+			if p.Owner == from {
+				p.Owner = into
+			}
+			p.Members, _ = replaceMember(p.Members, from, into)
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+		a.notifyProject(p.ID, "%s 的帳號已合併到 %s", from, into)
+		result.Projects++
+	}
+
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return result, err
+	}
+	for _, u := range users {
+		if u.Username == from || u.Username == into {
+			continue
+		}
+		if _, changed := replaceMember(u.Roster, from, into); changed {
+			_, err := a.store.ModifyUser(u.Username, func(t *User) error {
+				t.Roster, _ = replaceMember(t.Roster, from, into)
+				return nil
+			})
+			if err != nil {
+				return result, err
+			}
+			result.Rosters++
+		}
+	}
+
+	_, err = a.store.ModifyUser(into, func(u *User) error {
+		mergeSettings(u, fromUser)
+		u.Roster, _ = replaceMember(u.Roster, from, into)
+		u.Roster = removeString(u.Roster, into) // 老師不會在自己的學生名單上
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	if err := a.store.DeleteUser(from); err != nil {
+		return result, err
+	}
+	a.sessions.EndUser(from)
+
+	recordAudit(actor, "merge-account", fmt.Sprintf("%s → %s：%s", from, into, result))
+	return result, nil
+}
+
+func removeString(list []string, s string) []string {
+	var result []string
+	for _, v := range list {
+		if v != s {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// adminMergeUsers 是 /admin/users 的 action=merge
+func (a *App) adminMergeUsers(r *http.Request, admin string) {
+	from := strings.TrimSpace(r.FormValue("from"))
+	into := strings.TrimSpace(r.FormValue("into"))
+	if from == admin {
+		a.flashError(r, invalidInput("不能把自己目前登入的帳號併到別的帳號"), "")
+		return
+	}
+	result, err := a.mergeAccounts(from, into, admin)
+	if err != nil {
+		a.flashError(r, err, "合併帳號失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "已將 %s 合併到 %s（%s）", from, into, result)
+}
+
+// mergeOwnAccount 是設定頁的 action=merge：輸入另一個帳號的密碼，把它併進目前的帳號
+func (a *App) mergeOwnAccount(r *http.Request, username string) {
+	other := strings.TrimSpace(r.FormValue("other"))
+	if other == "" || other == username {
+		a.flashError(r, invalidInput("請輸入另一個帳號的使用者名稱"), "")
+		return
+	}
+	if _, err := a.auth.Verify(r, "merge", other, r.FormValue("password")); err != nil {
+		a.flashError(r, err, "驗證失敗，請稍後再試")
+		return
+	}
+	if a.isAdmin(other) && !a.isAdmin(username) {
+		a.flashError(r, invalidInput("管理員帳號只能由管理員合併"), "")
+		return
+	}
+	result, err := a.mergeAccounts(other, username, username)
+	if err != nil {
+		a.flashError(r, err, "合併帳號失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "已將 %s 併入目前的帳號（%s）", other, result)
+}
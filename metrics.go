@@ -0,0 +1,349 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- 監控指標與健康檢查 ---
+//
+// /metrics 以 Prometheus 文字格式輸出各路由的請求數與處理時間、登入中的 session 數、使用者與任務數，
+// 以及儲存層寫入失敗的次數；/healthz 確認儲存層讀得到資料，給反向代理或負載平衡器判斷能不能送流量過來。
+// 路由以 mux 的 pattern 當標籤（例如 /task/、/api/v1/tasks/），不會因為網址裡的 ID 而無限增加。
+// 兩者都不需要登入，也不含任何使用者的資料，這兩個路徑也不寫進請求紀錄。
+// /metrics 每次抓取都要數過所有使用者與任務，所以不對外開放：設定環境變數 METRICS_TOKEN 時
+// 要帶 Authorization: Bearer <token>，沒有設定時只接受本機直接連進來（沒有經過反向代理）的請求
+
+// latencyBuckets 是處理時間直方圖的上界（秒）
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	route  string
+	method string
+	status int
+}
+
+type routeLatency struct {
+	buckets []uint64 // 與 latencyBuckets 對應，各自只算落在該區間的次數，輸出時再累加
+	count   uint64
+	sum     float64
+}
+
+type httpMetrics struct {
+	mu       sync.Mutex
+	requests map[requestKey]uint64
+	latency  map[string]*routeLatency // key: route
+}
+
+var metrics = &httpMetrics{
+	requests: make(map[requestKey]uint64),
+	latency:  make(map[string]*routeLatency),
+}
+
+// persistErrors 是儲存層寫入失敗的次數：同步寫入時由 persistMetricsStore 計算，延後寫入時由 flusher 計算
+var persistErrors atomic.Int64
+
+var processStart = time.Now()
+
+func (m *httpMetrics) observe(route, method string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{route, method, status}]++
+	l, ok := m.latency[route]
+	if !ok {
+		l = &routeLatency{buckets: make([]uint64, len(latencyBuckets))}
+		m.latency[route] = l
+	}
+	secs := d.Seconds()
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			l.buckets[i]++
+			break
+		}
+	}
+	l.count++
+	l.sum += secs
+}
+
+// metricMethod 只保留常見的方法，避免奇怪的方法名稱讓標籤越來越多
+func metricMethod(method string) string {
+	switch method {
+	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS":
+		return method
+	}
+	return "OTHER"
+}
+
+// withMetrics 記錄每個請求的路由、方法、狀態碼與處理時間
+func withMetrics(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.observe(route, metricMethod(r.Method), rec.status, time.Since(start))
+	})
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// write 依路由排序輸出，每次抓取的順序都一樣
+func (m *httpMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	writeMetricHeader(w, "todo_http_requests_total", "counter", "依路由、方法與狀態碼統計的請求數")
+	for _, k := range keys {
+		fmt.Fprintf(w, "todo_http_requests_total{route=\"%s\",method=\"%s\",status=\"%d\"} %d\n",
+			labelEscaper.Replace(k.route), k.method, k.status, m.requests[k])
+	}
+
+	routes := make([]string, 0, len(m.latency))
+	for route := range m.latency {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	writeMetricHeader(w, "todo_http_request_duration_seconds", "histogram", "各路由的處理時間（秒）")
+	for _, route := range routes {
+		l, label := m.latency[route], labelEscaper.Replace(route)
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += l.buckets[i]
+			fmt.Fprintf(w, "todo_http_request_duration_seconds_bucket{route=\"%s\",le=\"%s\"} %d\n", label, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(w, "todo_http_request_duration_seconds_bucket{route=\"%s\",le=\"+Inf\"} %d\n", label, l.count)
+		fmt.Fprintf(w, "todo_http_request_duration_seconds_sum{route=\"%s\"} %s\n", label, formatFloat(l.sum))
+		fmt.Fprintf(w, "todo_http_request_duration_seconds_count{route=\"%s\"} %d\n", label, l.count)
+	}
+}
+
+// metricsHandler：GET /metrics，Prometheus 文字格式
+func (a *App) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	if !a.metricsAllowed(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "需要 METRICS_TOKEN", http.StatusUnauthorized)
+		return
+	}
+	users, err := a.store.ListUsers()
+	if err != nil {
+		http.Error(w, "讀取使用者失敗", http.StatusInternalServerError)
+		return
+	}
+	tasks, err := a.store.AllTasks()
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	open := 0
+	for _, t := range tasks {
+		if !t.Completed {
+			open++
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	metrics.write(w)
+	writeMetricHeader(w, "todo_active_sessions", "gauge", "登入中（尚未過期）的 session 數")
+	fmt.Fprintf(w, "todo_active_sessions %d\n", a.sessions.Active(time.Now()))
+	writeMetricHeader(w, "todo_users", "gauge", "使用者數")
+	fmt.Fprintf(w, "todo_users %d\n", len(users))
+	writeMetricHeader(w, "todo_tasks", "gauge", "任務數（不含垃圾桶），依是否完成分開")
+	fmt.Fprintf(w, "todo_tasks{state=\"open\"} %d\ntodo_tasks{state=\"completed\"} %d\n", open, len(tasks)-open)
+	writeMetricHeader(w, "todo_persistence_errors_total", "counter", "儲存層寫入失敗的次數")
+	fmt.Fprintf(w, "todo_persistence_errors_total %d\n", persistErrors.Load())
+	writeMetricHeader(w, "todo_process_start_time_seconds", "gauge", "伺服器啟動時間（Unix 秒）")
+	fmt.Fprintf(w, "todo_process_start_time_seconds %d\n", processStart.Unix())
+}
+
+// metricsAllowed 檢查抓取 /metrics 的請求：有設定 metricsToken 時比對 Bearer token，
+// 否則只接受來自 loopback、沒有 X-Forwarded-For 或 Forwarded 的請求，同一台機器上的反向代理轉來的也不算
+func (a *App) metricsAllowed(r *http.Request) bool {
+	if a.metricsToken != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return ok && subtle.ConstantTimeCompare([]byte(token), []byte(a.metricsToken)) == 1
+	}
+	if r.Header.Get("X-Forwarded-For") != "" || r.Header.Get("Forwarded") != "" {
+		return false
+	}
+	ip := net.ParseIP(remoteHost(r))
+	return ip != nil && ip.IsLoopback()
+}
+
+// healthzHandler：GET /healthz，儲存層讀得到資料時回 200，否則 503
+func (a *App) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := a.store.ListUsers(); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"status": "error", "error": "儲存層無法讀取"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":         "ok",
+		"uptime_seconds": int64(time.Since(processStart).Seconds()),
+	})
+}
+
+// persistMetricsStore 包住實際的儲存層，寫入時發生的內部錯誤（不是 DomainError）計入 persistErrors
+type persistMetricsStore struct {
+	Store
+}
+
+func withPersistMetrics(s Store) Store {
+	return &persistMetricsStore{Store: s}
+}
+
+// count 只計算內部錯誤：找不到資料、帳號重複或 ModifyTask 的 fn 回傳的驗證錯誤都不是寫入失敗
+func (s *persistMetricsStore) count(err error) {
+	if err != nil && errorStatus(err) == http.StatusInternalServerError {
+		persistErrors.Add(1)
+	}
+}
+
+func (s *persistMetricsStore) CreateUser(user User) error {
+	err := s.Store.CreateUser(user)
+	s.count(err)
+	return err
+}
+
+func (s *persistMetricsStore) RegisterUser(user User) (User, error) {
+	user, err := s.Store.RegisterUser(user)
+	s.count(err)
+	return user, err
+}
+
+func (s *persistMetricsStore) ModifyUser(username string, fn func(*User) error) (User, error) {
+	user, err := s.Store.ModifyUser(username, fn)
+	s.count(err)
+	return user, err
+}
+
+func (s *persistMetricsStore) UpdateUser(user User) error {
+	err := s.Store.UpdateUser(user)
+	s.count(err)
+	return err
+}
+
+func (s *persistMetricsStore) DeleteUser(username string) error {
+	err := s.Store.DeleteUser(username)
+	s.count(err)
+	return err
+}
+
+func (s *persistMetricsStore) CreateTask(task Task) (Task, error) {
+	task, err := s.Store.CreateTask(task)
+	s.count(err)
+	return task, err
+}
+
+func (s *persistMetricsStore) CreateTasks(tasks []Task) ([]Task, error) {
+	tasks, err := s.Store.CreateTasks(tasks)
+	s.count(err)
+	return tasks, err
+}
+
+func (s *persistMetricsStore) UpdateTask(task Task) error {
+	err := s.Store.UpdateTask(task)
+	s.count(err)
+	return err
+}
+
+func (s *persistMetricsStore) ModifyTask(id int, fn func(*Task) error) (Task, error) {
+	task, err := s.Store.ModifyTask(id, fn)
+	s.count(err)
+	return task, err
+}
+
+func (s *persistMetricsStore) ModifyTasks(ids []int, fn func(*Task) error) ([]Task, error) {
+	tasks, err := s.Store.ModifyTasks(ids, fn)
+	s.count(err)
+	return tasks, err
+}
+
+func (s *persistMetricsStore) DeleteTask(id int) error {
+	err := s.Store.DeleteTask(id)
+	s.count(err)
+	return err
+}
+
+func (s *persistMetricsStore) RestoreTask(id int) (Task, error) {
+	task, err := s.Store.RestoreTask(id)
+	s.count(err)
+	return task, err
+}
+
+func (s *persistMetricsStore) PurgeTrash(before time.Time) (int, error) {
+	n, err := s.Store.PurgeTrash(before)
+	s.count(err)
+	return n, err
+}
+
+func (s *persistMetricsStore) CreateAnnouncement(a Announcement) (Announcement, error) {
+	a, err := s.Store.CreateAnnouncement(a)
+	s.count(err)
+	return a, err
+}
+
+func (s *persistMetricsStore) CreateProject(p Project) (Project, error) {
+	p, err := s.Store.CreateProject(p)
+	s.count(err)
+	return p, err
+}
+
+func (s *persistMetricsStore) ModifyProject(id int, fn func(*Project) error) (Project, error) {
+	p, err := s.Store.ModifyProject(id, fn)
+	s.count(err)
+	return p, err
+}
+
+func (s *persistMetricsStore) SaveSession(sess Session) error {
+	err := s.Store.SaveSession(sess)
+	s.count(err)
+	return err
+}
+
+func (s *persistMetricsStore) DeleteSession(id string) error {
+	err := s.Store.DeleteSession(id)
+	s.count(err)
+	return err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// --- 表單防重送 ---
+//
+// 每次渲染含表單的頁面時發一個 nonce（同一頁的表單共用），POST 時消耗掉；
+// 重新整理或連點送出時 nonce 已用過，請求會被當成重送直接導回，不會重複新增任務。
+// 用過的 nonce 另外記到原本的期限為止，才能和過期、不認得的 nonce 分開：
+// 後者是頁面開太久或伺服器重新啟動過，要提示使用者重新送出，而不是當作成功
+
+const nonceTTL = 2 * time.Hour
+
+var ErrFormExpired = &DomainError{Code: "form_expired", Message: "表單已過期，請重新送出", Status: http.StatusBadRequest}
+
+type nonceEntry struct {
+	username string
+	expires  time.Time
+}
+
+var (
+	formNonces   = make(map[string]nonceEntry)
+	usedNonces   = make(map[string]nonceEntry) // 已消耗的 nonce，保留到原本的期限
+	formNoncesMu sync.Mutex
+)
+
+type nonceResult int
+
+const (
+	nonceValid   nonceResult = iota
+	nonceReused              // 已經用過：重新整理或連點送出
+	nonceInvalid             // 過期、不認得或不屬於這位使用者
+)
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// newNonce 發給 username 一個一次性的表單 nonce
+func newNonce(username string) string {
+	nonce := randomToken(16)
+	now := time.Now()
+
+	formNoncesMu.Lock()
+	defer formNoncesMu.Unlock()
+	for _, m := range []map[string]nonceEntry{formNonces, usedNonces} {
+		for k, e := range m {
+			if now.After(e.expires) {
+				delete(m, k)
+			}
+		}
+	}
+	formNonces[nonce] = nonceEntry{username: username, expires: now.Add(nonceTTL)}
+	return nonce
+}
+
+// consumeNonce 驗證並作廢 nonce；只有同一位使用者、尚未過期且未用過時回傳 nonceValid
+func consumeNonce(nonce, username string) nonceResult {
+	formNoncesMu.Lock()
+	defer formNoncesMu.Unlock()
+
+	now := time.Now()
+	if e, ok := usedNonces[nonce]; ok && e.username == username && now.Before(e.expires) {
+		return nonceReused
+	}
+	e, ok := formNonces[nonce]
+	if !ok {
+		return nonceInvalid
+	}
+	delete(formNonces, nonce)
+	if e.username != username || !now.Before(e.expires) {
+		return nonceInvalid
+	}
+	usedNonces[nonce] = e
+	return nonceValid
+}
+
+// preventDoubleSubmit 讓 POST 必須帶有效的 nonce；重送的請求照常 303 導回，
+// 對使用者來說就像成功送出一次。nonce 過期或不認得時顯示「表單已過期」再導回
+func (a *App) preventDoubleSubmit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			switch consumeNonce(r.FormValue("nonce"), a.getUsername(r)) {
+			case nonceReused:
+				redirectBack(w, r)
+				return
+			case nonceInvalid:
+				a.flashError(r, ErrFormExpired, "")
+				redirectBack(w, r)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
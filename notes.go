@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// --- 端對端加密筆記 ---
+//
+// 筆記在瀏覽器內用密語推導出的金鑰加密（PBKDF2-SHA256 → AES-256-GCM），
+// 伺服器與 API 只經手密文，格式為 v1.<salt>.<iv>.<ciphertext>（base64url，無補齊）。
+// 這裡只檢查格式，避免明文被誤存；密語不經過伺服器，忘記就無法解密
+
+const (
+	noteVersion       = "v1"
+	noteSaltBytes     = 16
+	noteIVBytes       = 12
+	noteTagBytes      = 16 // GCM 驗證標籤，密文至少這麼長
+	maxNoteCipherSize = 16 << 10
+)
+
+var ErrInvalidNote = &DomainError{Code: "invalid_note", Message: "加密筆記格式錯誤，伺服器只接受瀏覽器加密後的內容", Status: http.StatusBadRequest}
+
+// validEncryptedNote 檢查筆記是否為合法的密文；空字串代表沒有筆記
+func validEncryptedNote(note string) bool {
+	if note == "" {
+		return true
+	}
+	parts := strings.Split(note, ".")
+	if len(parts) != 4 || parts[0] != noteVersion {
+		return false
+	}
+	sizes := []struct{ min, max int }{
+		{noteSaltBytes, noteSaltBytes},
+		{noteIVBytes, noteIVBytes},
+		{noteTagBytes, maxNoteCipherSize},
+	}
+	for i, part := range parts[1:] {
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil || len(b) < sizes[i].min || len(b) > sizes[i].max {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 第三方登入（Google／GitHub） ---
+//
+// 架站者用 -google-client-id、-github-client-id 與環境變數 GOOGLE_CLIENT_SECRET、GITHUB_CLIENT_SECRET
+// 啟用需要的登入方式，兩者都設定才會出現在登入頁；回呼網址是 -base-url 加上 /oauth/{provider}/callback。
+// /oauth/{provider}/start 產生 state 存進短效 cookie 後導向第三方，callback 比對 state、以授權碼換
+// access token、讀取帳號資料。已連結的身分直接登入；已登入時從設定頁發起的是連結到目前帳號；
+// 都不是時建立一個沒有密碼的新帳號（可以之後在設定頁設定密碼）。Email 只採用第三方驗證過的
+
+const (
+	oauthStateCookie = "oauth_state"
+	oauthStateTTL    = 10 * time.Minute
+	maxOAuthBody     = 1 << 20
+)
+
+// OAuthIdentity 是連結到帳號的第三方身分；Subject 是第三方的使用者編號，Login 是顯示用的 Email 或帳號
+type OAuthIdentity struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	Login    string    `json:"login,omitempty"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// oauthProfile 是從第三方讀到的帳號資料；Email 為空表示沒有驗證過的 Email
+type oauthProfile struct {
+	Subject string
+	Login   string
+	Email   string
+}
+
+type oauthProvider struct {
+	Name         string
+	Label        string
+	AuthURL      string
+	TokenURL     string
+	Scope        string
+	ClientID     string
+	ClientSecret string
+	profile      func(ctx context.Context, token string) (oauthProfile, error)
+}
+
+// oauthProviders 是已啟用的登入方式，依登入頁顯示的順序排列
+var oauthProviders []*oauthProvider
+
+var oauthClient = &http.Client{Timeout: 8 * time.Second}
+
+// configureOAuth 依命令列參數與環境變數啟用登入方式，client secret 只從環境變數讀取
+func configureOAuth(googleClientID, githubClientID string) {
+	if secret := os.Getenv("GOOGLE_CLIENT_SECRET"); googleClientID != "" && secret != "" {
+		oauthProviders = append(oauthProviders, &oauthProvider{
+			Name: "google", Label: "Google",
+			AuthURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL: "https://oauth2.googleapis.com/token",
+			Scope:    "openid email",
+			ClientID: googleClientID, ClientSecret: secret,
+			profile: googleProfile,
+		})
+	} else if googleClientID != "" {
+		log.Printf("已設定 -google-client-id 但沒有 GOOGLE_CLIENT_SECRET，不啟用 Google 登入")
+	}
+	if secret := os.Getenv("GITHUB_CLIENT_SECRET"); githubClientID != "" && secret != "" {
+		oauthProviders = append(oauthProviders, &oauthProvider{
+			Name: "github", Label: "GitHub",
+			AuthURL:  "https://github.com/login/oauth/authorize",
+			TokenURL: "https://github.com/login/oauth/access_token",
+			Scope:    "read:user user:email",
+			ClientID: githubClientID, ClientSecret: secret,
+			profile: githubProfile,
+		})
+	} else if githubClientID != "" {
+		log.Printf("已設定 -github-client-id 但沒有 GITHUB_CLIENT_SECRET，不啟用 GitHub 登入")
+	}
+}
+
+func findOAuthProvider(name string) *oauthProvider {
+	for _, p := range oauthProviders {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func oauthProviderLabel(name string) string {
+	if p := findOAuthProvider(name); p != nil {
+		return p.Label
+	}
+	return name
+}
+
+func (p *oauthProvider) redirectURI() string {
+	return publicBaseURL + "/oauth/" + p.Name + "/callback"
+}
+
+// oauthGetJSON 以 access token 讀取第三方的 API
+func oauthGetJSON(ctx context.Context, endpoint, token string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := oauthClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 回應 %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxOAuthBody)).Decode(v)
+}
+
+// exchange 以授權碼換 access token
+func (p *oauthProvider) exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURI()},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := oauthClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOAuthBody)).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.AccessToken == "" {
+		return "", fmt.Errorf("%s 換發 token 失敗（%s %s）", p.Label, resp.Status, body.Error)
+	}
+	return body.AccessToken, nil
+}
+
+func googleProfile(ctx context.Context, token string) (oauthProfile, error) {
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := oauthGetJSON(ctx, "https://openidconnect.googleapis.com/v1/userinfo", token, &info); err != nil {
+		return oauthProfile{}, err
+	}
+	if info.Sub == "" {
+		return oauthProfile{}, fmt.Errorf("Google 沒有回傳使用者編號")
+	}
+	p := oauthProfile{Subject: info.Sub, Login: info.Email}
+	if info.EmailVerified {
+		p.Email = info.Email
+	}
+	return p, nil
+}
+
+func githubProfile(ctx context.Context, token string) (oauthProfile, error) {
+	var info struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := oauthGetJSON(ctx, "https://api.github.com/user", token, &info); err != nil {
+		return oauthProfile{}, err
+	}
+	if info.ID == 0 {
+		return oauthProfile{}, fmt.Errorf("GitHub 沒有回傳使用者編號")
+	}
+	p := oauthProfile{Subject: strconv.FormatInt(info.ID, 10), Login: info.Login}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := oauthGetJSON(ctx, "https://api.github.com/user/emails", token, &emails); err == nil {
+		for _, e := range emails {
+			if e.Primary && e.Verified {
+				p.Email = e.Email
+			}
+		}
+	}
+	return p, nil
+}
+
+// findOAuthUser 找出連結了這個第三方身分的帳號
+func (a *App) findOAuthUser(provider, subject string) (User, bool, error) {
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return User{}, false, err
+	}
+	for _, u := range users {
+		for _, id := range u.OAuthIdentities {
+			if id.Provider == provider && id.Subject == subject {
+				return u, true, nil
+			}
+		}
+	}
+	return User{}, false, nil
+}
+
+// oauthUsername 從第三方帳號推出一個還沒被用掉的使用者名稱
+func (a *App) oauthUsername(profile oauthProfile) (string, error) {
+	base := profile.Login
+	if i := strings.Index(base, "@"); i >= 0 {
+		base = base[:i]
+	}
+	base = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return -1
+	}, base)
+	if len(base) > 32 {
+		base = base[:32]
+	}
+	if base == "" {
+		base = "user"
+	}
+	for i := 1; i <= 100; i++ {
+		name := base
+		if i > 1 {
+			name = base + "-" + strconv.Itoa(i)
+		}
+		if _, err := a.store.GetUser(name); err == ErrNotFound {
+			return name, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return base + "-" + randomToken(4), nil
+}
+
+// createOAuthUser 以第三方身分建立沒有密碼的新帳號；和註冊一樣，第一位使用者是管理員
+func (a *App) createOAuthUser(provider string, profile oauthProfile, now time.Time) (User, error) {
+	username, err := a.oauthUsername(profile)
+	if err != nil {
+		return User{}, err
+	}
+	user := User{
+		Username:        username,
+		Email:           profile.Email,
+		CreatedAt:       now,
+		OAuthIdentities: []OAuthIdentity{{Provider: provider, Subject: profile.Subject, Login: profile.Login, LinkedAt: now}},
+	}
+	return a.store.RegisterUser(user)
+}
+
+func (a *App) setOAuthState(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		Path:     "/oauth/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   a.sessions.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// oauthHandler 處理 /oauth/{provider}/start 與 /oauth/{provider}/callback
+func (a *App) oauthHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/oauth/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	p := findOAuthProvider(parts[0])
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch parts[1] {
+	case "start":
+		a.oauthStart(w, r, p)
+	case "callback":
+		a.oauthCallback(w, r, p)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// oauthStart 把 state、模式（login 或 link）與登入後要去的頁面存進 cookie，再導向第三方
+func (a *App) oauthStart(w http.ResponseWriter, r *http.Request, p *oauthProvider) {
+	mode := "login"
+	if r.URL.Query().Get("link") != "" && a.getUsername(r) != "" {
+		mode = "link"
+	}
+	state := randomToken(24)
+	next := safeRedirectPath(r, r.URL.Query().Get("next"))
+	a.setOAuthState(w, state+"|"+mode+"|"+url.QueryEscape(next), int(oauthStateTTL/time.Second))
+
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.ClientID},
+		"redirect_uri":  {p.redirectURI()},
+		"scope":         {p.Scope},
+		"state":         {state},
+	}
+	http.Redirect(w, r, p.AuthURL+"?"+q.Encode(), http.StatusSeeOther)
+}
+
+func (a *App) oauthCallback(w http.ResponseWriter, r *http.Request, p *oauthProvider) {
+	cookie, err := r.Cookie(oauthStateCookie)
+	a.setOAuthState(w, "", -1)
+	var state, mode, next string
+	if err == nil {
+		fields := strings.SplitN(cookie.Value, "|", 3)
+		if len(fields) == 3 {
+			state, mode = fields[0], fields[1]
+			next, _ = url.QueryUnescape(fields[2])
+		}
+	}
+	got := r.URL.Query().Get("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(got), []byte(state)) != 1 {
+		a.renderLogin(w, r, map[string]interface{}{"Error": a.tr(r, "%s 登入逾時或連結無效，請重新再試一次", p.Label)})
+		return
+	}
+	if r.URL.Query().Get("error") != "" {
+		a.renderLogin(w, r, map[string]interface{}{"Error": a.tr(r, "已取消 %s 登入", p.Label)})
+		return
+	}
+
+	token, err := p.exchange(r.Context(), r.URL.Query().Get("code"))
+	var profile oauthProfile
+	if err == nil {
+		profile, err = p.profile(r.Context(), token)
+	}
+	if err != nil {
+		log.Printf("%s 登入失敗：%v", p.Label, err)
+		a.renderLogin(w, r, map[string]interface{}{"Error": a.tr(r, "無法向 %s 確認身分，請稍後再試", p.Label)})
+		return
+	}
+
+	now := time.Now()
+	owner, found, err := a.findOAuthUser(p.Name, profile.Subject)
+	if err != nil {
+		a.renderLogin(w, r, map[string]interface{}{"Error": a.tr(r, "登入失敗，請稍後再試")})
+		return
+	}
+
+	if username := a.getUsername(r); mode == "link" && username != "" {
+		switch {
+		case found && owner.Username == username:
+			a.flashSuccess(r, "這個 %s 帳號已經連結過了", p.Label)
+		case found:
+			a.flashError(r, invalidInput("這個 %s 帳號已經連結到其他使用者", p.Label), "")
+		default:
+			_, err := a.store.ModifyUser(username, func(u *User) error {
+				u.OAuthIdentities = append(u.OAuthIdentities, OAuthIdentity{Provider: p.Name, Subject: profile.Subject, Login: profile.Login, LinkedAt: now})
+				return nil
+			})
+			if err != nil {
+				a.flashError(r, err, "連結失敗，請稍後再試")
+			} else {
+				recordAudit(username, "oauth-link", p.Name+" "+profile.Login)
+				a.flashSuccess(r, "已連結 %s 帳號 %s，之後可以用它登入", p.Label, profile.Login)
+			}
+		}
+		http.Redirect(w, r, "/settings#oauth", http.StatusSeeOther)
+		return
+	}
+
+	if !found {
+		owner, err = a.createOAuthUser(p.Name, profile, now)
+		if err != nil {
+			a.renderLogin(w, r, map[string]interface{}{"Error": a.tr(r, "建立帳號失敗，請稍後再試")})
+			return
+		}
+		recordAudit(owner.Username, "oauth-register", p.Name+" "+profile.Login)
+	}
+	if owner.Disabled {
+		recordAudit(owner.Username, "auth-oauth", "disabled")
+		a.renderLogin(w, r, map[string]interface{}{"Error": a.tr(r, "這個帳號已停用，請聯絡管理員")})
+		return
+	}
+	recordAudit(owner.Username, "auth-oauth", "ok via "+p.Name)
+	a.startSession(w, r, owner.Username)
+	if !found {
+		next = "/settings#oauth"
+	}
+	http.Redirect(w, r, safeRedirectPath(r, next), http.StatusSeeOther)
+}
+
+// unlinkOAuth 是設定頁的 action=oauth-unlink；沒有密碼時至少要留下一個登入方式
+func (a *App) unlinkOAuth(r *http.Request, username string) {
+	provider, subject := r.FormValue("provider"), r.FormValue("subject")
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		var kept []OAuthIdentity
+		for _, id := range u.OAuthIdentities {
+			if id.Provider != provider || id.Subject != subject {
+				kept = append(kept, id)
+			}
+		}
+		switch {
+		case len(kept) == len(u.OAuthIdentities):
+			return ErrNotFound
+		case len(kept) == 0 && u.PasswordHash == "":
+			return invalidInput("這是目前唯一的登入方式，請先設定密碼再取消連結")
+		}
+		u.OAuthIdentities = kept
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "取消連結失敗，請稍後再試")
+		return
+	}
+	recordAudit(username, "oauth-unlink", provider)
+	a.flashSuccess(r, "已取消連結 %s 帳號", oauthProviderLabel(provider))
+}
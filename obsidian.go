@@ -0,0 +1,194 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 匯出成 Obsidian 筆記庫 ---
+//
+// /export?format=obsidian 下載一個 zip，解壓後可以直接放進 Obsidian 的筆記庫：
+// 每個專案一個 Markdown 檔（個人任務放在「我的任務.md」），任務寫成 Obsidian Tasks 外掛的格式，
+// 例如 "- [ ] 期末報告 ⏫ 🔁 every week 📅 2024-06-01 #課業"，子項目縮排在任務底下。
+// Obsidian Tasks 只有日期，到期的時間另外寫在每個檔案的 YAML front matter
+
+const obsidianPersonalFile = "我的任務"
+
+// obsidianPriority 對應 Obsidian Tasks 的優先順序符號；medium 是預設值，不加符號
+func obsidianPriority(priority string) string {
+	switch effectivePriority(priority) {
+	case PriorityHigh:
+		return "⏫"
+	case PriorityLow:
+		return "🔽"
+	}
+	return ""
+}
+
+func obsidianRecurrence(rule string) string {
+	switch rule {
+	case RecurDaily:
+		return "every day"
+	case RecurWeekly:
+		return "every week"
+	case RecurMonthly:
+		return "every month"
+	case RecurWeekdays:
+		return "every week on Monday, Tuesday, Wednesday, Thursday, Friday"
+	}
+	return ""
+}
+
+// obsidianText 把換行換成空白；一行就是一個任務，描述裡的換行會拆壞清單
+func obsidianText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// obsidianFileName 去掉檔名不能用的字元，空白時改用專案編號
+func obsidianFileName(name string, id int) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		name = "專案 " + strconv.Itoa(id)
+	}
+	return name
+}
+
+// obsidianTaskLine 產生一個任務（含子項目）的 Markdown
+func obsidianTaskLine(b *strings.Builder, t Task) {
+	box := " "
+	if t.Completed {
+		box = "x"
+	}
+	parts := []string{"- [" + box + "] " + obsidianText(t.Description)}
+	if p := obsidianPriority(t.Priority); p != "" {
+		parts = append(parts, p)
+	}
+	if rule := obsidianRecurrence(t.Recurrence); rule != "" {
+		parts = append(parts, "🔁 "+rule)
+	}
+	if !t.StartAt.IsZero() {
+		parts = append(parts, "🛫 "+t.StartAt.Format("2006-01-02"))
+	}
+	parts = append(parts, "➕ "+t.CreatedAt.Format("2006-01-02"), "📅 "+t.DueAt.Format("2006-01-02"))
+	if t.Completed && !t.CompletedAt.IsZero() {
+		parts = append(parts, "✅ "+t.CompletedAt.Format("2006-01-02"))
+	}
+	for _, tag := range t.Tags {
+		parts = append(parts, "#"+strings.Join(strings.Fields(tag), "-"))
+	}
+	b.WriteString(strings.Join(parts, " "))
+	b.WriteString("\n")
+	for _, item := range t.Checklist {
+		box := " "
+		if item.Done {
+			box = "x"
+		}
+		b.WriteString("    - [" + box + "] " + obsidianText(item.Text) + "\n")
+	}
+}
+
+// yamlString 以雙引號輸出 YAML 字串，專案名稱裡的冒號、引號不會破壞 front matter
+func yamlString(s string) string {
+	return strconv.Quote(s)
+}
+
+// obsidianNote 產生一個專案的筆記：front matter 記錄到期時間，未完成的任務在前
+func obsidianNote(title string, tasks []Task, exported time.Time) string {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Completed != tasks[j].Completed {
+			return !tasks[i].Completed
+		}
+		return tasks[i].DueAt.Before(tasks[j].DueAt)
+	})
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("title: " + yamlString(title) + "\n")
+	b.WriteString("exported: " + exported.Format("2006-01-02T15:04:05") + "\n")
+	open := 0
+	for _, t := range tasks {
+		if !t.Completed {
+			open++
+		}
+	}
+	fmt.Fprintf(&b, "tasks: %d\nopen: %d\n", len(tasks), open)
+	if open > 0 {
+		b.WriteString("next_due: " + tasks[0].DueAt.Format("2006-01-02T15:04") + "\n")
+	}
+	b.WriteString("due:\n")
+	for _, t := range tasks {
+		fmt.Fprintf(&b, "  - task: %s\n    at: %s\n", yamlString(obsidianText(t.Description)), t.DueAt.Format("2006-01-02T15:04"))
+	}
+	b.WriteString("---\n\n")
+	b.WriteString("# " + obsidianText(title) + "\n\n")
+
+	wroteDone := false
+	for _, t := range tasks {
+		if t.Completed && !wroteDone {
+			b.WriteString("\n## 已完成\n\n")
+			wroteDone = true
+		}
+		obsidianTaskLine(&b, t)
+	}
+	return b.String()
+}
+
+// writeObsidianVault 把任務依專案分檔寫成 zip
+func (a *App) writeObsidianVault(w io.Writer, tasks []Task, now time.Time) error {
+	byProject := make(map[int][]Task)
+	for _, t := range tasks {
+		byProject[t.ProjectID] = append(byProject[t.ProjectID], t)
+	}
+	ids := make([]int, 0, len(byProject))
+	for id := range byProject {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	zw := zip.NewWriter(w)
+	used := make(map[string]bool)
+	for _, id := range ids {
+		title := obsidianPersonalFile
+		if id != 0 {
+			title = "專案 " + strconv.Itoa(id)
+			if p, err := a.store.GetProject(id); err == nil {
+				title = p.Name
+			}
+		}
+		name := obsidianFileName(title, id)
+		if used[name] {
+			name += " (" + strconv.Itoa(id) + ")"
+		}
+		used[name] = true
+
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: "Todo/" + name + ".md", Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, obsidianNote(title, byProject[id], now)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (a *App) exportObsidian(w http.ResponseWriter, tasks []Task, filename string) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`-obsidian.zip"`)
+	if err := a.writeObsidianVault(w, tasks, time.Now()); err != nil {
+		log.Printf("匯出 Obsidian 筆記庫失敗：%v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// --- 逾期任務的處理方式 ---
+//
+// 每位使用者可以在設定頁選擇逾期未完成的任務要怎麼處理，每天午夜由 overdue 排程套用：
+// 移到今天（保留原本的時間）、提高一級優先順序，或加上 missed 標籤集中到「錯過」清單。
+// 只處理自己負責（專案任務看負責人的設定）、還沒封存的任務；重複任務由 recurrence 排程產生下一次，不在處理範圍內
+
+const (
+	OverdueKeep     = ""
+	OverdueRollover = "rollover"
+	OverdueEscalate = "escalate"
+	OverdueMissed   = "missed"
+)
+
+// missedTag 是 OverdueMissed 加上的標籤，清單頁以 tag:missed 篩選
+const missedTag = "missed"
+
+// overdueOptions 依設定頁選項的順序排列
+var overdueOptions = []struct {
+	Value string
+	Label string
+}{
+	{OverdueKeep, "不處理，維持逾期"},
+	{OverdueRollover, "自動移到今天"},
+	{OverdueEscalate, "提高一級優先順序"},
+	{OverdueMissed, "加上 missed 標籤，移到「錯過」清單"},
+}
+
+func validOverduePolicy(policy string) bool {
+	for _, opt := range overdueOptions {
+		if opt.Value == policy {
+			return true
+		}
+	}
+	return false
+}
+
+func overduePolicyLabel(policy string) string {
+	for _, opt := range overdueOptions {
+		if opt.Value == policy {
+			return opt.Label
+		}
+	}
+	return policy
+}
+
+// escalatedPriority 是高一級的優先順序，已經是高的維持不變
+func escalatedPriority(p string) string {
+	switch effectivePriority(p) {
+	case PriorityLow:
+		return PriorityMedium
+	default:
+		return PriorityHigh
+	}
+}
+
+// applyOverduePolicy 依 policy 修改在 now 已逾期的任務，回報是否有變更
+func applyOverduePolicy(t *Task, policy string, now time.Time) bool {
+	if !t.OverdueAt(now) || t.Archived || t.Recurrence != RecurNone {
+		return false
+	}
+	switch policy {
+	case OverdueRollover:
+		t.DueAt = onDay(t.DueAt, now)
+		return true
+	case OverdueEscalate:
+		if effectivePriority(t.Priority) == PriorityHigh {
+			return false
+		}
+		t.Priority = escalatedPriority(t.Priority)
+		return true
+	case OverdueMissed:
+		if t.HasTag(missedTag) {
+			return false
+		}
+		t.Tags = normalizeTags(append(append([]string(nil), t.Tags...), missedTag))
+		return true
+	}
+	return false
+}
+
+// applyOverduePolicies 是每天午夜執行的工作，對有設定處理方式的使用者套用到各自負責的逾期任務
+func (a *App) applyOverduePolicies() error {
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return err
+	}
+	policies := make(map[string]string)
+	for _, u := range users {
+		if u.OverduePolicy != OverdueKeep {
+			policies[u.Username] = u.OverduePolicy
+		}
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	tasks, err := a.store.AllTasks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, task := range tasks {
+		policy, ok := policies[task.Username]
+		if !ok || !applyOverduePolicy(&task, policy, now) {
+			continue
+		}
+		_, err := a.store.ModifyTask(task.ID, func(t *Task) error {
+			if t.Username != task.Username {
+				return nil
+			}
+			before := *t
+			if applyOverduePolicy(t, policy, now) {
+				t.recordEdit(t.Username, before, now)
+			}
+			return nil
+		})
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateOverduePolicy 是設定頁的 action=overdue
+func (a *App) updateOverduePolicy(r *http.Request, username string) {
+	policy := r.FormValue("policy")
+	if !validOverduePolicy(policy) {
+		a.flashError(r, invalidInput("逾期處理方式不正確"), "")
+		return
+	}
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		u.OverduePolicy = policy
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "更新逾期處理方式失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "逾期任務的處理方式：%s", a.tr(r, overduePolicyLabel(policy)))
+}
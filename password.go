@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// --- 密碼雜湊 ---
+//
+// 新格式為 "bcrypt-sha256$<bcrypt 字串>"：bcrypt 只看前 72 bytes，所以先把密碼做 SHA-256 再 base64，
+// 長密碼不會被截斷。之前存過 "pbkdf2-sha256$<迭代次數>$<salt>$<hash>"（hex），更早是未加鹽的 SHA-256 hex；
+// 這兩種都還能驗證，登入成功時會自動改存成新格式（見 auth.go）
+
+const (
+	passwordScheme = "bcrypt-sha256"
+	passwordPrefix = passwordScheme + "$"
+	passwordCost   = 10 // OWASP 對 bcrypt 建議的最低成本
+
+	pbkdf2Scheme = "pbkdf2-sha256"
+)
+
+// bcryptInput 是交給 bcrypt 的內容，固定 44 bytes
+func bcryptInput(password string) []byte {
+	sum := sha256.Sum256([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+func hashPassword(password string) string {
+	hash, err := bcrypt.GenerateFromPassword(bcryptInput(password), passwordCost)
+	if err != nil {
+		panic(err)
+	}
+	return passwordPrefix + string(hash)
+}
+
+// legacyHashPassword 是舊版的未加鹽 SHA-256，只用來驗證尚未升級的帳號
+func legacyHashPassword(password string) string {
+	hash := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(hash[:])
+}
+
+// verifyPassword 比對密碼；needsRehash 表示雜湊是舊格式或參數較弱，應以 hashPassword 重新產生
+func verifyPassword(stored, password string) (ok, needsRehash bool) {
+	switch {
+	case strings.HasPrefix(stored, passwordPrefix):
+		return verifyBcrypt(stored, password)
+	case strings.HasPrefix(stored, pbkdf2Scheme+"$"):
+		return verifyPBKDF2(stored, password), true
+	default:
+		ok = subtle.ConstantTimeCompare([]byte(stored), []byte(legacyHashPassword(password))) == 1
+		return ok, true
+	}
+}
+
+func verifyBcrypt(stored, password string) (ok, needsRehash bool) {
+	hash := []byte(strings.TrimPrefix(stored, passwordPrefix))
+	if bcrypt.CompareHashAndPassword(hash, bcryptInput(password)) != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost(hash)
+	return true, err != nil || cost < passwordCost
+}
+
+// verifyPBKDF2 比對上一版的 PBKDF2-SHA256 雜湊
+func verifyPBKDF2(stored, password string) bool {
+	parts := strings.Split(stored, "$")
+	if len(parts) != 4 {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iter, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// --- 番茄鐘 ---
+//
+// /focus 挑出清單最上面、沒有在等其他任務的未完成任務（也可以自己選），以番茄鐘專心做：
+// 預設工作 25 分鐘、休息 5 分鐘，時間可以調整並記在使用者設定裡。進行中的狀態（User.Focus）存在伺服器，
+// 換裝置或重新整理都接得上，命令列或其他程式可以透過 /api/v1/focus 查詢與開始、停止。
+// 狀態不靠排程推進：每次讀取時依時間判斷，工作時間到了就記一顆番茄到任務上（Task.Pomodoros，
+// 記的是工作結束的時間，同一個時間只記一次）並進入休息，休息結束後回到待命，由使用者開始下一輪。
+// 統計頁與 /api/v1/stats 會列出完成的番茄數
+
+const (
+	defaultFocusWork  = 25
+	defaultFocusBreak = 5
+	maxFocusWork      = 120
+	maxFocusBreak     = 60
+	maxPomodoros      = 1000 // 每個任務保留的番茄紀錄，超過時丟掉最舊的
+)
+
+const (
+	FocusIdle  = "idle"
+	FocusWork  = "work"
+	FocusBreak = "break"
+)
+
+var ErrNoPendingTask = &DomainError{Code: "no_pending_task", Message: "沒有可以專心做的未完成任務", Status: http.StatusConflict}
+
+// FocusState 是進行中的番茄鐘；Phase 是 work 或 break
+type FocusState struct {
+	TaskID    int       `json:"task_id"`
+	Phase     string    `json:"phase"`
+	StartedAt time.Time `json:"started_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Break     int       `json:"break_minutes"`
+}
+
+// focusMinutes 是使用者設定的工作與休息分鐘數，沒設定過時是 25／5
+func (u User) focusMinutes() (work, rest int) {
+	work, rest = u.FocusWork, u.FocusBreak
+	if work <= 0 {
+		work = defaultFocusWork
+	}
+	if rest <= 0 {
+		rest = defaultFocusBreak
+	}
+	return work, rest
+}
+
+// recordPomodoro 在任務上記一顆在 at 完成的番茄，同一個時間已經記過就不再記
+func (t *Task) recordPomodoro(at time.Time) {
+	for _, p := range t.Pomodoros {
+		if p.Equal(at) {
+			return
+		}
+	}
+	t.Pomodoros = append(t.Pomodoros, at)
+	if len(t.Pomodoros) > maxPomodoros {
+		t.Pomodoros = t.Pomodoros[len(t.Pomodoros)-maxPomodoros:]
+	}
+}
+
+// pomodorosBetween 是在 [start, end) 之間完成的番茄數
+func (t Task) pomodorosBetween(start, end time.Time) int {
+	n := 0
+	for _, p := range t.Pomodoros {
+		if within(p, start, end) {
+			n++
+		}
+	}
+	return n
+}
+
+// advanceFocus 依 now 推進 username 的番茄鐘並回傳最新的使用者資料：
+// 工作時間到了就記番茄並進入休息，休息結束後回到待命
+func (a *App) advanceFocus(username string, now time.Time) (User, error) {
+	user, err := a.store.GetUser(username)
+	if err != nil || user.Focus == nil || now.Before(user.Focus.EndsAt) {
+		return user, err
+	}
+	// 先在使用者資料上推進階段，搶到這次推進的請求才記番茄，兩個分頁同時輪詢也只會記一顆
+	var finished *FocusState
+	user, err = a.store.ModifyUser(username, func(u *User) error {
+		finished = nil
+		if u.Focus == nil || now.Before(u.Focus.EndsAt) {
+			return nil
+		}
+		f := *u.Focus
+		if f.Phase == FocusWork {
+			done := f
+			finished = &done
+			f.Phase, f.StartedAt, f.EndsAt = FocusBreak, f.EndsAt, f.EndsAt.Add(time.Duration(f.Break)*time.Minute)
+		}
+		if f.Phase == FocusBreak && !now.Before(f.EndsAt) {
+			u.Focus = nil
+		} else {
+			u.Focus = &f
+		}
+		return nil
+	})
+	if err != nil || finished == nil {
+		return user, err
+	}
+	_, err = a.modifyOwnTask(finished.TaskID, username, func(t *Task) error {
+		t.recordPomodoro(finished.EndsAt)
+		return nil
+	})
+	if err != nil && err != ErrNotFound {
+		return user, err
+	}
+	return user, nil
+}
+
+// startFocus 開始一輪番茄鐘；taskID 為 0 時挑清單最上面的任務。work、rest 是新的分鐘數，同時存成使用者的設定
+func (a *App) startFocus(username string, taskID, work, rest int, now time.Time) (User, error) {
+	if work < 1 || work > maxFocusWork {
+		return User{}, invalidInput("工作時間必須是 1 到 %d 分鐘", maxFocusWork)
+	}
+	if rest < 1 || rest > maxFocusBreak {
+		return User{}, invalidInput("休息時間必須是 1 到 %d 分鐘", maxFocusBreak)
+	}
+	user, err := a.advanceFocus(username, now)
+	if err != nil {
+		return user, err
+	}
+	if taskID == 0 {
+		top, ok := a.topPendingTask(user, now)
+		if !ok {
+			return user, ErrNoPendingTask
+		}
+		taskID = top.ID
+	}
+	task, err := a.store.GetTask(taskID)
+	if err != nil || !task.canEdit(username) || task.Trashed() {
+		return user, ErrNotFound
+	}
+	if task.Completed {
+		return user, invalidInput("任務已經完成了")
+	}
+	return a.store.ModifyUser(username, func(u *User) error {
+		u.FocusWork, u.FocusBreak = work, rest
+		u.Focus = &FocusState{
+			TaskID:    taskID,
+			Phase:     FocusWork,
+			StartedAt: now,
+			EndsAt:    now.Add(time.Duration(work) * time.Minute),
+			Break:     rest,
+		}
+		return nil
+	})
+}
+
+// stopFocus 放棄進行中的番茄鐘，還沒到時間的這一顆不算
+func (a *App) stopFocus(username string, now time.Time) (User, error) {
+	user, err := a.advanceFocus(username, now)
+	if err != nil || user.Focus == nil {
+		return user, err
+	}
+	return a.store.ModifyUser(username, func(u *User) error {
+		u.Focus = nil
+		return nil
+	})
+}
+
+// focusCandidates 是可以專心做的任務：自己負責、未完成、未封存、沒有在等其他任務，依清單的排序
+func (a *App) focusCandidates(user User, now time.Time) []Task {
+	tasks, err := a.store.ListTasks(user.Username)
+	if err != nil {
+		return nil
+	}
+	var list []Task
+	for _, t := range withoutArchived(tasks) {
+		if t.Username == user.Username && !t.Completed && len(a.openBlockers(t)) == 0 {
+			list = append(list, t)
+		}
+	}
+	sortTasks(list, user.SortBy, user.Username, now)
+	return list
+}
+
+func (a *App) topPendingTask(user User, now time.Time) (Task, bool) {
+	list := a.focusCandidates(user, now)
+	if len(list) == 0 {
+		return Task{}, false
+	}
+	return list[0], true
+}
+
+// pomodorosToday 是 username 今天完成的番茄數
+func (a *App) pomodorosToday(username string, now time.Time) int {
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		return 0
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	n := 0
+	for _, t := range tasks {
+		if t.Username == username {
+			n += t.pomodorosBetween(today, today.AddDate(0, 0, 1))
+		}
+	}
+	return n
+}
+
+// focusHandler 是 /focus：GET 顯示番茄鐘，POST 的 action 為 start 或 stop
+func (a *App) focusHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	now := time.Now()
+
+	if r.Method == "POST" {
+		var err error
+		switch r.FormValue("action") {
+		case "start":
+			var id int // 沒有指定任務時從最優先的待辦開始
+			if r.FormValue("id") != "" {
+				id, err = formID(r, "id")
+			}
+			work, _ := strconv.Atoi(r.FormValue("work"))
+			rest, _ := strconv.Atoi(r.FormValue("break"))
+			if err == nil {
+				_, err = a.startFocus(username, id, work, rest, now)
+			}
+		case "stop":
+			_, err = a.stopFocus(username, now)
+		}
+		if err != nil {
+			a.flashError(r, err, "番茄鐘操作失敗，請稍後再試")
+		}
+		http.Redirect(w, r, "/focus", http.StatusSeeOther)
+		return
+	}
+
+	user, err := a.advanceFocus(username, now)
+	if err != nil {
+		http.Error(w, "讀取使用者失敗", http.StatusInternalServerError)
+		return
+	}
+	candidates := a.focusCandidates(user, now)
+	var current *Task
+	if user.Focus != nil {
+		if t, err := a.store.GetTask(user.Focus.TaskID); err == nil {
+			current = &t
+		}
+	} else if len(candidates) > 0 {
+		current = &candidates[0]
+	}
+	work, rest := user.focusMinutes()
+	remaining := 0
+	if user.Focus != nil {
+		remaining = int(user.Focus.EndsAt.Sub(now).Seconds()) + 1
+	}
+
+	data := map[string]interface{}{
+		"Username":   username,
+		"Focus":      user.Focus,
+		"Task":       current,
+		"Candidates": candidates,
+		"Work":       work,
+		"Break":      rest,
+		"Remaining":  remaining,
+		"Today":      a.pomodorosToday(username, now),
+		"Nonce":      newNonce(username),
+		"CSRFToken":  a.sessions.CSRFToken(r),
+		"Flashes":    a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("focus"))
+	t.Execute(w, data)
+}
+
+// focusResponse 是 /api/v1/focus 的回應；phase 為 idle 時 task_id 是建議開始的任務
+type focusResponse struct {
+	Phase            string     `json:"phase"`
+	TaskID           int        `json:"task_id,omitempty"`
+	Description      string     `json:"description,omitempty"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	EndsAt           *time.Time `json:"ends_at,omitempty"`
+	RemainingSeconds int        `json:"remaining_seconds"`
+	WorkMinutes      int        `json:"work_minutes"`
+	BreakMinutes     int        `json:"break_minutes"`
+	PomodorosToday   int        `json:"pomodoros_today"`
+}
+
+func (a *App) newFocusResponse(user User, now time.Time) focusResponse {
+	resp := focusResponse{Phase: FocusIdle, PomodorosToday: a.pomodorosToday(user.Username, now)}
+	resp.WorkMinutes, resp.BreakMinutes = user.focusMinutes()
+	if f := user.Focus; f != nil {
+		resp.Phase, resp.TaskID, resp.StartedAt, resp.EndsAt = f.Phase, f.TaskID, &f.StartedAt, &f.EndsAt
+		resp.RemainingSeconds = int(f.EndsAt.Sub(now).Seconds())
+	} else if top, ok := a.topPendingTask(user, now); ok {
+		resp.TaskID = top.ID
+	}
+	if t, err := a.store.GetTask(resp.TaskID); err == nil {
+		resp.Description = t.Description
+	}
+	return resp
+}
+
+// focusInput 是 POST /api/v1/focus 的請求；action 為 start 或 stop，分鐘數省略時沿用使用者的設定
+type focusInput struct {
+	Action       string `json:"action"`
+	TaskID       int    `json:"task_id"`
+	WorkMinutes  int    `json:"work_minutes"`
+	BreakMinutes int    `json:"break_minutes"`
+}
+
+// apiFocusHandler 是 GET／POST /api/v1/focus
+func (a *App) apiFocusHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	now := time.Now()
+	var user User
+	var err error
+	switch r.Method {
+	case "GET":
+		user, err = a.advanceFocus(username, now)
+	case "POST":
+		var in focusInput
+		if !readJSON(w, r, &in) {
+			return
+		}
+		switch in.Action {
+		case "start":
+			current, _ := a.store.GetUser(username)
+			work, rest := current.focusMinutes()
+			if in.WorkMinutes != 0 {
+				work = in.WorkMinutes
+			}
+			if in.BreakMinutes != 0 {
+				rest = in.BreakMinutes
+			}
+			user, err = a.startFocus(username, in.TaskID, work, rest, now)
+		case "stop":
+			user, err = a.stopFocus(username, now)
+		default:
+			writeAPIError(w, http.StatusBadRequest, "action 必須是 start 或 stop")
+			return
+		}
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	if err != nil {
+		writeDomainError(w, err, "番茄鐘操作失敗")
+		return
+	}
+	writeJSON(w, http.StatusOK, a.newFocusResponse(user, now))
+}
+
+// pomodoroRow 是統計頁番茄鐘區塊的一列；Percent 是長條圖相對於最大值的寬度
+type pomodoroRow struct {
+	Label   string
+	Count   int
+	Percent int
+}
+
+func fillPomodoroPercent(rows []pomodoroRow) {
+	max := 0
+	for _, row := range rows {
+		if row.Count > max {
+			max = row.Count
+		}
+	}
+	for i := range rows {
+		if max > 0 {
+			rows[i].Percent = rows[i].Count * 100 / max
+		}
+	}
+}
+
+// pomodoroStats 是統計頁最近 days 天每天的番茄數與番茄最多的幾個任務
+func pomodoroStats(tasks []Task, prefs DatePrefs, today time.Time, days int) (daily, top []pomodoroRow, total int) {
+	for i := days - 1; i >= 0; i-- {
+		start := today.AddDate(0, 0, -i)
+		row := pomodoroRow{Label: prefs.Date(start)}
+		for _, t := range tasks {
+			row.Count += t.pomodorosBetween(start, start.AddDate(0, 0, 1))
+		}
+		total += row.Count
+		daily = append(daily, row)
+	}
+	fillPomodoroPercent(daily)
+
+	from, end := today.AddDate(0, 0, 1-days), today.AddDate(0, 0, 1)
+	for _, t := range tasks {
+		if n := t.pomodorosBetween(from, end); n > 0 {
+			top = append(top, pomodoroRow{Label: t.Description, Count: n})
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Count > top[j].Count })
+	if len(top) > 5 {
+		top = top[:5]
+	}
+	fillPomodoroPercent(top)
+	return daily, top, total
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// --- 列印用週曆 ---
+//
+// /print/week 是黑白、不含導覽列的一週行程表，方便印出來貼在牆上；?week=2024-W19 指定週次，
+// 沒有指定時是本週，一週從使用者設定的週首開始。每天先列全天任務，再依時間列出其他任務，
+// 跨日任務在開始日到到期前一天標成「進行中」。要 PDF 時用瀏覽器的「列印 -> 另存為 PDF」
+
+// agendaDay 是週曆上的一天
+type agendaDay struct {
+	Date  time.Time
+	Today bool
+	Items []agendaItem
+}
+
+// agendaItem 的 Span 表示是跨日任務還沒到期的那幾天
+type agendaItem struct {
+	Task
+	Span bool
+}
+
+// buildWeekAgenda 排出 from 起七天的週曆；tasks 應已排除封存的任務
+func buildWeekAgenda(tasks []Task, from, now time.Time) []agendaDay {
+	days := make([]agendaDay, 7)
+	index := make(map[string]int, 7)
+	for i := range days {
+		d := from.AddDate(0, 0, i)
+		days[i] = agendaDay{Date: d, Today: d.Format("2006-01-02") == now.Format("2006-01-02")}
+		index[d.Format("2006-01-02")] = i
+	}
+	for _, task := range tasks {
+		due := task.DueAt.Format("2006-01-02")
+		if i, ok := index[due]; ok {
+			days[i].Items = append(days[i].Items, agendaItem{task, false})
+		}
+		if task.StartAt.IsZero() {
+			continue
+		}
+		for d := startOfDay(task.StartAt); d.Format("2006-01-02") < due; d = d.AddDate(0, 0, 1) {
+			if i, ok := index[d.Format("2006-01-02")]; ok {
+				days[i].Items = append(days[i].Items, agendaItem{task, true})
+			}
+		}
+	}
+	for _, day := range days {
+		items := day.Items
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].AllDay != items[j].AllDay {
+				return items[i].AllDay
+			}
+			if items[i].Span != items[j].Span {
+				return items[i].Span
+			}
+			return items[i].DueAt.Before(items[j].DueAt)
+		})
+	}
+	return days
+}
+
+func (a *App) printWeekHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	prefs := a.requestDatePrefs(r)
+	now := time.Now()
+
+	from := prefs.StartOfWeek(now)
+	if week := r.URL.Query().Get("week"); week != "" {
+		monday, err := parseISOWeek(week, time.Local)
+		if err != nil {
+			a.flashError(r, err, "")
+			http.Redirect(w, r, "/calendar", http.StatusSeeOther)
+			return
+		}
+		from = prefs.StartOfWeek(monday)
+	}
+	// 週次以這一週的週四為準，週日開始的週曆也對得上 ISO 週次
+	thursday := from.AddDate(0, 0, (int(time.Thursday)-int(from.Weekday())+7)%7)
+
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Username": username,
+		"Week":     isoWeekLabel(thursday),
+		"From":     from,
+		"To":       from.AddDate(0, 0, 6),
+		"Days":     buildWeekAgenda(withoutArchived(tasks), from, now),
+		"PrevWeek": isoWeekLabel(thursday.AddDate(0, 0, -7)),
+		"NextWeek": isoWeekLabel(thursday.AddDate(0, 0, 7)),
+		"Weekdays": prefs.Weekdays(),
+	}
+	t := a.localize(r, a.pages.page("print-week"))
+	t.Execute(w, data)
+}
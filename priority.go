@@ -0,0 +1,58 @@
+package main
+
+// --- 優先順序 ---
+
+const (
+	PriorityHigh   = "high"
+	PriorityMedium = "medium"
+	PriorityLow    = "low"
+)
+
+// priorityOptions 依表單下拉選單的順序排列，預設選中等
+var priorityOptions = []struct {
+	Value string
+	Label string
+}{
+	{PriorityHigh, "高"},
+	{PriorityMedium, "中"},
+	{PriorityLow, "低"},
+}
+
+func validPriority(p string) bool {
+	for _, opt := range priorityOptions {
+		if opt.Value == p {
+			return true
+		}
+	}
+	return false
+}
+
+// effectivePriority 把舊資料沒有優先順序的任務視為中等
+func effectivePriority(p string) string {
+	if p == "" {
+		return PriorityMedium
+	}
+	return p
+}
+
+func priorityLabel(p string) string {
+	p = effectivePriority(p)
+	for _, opt := range priorityOptions {
+		if opt.Value == p {
+			return opt.Label
+		}
+	}
+	return p
+}
+
+// priorityRank 數字越小越優先，排序用
+func priorityRank(p string) int {
+	switch effectivePriority(p) {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 共享專案與任務認領 ---
+
+var ErrAlreadyClaimed = &DomainError{Code: "already_claimed", Message: "這個任務已經被其他成員認領了", Status: http.StatusConflict}
+
+// maxProjectActivity 是專案動態保留的筆數，超過時丟棄最舊的
+const maxProjectActivity = 50
+
+func (p Project) HasMember(username string) bool {
+	for _, m := range p.Members {
+		if m == username {
+			return true
+		}
+	}
+	return false
+}
+
+// loadMemberProject 取出 id 指定、且目前使用者為成員的專案
+func (a *App) loadMemberProject(id int, username string) (Project, error) {
+	p, err := a.store.GetProject(id)
+	if err != nil {
+		return Project{}, err
+	}
+	if !p.HasMember(username) {
+		return Project{}, ErrNotFound
+	}
+	return p, nil
+}
+
+// VisibleTo 是專案任務的可見性檢查：私人任務只有負責人看得到。
+// 所有讀取或操作其他成員任務的路徑都必須經過這裡
+func (t Task) VisibleTo(username string) bool {
+	return !t.Private || t.Username == username
+}
+
+// projectTasks 回傳 viewer 看得到的專案任務（含未認領），未認領的排前面，其餘依到期時間
+func (a *App) projectTasks(projectID int, viewer string) ([]Task, error) {
+	all, err := a.store.AllTasks()
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	for _, task := range all {
+		if task.ProjectID == projectID && task.VisibleTo(viewer) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if (tasks[i].Username == "") != (tasks[j].Username == "") {
+			return tasks[i].Username == ""
+		}
+		return tasks[i].DueAt.Before(tasks[j].DueAt)
+	})
+	return tasks, nil
+}
+
+// notifyProject 在專案動態加入一筆訊息，所有成員都會在專案頁看到
+func (a *App) notifyProject(projectID int, format string, args ...interface{}) error {
+	_, err := a.store.ModifyProject(projectID, func(p *Project) error {
+		p.Activity = append(p.Activity, ProjectEvent{
+			Time:    time.Now(),
+			Message: fmt.Sprintf(format, args...),
+		})
+		if len(p.Activity) > maxProjectActivity {
+			p.Activity = p.Activity[len(p.Activity)-maxProjectActivity:]
+		}
+		return nil
+	})
+	return err
+}
+
+// taskProject 取出任務所屬、且 username 為成員的專案；看不到的私人任務視為不存在
+func (a *App) taskProject(id int, username string) (Project, error) {
+	task, err := a.store.GetTask(id)
+	if err != nil {
+		return Project{}, err
+	}
+	if task.ProjectID == 0 || !task.VisibleTo(username) {
+		return Project{}, ErrNotFound
+	}
+	return a.loadMemberProject(task.ProjectID, username)
+}
+
+// claimTask 讓專案成員認領未指派的任務；是否已被認領在 ModifyTask 內檢查，先到者得
+func (a *App) claimTask(id int, username string) (Task, error) {
+	p, err := a.taskProject(id, username)
+	if err != nil {
+		return Task{}, err
+	}
+	return a.store.ModifyTask(id, func(task *Task) error {
+		if task.ProjectID != p.ID {
+			return ErrNotFound
+		}
+		if task.Username != "" {
+			return ErrAlreadyClaimed
+		}
+		before := *task
+		task.Username = username
+		task.recordEdit(username, before, time.Now())
+		return nil
+	})
+}
+
+// reassignTask 把任務轉給另一位成員；to 為空字串代表退回未認領。
+// 只有目前負責人或專案擁有者可以轉派，轉出的私人任務會恢復公開
+func (a *App) reassignTask(id int, username, to string) (Task, error) {
+	p, err := a.taskProject(id, username)
+	if err != nil {
+		return Task{}, err
+	}
+	if to != "" && !p.HasMember(to) {
+		return Task{}, ErrNotFound
+	}
+	return a.store.ModifyTask(id, func(task *Task) error {
+		if task.ProjectID != p.ID {
+			return ErrNotFound
+		}
+		if task.Username != username && p.Owner != username {
+			return ErrNotFound
+		}
+		if !task.VisibleTo(username) {
+			return ErrNotFound
+		}
+		if to != task.Username {
+			task.Private = false
+		}
+		before := *task
+		task.Username = to
+		task.recordEdit(username, before, time.Now())
+		return nil
+	})
+}
+
+// setTaskPrivate 切換專案任務的可見性，只有負責人可以設定
+func (a *App) setTaskPrivate(id int, username string, private bool) (Task, error) {
+	if _, err := a.taskProject(id, username); err != nil {
+		return Task{}, err
+	}
+	return a.store.ModifyTask(id, func(task *Task) error {
+		if task.ProjectID == 0 || task.Username != username {
+			return ErrNotFound
+		}
+		task.Private = private
+		return nil
+	})
+}
+
+// parseMembers 把以逗號或空白分隔的使用者名稱轉成清單，並確認每位都已註冊
+func (a *App) parseMembers(raw string) ([]string, error) {
+	var members []string
+	seen := make(map[string]bool)
+	for _, name := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' || r == '，' }) {
+		if seen[name] {
+			continue
+		}
+		if _, err := a.store.GetUser(name); err != nil {
+			return nil, invalidInput("找不到使用者 %s", name)
+		}
+		seen[name] = true
+		members = append(members, name)
+	}
+	return members, nil
+}
+
+func (a *App) projectsHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+
+	if r.Method == "POST" {
+		name := strings.TrimSpace(r.FormValue("name"))
+		members, err := a.parseMembers(r.FormValue("members"))
+		if name == "" && err == nil {
+			err = invalidInput("請填寫專案名稱")
+		}
+		if err != nil {
+			a.flashError(r, err, "")
+			http.Redirect(w, r, "/projects", http.StatusSeeOther)
+			return
+		}
+		p := Project{Name: name, Owner: username, Members: []string{username}}
+		for _, m := range members {
+			if m != username {
+				p.Members = append(p.Members, m)
+			}
+		}
+		p, err = a.store.CreateProject(p)
+		if err != nil {
+			a.flashError(r, err, "建立專案失敗，請稍後再試")
+			http.Redirect(w, r, "/projects", http.StatusSeeOther)
+			return
+		}
+		a.flashSuccess(r, "專案「%s」已建立", p.Name)
+		http.Redirect(w, r, "/project?id="+strconv.Itoa(p.ID), http.StatusSeeOther)
+		return
+	}
+
+	projects, err := a.store.ListProjects(username)
+	if err != nil {
+		http.Error(w, "讀取專案失敗", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Projects":  projects,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("projects"))
+	t.Execute(w, data)
+}
+
+func (a *App) projectHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+
+	p, err := a.loadMemberProject(id, username)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	tasks, err := a.projectTasks(p.ID, username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+
+	// 最新動態排最前面
+	activity := make([]ProjectEvent, len(p.Activity))
+	for i, e := range p.Activity {
+		activity[len(p.Activity)-1-i] = e
+	}
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Project":   p,
+		"Tasks":     tasks,
+		"Activity":  activity,
+		"IsOwner":   p.Owner == username,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("project"))
+	t.Execute(w, data)
+}
+
+func (a *App) projectAddHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, _ := strconv.Atoi(r.FormValue("project_id"))
+	p, err := a.loadMemberProject(id, username)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	desc := strings.TrimSpace(r.FormValue("description"))
+	dueAt, err := parseDueTime(r.FormValue("due_at"))
+	assignee := r.FormValue("assignee")
+	private := r.FormValue("private") == "on"
+	back := "/project?id=" + strconv.Itoa(p.ID)
+	descErr := checkDescription(desc)
+	switch {
+	case descErr != nil:
+		err = descErr
+	case err != nil: // parseDueTime 的錯誤已可直接顯示
+	case assignee != "" && !p.HasMember(assignee):
+		err = invalidInput("%s 不是專案成員", assignee)
+	case private && assignee != username:
+		err = invalidInput("只有指派給自己的任務可以設為私人")
+	}
+	if err != nil {
+		a.flashError(r, err, "")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	task := Task{
+		Description: desc,
+		CreatedAt:   time.Now(),
+		DueAt:       dueAt,
+		Username:    assignee,
+		ProjectID:   p.ID,
+		CreatedBy:   username,
+		Private:     private,
+	}
+	if _, err := a.store.CreateTask(task); err != nil {
+		a.flashError(r, err, "新增任務失敗，請稍後再試")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+	switch {
+	case private:
+		// 私人任務不寫進專案動態
+	case assignee == "":
+		a.notifyProject(p.ID, "%s 新增了待認領任務%s", username, quoted(desc))
+	default:
+		a.notifyProject(p.ID, "%s 新增了任務%s並指派給 %s", username, quoted(desc), assignee)
+	}
+	a.flashSuccess(r, "任務已新增")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+func (a *App) projectClaimHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, _ := strconv.Atoi(r.FormValue("id"))
+
+	task, err := a.claimTask(id, username)
+	switch err {
+	case nil:
+		a.notifyProject(task.ProjectID, "%s 認領了%s", username, quoted(task.Description))
+		a.flashSuccess(r, "已認領「%s」", brief(task.Description))
+	case ErrNotFound:
+		http.NotFound(w, r)
+		return
+	default:
+		a.flashError(r, err, "認領失敗，請稍後再試")
+		redirectBack(w, r)
+		return
+	}
+	http.Redirect(w, r, "/project?id="+strconv.Itoa(task.ProjectID), http.StatusSeeOther)
+}
+
+func (a *App) projectReassignHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	to := r.FormValue("to")
+
+	task, err := a.reassignTask(id, username, to)
+	if err == ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		a.flashError(r, err, "轉派失敗，請稍後再試")
+		redirectBack(w, r)
+		return
+	}
+	if to == "" {
+		a.notifyProject(task.ProjectID, "%s 將%s退回待認領", username, quoted(task.Description))
+	} else {
+		a.notifyProject(task.ProjectID, "%s 將%s轉派給 %s", username, quoted(task.Description), to)
+	}
+	http.Redirect(w, r, "/project?id="+strconv.Itoa(task.ProjectID), http.StatusSeeOther)
+}
+
+func (a *App) projectPrivateHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	private := r.FormValue("private") == "true"
+
+	task, err := a.setTaskPrivate(id, username, private)
+	if err == ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		a.flashError(r, err, "變更可見性失敗，請稍後再試")
+		redirectBack(w, r)
+		return
+	}
+	if private {
+		a.flashSuccess(r, "「%s」已設為私人，其他成員看不到", brief(task.Description))
+	} else {
+		a.flashSuccess(r, "「%s」已公開給專案成員", brief(task.Description))
+	}
+	redirectBack(w, r)
+}
+
+func (a *App) projectMembersHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, _ := strconv.Atoi(r.FormValue("project_id"))
+	back := "/project?id=" + strconv.Itoa(id)
+	members, err := a.parseMembers(r.FormValue("members"))
+	if err != nil {
+		a.flashError(r, err, "")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	var added []string
+	_, err = a.store.ModifyProject(id, func(p *Project) error {
+		if p.Owner != username {
+			return ErrNotFound
+		}
+		for _, m := range members {
+			if !p.HasMember(m) {
+				p.Members = append(p.Members, m)
+				added = append(added, m)
+			}
+		}
+		return nil
+	})
+	if err == ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		a.flashError(r, err, "新增成員失敗，請稍後再試")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+	if len(added) > 0 {
+		a.notifyProject(id, "%s 邀請 %s 加入專案", username, strings.Join(added, "、"))
+		a.flashSuccess(r, "已邀請 %s", strings.Join(added, a.tr(r, "、")))
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// --- 清除舊的已完成任務 ---
+//
+// 使用多年的帳號會累積大量已完成的任務，使用者可以在設定頁或 API 指定日期，
+// 先看會刪除幾個，確認後把該日期以前完成的任務永久刪除（不經過垃圾桶）。
+// 專案任務屬於整個專案，不在清除範圍內；舊資料沒有完成時間時以到期時間判斷
+
+// completedOn 是判斷新舊用的完成時間
+func (t Task) completedOn() time.Time {
+	if t.CompletedAt.IsZero() {
+		return t.DueAt
+	}
+	return t.CompletedAt
+}
+
+// parsePurgeDate 解析 YYYY-MM-DD；清除的是這一天 0 點以前完成的任務，不接受明天以後的日期
+func parsePurgeDate(s string) (time.Time, error) {
+	before, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, invalidInput("日期格式必須是 YYYY-MM-DD")
+	}
+	now := time.Now()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.Local)
+	if before.After(tomorrow) {
+		return time.Time{}, invalidInput("日期不可晚於明天")
+	}
+	return before, nil
+}
+
+// completedBefore 回傳 username 在 before 以前完成、可以清除的任務
+func (a *App) completedBefore(username string, before time.Time) ([]Task, error) {
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		return nil, err
+	}
+	var old []Task
+	for _, t := range tasks {
+		if t.Username == username && t.Completed && t.ProjectID == 0 && t.completedOn().Before(before) {
+			old = append(old, t)
+		}
+	}
+	return old, nil
+}
+
+// purgeCompleted 永久刪除 username 在 before 以前完成的任務，回傳刪除的數量；
+// 中途失敗時已刪除的不會復原，回傳的數量仍是實際刪除的
+func (a *App) purgeCompleted(username string, before time.Time) (int, error) {
+	old, err := a.completedBefore(username, before)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, t := range old {
+		if err := a.store.DeleteTask(t.ID); err != nil && err != ErrNotFound {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// purgeOwnCompleted 是設定頁的 action=purge，預覽在設定頁以 GET 參數 purge_before 顯示
+func (a *App) purgeOwnCompleted(r *http.Request, username string) {
+	before, err := parsePurgeDate(r.FormValue("before"))
+	if err != nil {
+		a.flashError(r, err, "")
+		return
+	}
+	n, err := a.purgeCompleted(username, before)
+	if err != nil {
+		a.flashError(r, displayError(err, "清除途中失敗"), "")
+		a.flashSuccess(r, "已刪除 %d 個任務", n)
+		return
+	}
+	a.flashSuccess(r, "已永久刪除 %d 個在 %s 以前完成的任務", n, before.Format("2006-01-02"))
+}
+
+type purgeResponse struct {
+	Before  string `json:"before"`
+	Count   int    `json:"count"`
+	Deleted bool   `json:"deleted"`
+}
+
+// apiPurgeCompleted：GET 預覽會刪除的數量，POST 實際刪除，兩者都以 ?before=YYYY-MM-DD 指定日期
+func (a *App) apiPurgeCompleted(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	username := a.getUsername(r)
+	before, err := parsePurgeDate(r.URL.Query().Get("before"))
+	if err != nil {
+		writeDomainError(w, err, "日期格式錯誤")
+		return
+	}
+	resp := purgeResponse{Before: before.Format("2006-01-02")}
+	if r.Method == "GET" {
+		old, err := a.completedBefore(username, before)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "讀取任務失敗")
+			return
+		}
+		resp.Count = len(old)
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	resp.Count, err = a.purgeCompleted(username, before)
+	if err != nil {
+		writeDomainError(w, err, fmt.Sprintf("清除途中失敗，已刪除 %d 個任務", resp.Count))
+		return
+	}
+	resp.Deleted = true
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// --- Web Push ---
+//
+// 瀏覽器訂閱後把推播服務的 endpoint 與金鑰交給我們（/push/subscribe），到期提醒在
+// 沒有分頁開著時改以推播送出，分頁關了也收得到。訊息依 RFC 8291（aes128gcm）加密，
+// 以 VAPID（RFC 8292）簽章證明推播來自本站；VAPID 私鑰第一次啟動時產生並存成 PEM 檔
+
+const (
+	maxPushSubscriptions = 10 // 每位使用者最多登記的裝置數
+	pushTTL              = 30 * time.Minute
+	pushRecordSize       = 4096
+	vapidTokenTTL        = 12 * time.Hour
+)
+
+type PushSubscription struct {
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"p256dh"`
+	Auth      string    `json:"auth"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	vapidKey     *ecdsa.PrivateKey // nil 表示停用推播
+	vapidSubject string
+	pushClient   = &http.Client{Timeout: 10 * time.Second}
+)
+
+var errPushGone = errors.New("推播訂閱已失效")
+
+// loadVAPIDKey 讀取 PEM 格式的私鑰，檔案不存在時產生新的一把
+func loadVAPIDKey(path string) (*ecdsa.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		pemBytes := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(path, pemBytes, 0600); err != nil {
+			return nil, err
+		}
+		log.Printf("已產生新的 VAPID 金鑰：%s", path)
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是 PEM 格式", path)
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// vapidPublicKey 是給瀏覽器 applicationServerKey 用的公鑰（未壓縮點，base64url）
+func vapidPublicKey() string {
+	if vapidKey == nil {
+		return ""
+	}
+	pub, err := vapidKey.PublicKey.ECDH()
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(pub.Bytes())
+}
+
+// vapidAuthorization 產生送往 endpoint 的 Authorization 標頭（ES256 JWT）
+func vapidAuthorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(vapidTokenTTL).Unix(),
+		"sub": vapidSubject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, vapidKey, digest[:])
+	if err != nil {
+		return "", err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return "vapid t=" + signingInput + "." + enc.EncodeToString(sig) + ", k=" + vapidPublicKey(), nil
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// encryptPushPayload 依 RFC 8291 以訂閱者的金鑰加密訊息，回傳 aes128gcm 格式的內容
+func encryptPushPayload(sub PushSubscription, payload []byte) ([]byte, error) {
+	uaRaw, err := decodeBase64URL(sub.P256dh)
+	if err != nil {
+		return nil, err
+	}
+	authSecret, err := decodeBase64URL(sub.Auth)
+	if err != nil {
+		return nil, err
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaRaw)
+	if err != nil {
+		return nil, err
+	}
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	shared, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaRaw...), asPublic...)
+	ikm, err := hkdf.Key(sha256.New, shared, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 整則訊息放在單一 record，結尾加上 0x02 表示最後一個 record
+	plain := append(append([]byte{}, payload...), 0x02)
+	if len(plain)+gcm.Overhead() > pushRecordSize {
+		return nil, errors.New("推播內容過長")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(salt)
+	binary.Write(&buf, binary.BigEndian, uint32(pushRecordSize))
+	buf.WriteByte(byte(len(asPublic)))
+	buf.Write(asPublic)
+	buf.Write(gcm.Seal(nil, nonce, plain, nil))
+	return buf.Bytes(), nil
+}
+
+// sendPush 送出一則推播；推播服務回 404/410 代表訂閱已失效，回傳 errPushGone
+func sendPush(sub PushSubscription, payload []byte) error {
+	body, err := encryptPushPayload(sub, payload)
+	if err != nil {
+		return err
+	}
+	auth, err := vapidAuthorization(sub.Endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprint(int(pushTTL.Seconds())))
+	req.Header.Set("Urgency", "high")
+
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return errPushGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("推播服務回應 %s", resp.Status)
+	}
+	return nil
+}
+
+// pushMessage 是 service worker 收到後顯示的內容
+type pushMessage struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Tag   string `json:"tag"`
+	URL   string `json:"url"`
+}
+
+// pushReminder 把即將到期的任務合併成一則推播
+func (a *App) pushReminder(user User, tasks []Task) bool {
+	if vapidKey == nil || len(user.PushSubscriptions) == 0 {
+		return false
+	}
+	msg := pushMessage{Title: "⏰ 任務即將到期", Tag: fmt.Sprintf("task-%d", tasks[0].ID), URL: taskPath(tasks[0].ID)}
+	if len(tasks) > 1 {
+		msg.Title = fmt.Sprintf("⏰ %d 個任務即將到期", len(tasks))
+		msg.URL = "/"
+	}
+	var lines []string
+	for _, t := range tasks {
+		lines = append(lines, fmt.Sprintf("%s（%s，%s）", clipText(taskLabel(user, t), clipLine), user.DatePrefs().Short(t.DueAt), remainingTimeIn(user.Locale, t.DueAt)))
+	}
+	msg.Body = strings.Join(lines, "\n")
+	return a.pushToUser(user, msg)
+}
+
+// pushToUser 把訊息推播到使用者所有裝置，至少一台收到就算送達；失效的訂閱順便移除
+func (a *App) pushToUser(user User, msg pushMessage) bool {
+	if vapidKey == nil || len(user.PushSubscriptions) == 0 {
+		return false
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return false
+	}
+
+	delivered := false
+	var gone []string
+	for _, sub := range user.PushSubscriptions {
+		switch err := sendPush(sub, payload); err {
+		case nil:
+			delivered = true
+		case errPushGone:
+			gone = append(gone, sub.Endpoint)
+		default:
+			log.Printf("推播給 %s 失敗：%v", user.Username, err)
+		}
+	}
+	for _, endpoint := range gone {
+		if err := a.removePushSubscription(user.Username, endpoint); err != nil {
+			log.Printf("移除失效的推播訂閱失敗：%v", err)
+		}
+	}
+	return delivered
+}
+
+// validPushEndpoint 只接受 https 的網域名稱，避免被當成向內網發送請求的跳板
+func validPushEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return false
+	}
+	host := u.Hostname()
+	return net.ParseIP(host) == nil && host != "localhost" && strings.Contains(host, ".")
+}
+
+func (a *App) savePushSubscription(username string, sub PushSubscription) error {
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		kept := []PushSubscription{sub}
+		for _, old := range u.PushSubscriptions {
+			if old.Endpoint != sub.Endpoint {
+				kept = append(kept, old)
+			}
+		}
+		if len(kept) > maxPushSubscriptions {
+			kept = kept[:maxPushSubscriptions] // 最新的排前面，丟掉最舊的裝置
+		}
+		u.PushSubscriptions = kept
+		return nil
+	})
+	return err
+}
+
+func (a *App) removePushSubscription(username, endpoint string) error {
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		var kept []PushSubscription
+		for _, sub := range u.PushSubscriptions {
+			if sub.Endpoint != endpoint {
+				kept = append(kept, sub)
+			}
+		}
+		u.PushSubscriptions = kept
+		return nil
+	})
+	return err
+}
+
+// pushSubscriptionInput 對應瀏覽器 PushSubscription.toJSON() 的格式
+type pushSubscriptionInput struct {
+	Endpoint       string `json:"endpoint"`
+	ExpirationTime *int64 `json:"expirationTime"`
+	Keys           struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+func (a *App) pushSubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	if vapidKey == nil {
+		writeAPIError(w, http.StatusNotFound, "伺服器未啟用推播")
+		return
+	}
+	var in pushSubscriptionInput
+	if !readJSON(w, r, &in) {
+		return
+	}
+	uaKey, err1 := decodeBase64URL(in.Keys.P256dh)
+	auth, err2 := decodeBase64URL(in.Keys.Auth)
+	if !validPushEndpoint(in.Endpoint) || err1 != nil || err2 != nil || len(uaKey) != 65 || len(auth) != 16 {
+		writeAPIError(w, http.StatusBadRequest, "推播訂閱資料不正確")
+		return
+	}
+	sub := PushSubscription{Endpoint: in.Endpoint, P256dh: in.Keys.P256dh, Auth: in.Keys.Auth, CreatedAt: time.Now()}
+	if err := a.savePushSubscription(a.getUsername(r), sub); err != nil {
+		writeDomainError(w, err, "儲存推播訂閱失敗")
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]bool{"subscribed": true})
+}
+
+func (a *App) pushUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	var in struct {
+		Endpoint string `json:"endpoint"`
+	}
+	if !readJSON(w, r, &in) {
+		return
+	}
+	if err := a.removePushSubscription(a.getUsername(r), in.Endpoint); err != nil {
+		writeDomainError(w, err, "移除推播訂閱失敗")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serviceWorkerHandler 提供 /sw.js；service worker 必須放在網站根目錄才能涵蓋整個站
+func serviceWorkerHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	io.WriteString(w, serviceWorkerScript)
+}
+
+const serviceWorkerScript = `
+self.addEventListener('push', function(event) {
+    var msg = event.data ? event.data.json() : {title: '待辦清單', body: ''};
+    event.waitUntil(self.registration.showNotification(msg.title, {
+        body: msg.body,
+        tag: msg.tag,
+        data: {url: msg.url || '/'}
+    }));
+});
+
+self.addEventListener('notificationclick', function(event) {
+    event.notification.close();
+    var url = event.notification.data.url;
+    event.waitUntil(clients.matchAll({type: 'window'}).then(function(list) {
+        for (var i = 0; i < list.length; i++) {
+            if ('focus' in list[i]) return list[i].focus();
+        }
+        return clients.openWindow(url);
+    }));
+});
+`
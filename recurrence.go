@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// --- 重複任務 ---
+
+const (
+	RecurNone     = ""
+	RecurDaily    = "daily"
+	RecurWeekly   = "weekly"
+	RecurMonthly  = "monthly"
+	RecurWeekdays = "weekdays"
+)
+
+// recurrenceOptions 依表單下拉選單的順序排列
+var recurrenceOptions = []struct {
+	Value string
+	Label string
+}{
+	{RecurNone, "不重複"},
+	{RecurDaily, "每天"},
+	{RecurWeekdays, "平日（週一至週五）"},
+	{RecurWeekly, "每週"},
+	{RecurMonthly, "每月"},
+}
+
+var errAlreadySpawned = errors.New("下一次任務已產生")
+
+func validRecurrence(rule string) bool {
+	for _, opt := range recurrenceOptions {
+		if opt.Value == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func recurrenceLabel(rule string) string {
+	for _, opt := range recurrenceOptions {
+		if opt.Value == rule {
+			return opt.Label
+		}
+	}
+	return rule
+}
+
+// addMonthClamped 加一個月，但 1/31 的下個月是 2/28（或 29），而不是 3/3
+func addMonthClamped(t time.Time) time.Time {
+	y, m, d := t.Date()
+	firstOfNext := time.Date(y, m+1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := firstOfNext.AddDate(0, 1, -1).Day()
+	if d > lastDay {
+		d = lastDay
+	}
+	return time.Date(y, m+1, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// nextOccurrence 依規則算出 due 之後的下一次到期時間，時刻保持不變
+func nextOccurrence(rule string, due time.Time) time.Time {
+	switch rule {
+	case RecurDaily:
+		return due.AddDate(0, 0, 1)
+	case RecurWeekly:
+		return due.AddDate(0, 0, 7)
+	case RecurMonthly:
+		return addMonthClamped(due)
+	case RecurWeekdays:
+		next := due.AddDate(0, 0, 1)
+		for next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+	return due
+}
+
+// upcomingOccurrence 從 due 往後推到第一個晚於 now 的時間，
+// 拖了好幾天才完成的每日任務不會一次補出一堆已逾期的副本
+func upcomingOccurrence(rule string, due, now time.Time) time.Time {
+	next := nextOccurrence(rule, due)
+	for !next.After(now) {
+		next = nextOccurrence(rule, next)
+	}
+	return next
+}
+
+// spawnNextOccurrence 為重複任務建立下一次的任務；每個任務只會產生一次下一筆，
+// 取消勾選後再勾選不會重複產生
+func (a *App) spawnNextOccurrence(id int) error {
+	task, err := a.store.ModifyTask(id, func(t *Task) error {
+		if t.Recurrence == RecurNone || t.NextSpawned {
+			return errAlreadySpawned
+		}
+		t.NextSpawned = true
+		return nil
+	})
+	if err == errAlreadySpawned {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	next := Task{
+		Description: task.Description,
+		CreatedAt:   time.Now(),
+		DueAt:       upcomingOccurrence(task.Recurrence, task.DueAt, time.Now()),
+		AllDay:      task.AllDay,
+		Username:    task.Username,
+		ProjectID:   task.ProjectID,
+		CreatedBy:   task.CreatedBy,
+		Private:     task.Private,
+		Recurrence:  task.Recurrence,
+		Priority:    task.Priority,
+		Tags:        task.Tags,
+		Checklist:   resetChecklist(task.Checklist),
+
+		EncryptedNote: task.EncryptedNote,
+	}
+	_, err = a.store.CreateTask(next)
+	return err
+}
+
+// materializeRecurring 是每天午夜執行的工作：已過期但還沒完成的重複任務，
+// 也要先產生下一次的任務，讓週期不因漏做而中斷
+func (a *App) materializeRecurring() error {
+	tasks, err := a.store.AllTasks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, task := range tasks {
+		if task.Recurrence != RecurNone && !task.NextSpawned && task.Deadline().Before(now) {
+			if err := a.spawnNextOccurrence(task.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
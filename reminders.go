@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 到期提醒 ---
+//
+// 每個任務可以設定一個或多個提醒時間（到期前幾分鐘，例如 30 分鐘前與 1 天前），沒有設定的任務
+// 使用負責人在設定頁選的預設提醒，使用者也沒選過時是 -remind-window 的時間。
+// 排程每隔 reminderInterval 掃描一次，把到了提醒時間的任務提醒負責人，同一位使用者的任務合併成一則。
+// 送出管道依序為：開著的分頁（桌面通知，見 events.go）、Web Push（見 push.go）、Email；
+// Email 裡每個任務附上完成與延後一天的一鍵連結（見 actionlink.go）。
+// 任務的 RemindedDue 與 RemindedOffset 記錄已提醒到哪一個時間，不論哪個管道送出，每個提醒只送一次；
+// 排程停過一陣子時只補送最接近到期的那個。到期時間被修改後重新提醒；已經逾期的不再寄「即將到期」
+
+const (
+	reminderInterval = 5 * time.Minute
+	reminderLinkTTL  = 48 * time.Hour // 提醒信裡一鍵連結的有效期限
+	maxReminders     = 5
+)
+
+// reminderWindow 由 -remind-window 設定，是使用者沒有選過預設提醒時的提醒時間，0 表示預設不提醒
+var reminderWindow = 30 * time.Minute
+
+// reminderPresets 是表單上可以勾選的提醒時間（到期前幾分鐘）
+var reminderPresets = []int{0, 5, 15, 30, 60, 120, 24 * 60, 2 * 24 * 60, 7 * 24 * 60}
+
+// reminderLabel 是提醒時間在 locale 的說明，例如「30 分鐘前」「1 天前」
+func reminderLabel(locale string, minutes int) string {
+	switch {
+	case minutes == 0:
+		return translate(locale, "到期時")
+	case minutes%(7*24*60) == 0:
+		return tr(locale, "%d 週前", minutes/(7*24*60))
+	case minutes%(24*60) == 0:
+		return tr(locale, "%d 天前", minutes/(24*60))
+	case minutes%60 == 0:
+		return tr(locale, "%d 小時前", minutes/60)
+	}
+	return tr(locale, "%d 分鐘前", minutes)
+}
+
+// reminderList 是一組提醒時間的說明，活動紀錄（一律中文）與設定頁用
+func reminderList(locale string, offsets []int) string {
+	if len(offsets) == 0 {
+		return translate(locale, "不提醒")
+	}
+	labels := make([]string, len(offsets))
+	for i, o := range offsets {
+		labels[i] = reminderLabel(locale, o)
+	}
+	return strings.Join(labels, translate(locale, "、"))
+}
+
+// taskReminderList 是任務自訂的提醒，沒有自訂時是「預設」
+func taskReminderList(locale string, t Task) string {
+	if !t.RemindersSet {
+		return translate(locale, "預設")
+	}
+	return reminderList(locale, t.Reminders)
+}
+
+// defaultReminders 是使用者的預設提醒
+func (u User) defaultReminders() []int {
+	if u.RemindersSet {
+		return u.Reminders
+	}
+	if reminderWindow <= 0 {
+		return nil
+	}
+	return []int{int(reminderWindow / time.Minute)}
+}
+
+// reminders 是任務實際使用的提醒時間，user 是負責人
+func (t Task) reminders(user User) []int {
+	if t.RemindersSet {
+		return t.Reminders
+	}
+	return user.defaultReminders()
+}
+
+type reminderOption struct {
+	Value   int
+	Label   string
+	Checked bool
+}
+
+// reminderOptions 是表單上的提醒勾選項，selected 裡不在 reminderPresets 的時間（例如 -remind-window 的值）也列出來
+func reminderOptions(locale string, selected []int) []reminderOption {
+	checked := make(map[int]bool, len(selected))
+	for _, o := range selected {
+		checked[o] = true
+	}
+	values := append([]int(nil), reminderPresets...)
+	for _, o := range selected {
+		if !containsInt(values, o) {
+			values = append(values, o)
+		}
+	}
+	sort.Ints(values)
+	options := make([]reminderOption, len(values))
+	for i, o := range values {
+		options[i] = reminderOption{o, reminderLabel(locale, o), checked[o]}
+	}
+	return options
+}
+
+// updateReminderDefaults 是設定頁的 action=reminders
+func (a *App) updateReminderDefaults(r *http.Request, username string) {
+	offsets, err := parseReminders(r.Form["reminder"])
+	if err != nil {
+		a.flashError(r, err, "")
+		return
+	}
+	_, err = a.store.ModifyUser(username, func(u *User) error {
+		u.Reminders = offsets
+		u.RemindersSet = true
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "更新預設提醒失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "預設提醒已儲存：%s", reminderList(a.requestLocale(r), offsets))
+}
+
+// reminderOwner 是決定任務預設提醒的使用者：負責人，未認領的專案任務沒有
+func (a *App) reminderOwner(t Task) User {
+	user, _ := a.store.GetUser(t.Username)
+	return user
+}
+
+// parseReminders 讀取表單勾選的提醒時間（欄位 reminder）
+func parseReminders(values []string) ([]int, error) {
+	offsets := make([]int, len(values))
+	for i, v := range values {
+		o, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, invalidInput("提醒時間不正確")
+		}
+		offsets[i] = o
+	}
+	return normalizeReminders(offsets)
+}
+
+// normalizeReminders 檢查提醒時間（到期前 0 分鐘到 30 天），由大到小排列並去掉重複
+func normalizeReminders(values []int) ([]int, error) {
+	offsets := []int{}
+	for _, o := range values {
+		if o < 0 || o > 30*24*60 {
+			return nil, invalidInput("提醒時間必須是到期前 0 分鐘到 30 天")
+		}
+		if !containsInt(offsets, o) {
+			offsets = append(offsets, o)
+		}
+	}
+	if len(offsets) > maxReminders {
+		return nil, invalidInput("每個任務最多 %d 個提醒", maxReminders)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	return offsets, nil
+}
+
+// dueReminder 回傳任務在 now 該送出的提醒（到期前幾分鐘）；沒有要送的時 ok 為 false。
+// 已經送過的提醒與比它更早的都跳過，同時到了好幾個時只送最接近到期的一個
+func dueReminder(t Task, offsets []int, now time.Time) (offset int, ok bool) {
+	if t.Completed || t.Username == "" {
+		return 0, false
+	}
+	sent := t.RemindedDue.Equal(t.DueAt)
+	for _, o := range offsets {
+		if sent && o >= t.RemindedOffset {
+			continue
+		}
+		if now.Before(t.DueAt.Add(-time.Duration(o) * time.Minute)) {
+			continue
+		}
+		// 到期時的提醒容許排程晚一點掃到，其他的過了到期時間就不送
+		if late := now.Sub(t.DueAt); late > 0 && (o > 0 || late > 2*reminderInterval) {
+			continue
+		}
+		if !ok || o < offset {
+			offset, ok = o, true
+		}
+	}
+	return offset, ok
+}
+
+func reminderBody(user User, tasks []Task, now time.Time) string {
+	prefs := user.DatePrefs()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s 你好，\n\n以下任務即將到期：\n\n", user.Username)
+	for _, t := range tasks {
+		fmt.Fprintf(&b, "・%s（%s，%s）\n", clipText(taskLabel(user, t), clipLine), prefs.ShortDue(t), remainingDueIn(prefs.Locale, t, now))
+		fmt.Fprintf(&b, "  查看：%s\n", taskURL(t.ID))
+		for _, name := range []string{"complete", "snooze"} {
+			if link := actionURL(name, t, reminderLinkTTL); link != "" {
+				fmt.Fprintf(&b, "  %s：%s\n", linkActions[name].Label, link)
+			}
+		}
+	}
+	b.WriteString("\n這封信由待辦清單自動寄出。\n")
+	return b.String()
+}
+
+// sendReminders 是排程工作：寄出提醒並記錄狀態。單一使用者寄送失敗不影響其他人
+func (a *App) sendReminders() error {
+	tasks, err := a.store.AllTasks()
+	if err != nil {
+		return err
+	}
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]User, len(users))
+	for _, u := range users {
+		byName[u.Username] = u
+	}
+	now := time.Now()
+	due := make(map[string][]Task)
+	offsets := make(map[int]int) // 任務編號 → 這次送出的提醒
+	for _, t := range tasks {
+		user, found := byName[t.Username]
+		if !found || !t.DeletedAt.IsZero() {
+			continue
+		}
+		if o, ok := dueReminder(t, t.reminders(user), now); ok {
+			due[t.Username] = append(due[t.Username], t)
+			offsets[t.ID] = o
+		}
+	}
+
+	var failed []string
+	for username, list := range due {
+		user := byName[username]
+		sort.Slice(list, func(i, j int) bool { return list[i].DueAt.Before(list[j].DueAt) })
+
+		if (user.DesktopNotify && notifyDesktop(user, list)) || a.pushReminder(user, list) {
+			if err := a.markReminded(list, offsets); err != nil {
+				return err
+			}
+			continue
+		}
+		if user.Email == "" {
+			continue // 沒有 Email 的帳號只能等分頁在線或有推播時提醒
+		}
+		subject := fmt.Sprintf("提醒：%d 個任務即將到期", len(list))
+		if len(list) == 1 {
+			subject = "提醒：「" + clipText(taskLabel(user, list[0]), clipSubject) + "」即將到期"
+		}
+		if err := mailer.Send(user.Email, subject, reminderBody(user, list, now)); err != nil {
+			log.Printf("寄送提醒給 %s 失敗：%v", username, err)
+			failed = append(failed, username)
+			continue
+		}
+		if err := a.markReminded(list, offsets); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("寄送提醒失敗：%s", strings.Join(failed, "、"))
+	}
+	return nil
+}
+
+// markReminded 記下 tasks 已經送出 offsets 裡的提醒
+func (a *App) markReminded(tasks []Task, offsets map[int]int) error {
+	for _, t := range tasks {
+		dueAt := t.DueAt
+		_, err := a.store.ModifyTask(t.ID, func(task *Task) error {
+			// 送出期間到期時間被改了就不標記，下次掃描依新的時間判斷
+			if task.DueAt.Equal(dueAt) {
+				task.RemindedDue = dueAt
+				task.RemindedOffset = offsets[t.ID]
+			}
+			return nil
+		})
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
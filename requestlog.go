@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// --- 請求紀錄與 panic 復原 ---
+//
+// 每個請求結束時寫一筆結構化的紀錄：方法、路徑、使用者、狀態碼、回應大小與花費時間，
+// -request-log 選 text（key=value，預設）、json（一行一個 JSON 物件，方便丟進 log 收集系統）或 off。
+// handler panic 時 withRecovery 把它變成 500 並記下 stack trace，連線不會被直接切斷；
+// 已經開始送出回應時只能記錄，無法再改狀態碼
+
+// requestLogFormat 是 -request-log 的值
+var requestLogFormat = "text"
+
+// requestLogger 由 setupRequestLog 依 -request-log 建立，nil 表示不記錄
+var requestLogger *slog.Logger
+
+func setupRequestLog(format string) error {
+	opts := &slog.HandlerOptions{}
+	switch format {
+	case "text":
+		requestLogger = slog.New(slog.NewTextHandler(os.Stderr, opts))
+	case "json":
+		requestLogger = slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	case "off":
+		requestLogger = nil
+	default:
+		return fmt.Errorf("未知的 -request-log %q（可用：text、json、off）", format)
+	}
+	return nil
+}
+
+// quietPaths 是監控系統定期抓取的路徑，不寫進請求紀錄
+var quietPaths = map[string]bool{"/healthz": true, "/metrics": true}
+
+// statusRecorder 記下 handler 寫出的狀態碼與位元組數；Flush 照樣轉給底層，/events 的串流才能運作
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withRequestLog 包在最外層，連逾時的 503 與 panic 轉成的 500 都會記到
+func (a *App) withRequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestLogger == nil || quietPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		user := a.getUsername(r) // 先取：登出之後 session 就不在了
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		if rec.status >= 500 {
+			level = slog.LevelError
+		}
+		requestLogger.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("user", user),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Duration("latency", time.Since(start)),
+		)
+	})
+}
+
+// withRecovery 把 handler 的 panic 變成 500；http.ErrAbortHandler 是 handler 故意中斷連線，照樣往上丟。
+// 放在 withTimeouts 裡面：TimeoutHandler 在另一個 goroutine 執行 handler，在那裡 recover 才拿得到原本的 stack
+func (a *App) withRecovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			logger := requestLogger
+			if logger == nil {
+				logger = slog.Default() // -request-log off 時 panic 仍然要記下來
+			}
+			logger.Error("handler panic",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("user", a.getUsername(r)),
+				slog.Any("panic", p),
+				slog.String("stack", string(debug.Stack())),
+			)
+			if rec.status != 0 {
+				return // 已經送出部分回應，來不及改成 500
+			}
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				writeAPIError(rec, http.StatusInternalServerError, "伺服器發生錯誤，請稍後再試")
+				return
+			}
+			http.Error(rec, "伺服器發生錯誤，請稍後再試", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
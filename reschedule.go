@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// --- 改期 ---
+//
+// 月曆上把任務拖到另一天時送到 /reschedule，只改日期，保留原本的時間
+
+// onDay 回傳 day 那天、時間與 due 相同的時間點
+func onDay(due, day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		due.Hour(), due.Minute(), 0, 0, due.Location())
+}
+
+func (a *App) rescheduleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	username := a.getUsername(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	day, err := time.ParseInLocation("2006-01-02", r.FormValue("date"), time.Local)
+	if err != nil {
+		err = ErrInvalidDueDate
+	} else {
+		err = checkDueAt(day)
+	}
+	if err != nil {
+		a.flashError(r, err, "")
+		redirectBack(w, r)
+		return
+	}
+
+	var moved bool
+	task, err := a.store.ModifyTask(id, func(t *Task) error {
+		if t.Username != username {
+			return ErrNotFound
+		}
+		due := onDay(t.DueAt, day)
+		moved = !due.Equal(t.DueAt)
+		before := *t
+		t.DueAt = due
+		t.recordEdit(username, before, time.Now())
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "改期失敗，請稍後再試")
+		redirectBack(w, r)
+		return
+	}
+	if moved {
+		a.flashSuccess(r, "已將「%s」改到 %s", brief(task.Description), a.requestDatePrefs(r).DateTime(task.DueAt))
+		a.warnConflicts(r, username, task)
+	}
+	redirectBack(w, r)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// --- 不中斷重新啟動 ---
+//
+// 更新執行檔後送 SIGHUP：先停止接受新連線並等進行中的請求結束，再以 exec 換成新的執行檔，
+// 同一個 listening socket 以 fd 交給新行程。排空與啟動期間新進的連線留在 kernel 的 backlog，
+// 不會被拒絕；session 已持久化，使用者不必重新登入。
+// 先排空再 exec，新舊行程不會同時寫入資料檔。
+// exec 直接換掉行程、不會回到 main，所以排空後要先停止排程並寫回延後寫入的資料（suspendForExec），
+// exec 失敗時再恢復（resumeAfterExec）。
+// SIGINT（Ctrl+C）與 SIGTERM 同樣先排空，之後 serve 正常返回，由 main 停止排程並寫回資料再結束
+
+const (
+	drainTimeout    = 30 * time.Second
+	inheritFDEnvVar = "TODO_LISTEN_FD" // 重新啟動時帶給新行程的 listener fd
+)
+
+// inheritedListener 取回上一個行程交接過來的 listener；一般啟動時回傳 nil
+func inheritedListener() (net.Listener, error) {
+	raw := os.Getenv(inheritFDEnvVar)
+	if raw == "" {
+		return nil, nil
+	}
+	os.Unsetenv(inheritFDEnvVar)
+	fd, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s 格式錯誤：%q", inheritFDEnvVar, raw)
+	}
+	f := os.NewFile(uintptr(fd), "inherited-socket")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// listenerFile 複製一份 listener 的 fd；原本的 listener 關閉後 socket 仍然開著
+func listenerFile(ln net.Listener) (*os.File, error) {
+	if ul, ok := ln.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false) // socket 檔要留給新行程
+	}
+	filer, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("不支援的 listener 類型 %T", ln)
+	}
+	return filer.File()
+}
+
+// serve 提供 HTTP 服務直到發生錯誤；收到重新啟動訊號時排空請求並 exec 新的執行檔，
+// exec 失敗時在同一個 socket 上繼續服務。收到結束訊號時排空請求後回傳 nil。
+// tlsConfig 不是 nil 時以 HTTPS 服務；交接給新行程的仍是原本的 socket，由新行程重新載入憑證
+func (a *App) serve(ln net.Listener, handler http.Handler, tlsConfig *tls.Config) error {
+	restartc := make(chan os.Signal, 1)
+	notifyRestart(restartc)
+	stopc := make(chan os.Signal, 1)
+	signal.Notify(stopc, os.Interrupt, syscall.SIGTERM)
+
+	for {
+		srv := &http.Server{Handler: handler, TLSConfig: tlsConfig}
+		srv.RegisterOnShutdown(events.DisconnectAll)
+		errc := make(chan error, 1)
+		go func() {
+			if tlsConfig != nil {
+				errc <- srv.ServeTLS(ln, "", "")
+			} else {
+				errc <- srv.Serve(ln)
+			}
+		}()
+
+		var f *os.File
+		for f == nil {
+			select {
+			case err := <-errc:
+				return err
+			case sig := <-stopc:
+				log.Printf("收到 %v，等待進行中的請求完成後結束", sig)
+				drain(srv)
+				<-errc
+				return nil
+			case <-restartc:
+			}
+			var err error
+			if f, err = listenerFile(ln); err != nil {
+				log.Printf("無法重新啟動：%v", err)
+			}
+		}
+
+		log.Println("重新啟動中：等待進行中的請求完成")
+		drain(srv)
+		<-errc
+
+		err := suspendForExec(a.store, scheduler)
+		if err == nil {
+			err = execSelf(f)
+		}
+		log.Printf("重新啟動失敗，繼續使用目前的版本：%v", err)
+		resumeAfterExec(a.store, scheduler)
+		ln, err = net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// suspender 是會延後寫入的儲存層（見 store_json.go 的 Suspend）
+type suspender interface {
+	Suspend() error
+	Resume()
+}
+
+// suspendForExec 停止排程並寫回延後寫入的異動；寫回失敗時不能 exec，否則這些異動就遺失了
+func suspendForExec(st Store, sched *jobScheduler) error {
+	sched.Stop()
+	if s, ok := st.(suspender); ok {
+		if err := s.Suspend(); err != nil {
+			return fmt.Errorf("寫回資料失敗：%w", err)
+		}
+	}
+	return nil
+}
+
+// resumeAfterExec 在 exec 失敗、繼續使用目前的版本時恢復排程與延後寫入
+func resumeAfterExec(st Store, sched *jobScheduler) {
+	if s, ok := st.(suspender); ok {
+		s.Resume()
+	}
+	sched.Resume()
+}
+
+// drain 停止接受新連線並等進行中的請求結束，最多等 drainTimeout
+func drain(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("排空逾時，仍有連線未結束：%v", err)
+	}
+}
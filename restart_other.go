@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// 其他平台（例如 Windows）沒有 exec，不支援不中斷重新啟動
+func notifyRestart(c chan<- os.Signal) {}
+
+func execSelf(f *os.File) error {
+	return errors.New("此平台不支援重新啟動")
+}
@@ -0,0 +1,31 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+func notifyRestart(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGHUP)
+}
+
+// execSelf 以磁碟上目前的執行檔取代自己（PID 不變，systemd 仍追蹤得到），
+// f 的 fd 會留給新行程使用
+func execSelf(f *os.File) error {
+	// os.Executable 在 Linux 會指向已被覆蓋掉的舊檔，要用啟動時的路徑重新找
+	path, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return err
+	}
+	fd := f.Fd()
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETFD, 0); errno != 0 {
+		return errno
+	}
+	env := append(os.Environ(), inheritFDEnvVar+"="+strconv.Itoa(int(fd)))
+	return syscall.Exec(path, os.Args, env)
+}
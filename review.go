@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- 「有空再做」回顧 ---
+//
+// 標上 someday 或「有空再做」的任務很容易一放就忘了。每月一日寄一次回顧信，
+// 列出放了 staleAfter 以上的任務，每個都附上「排入下週／繼續保留／不做了」的一鍵連結（見 actionlink.go）。
+// 沒有 Email 的使用者改用推播，點開後在 /review 處理。選擇保留會記下 ReviewedAt，之後重新計算
+
+const (
+	staleAfter    = 60 * 24 * time.Hour
+	reviewLinkTTL = 30 * 24 * time.Hour // 連結有效到下一封回顧信寄出為止
+)
+
+var somedayTags = []string{"someday", "有空再做"}
+
+// reviewActions 是回顧頁與信件連結共用的選項，依顯示順序排列
+var reviewActions = []string{"someday-schedule", "someday-keep", "someday-drop"}
+
+func isSomedayTag(tag string) bool {
+	for _, s := range somedayTags {
+		if strings.EqualFold(s, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Task) isSomeday() bool {
+	for _, tag := range t.Tags {
+		if isSomedayTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// staleItem 是一個待回顧的任務，Days 是距離建立或上次保留過了幾天
+type staleItem struct {
+	Task
+	Days int
+}
+
+// staleSomeday 挑出放太久的「有空再做」任務，放最久的排前面
+func staleSomeday(tasks []Task, now time.Time) []staleItem {
+	var result []staleItem
+	for _, t := range tasks {
+		if t.Completed || t.Archived || !t.isSomeday() {
+			continue
+		}
+		since := t.CreatedAt
+		if t.ReviewedAt.After(since) {
+			since = t.ReviewedAt
+		}
+		if age := now.Sub(since); age >= staleAfter {
+			result = append(result, staleItem{t, int(age.Hours() / 24)})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Days > result[j].Days })
+	return result
+}
+
+// scheduleSomeday 把任務排到一週後（保留原本的時間）並拿掉「有空再做」的標籤
+func scheduleSomeday(t *Task, now time.Time) {
+	t.DueAt = onDay(t.DueAt, now.AddDate(0, 0, 7))
+	var tags []string
+	for _, tag := range t.Tags {
+		if !isSomedayTag(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	t.Tags = tags
+}
+
+func keepSomeday(t *Task, now time.Time) {
+	t.ReviewedAt = now
+}
+
+func dropSomeday(t *Task, now time.Time) {
+	t.DeletedAt = now
+}
+
+func reviewBody(user User, items []staleItem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s 你好，\n\n以下「有空再做」的任務已經放了一段時間，要不要決定一下？\n", user.Username)
+	for _, item := range items {
+		fmt.Fprintf(&b, "\n・%s（放了 %d 天）\n", clipText(taskLabel(user, item.Task), clipLine), item.Days)
+		fmt.Fprintf(&b, "  查看：%s\n", taskURL(item.ID))
+		for _, name := range reviewActions {
+			if link := actionURL(name, item.Task, reviewLinkTTL); link != "" {
+				fmt.Fprintf(&b, "  %s：%s\n", linkActions[name].Label, link)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n也可以登入後到 %s/review 一次處理。\n\n這封信由待辦清單每月自動寄出。\n", publicBaseURL)
+	return b.String()
+}
+
+// sendSomedayReviews 是每月的排程工作；有 Email 的寄信，沒有的改用推播
+func (a *App) sendSomedayReviews() error {
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var failed []string
+	for _, user := range users {
+		tasks, err := a.store.ListTasks(user.Username)
+		if err != nil {
+			return err
+		}
+		var own []Task
+		for _, t := range tasks {
+			if t.Username == user.Username {
+				own = append(own, t) // 專案裡別人指派的任務不算在回顧裡
+			}
+		}
+		items := staleSomeday(own, now)
+		if len(items) == 0 {
+			continue
+		}
+		if user.Email == "" {
+			a.pushToUser(user, pushMessage{
+				Title: "🗂️ 有空再做的任務回顧",
+				Body:  fmt.Sprintf("有 %d 個任務放了兩個月以上，要排進行程、保留還是放棄？", len(items)),
+				Tag:   "someday-review",
+				URL:   "/review",
+			})
+			continue
+		}
+		subject := fmt.Sprintf("每月回顧：%d 個「有空再做」的任務等你決定", len(items))
+		if err := mailer.Send(user.Email, subject, reviewBody(user, items)); err != nil {
+			log.Printf("寄送回顧信給 %s 失敗：%v", user.Username, err)
+			failed = append(failed, user.Username)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("寄送回顧信失敗：%s", strings.Join(failed, "、"))
+	}
+	return nil
+}
+
+func (a *App) reviewHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	now := time.Now()
+
+	if r.Method == "POST" {
+		name := r.FormValue("action")
+		action, ok := linkActions[name]
+		if !ok || !strings.HasPrefix(name, "someday-") {
+			a.flashError(r, invalidInput("不支援的操作"), "")
+			redirectBack(w, r)
+			return
+		}
+		id, ok := a.requireFormID(w, r, "id")
+		if !ok {
+			return
+		}
+		task, err := a.store.ModifyTask(id, func(t *Task) error {
+			if t.Username != username {
+				return ErrNotFound
+			}
+			before := *t
+			action.Apply(t, now)
+			t.recordEdit(username, before, now)
+			return nil
+		})
+		switch {
+		case err != nil:
+			a.flashError(r, err, "更新任務失敗，請稍後再試")
+		case name == "someday-drop":
+			a.flashUndo(r, task.ID, "「%s」%s", brief(task.Description), a.tr(r, action.Done))
+		default:
+			a.flashSuccess(r, "「%s」%s", brief(task.Description), a.tr(r, action.Done))
+		}
+		redirectBack(w, r)
+		return
+	}
+
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	var own []Task
+	for _, t := range tasks {
+		if t.Username == username {
+			own = append(own, t)
+		}
+	}
+	var actions []map[string]string
+	for _, name := range reviewActions {
+		actions = append(actions, map[string]string{"Name": name, "Label": linkActions[name].Label})
+	}
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Items":     staleSomeday(own, now),
+		"Actions":   actions,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("review"))
+	t.Execute(w, data)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// --- 變更版本與輪詢 ---
+//
+// 每位使用者有一個版本號，看得到的任務（自己的、分享給自己的、所屬專案的）有變動就往前推。
+// 清單頁記下載入時的版本號，定期問 /api/v1/poll?rev=N，只有版本前進了才重新整理，
+// 閒置的分頁每次只花一個小小的 JSON 回應，不必每分鐘重新產生整頁。
+// 版本號從啟動時的毫秒時間開始遞增，重新啟動後不會和舊分頁手上的號碼撞在一起。
+// 永久刪除（DeleteTask、PurgeTrash）只影響垃圾桶，不推進版本
+
+type revisionLog struct {
+	mu     sync.Mutex
+	start  int64 // 啟動時的版本號，啟動後沒有變動過的使用者都是這個號碼
+	seq    int64
+	byUser map[string]int64
+}
+
+var revisions = newRevisionLog()
+
+func newRevisionLog() *revisionLog {
+	start := time.Now().UnixMilli()
+	return &revisionLog{start: start, seq: start, byUser: make(map[string]int64)}
+}
+
+// Of 是 username 目前的版本號
+func (l *revisionLog) Of(username string) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rev, ok := l.byUser[username]; ok {
+		return rev
+	}
+	return l.start
+}
+
+// bump 讓 usernames 的版本號前進；空字串（未認領的專案任務）略過
+func (l *revisionLog) bump(usernames ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	for _, name := range usernames {
+		if name != "" {
+			l.byUser[name] = l.seq
+		}
+	}
+}
+
+// revisionStore 包住實際的儲存層，任務與專案變動後推進相關使用者的版本號
+type revisionStore struct {
+	Store
+}
+
+func withRevisions(s Store) Store {
+	return &revisionStore{Store: s}
+}
+
+// watchers 是看得到 task 的使用者；專案任務是所有成員（私人任務只有負責人）
+func (s *revisionStore) watchers(task Task) []string {
+	names := append([]string{task.Username, task.CreatedBy}, task.SharedWith...)
+	if task.ProjectID != 0 && !task.Private {
+		if p, err := s.Store.GetProject(task.ProjectID); err == nil {
+			names = append(names, p.Members...)
+		}
+	}
+	return names
+}
+
+func (s *revisionStore) touched(tasks ...Task) {
+	var names []string
+	for _, task := range tasks {
+		names = append(names, s.watchers(task)...)
+	}
+	revisions.bump(names...)
+}
+
+func (s *revisionStore) CreateTask(task Task) (Task, error) {
+	task, err := s.Store.CreateTask(task)
+	if err == nil {
+		s.touched(task)
+	}
+	return task, err
+}
+
+func (s *revisionStore) CreateTasks(tasks []Task) ([]Task, error) {
+	tasks, err := s.Store.CreateTasks(tasks)
+	if err == nil {
+		s.touched(tasks...)
+	}
+	return tasks, err
+}
+
+func (s *revisionStore) UpdateTask(task Task) error {
+	before, _ := s.Store.GetTask(task.ID)
+	err := s.Store.UpdateTask(task)
+	if err == nil {
+		s.touched(before, task)
+	}
+	return err
+}
+
+// ModifyTask 記下修改前的任務，轉派或取消分享時原本看得到的人也要重新整理
+func (s *revisionStore) ModifyTask(id int, fn func(*Task) error) (Task, error) {
+	var before Task
+	task, err := s.Store.ModifyTask(id, func(t *Task) error {
+		before = *t
+		return fn(t)
+	})
+	if err == nil {
+		s.touched(before, task)
+	}
+	return task, err
+}
+
+func (s *revisionStore) ModifyTasks(ids []int, fn func(*Task) error) ([]Task, error) {
+	var before []Task
+	tasks, err := s.Store.ModifyTasks(ids, func(t *Task) error {
+		before = append(before, *t)
+		return fn(t)
+	})
+	if err == nil {
+		s.touched(append(before, tasks...)...)
+	}
+	return tasks, err
+}
+
+func (s *revisionStore) RestoreTask(id int) (Task, error) {
+	task, err := s.Store.RestoreTask(id)
+	if err == nil {
+		s.touched(task)
+	}
+	return task, err
+}
+
+// ModifyProject 推進修改前後所有成員的版本號，加入或退出專案的人看得到的任務都變了
+func (s *revisionStore) ModifyProject(id int, fn func(*Project) error) (Project, error) {
+	var before []string
+	p, err := s.Store.ModifyProject(id, func(p *Project) error {
+		before = append([]string(nil), p.Members...)
+		return fn(p)
+	})
+	if err == nil {
+		revisions.bump(append(before, p.Members...)...)
+	}
+	return p, err
+}
+
+// apiPollHandler：GET /api/v1/poll?rev=N 回傳目前的版本號，status 是 changed 或 unchanged
+func (a *App) apiPollHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	rev, err := strconv.ParseInt(r.URL.Query().Get("rev"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "rev 必須是整數")
+		return
+	}
+	current := revisions.Of(a.getUsername(r))
+	status := "unchanged"
+	if current != rev {
+		status = "changed"
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"rev": current, "status": status})
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// --- 背景排程 ---
+
+// job 是一個定期執行的背景工作，next 依目前時間算出下一次執行時間
+type job struct {
+	name string
+	next func(time.Time) time.Time
+	run  func() error
+
+	runMu   sync.Mutex // 同一個工作不會同時執行兩次（排程與手動觸發撞在一起時）
+	stopped bool       // 由 runMu 保護；Stop 之後不再執行
+
+	mu      sync.Mutex
+	running bool
+	lastRun time.Time
+	lastErr error
+	nextRun time.Time
+}
+
+// JobStatus 是工作狀態的快照，給管理主控台顯示
+type JobStatus struct {
+	Name    string
+	Running bool
+	LastRun time.Time // 尚未執行過為零值
+	LastErr string
+	NextRun time.Time
+}
+
+var (
+	ErrJobNotFound      = &DomainError{Code: "job_not_found", Message: "找不到排程工作", Status: http.StatusNotFound}
+	ErrSchedulerStopped = &DomainError{Code: "scheduler_stopped", Message: "伺服器正在關閉，排程工作已停止", Status: http.StatusServiceUnavailable}
+)
+
+type jobScheduler struct {
+	mu   sync.Mutex
+	jobs []*job
+}
+
+var scheduler = &jobScheduler{}
+
+// Add 登記一個工作；須在 Start 之前呼叫
+func (s *jobScheduler) Add(name string, next func(time.Time) time.Time, run func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, &job{name: name, next: next, run: run})
+}
+
+// Start 為每個工作啟動一個 goroutine，依各自的排程反覆執行
+func (s *jobScheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		go j.loop()
+	}
+}
+
+// Stop 等進行中的工作做完，之後不再執行任何工作；關閉伺服器時在寫回資料之前呼叫
+func (s *jobScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		j.runMu.Lock()
+		j.stopped = true
+		j.runMu.Unlock()
+	}
+}
+
+// Resume 讓 Stop 之後的工作恢復執行；重新啟動 exec 失敗、繼續使用目前的版本時呼叫
+func (s *jobScheduler) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		j.runMu.Lock()
+		j.stopped = false
+		j.runMu.Unlock()
+	}
+}
+
+func (j *job) loop() {
+	for {
+		next := j.next(time.Now())
+		j.mu.Lock()
+		j.nextRun = next
+		j.mu.Unlock()
+
+		time.Sleep(time.Until(next))
+		j.execute()
+	}
+}
+
+func (j *job) execute() error {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+	if j.stopped {
+		return ErrSchedulerStopped
+	}
+	j.mu.Lock()
+	j.running = true
+	j.mu.Unlock()
+
+	err := j.run()
+	if err != nil {
+		log.Printf("排程工作 %s 失敗：%v", j.name, err)
+	}
+	j.mu.Lock()
+	j.running = false
+	j.lastRun = time.Now()
+	j.lastErr = err
+	j.mu.Unlock()
+	return err
+}
+
+// Status 依登記順序回傳所有工作的狀態
+func (s *jobScheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		j.mu.Lock()
+		st := JobStatus{Name: j.name, Running: j.running, LastRun: j.lastRun, NextRun: j.nextRun}
+		if j.lastErr != nil {
+			st.LastErr = j.lastErr.Error()
+		}
+		j.mu.Unlock()
+		result = append(result, st)
+	}
+	return result
+}
+
+// RunNow 立即執行一次指定的工作並等它完成，不影響原本的排程
+func (s *jobScheduler) RunNow(name string) error {
+	s.mu.Lock()
+	var target *job
+	for _, j := range s.jobs {
+		if j.name == name {
+			target = j
+		}
+	}
+	s.mu.Unlock()
+	if target == nil {
+		return ErrJobNotFound
+	}
+	return target.execute()
+}
+
+// every 回傳固定間隔的排程函式
+func every(d time.Duration) func(time.Time) time.Time {
+	return func(now time.Time) time.Time { return now.Add(d) }
+}
+
+// nextHour 回傳 now 之後的下一個整點
+func nextHour(now time.Time) time.Time {
+	return now.Truncate(time.Hour).Add(time.Hour)
+}
+
+// nextMonth 回傳下個月一日早上 9 點，給每月一次、會寄信給使用者的工作
+func nextMonth(now time.Time) time.Time {
+	y, m, _ := now.Date()
+	return time.Date(y, m+1, 1, 9, 0, 0, 0, now.Location())
+}
+
+// nextMidnight 回傳 now 之後的下一個本地午夜
+func nextMidnight(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+}
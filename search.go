@@ -0,0 +1,393 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// --- 全文搜尋 ---
+//
+// 任務的描述、標籤與子項目內容建成記憶體內的反向索引，以字元的 unigram／bigram 為單位，
+// 中文不需要斷詞也能做子字串搜尋。索引由 indexedStore 包住儲存層，在任務新增、修改、刪除時同步更新。
+// 加密筆記伺服器看不到內容，不列入搜尋。
+// 任務延遲載入時（見 taskcache.go）啟動時不建索引，使用者第一次搜尋時才把他的任務加進來
+
+const maxSearchResults = 100
+
+type indexedDoc struct {
+	username string
+	text     string // 已轉小寫，用來確認候選結果
+	grams    []string
+
+	// 以下是原文，給輸入建議使用（見 suggest.go），不必再讀儲存層
+	description string
+	tags        []string
+	completed   bool
+}
+
+type searchIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[int]bool
+	docs     map[int]indexedDoc
+
+	// loader 不為 nil 時是延遲建立：loaded 記錄已建好索引的使用者，gen 在每次變動時加一
+	loader func(username string) ([]Task, error)
+	loaded map[string]bool
+	gen    int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{postings: make(map[string]map[int]bool), docs: make(map[int]indexedDoc)}
+}
+
+// foldRunes 逐字轉小寫；逐字轉換可以保持字元數不變，標示命中位置時對得上原文
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// textGrams 取出文字中所有不含空白的 unigram 與 bigram
+func textGrams(text string) []string {
+	runes := foldRunes(text)
+	seen := make(map[string]bool)
+	var grams []string
+	add := func(g string) {
+		if !seen[g] {
+			seen[g] = true
+			grams = append(grams, g)
+		}
+	}
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		add(string(r))
+		if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			add(string(runes[i : i+2]))
+		}
+	}
+	return grams
+}
+
+// termGrams 是查詢詞用來查索引的 gram：一個字查 unigram，其餘查所有 bigram
+func termGrams(term string) []string {
+	runes := []rune(term)
+	if len(runes) == 1 {
+		return []string{term}
+	}
+	grams := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+func searchTerms(query string) []string {
+	return strings.Fields(string(foldRunes(query)))
+}
+
+// searchableText 是任務中可被搜尋的文字
+func searchableText(t Task) string {
+	parts := []string{t.Description}
+	parts = append(parts, t.Tags...)
+	for _, item := range t.Checklist {
+		parts = append(parts, item.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (idx *searchIndex) put(t Task) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.gen++
+	idx.removeLocked(t.ID)
+	text := searchableText(t)
+	doc := indexedDoc{username: t.Username, text: string(foldRunes(text)), grams: textGrams(text),
+		description: t.Description, tags: t.Tags, completed: t.Completed}
+	for _, g := range doc.grams {
+		if idx.postings[g] == nil {
+			idx.postings[g] = make(map[int]bool)
+		}
+		idx.postings[g][t.ID] = true
+	}
+	idx.docs[t.ID] = doc
+}
+
+func (idx *searchIndex) remove(id int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.gen++
+	idx.removeLocked(id)
+}
+
+// ensureLoaded 在延遲建立時把 username 的任務加進索引；
+// 載入途中有任務變動時不標記為已載入，下次搜尋再重新載入一次
+func (idx *searchIndex) ensureLoaded(username string) error {
+	if idx.loader == nil {
+		return nil
+	}
+	idx.mu.RLock()
+	done, gen := idx.loaded[username], idx.gen
+	idx.mu.RUnlock()
+	if done {
+		return nil
+	}
+	tasks, err := idx.loader(username)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		idx.put(t)
+	}
+	idx.mu.Lock()
+	if idx.gen == gen+len(tasks) { // 期間只有上面的 put 讓 gen 前進
+		idx.loaded[username] = true
+	}
+	idx.mu.Unlock()
+	return nil
+}
+
+func (idx *searchIndex) removeLocked(id int) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, g := range doc.grams {
+		delete(idx.postings[g], id)
+		if len(idx.postings[g]) == 0 {
+			delete(idx.postings, g)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// Search 回傳 username 的任務中包含所有查詢詞的任務 ID
+func (idx *searchIndex) Search(username, query string) []int {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// 從最短的 posting list 開始交集，其餘 gram 逐一過濾
+	var grams []string
+	for _, term := range terms {
+		grams = append(grams, termGrams(term)...)
+	}
+	sort.Slice(grams, func(i, j int) bool { return len(idx.postings[grams[i]]) < len(idx.postings[grams[j]]) })
+
+	var ids []int
+	for id := range idx.postings[grams[0]] {
+		doc := idx.docs[id]
+		if doc.username != username {
+			continue
+		}
+		match := true
+		for _, g := range grams[1:] {
+			if !idx.postings[g][id] {
+				match = false
+				break
+			}
+		}
+		// gram 都有出現不代表相連，最後以子字串確認
+		for _, term := range terms {
+			if match && !strings.Contains(doc.text, term) {
+				match = false
+			}
+		}
+		if match {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// indexedStore 包住實際的儲存層，任務變動時同步更新搜尋索引
+type indexedStore struct {
+	Store
+	index *searchIndex
+}
+
+// withSearchIndex 以現有的任務建立索引並回傳包裝後的儲存層與索引；lazy 時改成第一次搜尋才建立
+func withSearchIndex(s Store, lazy bool) (Store, *searchIndex, error) {
+	idx := newSearchIndex()
+	if lazy {
+		idx.loader, idx.loaded = s.ListTasks, make(map[string]bool)
+	} else {
+		tasks, err := s.AllTasks()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, t := range tasks {
+			idx.put(t)
+		}
+	}
+	return &indexedStore{Store: s, index: idx}, idx, nil
+}
+
+func (s *indexedStore) CreateTask(task Task) (Task, error) {
+	task, err := s.Store.CreateTask(task)
+	if err == nil {
+		s.index.put(task)
+	}
+	return task, err
+}
+
+func (s *indexedStore) CreateTasks(tasks []Task) ([]Task, error) {
+	tasks, err := s.Store.CreateTasks(tasks)
+	if err == nil {
+		for _, task := range tasks {
+			s.index.put(task)
+		}
+	}
+	return tasks, err
+}
+
+func (s *indexedStore) UpdateTask(task Task) error {
+	err := s.Store.UpdateTask(task)
+	if err == nil {
+		s.index.put(task)
+	}
+	return err
+}
+
+// ModifyTask 也負責把移到垃圾桶的任務移出索引
+func (s *indexedStore) ModifyTask(id int, fn func(*Task) error) (Task, error) {
+	task, err := s.Store.ModifyTask(id, fn)
+	if err == nil {
+		if task.Trashed() {
+			s.index.remove(id)
+		} else {
+			s.index.put(task)
+		}
+	}
+	return task, err
+}
+
+func (s *indexedStore) ModifyTasks(ids []int, fn func(*Task) error) ([]Task, error) {
+	tasks, err := s.Store.ModifyTasks(ids, fn)
+	if err == nil {
+		for _, task := range tasks {
+			if task.Trashed() {
+				s.index.remove(task.ID)
+			} else {
+				s.index.put(task)
+			}
+		}
+	}
+	return tasks, err
+}
+
+func (s *indexedStore) RestoreTask(id int) (Task, error) {
+	task, err := s.Store.RestoreTask(id)
+	if err == nil {
+		s.index.put(task)
+	}
+	return task, err
+}
+
+func (s *indexedStore) DeleteTask(id int) error {
+	err := s.Store.DeleteTask(id)
+	if err == nil {
+		s.index.remove(id)
+	}
+	return err
+}
+
+// highlight 把 text 中符合查詢詞的部分包上 <mark>，其餘內容照常跳脫
+func highlight(text, query string) template.HTML {
+	runes := []rune(text)
+	folded := foldRunes(text)
+	marked := make([]bool, len(runes))
+	for _, term := range searchTerms(query) {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(folded); i++ {
+			if string(folded[i:i+len(t)]) == term {
+				for j := i; j < i+len(t); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			b.WriteString("<mark>" + segment + "</mark>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	return template.HTML(b.String())
+}
+
+func (a *App) searchHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	var results []Task
+	if query != "" {
+		if err := a.taskIndex.ensureLoaded(username); err != nil {
+			http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+			return
+		}
+		matched := make(map[int]bool)
+		for _, id := range a.taskIndex.Search(username, query) {
+			matched[id] = true
+		}
+		tasks, err := a.store.ListTasks(username)
+		if err != nil {
+			http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+			return
+		}
+		for _, t := range tasks {
+			if matched[t.ID] {
+				results = append(results, t)
+			}
+		}
+		smartSort(results, time.Now())
+	}
+	total := len(results)
+	if total > maxSearchResults {
+		results = results[:maxSearchResults]
+	}
+
+	funcMap := template.FuncMap{
+		"hl": func(text string) template.HTML { return highlight(text, query) },
+		"hasMatch": func(text string) bool {
+			for _, term := range searchTerms(query) {
+				if strings.Contains(string(foldRunes(text)), term) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	data := map[string]interface{}{
+		"Username":  username,
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Query":     query,
+		"Results":   results,
+		"Total":     total,
+		"Limited":   total > maxSearchResults,
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("search")).Funcs(funcMap)
+	t.Execute(w, data)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// --- 範例資料 ---
+//
+// -seed N 在空白的儲存層放入一組範例帳號、專案與任務，搭配 -store memory 就是不需要資料檔的展示模式，
+// 本機開發與測試也用同一份資料。內容只由 N 與「今天」決定：同一天用同一個 N 啟動，
+// 得到的任務、到期時間與完成狀態都一樣，換一個 N 就換一組。已經有使用者的資料不會放入範例資料
+
+// seedPassword 是範例帳號共用的密碼
+const seedPassword = "demo"
+
+// seedUsers 的第一位是管理員
+var seedUsers = []string{"demo", "alice", "bob"}
+
+var seedTasks = []struct {
+	Description string
+	Tags        []string
+}{
+	{"繳交期末專題報告", []string{"學校"}},
+	{"準備簡報投影片", []string{"學校", "專題"}},
+	{"回覆客戶的報價信", []string{"工作"}},
+	{"整理會議紀錄", []string{"工作"}},
+	{"買牛奶和雞蛋", []string{"生活"}},
+	{"繳電話費", []string{"生活"}},
+	{"跑步 5 公里", []string{"運動"}},
+	{"讀完《Go 程式設計》第三章", []string{"學習"}},
+	{"更新履歷", nil},
+	{"預約牙醫", []string{"生活"}},
+	{"複習資料結構期中考範圍", []string{"學校"}},
+	{"修好登入頁的錯字", []string{"工作", "專題"}},
+}
+
+var seedChecklist = []string{"列出大綱", "找參考資料", "寫初稿", "請同學校對"}
+
+// seedStore 在 s 放入 seed 對應的範例資料，now 決定到期時間的基準（取當天零點）
+func seedStore(s Store, seed int64, now time.Time) error {
+	rng := rand.New(rand.NewSource(seed))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for i, username := range seedUsers {
+		user := User{Username: username, PasswordHash: hashPassword(seedPassword), CreatedAt: today.AddDate(0, 0, -30+i)}
+		if i == 0 {
+			user.Role = RoleAdmin
+		}
+		if err := s.CreateUser(user); err != nil {
+			return err
+		}
+	}
+	project, err := s.CreateProject(Project{Name: "期末專題", Owner: seedUsers[0], Members: seedUsers})
+	if err != nil {
+		return err
+	}
+
+	priorities := []string{PriorityHigh, PriorityMedium, PriorityMedium, PriorityLow}
+	recurrences := []string{RecurNone, RecurNone, RecurNone, RecurNone, RecurDaily, RecurWeekly}
+	var tasks []Task
+	for _, username := range seedUsers {
+		for _, n := range rng.Perm(len(seedTasks))[:8] {
+			// 到期時間散在前 3 天到後 14 天之間的整點，大約三成已完成
+			due := today.AddDate(0, 0, rng.Intn(18)-3).Add(time.Duration(8+rng.Intn(12)) * time.Hour)
+			task := Task{
+				Description: seedTasks[n].Description,
+				CreatedAt:   today.AddDate(0, 0, -rng.Intn(7)-4),
+				DueAt:       due,
+				Username:    username,
+				Priority:    priorities[rng.Intn(len(priorities))],
+				Recurrence:  recurrences[rng.Intn(len(recurrences))],
+				Tags:        append([]string(nil), seedTasks[n].Tags...),
+			}
+			if rng.Intn(10) < 3 {
+				task.Completed = true
+				task.CompletedAt = due.Add(-time.Duration(1+rng.Intn(48)) * time.Hour)
+				if task.CompletedAt.After(now) {
+					task.CompletedAt = now // 還沒到期的任務不能在未來完成，否則連續紀錄會算錯
+				}
+				task.Status = StatusDone
+			}
+			if rng.Intn(4) == 0 {
+				for j, text := range seedChecklist {
+					task.Checklist = append(task.Checklist, ChecklistItem{ID: j + 1, Text: text, Done: j < rng.Intn(len(seedChecklist))})
+				}
+			}
+			tasks = append(tasks, task)
+		}
+	}
+	// 專案任務：前兩個已由成員認領，最後一個還沒有人認領
+	for i, desc := range []string{"設計資料庫結構", "實作月曆頁", "撰寫使用說明"} {
+		owner := ""
+		if i < len(seedUsers)-1 {
+			owner = seedUsers[i+1]
+		}
+		tasks = append(tasks, Task{
+			Description: desc,
+			CreatedAt:   today.AddDate(0, 0, -7),
+			DueAt:       today.AddDate(0, 0, 3+i*2).Add(18 * time.Hour),
+			Username:    owner,
+			ProjectID:   project.ID,
+			CreatedBy:   seedUsers[0],
+			Priority:    PriorityMedium,
+			Tags:        []string{"專題"},
+		})
+	}
+	_, err = s.CreateTasks(tasks)
+	return err
+}
+
+// seedIfEmpty 是 -seed 的進入點：儲存層已經有使用者時不做任何事，回傳 false
+func seedIfEmpty(s Store, seed int64) (bool, error) {
+	users, err := s.ListUsers()
+	if err != nil {
+		return false, err
+	}
+	if len(users) > 0 {
+		return false, nil
+	}
+	if err := seedStore(s, seed, time.Now()); err != nil {
+		return false, fmt.Errorf("放入範例資料失敗：%v", err)
+	}
+	return true, nil
+}
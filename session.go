@@ -0,0 +1,333 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --- Session 管理 ---
+//
+// Cookie 內是 32 bytes 的隨機 token；伺服器端（含持久化到 store 的資料）
+// 只存 token 的 SHA-256，資料檔外流也無法拿來冒用登入。
+// 每個 session 記下登入時的 User-Agent 與 IP 和最後使用時間，設定頁據此列出登入中的裝置（見 devices.go）
+
+const sessionCookieName = "session"
+
+// lastSeenInterval 是最後使用時間寫回 store 的間隔，避免每個請求都寫入
+const lastSeenInterval = 5 * time.Minute
+
+// maxUserAgent 是記下的 User-Agent 字數上限
+const maxUserAgent = 200
+
+// Session 是一個登入中的裝置；ID 是 cookie token 的雜湊
+type Session struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CSRFToken string    `json:"csrf_token"`
+	LastSeen  time.Time `json:"last_seen"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+
+	flashes []Flash // 只放在記憶體，不持久化
+
+	undo, redo []undoOp // 操作紀錄（見 undo.go），同樣只放在記憶體
+}
+
+type sessionManager struct {
+	store    Store // persist 時 session 寫在這裡
+	mu       sync.RWMutex
+	sessions map[string]*Session // key: Session.ID
+
+	ttl     time.Duration
+	secure  bool // cookie 加上 Secure，部署在 HTTPS 後方時開啟
+	persist bool // 是否寫入 store，重新啟動後仍維持登入
+}
+
+func newSessionManager(store Store, ttl time.Duration, secure, persist bool) *sessionManager {
+	return &sessionManager{
+		store:    store,
+		sessions: make(map[string]*Session),
+		ttl:      ttl,
+		secure:   secure,
+		persist:  persist,
+	}
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// load 從 store 載回尚未過期的 session
+func (m *sessionManager) load() error {
+	if !m.persist {
+		return nil
+	}
+	list, err := m.store.ListSessions()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range list {
+		s := list[i]
+		if now.Before(s.ExpiresAt) {
+			if s.CSRFToken == "" {
+				s.CSRFToken = randomToken(32) // 加入 CSRF 防護前存下的 session
+			}
+			if s.LastSeen.IsZero() {
+				s.LastSeen = s.CreatedAt // 記錄裝置資訊前存下的 session
+			}
+			m.sessions[s.ID] = &s
+		} else if err := m.store.DeleteSession(s.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *sessionManager) setCookie(w http.ResponseWriter, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// lookup 依 cookie 找出仍有效的 session，回傳的是複本
+func (m *sessionManager) lookup(r *http.Request) (Session, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return Session{}, false
+	}
+	id := hashSessionToken(cookie.Value)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[id]
+	if !ok || time.Now().After(s.ExpiresAt) {
+		return Session{}, false
+	}
+	return *s, true
+}
+
+// Start 建立新 session 並寫入 cookie，r 是登入的請求，用來記下裝置資訊
+func (m *sessionManager) Start(w http.ResponseWriter, r *http.Request, username string) {
+	token := randomToken(32)
+	now := time.Now()
+	s := &Session{
+		ID:        hashSessionToken(token),
+		Username:  username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.ttl),
+		CSRFToken: randomToken(32),
+		LastSeen:  now,
+		UserAgent: clipText(r.UserAgent(), maxUserAgent),
+		IP:        remoteHost(r),
+	}
+
+	m.mu.Lock()
+	m.sessions[s.ID] = s
+	m.mu.Unlock()
+	m.save(*s)
+	m.setCookie(w, token, s.ExpiresAt)
+}
+
+// End 移除目前的 session 並清除 cookie
+func (m *sessionManager) End(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		id := hashSessionToken(cookie.Value)
+		m.mu.Lock()
+		delete(m.sessions, id)
+		m.mu.Unlock()
+		if m.persist {
+			if err := m.store.DeleteSession(id); err != nil && err != ErrNotFound {
+				log.Printf("刪除 session 失敗：%v", err)
+			}
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Username 回傳目前登入的使用者，未登入或已過期時為空字串
+func (m *sessionManager) Username(r *http.Request) string {
+	s, ok := m.lookup(r)
+	if !ok {
+		return ""
+	}
+	return s.Username
+}
+
+// Touch 更新最後使用時間與 IP，並實作滑動過期：剩餘時間少於 TTL 的九成時才延長，
+// 避免每個請求都重寫 cookie 與 store；最後使用時間每 lastSeenInterval 才寫回 store 一次
+func (m *sessionManager) Touch(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return
+	}
+	id := hashSessionToken(cookie.Value)
+	now := time.Now()
+
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if !ok || now.After(s.ExpiresAt) {
+		m.mu.Unlock()
+		return
+	}
+	stale := now.Sub(s.LastSeen) >= lastSeenInterval
+	s.LastSeen = now
+	s.IP = remoteHost(r)
+	extend := s.ExpiresAt.Sub(now) <= m.ttl*9/10
+	if extend {
+		s.ExpiresAt = now.Add(m.ttl)
+	}
+	updated := *s
+	m.mu.Unlock()
+
+	if extend || stale {
+		m.save(updated)
+	}
+	if extend {
+		m.setCookie(w, cookie.Value, updated.ExpiresAt)
+	}
+}
+
+func (m *sessionManager) save(s Session) {
+	if !m.persist {
+		return
+	}
+	if err := m.store.SaveSession(s); err != nil {
+		log.Printf("儲存 session 失敗：%v", err)
+	}
+}
+
+// Purge 清掉已過期的 session，由排程定期呼叫
+func (m *sessionManager) Purge() error {
+	now := time.Now()
+	var expired []string
+
+	m.mu.Lock()
+	for id, s := range m.sessions {
+		if now.After(s.ExpiresAt) {
+			delete(m.sessions, id)
+			expired = append(expired, id)
+		}
+	}
+	m.mu.Unlock()
+
+	if !m.persist {
+		return nil
+	}
+	for _, id := range expired {
+		if err := m.store.DeleteSession(id); err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// EndUser 登出 username 在所有裝置上的 session，帳號被合併或刪除時使用
+func (m *sessionManager) EndUser(username string) {
+	m.endUser(username, "")
+}
+
+// EndOthers 登出 username 在其他裝置上的 session，保留 r 目前使用的這個，回傳登出了幾個
+func (m *sessionManager) EndOthers(r *http.Request, username string) int {
+	current, _ := m.lookup(r)
+	return m.endUser(username, current.ID)
+}
+
+// Active 是尚未過期的 session 總數，給 /metrics 用
+func (m *sessionManager) Active(now time.Time) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := 0
+	for _, s := range m.sessions {
+		if now.Before(s.ExpiresAt) {
+			n++
+		}
+	}
+	return n
+}
+
+// Count 回傳 username 尚未過期的 session 數量；不寫入 store 時資料用量頁以此為準
+func (m *sessionManager) Count(username string) int {
+	return len(m.userSessions(username))
+}
+
+// userSessions 回傳 username 尚未過期的 session，依建立時間排列
+func (m *sessionManager) userSessions(username string) []Session {
+	now := time.Now()
+	var list []Session
+	m.mu.RLock()
+	for _, s := range m.sessions {
+		if s.Username == username && !now.After(s.ExpiresAt) {
+			list = append(list, *s)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// EndOne 登出 username 的某個 session，id 不存在或不是 username 的時回傳 false
+func (m *sessionManager) EndOne(username, id string) bool {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if ok && s.Username == username {
+		delete(m.sessions, id)
+	}
+	m.mu.Unlock()
+	if !ok || s.Username != username {
+		return false
+	}
+	if m.persist {
+		if err := m.store.DeleteSession(id); err != nil && err != ErrNotFound {
+			log.Printf("刪除 session 失敗：%v", err)
+		}
+	}
+	return true
+}
+
+// endUser 移除 username 除了 keep 以外的所有 session
+func (m *sessionManager) endUser(username, keep string) int {
+	var ended []string
+	m.mu.Lock()
+	for id, s := range m.sessions {
+		if s.Username == username && id != keep {
+			delete(m.sessions, id)
+			ended = append(ended, id)
+		}
+	}
+	m.mu.Unlock()
+
+	if !m.persist {
+		return len(ended)
+	}
+	for _, id := range ended {
+		if err := m.store.DeleteSession(id); err != nil && err != ErrNotFound {
+			log.Printf("刪除 session 失敗：%v", err)
+		}
+	}
+	return len(ended)
+}
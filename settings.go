@@ -0,0 +1,285 @@
+package main
+
+import (
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 個人設定 ---
+
+func (a *App) settingsHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+
+	if r.Method == "POST" {
+		switch r.FormValue("action") {
+		case "email":
+			a.updateEmail(r, username)
+		case "digest":
+			a.updateDigest(r, username)
+		case "reminders":
+			a.updateReminderDefaults(r, username)
+		case "test-digest":
+			a.sendTestDigest(r, username)
+		case "datefmt":
+			a.updateDatePrefs(r, username)
+		case "taskids":
+			a.updateShowTaskIDs(r, username)
+		case "conflicts":
+			a.updateConflictLimits(r, username)
+		case "overdue":
+			a.updateOverduePolicy(r, username)
+		case "merge":
+			a.mergeOwnAccount(r, username)
+		case "purge":
+			a.purgeOwnCompleted(r, username)
+		case "sharelink":
+			a.createShareLink(r, username)
+		case "sharelink-revoke":
+			a.revokeShareLink(r, username)
+		case "apitoken":
+			a.createAPIToken(r, username)
+		case "apitoken-revoke":
+			a.revokeAPIToken(r, username)
+		case "session-revoke":
+			a.endDevice(r, username)
+		case "sessions-end-all":
+			a.endAllDevices(w, r, username)
+			http.Redirect(w, r, "/login?signedout=1", http.StatusSeeOther)
+			return
+		case "displayname":
+			a.updateDisplayName(r, username)
+		case "password":
+			a.changePassword(w, r, username)
+		case "oauth-unlink":
+			a.unlinkOAuth(r, username)
+		case "delete-account":
+			if a.deleteAccount(w, r, username) {
+				http.Redirect(w, r, "/login?deleted=1", http.StatusSeeOther)
+				return
+			}
+		}
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+
+	user, err := a.store.GetUser(username)
+	if err != nil {
+		http.Error(w, "讀取使用者失敗", http.StatusInternalServerError)
+		return
+	}
+	digestHour := user.DigestHour
+	if !user.DigestEnabled && user.DigestSentOn == "" && digestHour == 0 {
+		digestHour = defaultDigestHour // 還沒設定過時預設早上
+	}
+	hours := make([]int, 24)
+	for i := range hours {
+		hours[i] = i
+	}
+	conflictHour, conflictDay := user.conflictLimits()
+	limits := make([]int, maxConflictLimit)
+	for i := range limits {
+		limits[i] = i + 1
+	}
+	days := make([]int, maxDigestDays+1)
+	for i := range days {
+		days[i] = i
+	}
+
+	projects, _ := a.store.ListProjects(username)
+
+	// 清除舊任務的預覽：先以 GET 選日期看數量，確認後才送出 action=purge
+	purgeBefore, purgeCount, purgeError := r.URL.Query().Get("purge_before"), -1, ""
+	if purgeBefore != "" {
+		before, err := parsePurgeDate(purgeBefore)
+		if err == nil {
+			var old []Task
+			old, err = a.completedBefore(username, before)
+			purgeCount = len(old)
+		}
+		if err != nil {
+			purgeError = localMessage(a.requestLocale(r), err, "讀取任務失敗")
+		}
+	}
+
+	data := map[string]interface{}{
+		"Username":       username,
+		"User":           user,
+		"ShareLinks":     a.shareLinkViews(r, user),
+		"APITokens":      user.APITokens,
+		"MaxTokenName":   maxAPITokenName,
+		"Devices":        a.deviceViews(r, username),
+		"Reminders":      reminderOptions(a.requestLocale(r), user.defaultReminders()),
+		"Providers":      oauthProviders,
+		"Projects":       projects,
+		"MaxDisplayName": maxDisplayNameLength,
+		"PurgeBefore":    purgeBefore,
+		"PurgeCount":     purgeCount,
+		"PurgeError":     purgeError,
+		"DigestHour":     digestHour,
+		"Hours":          hours,
+		"Days":           days,
+		"Locales":        localeOptions,
+		"Limits":         limits,
+		"ConflictHour":   conflictHour,
+		"ConflictDay":    conflictDay,
+		"OverdueOptions": overdueOptions,
+		"Sample":         a.requestDatePrefs(r).DateTime(time.Now()),
+		"Nonce":          newNonce(username),
+		"CSRFToken":      a.sessions.CSRFToken(r),
+		"Flashes":        a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("settings"))
+	t.Execute(w, data)
+}
+
+func (a *App) updateEmail(r *http.Request, username string) {
+	raw := strings.TrimSpace(r.FormValue("email"))
+	email := ""
+	if raw != "" {
+		addr, err := mail.ParseAddress(raw)
+		if err != nil {
+			a.flashError(r, invalidInput("Email 格式不正確"), "")
+			return
+		}
+		email = addr.Address
+	}
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		u.Email = email
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "更新 Email 失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "Email 已更新")
+}
+
+func (a *App) updateDigest(r *http.Request, username string) {
+	hour, err1 := strconv.Atoi(r.FormValue("hour"))
+	days, err2 := strconv.Atoi(r.FormValue("days"))
+	if err1 != nil || err2 != nil || hour < 0 || hour > 23 || days < 0 || days > maxDigestDays {
+		a.flashError(r, invalidInput("摘要設定不正確"), "")
+		return
+	}
+	enabled := r.FormValue("enabled") == "on"
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		if enabled && u.Email == "" {
+			return invalidInput("請先設定 Email 才能開啟摘要信")
+		}
+		if hour != u.DigestHour && hour > time.Now().Hour() {
+			u.DigestSentOn = "" // 改到今天稍晚的時間時，今天會照新時間再寄一次
+		}
+		u.DigestEnabled = enabled
+		u.DigestHour = hour
+		u.DigestDays = days
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "更新摘要設定失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "摘要設定已儲存")
+}
+
+func (a *App) updateDatePrefs(r *http.Request, username string) {
+	locale := r.FormValue("locale")
+	if locale != "" && locale != LocaleZhTW && locale != LocaleEn {
+		a.flashError(r, invalidInput("不支援的語系"), "")
+		return
+	}
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		u.Locale = locale // 空字串是自動，依瀏覽器語言
+		u.Clock12 = r.FormValue("clock") == "12"
+		u.ROCYear = r.FormValue("roc_year") == "on"
+		u.WeekNumbers = r.FormValue("week_numbers") == "on"
+		u.WeekStart = time.Sunday
+		if r.FormValue("week_start") == "monday" {
+			u.WeekStart = time.Monday
+		}
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "更新語言與日期格式失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "語言與日期格式已更新")
+}
+
+func (a *App) updateShowTaskIDs(r *http.Request, username string) {
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		u.ShowTaskIDs = r.FormValue("show") == "on"
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "更新設定失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "任務編號設定已更新")
+}
+
+// updateConflictLimits 儲存排程衝突提醒的門檻，選「關閉」時存成 -1
+func (a *App) updateConflictLimits(r *http.Request, username string) {
+	hour, err1 := strconv.Atoi(r.FormValue("hour"))
+	day, err2 := strconv.Atoi(r.FormValue("day"))
+	if err1 != nil || err2 != nil || hour < 0 || hour > maxConflictLimit || day < 0 || day > maxConflictLimit {
+		a.flashError(r, invalidInput("衝突提醒設定不正確"), "")
+		return
+	}
+	if hour == 0 {
+		hour = -1
+	}
+	if day == 0 {
+		day = -1
+	}
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		u.ConflictHour = hour
+		u.ConflictDay = day
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "更新衝突提醒失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "衝突提醒設定已儲存")
+}
+
+// sendTestDigest 立刻寄一封摘要給自己，不影響排程的寄送狀態
+func (a *App) sendTestDigest(r *http.Request, username string) {
+	user, err := a.store.GetUser(username)
+	if err != nil {
+		a.flashError(r, err, "寄送測試信失敗")
+		return
+	}
+	if user.Email == "" {
+		a.flashError(r, invalidInput("請先設定 Email"), "")
+		return
+	}
+	if err := a.sendDigest(user, time.Now()); err != nil {
+		a.flashError(r, err, "寄送測試信失敗，請確認 SMTP 設定")
+		return
+	}
+	a.flashSuccess(r, "測試摘要已寄到 %s", user.Email)
+}
+
+// digestPreviewHandler 在瀏覽器顯示今天的摘要內容
+func (a *App) digestPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := a.store.GetUser(a.getUsername(r))
+	if err != nil {
+		http.Error(w, "讀取使用者失敗", http.StatusInternalServerError)
+		return
+	}
+	tasks, err := a.store.ListTasks(user.Username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	data := map[string]interface{}{
+		"Username": user.Username,
+		"Digest":   buildDigest(user, tasks, time.Now()),
+	}
+	t := a.localize(r, a.pages.page("digest-preview"))
+	t.Execute(w, data)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 唯讀分享連結 ---
+//
+// /shared/{token} 不用登入就能看到一份唯讀的清單：自己的未完成任務，或某個專案的任務，
+// 方便把截止日期傳給還沒註冊的同學。和行事曆訂閱一樣，token 只能讀取，以明碼存在使用者資料中
+// 方便在設定頁再次複製；外流時在設定頁撤銷即可。加密筆記與專案裡的私人任務不會出現在分享頁
+
+const maxShareLinks = 20
+
+// ShareLink 是一條唯讀分享連結；ProjectID 為 0 時分享自己的清單
+type ShareLink struct {
+	Token     string    `json:"token"`
+	ProjectID int       `json:"project_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// findShareLink 依 token 找出分享連結與建立的使用者
+func (a *App) findShareLink(token string) (User, ShareLink, bool) {
+	if token == "" {
+		return User{}, ShareLink{}, false
+	}
+	users, err := a.store.ListUsers()
+	if err != nil {
+		return User{}, ShareLink{}, false
+	}
+	for _, u := range users {
+		if u.Disabled {
+			continue
+		}
+		for _, link := range u.ShareLinks {
+			if subtle.ConstantTimeCompare([]byte(link.Token), []byte(token)) == 1 {
+				return u, link, true
+			}
+		}
+	}
+	return User{}, ShareLink{}, false
+}
+
+// shareLinkView 是設定頁上列出的分享連結
+type shareLinkView struct {
+	ShareLink
+	Name string
+	URL  string
+}
+
+// shareLinkViews 列出使用者的分享連結，已經不是成員的專案標示出來
+func (a *App) shareLinkViews(r *http.Request, user User) []shareLinkView {
+	var views []shareLinkView
+	for _, link := range user.ShareLinks {
+		v := shareLinkView{ShareLink: link, Name: a.tr(r, "我的清單"), URL: a.requestBaseURL(r) + "/shared/" + link.Token}
+		if link.ProjectID != 0 {
+			if p, err := a.loadMemberProject(link.ProjectID, user.Username); err == nil {
+				v.Name = a.tr(r, "專案：%s", p.Name)
+			} else {
+				v.Name = a.tr(r, "（已無法存取的專案）")
+			}
+		}
+		views = append(views, v)
+	}
+	return views
+}
+
+// createShareLink 是設定頁的 action=sharelink
+func (a *App) createShareLink(r *http.Request, username string) {
+	projectID, _ := strconv.Atoi(r.FormValue("project"))
+	if projectID != 0 {
+		if _, err := a.loadMemberProject(projectID, username); err != nil {
+			a.flashError(r, invalidInput("找不到這個專案"), "")
+			return
+		}
+	}
+	token := randomToken(24)
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		if len(u.ShareLinks) >= maxShareLinks {
+			return invalidInput("最多只能建立 %d 條分享連結，請先撤銷用不到的", maxShareLinks)
+		}
+		u.ShareLinks = append(u.ShareLinks, ShareLink{Token: token, ProjectID: projectID, CreatedAt: time.Now()})
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "建立分享連結失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "已建立唯讀分享連結，複製下方的網址傳給對方即可")
+}
+
+// revokeShareLink 是設定頁的 action=sharelink-revoke
+func (a *App) revokeShareLink(r *http.Request, username string) {
+	token := r.FormValue("token")
+	_, err := a.store.ModifyUser(username, func(u *User) error {
+		var kept []ShareLink
+		for _, link := range u.ShareLinks {
+			if link.Token != token {
+				kept = append(kept, link)
+			}
+		}
+		if len(kept) == len(u.ShareLinks) {
+			return ErrNotFound
+		}
+		u.ShareLinks = kept
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "撤銷分享連結失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "分享連結已撤銷，舊的網址不能再使用")
+}
+
+// sharedListHandler 是不需登入的 /shared/{token}
+func (a *App) sharedListHandler(w http.ResponseWriter, r *http.Request) {
+	owner, link, ok := a.findShareLink(strings.TrimPrefix(r.URL.Path, "/shared/"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	title := a.tr(r, "%s 的待辦清單", owner.Name())
+	var tasks []Task
+	if link.ProjectID != 0 {
+		// 建立連結的人離開專案後連結跟著失效
+		p, err := a.loadMemberProject(link.ProjectID, owner.Username)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		title = p.Name
+		all, err := a.projectTasks(p.ID, "")
+		if err != nil {
+			http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+			return
+		}
+		for _, t := range all {
+			if !t.Completed {
+				tasks = append(tasks, t)
+			}
+		}
+	} else {
+		all, err := a.store.ListTasks(owner.Username)
+		if err != nil {
+			http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+			return
+		}
+		for _, t := range withoutArchived(all) {
+			if !t.Completed && t.Username == owner.Username {
+				tasks = append(tasks, t)
+			}
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].DueAt.Before(tasks[j].DueAt) })
+
+	data := map[string]interface{}{
+		"Title":     title,
+		"Owner":     owner.Name(),
+		"IsProject": link.ProjectID != 0,
+		"Tasks":     tasks,
+	}
+	// 網址本身就是密碼：不讓搜尋引擎收錄，也不透過 Referer 帶到其他網站
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	// 日期照擁有者的格式，文字跟著看的人的語系
+	prefs := owner.DatePrefs()
+	prefs.Locale = a.requestLocale(r)
+	t := a.pages.page("shared").Funcs(prefs.Funcs()).Funcs(i18nFuncs(prefs.Locale))
+	t.Execute(w, data)
+}
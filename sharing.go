@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 分享任務 ---
+//
+// 擁有者可以把任務分享給其他已註冊的使用者，被分享的人在自己的清單裡看得到這個任務，
+// 並標示「由 X 分享」。被分享的人可以勾選完成、編輯內容與子項目，也可以退出分享；
+// 刪除、計時、倒數與再分享仍只有擁有者能做
+
+const maxSharedWith = 20
+
+// isSharedWith 回傳任務是否分享給 username
+func (t Task) isSharedWith(username string) bool {
+	return containsString(t.SharedWith, username)
+}
+
+// canEdit 回傳 username 能否修改任務內容：擁有者或被分享的人
+func (t Task) canEdit(username string) bool {
+	return t.Username == username || t.isSharedWith(username)
+}
+
+// sharedTasks 回傳其他人分享給 username、不在封存中的任務
+func (a *App) sharedTasks(username string) ([]Task, error) {
+	shared, err := a.store.ListSharedWith(username)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	for _, t := range shared {
+		if t.Username != username && !t.Archived {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+func sharedWithLabel(list []string) string {
+	if len(list) == 0 {
+		return "（無）"
+	}
+	return strings.Join(list, "、")
+}
+
+// shareHandler 處理分享對話框：action=add 與 remove 由擁有者操作，leave 由被分享的人退出
+func (a *App) shareHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	username := a.getUsername(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	other := strings.TrimSpace(r.FormValue("username"))
+
+	switch r.FormValue("action") {
+	case "add":
+		target, err := a.store.GetUser(other)
+		switch {
+		case other == "" || other == username:
+			a.flashError(r, invalidInput("請輸入要分享的使用者名稱"), "")
+		case err == ErrNotFound:
+			a.flashError(r, invalidInput("找不到使用者 %s", other), "")
+		case err != nil:
+			a.flashError(r, err, "分享失敗，請稍後再試")
+		default:
+			task, err := a.modifySharing(id, username, func(t *Task) error {
+				if t.isSharedWith(other) {
+					return invalidInput("已經分享給 %s", other)
+				}
+				if len(t.SharedWith) >= maxSharedWith {
+					return invalidInput("一個任務最多分享給 %d 人", maxSharedWith)
+				}
+				t.SharedWith = append(t.SharedWith, other)
+				return nil
+			})
+			if err != nil {
+				a.flashError(r, err, "分享失敗，請稍後再試")
+				break
+			}
+			a.pushToUser(target, pushMessage{
+				Title: "🤝 " + a.displayName(username) + " 分享了任務給你",
+				Body:  clipText(taskLabel(target, task), clipLine),
+				Tag:   "share-" + strconv.Itoa(task.ID),
+				URL:   taskPath(task.ID),
+			})
+			a.flashSuccess(r, "已分享給 %s", other)
+		}
+	case "remove":
+		_, err := a.modifySharing(id, username, func(t *Task) error {
+			t.SharedWith = removeString(t.SharedWith, other)
+			return nil
+		})
+		if err != nil {
+			a.flashError(r, err, "取消分享失敗，請稍後再試")
+		} else {
+			a.flashSuccess(r, "已取消分享給 %s", other)
+		}
+	case "leave":
+		_, err := a.store.ModifyTask(id, func(t *Task) error {
+			if !t.isSharedWith(username) {
+				return ErrNotFound
+			}
+			before := *t
+			t.SharedWith = removeString(t.SharedWith, username)
+			t.recordEdit(username, before, time.Now())
+			return nil
+		})
+		if err != nil {
+			a.flashError(r, err, "退出分享失敗，請稍後再試")
+		} else {
+			a.flashSuccess(r, "已退出分享，這個任務不會再出現在你的清單")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+	default:
+		a.flashError(r, invalidInput("不支援的操作"), "")
+	}
+	redirectBack(w, r)
+}
+
+// modifySharing 只允許擁有者修改分享對象，並把變更記進任務動態
+func (a *App) modifySharing(id int, username string, fn func(*Task) error) (Task, error) {
+	return a.store.ModifyTask(id, func(t *Task) error {
+		if t.Username != username {
+			return ErrNotFound
+		}
+		before := *t
+		before.SharedWith = append([]string(nil), t.SharedWith...)
+		if err := fn(t); err != nil {
+			return err
+		}
+		t.recordEdit(username, before, time.Now())
+		return nil
+	})
+}
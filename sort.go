@@ -0,0 +1,193 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- 排序 ---
+//
+// 清單頁可以選擇排序方式，選擇存在使用者資料裡（User.SortBy），每次打開清單都沿用。
+// 預設的「到期時間」是原本的 smartSort：逾期的排前面，其次依到期時間。
+// 「手動」依 Task.SortOrder 排列，用 ↑／↓ 按鈕或拖曳調整；SortOrder 為 0 的新任務排在最前面，
+// 第一次調整時把自己的任務依目前的順序重新編號。別人分享的任務與不是自己負責的專案任務不能調整，
+// 手動排序時排在自己的任務後面
+
+const (
+	SortDue      = "due"
+	SortCreated  = "created"
+	SortPriority = "priority"
+	SortAlpha    = "alpha"
+	SortManual   = "manual"
+)
+
+var sortOptions = []struct {
+	Value string
+	Label string
+}{
+	{SortDue, "到期時間"},
+	{SortCreated, "建立時間（新到舊）"},
+	{SortPriority, "優先順序"},
+	{SortAlpha, "名稱"},
+	{SortManual, "手動"},
+}
+
+func validSort(s string) bool {
+	for _, opt := range sortOptions {
+		if opt.Value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sortTasks 依 by 排序 username 清單上的任務；by 是空字串或不認得的值時等同 SortDue
+func sortTasks(tasks []Task, by, username string, now time.Time) {
+	smartSort(tasks, now)
+	switch by {
+	case SortCreated:
+		sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].CreatedAt.After(tasks[j].CreatedAt) })
+	case SortPriority:
+		sort.SliceStable(tasks, func(i, j int) bool {
+			return priorityRank(tasks[i].Priority) < priorityRank(tasks[j].Priority)
+		})
+	case SortAlpha:
+		sort.SliceStable(tasks, func(i, j int) bool {
+			return strings.ToLower(tasks[i].Description) < strings.ToLower(tasks[j].Description)
+		})
+	case SortManual:
+		sort.SliceStable(tasks, func(i, j int) bool {
+			iOwn, jOwn := tasks[i].Username == username, tasks[j].Username == username
+			if iOwn != jOwn {
+				return iOwn
+			}
+			return iOwn && tasks[i].SortOrder < tasks[j].SortOrder
+		})
+	}
+}
+
+// sortPreferenceHandler 儲存清單頁的排序方式
+func (a *App) sortPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	by := r.FormValue("sort")
+	if !validSort(by) {
+		a.flashError(r, invalidInput("不支援的排序方式"), "")
+		redirectBack(w, r)
+		return
+	}
+	_, err := a.store.ModifyUser(a.getUsername(r), func(u *User) error {
+		u.SortBy = by
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "更新排序方式失敗，請稍後再試")
+	}
+	redirectBack(w, r)
+}
+
+// reorderHandler 調整手動排序：direction 為 up／down 時與清單上（依 filter 篩選後）相鄰的任務交換位置，
+// 拖曳時以 before 指定放在哪個任務前面，before 為空代表放到最後
+func (a *App) reorderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := a.reorderTask(r, a.getUsername(r), time.Now()); err != nil {
+		a.flashError(r, err, "調整順序失敗，請稍後再試")
+	}
+	redirectBack(w, r)
+}
+
+func (a *App) reorderTask(r *http.Request, username string, now time.Time) error {
+	id, err := formID(r, "id")
+	if err != nil {
+		return err
+	}
+	user, err := a.store.GetUser(username)
+	if err != nil {
+		return err
+	}
+	all, err := a.store.ListTasks(username)
+	if err != nil {
+		return err
+	}
+	var own []Task
+	for _, t := range withoutArchived(all) {
+		if t.Username == username {
+			own = append(own, t)
+		}
+	}
+	sortTasks(own, SortManual, username, now)
+
+	from := positionOf(own, id)
+	if from < 0 {
+		return ErrNotFound
+	}
+	moving := own[from]
+	order := append(append([]Task(nil), own[:from]...), own[from+1:]...)
+
+	to := len(order)
+	switch r.FormValue("direction") {
+	case "up", "down":
+		visible := filterTasks(own, r.FormValue("filter"), now, user.WeekStart)
+		i := positionOf(visible, id)
+		if r.FormValue("direction") == "up" {
+			i--
+		} else {
+			i++
+		}
+		if i < 0 || i >= len(visible) {
+			return nil // 已經在最前面或最後面
+		}
+		to = positionOf(order, visible[i].ID)
+		if r.FormValue("direction") == "down" {
+			to++
+		}
+	default:
+		if before := r.FormValue("before"); before != "" {
+			beforeID, err := parseID(before)
+			if err != nil {
+				return err
+			}
+			if to = positionOf(order, beforeID); to < 0 {
+				return ErrNotFound
+			}
+		}
+	}
+	order = append(order[:to], append([]Task{moving}, order[to:]...)...)
+
+	positions := make(map[int]int)
+	var changed []int
+	for i, t := range order {
+		if t.SortOrder != i+1 {
+			positions[t.ID] = i + 1
+			changed = append(changed, t.ID)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	_, err = a.store.ModifyTasks(changed, func(t *Task) error {
+		if t.Username != username {
+			return ErrNotFound
+		}
+		t.SortOrder = positions[t.ID]
+		return nil
+	})
+	return err
+}
+
+// positionOf 回傳 id 在 tasks 裡的位置，找不到時回傳 -1
+func positionOf(tasks []Task, id int) int {
+	for i, t := range tasks {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package main
+
+// 以 go build -tags sqlite 編譯時才引入 cgo 的 SQLite 驅動，
+// 預設建置不需要 cgo
+import _ "github.com/mattn/go-sqlite3"
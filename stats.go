@@ -0,0 +1,266 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// --- 統計 API ---
+//
+// GET /api/v1/stats?from=2024-05-01&to=2024-05-31&interval=day|week|month
+// 回傳期間內每一段的新增、完成數與該段結束時的逾期數，以及各專案的彙總，
+// 給 Grafana 之類的儀表板使用。未指定時為最近 30 天、以天為單位。
+// tracked_minutes 是計時紀錄落在該段的分鐘數（見 timetrack.go），pomodoros 是完成的番茄鐘數（見 pomodoro.go）。
+// on_time、late 是該段完成的任務中在期限內與逾期才完成的數量，on_time_rate 是準時的比例（0～1），
+// 沒有完成的任務時為 0；以 CompletedAt 判斷，不知道完成時間的舊資料不列入
+
+const (
+	defaultStatsDays = 30
+	maxStatsBuckets  = 400
+)
+
+// statsBucket 是一段時間的統計；Overdue 是這段結束時已逾期且未完成的任務數
+type statsBucket struct {
+	Start          string `json:"start"`
+	Created        int    `json:"created"`
+	Completed      int    `json:"completed"`
+	Overdue        int    `json:"overdue"`
+	TrackedMinutes int    `json:"tracked_minutes"`
+	Pomodoros      int    `json:"pomodoros"`
+	completionRate
+}
+
+// statsTotals 是一組任務在期間內的彙總；Open 與 Overdue 是目前的狀態
+type statsTotals struct {
+	Created        int `json:"created"`
+	Completed      int `json:"completed"`
+	Open           int `json:"open"`
+	Overdue        int `json:"overdue"`
+	TrackedMinutes int `json:"tracked_minutes"`
+	Pomodoros      int `json:"pomodoros"`
+	completionRate
+}
+
+// completionRate 是準時與逾期完成的數量
+type completionRate struct {
+	OnTime     int     `json:"on_time"`
+	Late       int     `json:"late"`
+	OnTimeRate float64 `json:"on_time_rate"`
+}
+
+// count 把一個已完成的任務計入；不知道完成時間的不算
+func (c *completionRate) count(t Task) {
+	onTime, ok := t.completedOnTime()
+	switch {
+	case !ok:
+		return
+	case onTime:
+		c.OnTime++
+	default:
+		c.Late++
+	}
+	c.OnTimeRate = float64(c.OnTime) / float64(c.OnTime+c.Late)
+}
+
+// Percent 是準時比例的百分比，給統計頁顯示
+func (c completionRate) Percent() int {
+	return int(c.OnTimeRate*100 + 0.5)
+}
+
+// completedOnTime 回報已完成的任務是否在期限內完成；未完成或不知道完成時間時 ok 為 false
+func (t Task) completedOnTime() (onTime, ok bool) {
+	if !t.Completed || t.CompletedAt.IsZero() {
+		return false, false
+	}
+	return !t.CompletedAt.After(t.Deadline()), true
+}
+
+type projectStats struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	statsTotals
+}
+
+type statsResponse struct {
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	Interval string         `json:"interval"`
+	Totals   statsTotals    `json:"totals"`
+	Series   []statsBucket  `json:"series"`
+	Projects []projectStats `json:"projects"`
+}
+
+// statsBuckets 從 from 開始依 interval 切段直到涵蓋 to，回傳各段的起點，最後一個是結束點
+func statsBuckets(from, to time.Time, interval string) ([]time.Time, error) {
+	var step func(time.Time) time.Time
+	switch interval {
+	case "day":
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case "week":
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case "month":
+		step = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	default:
+		return nil, invalidInput("interval 必須是 day、week 或 month")
+	}
+	bounds := []time.Time{from}
+	for t := from; !t.After(to); {
+		t = step(t)
+		bounds = append(bounds, t)
+		if len(bounds) > maxStatsBuckets+1 {
+			return nil, invalidInput("期間太長，最多 %d 段，請改用較大的 interval", maxStatsBuckets)
+		}
+	}
+	return bounds, nil
+}
+
+// within 回報 t 是否落在 [start, end)；零值時間不算
+func within(t, start, end time.Time) bool {
+	return !t.IsZero() && !t.Before(start) && t.Before(end)
+}
+
+// overdueAt 回報任務在 at 這個時間點是否已逾期且尚未完成
+func overdueAt(t Task, at time.Time) bool {
+	if t.CreatedAt.After(at) || !t.Deadline().Before(at) {
+		return false
+	}
+	return !t.Completed || t.CompletedAt.After(at)
+}
+
+func summarize(tasks []Task, from, end, now time.Time) statsTotals {
+	var s statsTotals
+	var tracked time.Duration
+	for _, t := range tasks {
+		tracked += t.trackedBetween(from, end, now)
+		s.Pomodoros += t.pomodorosBetween(from, end)
+		if within(t.CreatedAt, from, end) {
+			s.Created++
+		}
+		if t.Completed && within(t.CompletedAt, from, end) {
+			s.Completed++
+			s.count(t)
+		}
+		if !t.Completed {
+			s.Open++
+			if t.OverdueAt(now) {
+				s.Overdue++
+			}
+		}
+	}
+	s.TrackedMinutes = int(tracked / time.Minute)
+	return s
+}
+
+func (a *App) apiStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	username := a.getUsername(r)
+	now := time.Now()
+	q := r.URL.Query()
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	to, from := today, today.AddDate(0, 0, 1-defaultStatsDays)
+	var err error
+	if v := q.Get("to"); v != "" {
+		if to, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "to 必須是 YYYY-MM-DD")
+			return
+		}
+		from = to.AddDate(0, 0, 1-defaultStatsDays)
+	}
+	if v := q.Get("from"); v != "" {
+		if from, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "from 必須是 YYYY-MM-DD")
+			return
+		}
+	}
+	if from.After(to) {
+		writeAPIError(w, http.StatusBadRequest, "from 不可晚於 to")
+		return
+	}
+	interval := q.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	bounds, err := statsBuckets(from, to, interval)
+	if err != nil {
+		writeDomainError(w, err, "")
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "讀取任務失敗")
+		return
+	}
+	var own []Task
+	for _, t := range tasks {
+		if t.Username == username {
+			own = append(own, t)
+		}
+	}
+
+	resp := statsResponse{
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Interval: interval,
+		Totals:   summarize(own, from, end, now),
+		Series:   []statsBucket{},
+		Projects: []projectStats{},
+	}
+	for i := 0; i+1 < len(bounds); i++ {
+		start, stop := bounds[i], bounds[i+1]
+		if stop.After(end) {
+			stop = end
+		}
+		b := statsBucket{Start: start.Format("2006-01-02")}
+		var tracked time.Duration
+		for _, t := range own {
+			tracked += t.trackedBetween(start, stop, now)
+			b.Pomodoros += t.pomodorosBetween(start, stop)
+			if within(t.CreatedAt, start, stop) {
+				b.Created++
+			}
+			if t.Completed && within(t.CompletedAt, start, stop) {
+				b.Completed++
+				b.count(t)
+			}
+			if overdueAt(t, stop) {
+				b.Overdue++
+			}
+		}
+		b.TrackedMinutes = int(tracked / time.Minute)
+		resp.Series = append(resp.Series, b)
+	}
+
+	// 專案彙總包含成員看得到的所有專案任務，不只指派給自己的
+	projects, err := a.store.ListProjects(username)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "讀取專案失敗")
+		return
+	}
+	if len(projects) > 0 {
+		all, err := a.store.AllTasks()
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "讀取任務失敗")
+			return
+		}
+		byProject := make(map[int][]Task)
+		for _, t := range all {
+			if t.ProjectID != 0 && t.VisibleTo(username) {
+				byProject[t.ProjectID] = append(byProject[t.ProjectID], t)
+			}
+		}
+		for _, p := range projects {
+			resp.Projects = append(resp.Projects, projectStats{
+				ID:          p.ID,
+				Name:        p.Name,
+				statsTotals: summarize(byProject[p.ID], from, end, now),
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// --- 儲存層介面 ---
+//
+// 所有實作都必須可同時被多個 goroutine 呼叫（每個 HTTP 請求各一個）
+
+var (
+	ErrNotFound   = &DomainError{Code: "not_found", Message: "找不到資料", Status: http.StatusNotFound}
+	ErrUserExists = &DomainError{Code: "user_exists", Message: "使用者名稱已存在", Status: http.StatusConflict}
+)
+
+// UserStore 負責使用者帳號的存取
+type UserStore interface {
+	GetUser(username string) (User, error)
+	ListUsers() ([]User, error)
+	CreateUser(user User) error
+	// RegisterUser 和 CreateUser 一樣，但還沒有任何使用者時以 RoleAdmin 建立並回傳存入的使用者；
+	// 判斷與新增在同一個鎖（或交易）內，同時註冊的兩個人只會有一位成為管理員
+	RegisterUser(user User) (User, error)
+	UpdateUser(user User) error
+	// ModifyUser 和 ModifyTask 一樣在同一個鎖（或交易）內讀出、修改並寫回使用者，fn 回傳錯誤則不寫入；
+	// 改使用者設定一律用它，避免兩個請求各自「GetUser -> 修改 -> UpdateUser」時互相覆蓋
+	ModifyUser(username string, fn func(*User) error) (User, error)
+	DeleteUser(username string) error
+}
+
+// TaskStore 負責任務的存取，CreateTask 會配發新的 ID 並回傳完整任務。
+// ModifyTask 在同一個鎖（或交易）內讀出、修改並寫回任務，
+// 避免兩個請求同時「讀取 -> 修改 -> UpdateTask」時互相覆蓋；fn 回傳錯誤則不寫入。
+// fn 執行時持有儲存層的鎖，不可在 fn 內再呼叫 store 的方法。
+// ModifyTasks 對多個任務做同樣的事，全部成功才一起寫入，任一個找不到或 fn 回傳錯誤就全部不寫。
+// CreateTasks 一次新增多個任務，同樣全部成功或全部不寫。
+//
+// 在垃圾桶裡的任務（DeletedAt 不為零值）對 GetTask、ListTasks、AllTasks、UpdateTask、
+// ModifyTask 而言等同不存在，只能透過 ListTrash、RestoreTask 存取；
+// 用 ModifyTask 設定 DeletedAt 即移到垃圾桶。DeleteTask 與 PurgeTrash 是永久刪除
+type TaskStore interface {
+	GetTask(id int) (Task, error)
+	ListTasks(username string) ([]Task, error)
+	// ListSharedWith 回傳 SharedWith 含 username、不在垃圾桶的任務，由儲存層的索引查出，不必掃過所有任務
+	ListSharedWith(username string) ([]Task, error)
+	// ListBlockedBy 回傳 BlockedBy 含 id、不在垃圾桶的任務，也就是在等 id 完成的任務
+	ListBlockedBy(id int) ([]Task, error)
+	AllTasks() ([]Task, error)
+	CreateTask(task Task) (Task, error)
+	CreateTasks(tasks []Task) ([]Task, error)
+	UpdateTask(task Task) error
+	ModifyTask(id int, fn func(*Task) error) (Task, error)
+	ModifyTasks(ids []int, fn func(*Task) error) ([]Task, error)
+	DeleteTask(id int) error
+
+	ListTrash(username string) ([]Task, error)
+	RestoreTask(id int) (Task, error)
+	PurgeTrash(before time.Time) (int, error)
+}
+
+// AnnouncementStore 負責公告的存取，各成員的副本仍是一般 Task
+type AnnouncementStore interface {
+	GetAnnouncement(id int) (Announcement, error)
+	ListAnnouncements() ([]Announcement, error)
+	CreateAnnouncement(a Announcement) (Announcement, error)
+}
+
+// ProjectStore 負責共享專案的存取，ListProjects 只回傳 username 參與的專案
+type ProjectStore interface {
+	GetProject(id int) (Project, error)
+	ListProjects(username string) ([]Project, error)
+	CreateProject(p Project) (Project, error)
+	ModifyProject(id int, fn func(*Project) error) (Project, error)
+}
+
+// SessionStore 保存登入中的 session，SaveSession 以 ID 新增或覆寫
+type SessionStore interface {
+	ListSessions() ([]Session, error)
+	SaveSession(s Session) error
+	DeleteSession(id string) error
+}
+
+// UsageStore 彙總單一帳號佔用的資料量（見 datausage.go），
+// 由儲存層自己計算，不必把所有任務讀出來再逐一加總
+type UsageStore interface {
+	DataUsage(username string) (DataUsage, error)
+}
+
+type Store interface {
+	UserStore
+	TaskStore
+	AnnouncementStore
+	ProjectStore
+	SessionStore
+	UsageStore
+	Close() error
+}
+
+// openStore 依啟動參數選擇儲存後端
+func openStore(kind, path string) (Store, error) {
+	switch kind {
+	case "json":
+		s, err := openJSONStore(path)
+		if err != nil {
+			return nil, err
+		}
+		s.startFlusher(flushInterval)
+		return s, nil
+	case "sqlite":
+		return openSQLiteStore(path)
+	case "eventlog":
+		return openEventStore(path)
+	case "memory":
+		return newMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("未知的儲存後端 %q（可用：json、sqlite、eventlog、memory）", kind)
+	}
+}
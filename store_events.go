@@ -0,0 +1,566 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- 事件紀錄儲存 ---
+//
+// eventStore 不在每次異動後重寫整份資料，而是把變更後的內容寫成一筆事件附加到 <path>.log。
+// 啟動時讀取快照（path，格式與 JSON 儲存相同，可直接沿用舊的資料檔）再依序套用 log。
+// log 累積 compactEvery 筆後把目前狀態寫成新的快照，舊的 log 改名為 <path>.log.<最後序號> 保留下來，
+// 同步、復原與稽核功能可以透過 Events 從頭讀取完整的變更紀錄。
+//
+// 記憶體中的狀態與所有查詢沿用 jsonStore（不自行存檔），這裡只覆寫會異動資料的方法
+
+// compactEvery 是 log 累積多少筆後寫一次快照
+const compactEvery = 1000
+
+type eventStore struct {
+	*jsonStore
+
+	wmu      sync.Mutex // 異動與寫入事件時一起持有，讓 log 的順序與記憶體中的變更順序一致
+	snapshot string
+	logFile  *os.File
+	seq      int64 // 最後一筆事件的序號
+	pending  int   // 目前 log 中的筆數
+}
+
+// storeEvent 是一項變更：*.put 的 Data 是變更後的完整資料，*.delete 的 Key 是被刪除的 ID
+type storeEvent struct {
+	Type string          `json:"type"`
+	Key  string          `json:"key,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// eventEntry 是 log 中的一行；同一次操作的多項變更放在同一行，重播時一起套用，
+// 寫到一半當機留下的殘缺行會整行忽略
+type eventEntry struct {
+	Seq    int64        `json:"seq"`
+	Time   time.Time    `json:"time"`
+	Events []storeEvent `json:"events"`
+}
+
+func putEvent(kind string, v interface{}) storeEvent {
+	data, _ := json.Marshal(v)
+	return storeEvent{Type: kind + ".put", Data: data}
+}
+
+func deleteEvent(kind, key string) storeEvent {
+	return storeEvent{Type: kind + ".delete", Key: key}
+}
+
+func openEventStore(path string) (*eventStore, error) {
+	mem, err := openJSONStore(path)
+	if err != nil {
+		return nil, err
+	}
+	mem.path = "" // 只放在記憶體，持久化由 log 負責
+	s := &eventStore{jsonStore: mem, snapshot: path, seq: mem.data.EventSeq}
+
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	s.logFile, err = os.OpenFile(s.logPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *eventStore) logPath() string {
+	return s.snapshot + ".log"
+}
+
+// replay 把快照之後的事件套用到記憶體；序號不大於快照的事件已經包含在快照裡
+func (s *eventStore) replay() error {
+	raw, err := os.ReadFile(s.logPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	lines := bytes.Split(raw, []byte("\n"))
+	offset, valid := 0, 0 // valid 是最後一筆完整事件結束的位置
+	torn := false
+	for i, line := range lines {
+		offset += len(line) + 1
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry eventEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if i == len(lines)-1 {
+				torn = true // 最後一行沒寫完就當機，丟掉即可
+				break
+			}
+			return fmt.Errorf("%s 第 %d 行損毀：%v", s.logPath(), i+1, err)
+		}
+		valid = offset
+		s.pending++
+		if entry.Seq <= s.seq {
+			continue
+		}
+		for _, ev := range entry.Events {
+			if err := s.apply(ev); err != nil {
+				return fmt.Errorf("%s 第 %d 行：%v", s.logPath(), i+1, err)
+			}
+		}
+		s.seq = entry.Seq
+	}
+	if torn {
+		if err := os.Truncate(s.logPath(), int64(valid)); err != nil {
+			return err
+		}
+	}
+	s.data.EventSeq = s.seq
+	s.reindex()
+	return nil
+}
+
+// apply 把一項變更套用到記憶體中的資料，呼叫後需要 reindex
+func (s *eventStore) apply(ev storeEvent) error {
+	d := s.data
+	switch ev.Type {
+	case "user.put":
+		var u User
+		if err := json.Unmarshal(ev.Data, &u); err != nil {
+			return err
+		}
+		for i := range d.Users {
+			if d.Users[i].Username == u.Username {
+				d.Users[i] = u
+				return nil
+			}
+		}
+		d.Users = append(d.Users, u)
+	case "user.delete":
+		for i := range d.Users {
+			if d.Users[i].Username == ev.Key {
+				d.Users = append(d.Users[:i], d.Users[i+1:]...)
+				break
+			}
+		}
+	case "task.put":
+		var t Task
+		if err := json.Unmarshal(ev.Data, &t); err != nil {
+			return err
+		}
+		if t.ID >= d.NextID {
+			d.NextID = t.ID + 1
+		}
+		for i := range d.Tasks {
+			if d.Tasks[i].ID == t.ID {
+				d.Tasks[i] = t
+				return nil
+			}
+		}
+		d.Tasks = append(d.Tasks, t)
+	case "task.delete":
+		id, _ := strconv.Atoi(ev.Key)
+		for i := range d.Tasks {
+			if d.Tasks[i].ID == id {
+				d.Tasks = append(d.Tasks[:i], d.Tasks[i+1:]...)
+				break
+			}
+		}
+	case "announcement.put":
+		var a Announcement
+		if err := json.Unmarshal(ev.Data, &a); err != nil {
+			return err
+		}
+		if a.ID >= d.NextAnnouncementID {
+			d.NextAnnouncementID = a.ID + 1
+		}
+		for i := range d.Announcements {
+			if d.Announcements[i].ID == a.ID {
+				d.Announcements[i] = a
+				return nil
+			}
+		}
+		d.Announcements = append(d.Announcements, a)
+	case "project.put":
+		var p Project
+		if err := json.Unmarshal(ev.Data, &p); err != nil {
+			return err
+		}
+		if p.ID >= d.NextProjectID {
+			d.NextProjectID = p.ID + 1
+		}
+		for i := range d.Projects {
+			if d.Projects[i].ID == p.ID {
+				d.Projects[i] = p
+				return nil
+			}
+		}
+		d.Projects = append(d.Projects, p)
+	case "session.put":
+		var sess Session
+		if err := json.Unmarshal(ev.Data, &sess); err != nil {
+			return err
+		}
+		for i := range d.Sessions {
+			if d.Sessions[i].ID == sess.ID {
+				d.Sessions[i] = sess
+				return nil
+			}
+		}
+		d.Sessions = append(d.Sessions, sess)
+	case "session.delete":
+		for i := range d.Sessions {
+			if d.Sessions[i].ID == ev.Key {
+				d.Sessions = append(d.Sessions[:i], d.Sessions[i+1:]...)
+				break
+			}
+		}
+	default:
+		return fmt.Errorf("未知的事件 %q", ev.Type)
+	}
+	return nil
+}
+
+// record 把一次操作的變更寫進 log，呼叫時須持有 wmu。
+// 記憶體已經先改好了，寫入失敗時與 JSON 儲存存檔失敗一樣回傳錯誤
+func (s *eventStore) record(events ...storeEvent) error {
+	entry := eventEntry{Seq: s.seq + 1, Time: time.Now(), Events: events}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := s.logFile.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := s.logFile.Sync(); err != nil {
+		return err
+	}
+	s.seq = entry.Seq
+	s.pending++
+	if s.pending >= compactEvery {
+		if err := s.compact(); err != nil {
+			log.Printf("寫入快照失敗，下次異動時再試：%v", err)
+		}
+	}
+	return nil
+}
+
+// compact 把目前狀態寫成快照，再把 log 換成新的空檔；呼叫時須持有 wmu。
+// 快照先寫到暫存檔再改名，任何一步當機重新啟動後都能從快照與 log 還原
+func (s *eventStore) compact() error {
+	s.mu.Lock()
+	s.data.EventSeq = s.seq
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := s.snapshot + ".tmp"
+	if err := writeFileSync(tmp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.snapshot); err != nil {
+		return err
+	}
+
+	if err := s.logFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(s.logPath(), fmt.Sprintf("%s.%d", s.logPath(), s.seq)); err != nil {
+		return err
+	}
+	s.logFile, err = os.OpenFile(s.logPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.pending = 0
+	return nil
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Events 依序回傳序號大於 since 的所有事件，包含已經壓縮進快照的舊 log
+func (s *eventStore) Events(since int64) ([]eventEntry, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	segments, err := filepath.Glob(s.logPath() + ".*")
+	if err != nil {
+		return nil, err
+	}
+	lastSeq := func(name string) int64 {
+		n, _ := strconv.ParseInt(strings.TrimPrefix(name, s.logPath()+"."), 10, 64)
+		return n
+	}
+	sort.Slice(segments, func(i, j int) bool { return lastSeq(segments[i]) < lastSeq(segments[j]) })
+
+	var entries []eventEntry
+	for _, name := range append(segments, s.logPath()) {
+		if name != s.logPath() && lastSeq(name) <= since {
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var entry eventEntry
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Seq > since {
+				entries = append(entries, entry)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func (s *eventStore) Close() error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if s.pending > 0 {
+		if err := s.compact(); err != nil {
+			s.logFile.Close()
+			return err
+		}
+	}
+	return s.logFile.Close()
+}
+
+func (s *eventStore) CreateUser(user User) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if err := s.jsonStore.CreateUser(user); err != nil {
+		return err
+	}
+	return s.record(putEvent("user", user))
+}
+
+func (s *eventStore) RegisterUser(user User) (User, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	user, err := s.jsonStore.RegisterUser(user)
+	if err != nil {
+		return User{}, err
+	}
+	return user, s.record(putEvent("user", user))
+}
+
+func (s *eventStore) UpdateUser(user User) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if err := s.jsonStore.UpdateUser(user); err != nil {
+		return err
+	}
+	return s.record(putEvent("user", user))
+}
+
+func (s *eventStore) ModifyUser(username string, fn func(*User) error) (User, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	user, err := s.jsonStore.ModifyUser(username, fn)
+	if err != nil {
+		return User{}, err
+	}
+	return user, s.record(putEvent("user", user))
+}
+
+func (s *eventStore) DeleteUser(username string) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if err := s.jsonStore.DeleteUser(username); err != nil {
+		return err
+	}
+	return s.record(deleteEvent("user", username))
+}
+
+func (s *eventStore) CreateTask(task Task) (Task, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	task, err := s.jsonStore.CreateTask(task)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, s.record(putEvent("task", task))
+}
+
+func (s *eventStore) CreateTasks(tasks []Task) ([]Task, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	tasks, err := s.jsonStore.CreateTasks(tasks)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]storeEvent, len(tasks))
+	for i, task := range tasks {
+		events[i] = putEvent("task", task)
+	}
+	return tasks, s.record(events...)
+}
+
+func (s *eventStore) UpdateTask(task Task) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if err := s.jsonStore.UpdateTask(task); err != nil {
+		return err
+	}
+	return s.record(putEvent("task", task))
+}
+
+func (s *eventStore) ModifyTask(id int, fn func(*Task) error) (Task, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	task, err := s.jsonStore.ModifyTask(id, fn)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, s.record(putEvent("task", task))
+}
+
+func (s *eventStore) ModifyTasks(ids []int, fn func(*Task) error) ([]Task, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	tasks, err := s.jsonStore.ModifyTasks(ids, fn)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]storeEvent, len(tasks))
+	for i, task := range tasks {
+		events[i] = putEvent("task", task)
+	}
+	return tasks, s.record(events...)
+}
+
+func (s *eventStore) DeleteTask(id int) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if err := s.jsonStore.DeleteTask(id); err != nil {
+		return err
+	}
+	return s.record(deleteEvent("task", strconv.Itoa(id)))
+}
+
+func (s *eventStore) RestoreTask(id int) (Task, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	task, err := s.jsonStore.RestoreTask(id)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, s.record(putEvent("task", task))
+}
+
+func (s *eventStore) PurgeTrash(before time.Time) (int, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	// 持有 wmu 時資料不會變，先記下會被清掉的任務
+	var events []storeEvent
+	s.mu.RLock()
+	for _, task := range s.data.Tasks {
+		if task.Trashed() && task.DeletedAt.Before(before) {
+			events = append(events, deleteEvent("task", strconv.Itoa(task.ID)))
+		}
+	}
+	s.mu.RUnlock()
+
+	purged, err := s.jsonStore.PurgeTrash(before)
+	if err != nil || purged == 0 {
+		return purged, err
+	}
+	return purged, s.record(events...)
+}
+
+func (s *eventStore) CreateAnnouncement(a Announcement) (Announcement, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	a, err := s.jsonStore.CreateAnnouncement(a)
+	if err != nil {
+		return Announcement{}, err
+	}
+	return a, s.record(putEvent("announcement", a))
+}
+
+func (s *eventStore) CreateProject(p Project) (Project, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	p, err := s.jsonStore.CreateProject(p)
+	if err != nil {
+		return Project{}, err
+	}
+	return p, s.record(putEvent("project", p))
+}
+
+func (s *eventStore) ModifyProject(id int, fn func(*Project) error) (Project, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	p, err := s.jsonStore.ModifyProject(id, fn)
+	if err != nil {
+		return Project{}, err
+	}
+	return p, s.record(putEvent("project", p))
+}
+
+func (s *eventStore) SaveSession(sess Session) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if err := s.jsonStore.SaveSession(sess); err != nil {
+		return err
+	}
+	return s.record(putEvent("session", sess))
+}
+
+func (s *eventStore) DeleteSession(id string) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if err := s.jsonStore.DeleteSession(id); err != nil {
+		return err
+	}
+	return s.record(deleteEvent("session", id))
+}
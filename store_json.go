@@ -0,0 +1,768 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- JSON 檔案儲存 ---
+
+// jsonStore 把所有資料放在記憶體，異動後整份寫回檔案；
+// mu 保護 data 與索引，讀取用 RLock，異動用 Lock。
+// pos、byUser、shared 與 blocks 是任務的索引，讓查單一任務、單一使用者的任務、分享給某人的任務
+// 或等待某個任務的任務都不必掃過所有人的資料。
+//
+// 預設是延後寫入（-flush-interval）：異動只標記 dirty，由背景的 flusher 定期整份寫回，
+// 請求不必等序列化與寫檔，資料變多也不會拖慢每個新增、勾選。代價是當機時最多遺失最後一個間隔的異動；
+// 正常關閉（SIGINT／SIGTERM）時 Close 會先寫回。間隔設為 0 則回到每次異動都同步寫檔
+type jsonStore struct {
+	mu     sync.RWMutex
+	path   string
+	data   *AppData
+	pos    map[int]int      // 任務 ID -> data.Tasks 中的位置
+	byUser map[string][]int // 使用者 -> 任務 ID，依 ID 遞增
+	shared map[string][]int // 被分享的使用者 -> 任務 ID，依 ID 遞增
+	blocks map[int][]int    // 任務 ID -> BlockedBy 含它的任務 ID，依 ID 遞增
+
+	dirty    atomic.Bool   // 有異動還沒寫回
+	fmu      sync.Mutex    // 同一時間只有一個寫回
+	stop     chan struct{} // 不為 nil 表示 flusher 在跑；stop、done、interval 都由 mu 保護
+	done     chan struct{}
+	interval time.Duration // flusher 的間隔，Resume 時照原本的間隔重新啟動
+}
+
+// flushInterval 是延後寫入的間隔（-flush-interval），0 表示每次異動都同步寫檔
+var flushInterval = time.Second
+
+// newMemoryStore 是不存檔的空白 jsonStore（-store memory），資料只活到程式結束，
+// 給展示模式、本機開發與測試用，通常搭配 -seed 放入範例資料（見 seed.go）
+func newMemoryStore() *jsonStore {
+	s := &jsonStore{
+		data: &AppData{
+			Users:  []User{},
+			Tasks:  []Task{},
+			NextID: 1,
+		},
+	}
+	s.reindex()
+	return s
+}
+
+func openJSONStore(path string) (*jsonStore, error) {
+	s := newMemoryStore()
+	s.path = path
+
+	file, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(file) > 0 {
+		if err := json.Unmarshal(file, s.data); err != nil {
+			return nil, err
+		}
+	}
+	s.reindex()
+	return s, nil
+}
+
+// reindex 依 data.Tasks 重建索引；刪除任務會讓後面的位置全部位移，直接重建最單純
+func (s *jsonStore) reindex() {
+	s.pos = make(map[int]int, len(s.data.Tasks))
+	s.byUser = make(map[string][]int)
+	s.shared = make(map[string][]int)
+	s.blocks = make(map[int][]int)
+	for i, task := range s.data.Tasks {
+		s.pos[task.ID] = i
+		s.byUser[task.Username] = append(s.byUser[task.Username], task.ID)
+		for _, name := range task.SharedWith {
+			s.shared[name] = append(s.shared[name], task.ID)
+		}
+		for _, blocker := range task.BlockedBy {
+			s.blocks[blocker] = append(s.blocks[blocker], task.ID)
+		}
+	}
+	for _, ids := range s.byUser {
+		sort.Ints(ids)
+	}
+	for _, ids := range s.shared {
+		sort.Ints(ids)
+	}
+	for _, ids := range s.blocks {
+		sort.Ints(ids)
+	}
+}
+
+// updateIndex 在任務從 old 改成 task 時更新 byUser、shared 與 blocks；新增的任務 old 傳零值
+func (s *jsonStore) updateIndex(old, task Task) {
+	if old.Username != task.Username {
+		unindexID(s.byUser, old.Username, task.ID)
+		indexID(s.byUser, task.Username, task.ID)
+	}
+	for _, name := range old.SharedWith {
+		if !slices.Contains(task.SharedWith, name) {
+			unindexID(s.shared, name, task.ID)
+		}
+	}
+	for _, name := range task.SharedWith {
+		indexID(s.shared, name, task.ID)
+	}
+	for _, blocker := range old.BlockedBy {
+		if !slices.Contains(task.BlockedBy, blocker) {
+			unindexID(s.blocks, blocker, task.ID)
+		}
+	}
+	for _, blocker := range task.BlockedBy {
+		indexID(s.blocks, blocker, task.ID)
+	}
+}
+
+// indexID 把 id 依序放進 index[key]，已經在裡面就不動
+func indexID[K comparable](index map[K][]int, key K, id int) {
+	ids := index[key]
+	i := sort.SearchInts(ids, id)
+	if i < len(ids) && ids[i] == id {
+		return
+	}
+	ids = append(ids, 0)
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+	index[key] = ids
+}
+
+// unindexID 把 id 從 index[key] 拿掉，空了就刪掉整個 key
+func unindexID[K comparable](index map[K][]int, key K, id int) {
+	ids := index[key]
+	if i := sort.SearchInts(ids, id); i < len(ids) && ids[i] == id {
+		ids = append(ids[:i], ids[i+1:]...)
+	}
+	if len(ids) == 0 {
+		delete(index, key)
+	} else {
+		index[key] = ids
+	}
+}
+
+// dataBackups 是 JSON 資料檔保留的舊版本數（-backups），app_data.json.1 是上一版，數字越大越舊
+var dataBackups = 3
+
+// save 在異動後呼叫，呼叫時持有 mu；path 為空時只放在記憶體（事件紀錄儲存用，見 store_events.go）。
+// flusher 在跑時只標記 dirty，否則立刻寫檔
+func (s *jsonStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if s.stop != nil {
+		s.dirty.Store(true)
+		return nil
+	}
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+	return s.writeFile(data)
+}
+
+// startFlusher 啟動延後寫入，每 interval 檢查一次 dirty；interval 為 0 或只放在記憶體時不啟動
+func (s *jsonStore) startFlusher(interval time.Duration) {
+	if interval <= 0 || s.path == "" {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	s.mu.Lock()
+	s.interval = interval
+	s.stop, s.done = stop, done
+	s.mu.Unlock()
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.flush(); err != nil {
+					persistErrors.Add(1)
+					log.Printf("寫回資料檔失敗，下次再試：%v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// flush 在有異動時把資料寫回檔案。序列化只持有 RLock，寫檔時不擋任何請求；
+// 序列化途中又有異動時 dirty 會再被設起來，下一次再寫
+func (s *jsonStore) flush() error {
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+	if !s.dirty.Swap(false) {
+		return nil
+	}
+	s.mu.RLock()
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	s.mu.RUnlock()
+	if err == nil {
+		err = s.writeFile(data)
+	}
+	if err != nil {
+		s.dirty.Store(true)
+	}
+	return err
+}
+
+// writeFile 先完整寫進暫存檔並 fsync，再把舊檔輪替成備份、以改名換上新檔，
+// 寫到一半當機時資料檔仍是上一版，不會只剩半份 JSON；呼叫時持有 fmu
+func (s *jsonStore) writeFile(data []byte) error {
+	tmp := s.path + ".tmp"
+	if err := writeFileSync(tmp, data); err != nil {
+		return err
+	}
+	if err := rotateBackups(s.path, dataBackups); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// rotateBackups 把 path 的舊版本往後推一格（.1 -> .2 …，超過 n 份的丟掉），目前的 path 成為 .1。
+// .1 以硬連結建立，path 在換上新檔之前一直存在；不支援硬連結的檔案系統改用複製
+func rotateBackups(path string, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	for i := n - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	backup := path + ".1"
+	os.Remove(backup)
+	if os.Link(path, backup) == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return writeFileSync(backup, data)
+}
+
+// Close 停止 flusher 並把尚未寫回的異動寫進檔案。等 flusher 結束時不能持有 mu（flush 要拿 RLock），
+// 但 stop 要等 flusher 真的停了才清掉，否則這段期間的 save 會同步寫檔，和還沒結束的 flush 搶 fmu
+func (s *jsonStore) Close() error {
+	s.mu.RLock()
+	stop, done := s.stop, s.done
+	s.mu.RUnlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop, s.done = nil, nil
+	return s.save()
+}
+
+// Suspend 停止 flusher 並把尚未寫回的異動寫進檔案，之後每次異動都同步寫檔；
+// 重新啟動時在 exec 之前呼叫，exec 失敗時以 Resume 恢復延後寫入
+func (s *jsonStore) Suspend() error {
+	return s.Close()
+}
+
+// Resume 以原本的間隔重新啟動 flusher；沒有延後寫入時什麼都不做
+func (s *jsonStore) Resume() {
+	s.mu.RLock()
+	interval := s.interval
+	s.mu.RUnlock()
+	s.startFlusher(interval)
+}
+
+func (s *jsonStore) GetUser(username string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.data.Users {
+		if user.Username == username {
+			return user.clone(), nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+func (s *jsonStore) ListUsers() ([]User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]User, len(s.data.Users))
+	for i, user := range s.data.Users {
+		users[i] = user.clone()
+	}
+	return users, nil
+}
+
+func (s *jsonStore) CreateUser(user User) error {
+	_, err := s.createUser(user, false)
+	return err
+}
+
+func (s *jsonStore) RegisterUser(user User) (User, error) {
+	return s.createUser(user, true)
+}
+
+// createUser 新增 user；firstAdmin 時若還沒有任何使用者就設成管理員
+func (s *jsonStore) createUser(user User, firstAdmin bool) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.data.Users {
+		if u.Username == user.Username {
+			return User{}, ErrUserExists
+		}
+	}
+	if firstAdmin && len(s.data.Users) == 0 {
+		user.Role = RoleAdmin
+	}
+	s.data.Users = append(s.data.Users, user.clone())
+	return user, s.save()
+}
+
+func (s *jsonStore) UpdateUser(user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Users {
+		if s.data.Users[i].Username == user.Username {
+			s.data.Users[i] = user.clone()
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *jsonStore) ModifyUser(username string, fn func(*User) error) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Users {
+		if s.data.Users[i].Username == username {
+			user := s.data.Users[i].clone()
+			if err := fn(&user); err != nil {
+				return User{}, err
+			}
+			user.Username = username
+			s.data.Users[i] = user
+			return user.clone(), s.save()
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+func (s *jsonStore) DeleteUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, u := range s.data.Users {
+		if u.Username == username {
+			s.data.Users = append(s.data.Users[:i], s.data.Users[i+1:]...)
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// clone 複製任務連同所有 slice 欄位。存進去與讀出來的任務都要是獨立的副本，
+// 否則在鎖外讀取的任務和 ModifyTask 的 fn 會共用同一塊記憶體（例如勾選子項目是原地修改），
+// 造成 data race，fn 回傳錯誤時改到一半的內容也會留在資料裡
+func (t Task) clone() Task {
+	t.Tags = slices.Clone(t.Tags)
+	t.Checklist = slices.Clone(t.Checklist)
+	t.BlockedBy = slices.Clone(t.BlockedBy)
+	t.Activity = slices.Clone(t.Activity)
+	t.TimeEntries = slices.Clone(t.TimeEntries)
+	t.Pomodoros = slices.Clone(t.Pomodoros)
+	t.Reminders = slices.Clone(t.Reminders)
+	t.SharedWith = slices.Clone(t.SharedWith)
+	return t
+}
+
+// clone 複製使用者連同 slice、map 與指標欄位，理由和 Task.clone 一樣
+func (u User) clone() User {
+	u.Roster = slices.Clone(u.Roster)
+	u.OAuthIdentities = slices.Clone(u.OAuthIdentities)
+	u.ShareLinks = slices.Clone(u.ShareLinks)
+	u.APITokens = slices.Clone(u.APITokens)
+	u.Reminders = slices.Clone(u.Reminders)
+	u.PushSubscriptions = slices.Clone(u.PushSubscriptions)
+	u.Achievements = maps.Clone(u.Achievements)
+	if u.Focus != nil {
+		focus := *u.Focus
+		u.Focus = &focus
+	}
+	return u
+}
+
+// clone 複製專案連同成員與動態
+func (p Project) clone() Project {
+	p.Members = slices.Clone(p.Members)
+	p.Activity = slices.Clone(p.Activity)
+	return p
+}
+
+func (s *jsonStore) GetTask(id int) (Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i, ok := s.pos[id]; ok && !s.data.Tasks[i].Trashed() {
+		return s.data.Tasks[i].clone(), nil
+	}
+	return Task{}, ErrNotFound
+}
+
+func (s *jsonStore) ListTasks(username string) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userTasks(username, false), nil
+}
+
+// userTasks 依索引取出使用者的任務，trashed 決定要垃圾桶內或外的
+func (s *jsonStore) userTasks(username string, trashed bool) []Task {
+	var tasks []Task
+	for _, id := range s.byUser[username] {
+		if task := s.data.Tasks[s.pos[id]]; task.Trashed() == trashed {
+			tasks = append(tasks, task.clone())
+		}
+	}
+	return tasks
+}
+
+func (s *jsonStore) ListSharedWith(username string) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tasks []Task
+	for _, id := range s.shared[username] {
+		if task := s.data.Tasks[s.pos[id]]; !task.Trashed() {
+			tasks = append(tasks, task.clone())
+		}
+	}
+	return tasks, nil
+}
+
+func (s *jsonStore) ListBlockedBy(id int) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tasks []Task
+	for _, dep := range s.blocks[id] {
+		if task := s.data.Tasks[s.pos[dep]]; !task.Trashed() {
+			tasks = append(tasks, task.clone())
+		}
+	}
+	return tasks, nil
+}
+
+func (s *jsonStore) AllTasks() ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := make([]Task, 0, len(s.data.Tasks))
+	for _, task := range s.data.Tasks {
+		if !task.Trashed() {
+			tasks = append(tasks, task.clone())
+		}
+	}
+	return tasks, nil
+}
+
+func (s *jsonStore) CreateTask(task Task) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task.ID = s.data.NextID
+	s.data.Tasks = append(s.data.Tasks, task.clone())
+	s.data.NextID++
+	s.pos[task.ID] = len(s.data.Tasks) - 1
+	s.updateIndex(Task{}, task)
+	return task, s.save()
+}
+
+func (s *jsonStore) CreateTasks(tasks []Task) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := make([]Task, len(tasks))
+	for i, task := range tasks {
+		task.ID = s.data.NextID
+		s.data.Tasks = append(s.data.Tasks, task.clone())
+		s.data.NextID++
+		s.pos[task.ID] = len(s.data.Tasks) - 1
+		s.updateIndex(Task{}, task)
+		created[i] = task
+	}
+	return created, s.save()
+}
+
+func (s *jsonStore) UpdateTask(task Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.pos[task.ID]
+	if !ok || s.data.Tasks[i].Trashed() {
+		return ErrNotFound
+	}
+	s.updateIndex(s.data.Tasks[i], task)
+	s.data.Tasks[i] = task.clone()
+	return s.save()
+}
+
+func (s *jsonStore) ModifyTask(id int, fn func(*Task) error) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.pos[id]
+	if !ok || s.data.Tasks[i].Trashed() {
+		return Task{}, ErrNotFound
+	}
+	task := s.data.Tasks[i].clone()
+	if err := fn(&task); err != nil {
+		return Task{}, err
+	}
+	task.ID = id
+	s.updateIndex(s.data.Tasks[i], task)
+	s.data.Tasks[i] = task
+	return task.clone(), s.save()
+}
+
+func (s *jsonStore) ModifyTasks(ids []int, fn func(*Task) error) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 先在副本上改完，全部成功才寫回，最後只存一次檔
+	tasks := make([]Task, len(ids))
+	for n, id := range ids {
+		i, ok := s.pos[id]
+		if !ok || s.data.Tasks[i].Trashed() {
+			return nil, ErrNotFound
+		}
+		task := s.data.Tasks[i].clone()
+		if err := fn(&task); err != nil {
+			return nil, err
+		}
+		task.ID = id
+		tasks[n] = task
+	}
+	for n, task := range tasks {
+		i := s.pos[task.ID]
+		s.updateIndex(s.data.Tasks[i], task)
+		s.data.Tasks[i] = task
+		tasks[n] = task.clone()
+	}
+	return tasks, s.save()
+}
+
+func (s *jsonStore) DeleteTask(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.pos[id]
+	if !ok {
+		return ErrNotFound
+	}
+	s.data.Tasks = append(s.data.Tasks[:i], s.data.Tasks[i+1:]...)
+	s.reindex()
+	return s.save()
+}
+
+func (s *jsonStore) ListTrash(username string) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userTasks(username, true), nil
+}
+
+func (s *jsonStore) RestoreTask(id int) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.pos[id]
+	if !ok || !s.data.Tasks[i].Trashed() {
+		return Task{}, ErrNotFound
+	}
+	s.data.Tasks[i].DeletedAt = time.Time{}
+	return s.data.Tasks[i].clone(), s.save()
+}
+
+func (s *jsonStore) PurgeTrash(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.data.Tasks[:0]
+	for _, task := range s.data.Tasks {
+		if !task.Trashed() || !task.DeletedAt.Before(before) {
+			kept = append(kept, task)
+		}
+	}
+	purged := len(s.data.Tasks) - len(kept)
+	if purged == 0 {
+		return 0, nil
+	}
+	s.data.Tasks = kept
+	s.reindex()
+	return purged, s.save()
+}
+
+func (s *jsonStore) GetAnnouncement(id int) (Announcement, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, a := range s.data.Announcements {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return Announcement{}, ErrNotFound
+}
+
+func (s *jsonStore) ListAnnouncements() ([]Announcement, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Announcement, len(s.data.Announcements))
+	copy(list, s.data.Announcements)
+	return list, nil
+}
+
+func (s *jsonStore) CreateAnnouncement(a Announcement) (Announcement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.NextAnnouncementID == 0 {
+		s.data.NextAnnouncementID = 1
+	}
+	a.ID = s.data.NextAnnouncementID
+	s.data.Announcements = append(s.data.Announcements, a)
+	s.data.NextAnnouncementID++
+	return a, s.save()
+}
+
+func (s *jsonStore) GetProject(id int) (Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.data.Projects {
+		if p.ID == id {
+			return p.clone(), nil
+		}
+	}
+	return Project{}, ErrNotFound
+}
+
+func (s *jsonStore) ListProjects(username string) ([]Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []Project
+	for _, p := range s.data.Projects {
+		if p.HasMember(username) {
+			list = append(list, p.clone())
+		}
+	}
+	return list, nil
+}
+
+func (s *jsonStore) CreateProject(p Project) (Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.NextProjectID == 0 {
+		s.data.NextProjectID = 1
+	}
+	p.ID = s.data.NextProjectID
+	s.data.Projects = append(s.data.Projects, p.clone())
+	s.data.NextProjectID++
+	return p, s.save()
+}
+
+func (s *jsonStore) ModifyProject(id int, fn func(*Project) error) (Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Projects {
+		if s.data.Projects[i].ID == id {
+			p := s.data.Projects[i].clone()
+			if err := fn(&p); err != nil {
+				return Project{}, err
+			}
+			p.ID = id
+			s.data.Projects[i] = p
+			return p.clone(), s.save()
+		}
+	}
+	return Project{}, ErrNotFound
+}
+
+func (s *jsonStore) ListSessions() ([]Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Session, len(s.data.Sessions))
+	copy(list, s.data.Sessions)
+	return list, nil
+}
+
+func (s *jsonStore) DataUsage(username string) (DataUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var usage DataUsage
+	for _, id := range s.byUser[username] {
+		usage.add(username, s.data.Tasks[s.pos[id]])
+	}
+	for _, sess := range s.data.Sessions {
+		if sess.Username == username {
+			usage.Sessions++
+		}
+	}
+	return usage, nil
+}
+
+func (s *jsonStore) SaveSession(sess Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Sessions {
+		if s.data.Sessions[i].ID == sess.ID {
+			s.data.Sessions[i] = sess
+			return s.save()
+		}
+	}
+	s.data.Sessions = append(s.data.Sessions, sess)
+	return s.save()
+}
+
+func (s *jsonStore) DeleteSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, sess := range s.data.Sessions {
+		if sess.ID == id {
+			s.data.Sessions = append(s.data.Sessions[:i], s.data.Sessions[i+1:]...)
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,746 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// --- SQLite 儲存 ---
+
+// sqliteDriver 是註冊到 database/sql 的驅動名稱，
+// 實際驅動由 sqlite_driver.go（build tag: sqlite）引入
+const sqliteDriver = "sqlite3"
+
+// sqliteStore 的索引欄位（id、username）獨立成欄，
+// 其餘欄位以 JSON 存在 data 欄，Task/User 新增欄位時不需遷移資料表。
+// task_shares 與 task_blockers 是 SharedWith 與 BlockedBy 的索引，寫入任務時一起在同一個交易裡更新
+type sqliteStore struct {
+	db *sql.DB
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	username TEXT PRIMARY KEY,
+	data     TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS tasks (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL,
+	data     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tasks_username ON tasks(username);
+CREATE TABLE IF NOT EXISTS task_shares (
+	username TEXT NOT NULL,
+	task_id  INTEGER NOT NULL,
+	PRIMARY KEY (username, task_id)
+);
+CREATE INDEX IF NOT EXISTS task_shares_task ON task_shares(task_id);
+CREATE TABLE IF NOT EXISTS task_blockers (
+	blocker_id INTEGER NOT NULL,
+	task_id    INTEGER NOT NULL,
+	PRIMARY KEY (blocker_id, task_id)
+);
+CREATE INDEX IF NOT EXISTS task_blockers_task ON task_blockers(task_id);
+CREATE TABLE IF NOT EXISTS announcements (
+	id   INTEGER PRIMARY KEY AUTOINCREMENT,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS projects (
+	id   INTEGER PRIMARY KEY AUTOINCREMENT,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS sessions (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+`
+
+func openSQLiteStore(path string) (*sqliteStore, error) {
+	if !sqliteAvailable() {
+		return nil, errors.New("此執行檔未包含 SQLite 驅動，請以 -tags sqlite 重新編譯")
+	}
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite 同時只允許一個寫入者，單一連線可避免 database is locked
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	// 舊的資料庫還沒有 task_shares、task_blockers，從任務的 JSON 補上；INSERT OR IGNORE 每次啟動重跑也沒關係
+	if _, err := db.Exec(`INSERT OR IGNORE INTO task_shares (username, task_id)
+		SELECT s.value, tasks.id FROM tasks, json_each(tasks.data, '$.shared_with') AS s;
+		INSERT OR IGNORE INTO task_blockers (blocker_id, task_id)
+		SELECT b.value, tasks.id FROM tasks, json_each(tasks.data, '$.blocked_by') AS b`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func sqliteAvailable() bool {
+	for _, name := range sql.Drivers() {
+		if name == sqliteDriver {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqliteStore) GetUser(username string) (User, error) {
+	var raw string
+	err := s.db.QueryRow(`SELECT data FROM users WHERE username = ?`, username).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	var user User
+	err = json.Unmarshal([]byte(raw), &user)
+	return user, err
+}
+
+func (s *sqliteStore) ListUsers() ([]User, error) {
+	rows, err := s.db.Query(`SELECT data FROM users ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var user User
+		if err := json.Unmarshal([]byte(raw), &user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (s *sqliteStore) CreateUser(user User) error {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO users (username, data) VALUES (?, ?)`, user.Username, string(raw))
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return ErrUserExists
+	}
+	return err
+}
+
+// RegisterUser 把「還沒有使用者」的判斷寫在 INSERT 裡，由 SQLite 保證判斷與新增不可分割
+func (s *sqliteStore) RegisterUser(user User) (User, error) {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return User{}, err
+	}
+	admin := user
+	admin.Role = RoleAdmin
+	rawAdmin, err := json.Marshal(admin)
+	if err != nil {
+		return User{}, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`INSERT INTO users (username, data)
+		SELECT ?, CASE WHEN EXISTS (SELECT 1 FROM users) THEN ? ELSE ? END`, user.Username, string(raw), string(rawAdmin))
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return User{}, ErrUserExists
+		}
+		return User{}, err
+	}
+	var stored string
+	if err := tx.QueryRow(`SELECT data FROM users WHERE username = ?`, user.Username).Scan(&stored); err != nil {
+		return User{}, err
+	}
+	var created User
+	if err := json.Unmarshal([]byte(stored), &created); err != nil {
+		return User{}, err
+	}
+	return created, tx.Commit()
+}
+
+func (s *sqliteStore) UpdateUser(user User) error {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`UPDATE users SET data = ? WHERE username = ?`, string(raw), user.Username)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *sqliteStore) ModifyUser(username string, fn func(*User) error) (User, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback()
+
+	var raw string
+	err = tx.QueryRow(`SELECT data FROM users WHERE username = ?`, username).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	var user User
+	if err := json.Unmarshal([]byte(raw), &user); err != nil {
+		return User{}, err
+	}
+	if err := fn(&user); err != nil {
+		return User{}, err
+	}
+	user.Username = username
+	data, err := json.Marshal(user)
+	if err != nil {
+		return User{}, err
+	}
+	if _, err := tx.Exec(`UPDATE users SET data = ? WHERE username = ?`, string(data), username); err != nil {
+		return User{}, err
+	}
+	return user, tx.Commit()
+}
+
+func (s *sqliteStore) DeleteUser(username string) error {
+	res, err := s.db.Exec(`DELETE FROM users WHERE username = ?`, username)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *sqliteStore) GetTask(id int) (Task, error) {
+	var raw string
+	err := s.db.QueryRow(`SELECT data FROM tasks WHERE id = ?`, id).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return Task{}, ErrNotFound
+	}
+	if err != nil {
+		return Task{}, err
+	}
+	task, err := decodeTask(id, raw)
+	if err == nil && task.Trashed() {
+		return Task{}, ErrNotFound
+	}
+	return task, err
+}
+
+func (s *sqliteStore) ListTasks(username string) ([]Task, error) {
+	return s.queryTasks(false, `SELECT id, data FROM tasks WHERE username = ? ORDER BY id`, username)
+}
+
+func (s *sqliteStore) ListSharedWith(username string) ([]Task, error) {
+	return s.queryTasks(false, `SELECT tasks.id, tasks.data FROM task_shares JOIN tasks ON tasks.id = task_shares.task_id
+		WHERE task_shares.username = ? ORDER BY tasks.id`, username)
+}
+
+func (s *sqliteStore) ListBlockedBy(id int) ([]Task, error) {
+	return s.queryTasks(false, `SELECT tasks.id, tasks.data FROM task_blockers JOIN tasks ON tasks.id = task_blockers.task_id
+		WHERE task_blockers.blocker_id = ? ORDER BY tasks.id`, id)
+}
+
+func (s *sqliteStore) AllTasks() ([]Task, error) {
+	return s.queryTasks(false, `SELECT id, data FROM tasks ORDER BY id`)
+}
+
+// queryTasks 執行查詢並只留下垃圾桶內（trashed）或外的任務；
+// DeletedAt 存在 JSON 裡，由這裡過濾
+func (s *sqliteStore) queryTasks(trashed bool, query string, args ...interface{}) ([]Task, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var id int
+		var raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		task, err := decodeTask(id, raw)
+		if err != nil {
+			return nil, err
+		}
+		if task.Trashed() == trashed {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, rows.Err()
+}
+
+func (s *sqliteStore) CreateTask(task Task) (Task, error) {
+	created, err := s.CreateTasks([]Task{task})
+	if err != nil {
+		return Task{}, err
+	}
+	return created[0], nil
+}
+
+func (s *sqliteStore) CreateTasks(tasks []Task) ([]Task, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created := make([]Task, len(tasks))
+	for i, task := range tasks {
+		raw, err := json.Marshal(task)
+		if err != nil {
+			return nil, err
+		}
+		res, err := tx.Exec(`INSERT INTO tasks (username, data) VALUES (?, ?)`, task.Username, string(raw))
+		if err != nil {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		task.ID = int(id)
+		if err := writeTaskLinks(tx, task); err != nil {
+			return nil, err
+		}
+		created[i] = task
+	}
+	return created, tx.Commit()
+}
+
+// UpdateTask 透過 modifyTask 整筆覆寫，才能在同一個交易裡排除垃圾桶內的任務
+func (s *sqliteStore) UpdateTask(task Task) error {
+	_, err := s.modifyTask(task.ID, false, func(t *Task) error {
+		*t = task
+		return nil
+	})
+	return err
+}
+
+func (s *sqliteStore) ModifyTask(id int, fn func(*Task) error) (Task, error) {
+	return s.modifyTask(id, false, fn)
+}
+
+func (s *sqliteStore) ModifyTasks(ids []int, fn func(*Task) error) ([]Task, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tasks := make([]Task, len(ids))
+	for n, id := range ids {
+		task, err := modifyTaskTx(tx, id, false, fn)
+		if err != nil {
+			return nil, err
+		}
+		tasks[n] = task
+	}
+	return tasks, tx.Commit()
+}
+
+func (s *sqliteStore) RestoreTask(id int) (Task, error) {
+	return s.modifyTask(id, true, func(t *Task) error {
+		t.DeletedAt = time.Time{}
+		return nil
+	})
+}
+
+// modifyTask 是 ModifyTask 與 RestoreTask 的共同實作，trashed 指定任務必須在垃圾桶內或外
+func (s *sqliteStore) modifyTask(id int, trashed bool, fn func(*Task) error) (Task, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Task{}, err
+	}
+	defer tx.Rollback()
+
+	task, err := modifyTaskTx(tx, id, trashed, fn)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, tx.Commit()
+}
+
+// modifyTaskTx 在交易 tx 內讀出、修改並寫回一個任務，由呼叫端決定何時 Commit
+func modifyTaskTx(tx *sql.Tx, id int, trashed bool, fn func(*Task) error) (Task, error) {
+	var raw string
+	err := tx.QueryRow(`SELECT data FROM tasks WHERE id = ?`, id).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return Task{}, ErrNotFound
+	}
+	if err != nil {
+		return Task{}, err
+	}
+	task, err := decodeTask(id, raw)
+	if err != nil {
+		return Task{}, err
+	}
+	if task.Trashed() != trashed {
+		return Task{}, ErrNotFound
+	}
+	if err := fn(&task); err != nil {
+		return Task{}, err
+	}
+	task.ID = id
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return Task{}, err
+	}
+	if _, err := tx.Exec(`UPDATE tasks SET username = ?, data = ? WHERE id = ?`, task.Username, string(data), id); err != nil {
+		return Task{}, err
+	}
+	return task, writeTaskLinks(tx, task)
+}
+
+// writeTaskLinks 依 task.SharedWith 與 BlockedBy 重寫 task_shares、task_blockers 裡這個任務的列
+func writeTaskLinks(tx *sql.Tx, task Task) error {
+	if err := deleteTaskLinks(tx, task.ID); err != nil {
+		return err
+	}
+	for _, name := range task.SharedWith {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO task_shares (username, task_id) VALUES (?, ?)`, name, task.ID); err != nil {
+			return err
+		}
+	}
+	for _, blocker := range task.BlockedBy {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO task_blockers (blocker_id, task_id) VALUES (?, ?)`, blocker, task.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteTaskLinks(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec(`DELETE FROM task_shares WHERE task_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM task_blockers WHERE task_id = ?`, id)
+	return err
+}
+
+func (s *sqliteStore) DeleteTask(id int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteTaskTx(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteTaskTx 刪掉任務與它在 task_shares、task_blockers 的列
+func deleteTaskTx(tx *sql.Tx, id int) error {
+	if err := deleteTaskLinks(tx, id); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *sqliteStore) ListTrash(username string) ([]Task, error) {
+	return s.queryTasks(true, `SELECT id, data FROM tasks WHERE username = ? ORDER BY id`, username)
+}
+
+func (s *sqliteStore) PurgeTrash(before time.Time) (int, error) {
+	trash, err := s.queryTasks(true, `SELECT id, data FROM tasks ORDER BY id`)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, task := range trash {
+		if !task.DeletedAt.Before(before) {
+			continue
+		}
+		if err := s.DeleteTask(task.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (s *sqliteStore) GetAnnouncement(id int) (Announcement, error) {
+	var raw string
+	err := s.db.QueryRow(`SELECT data FROM announcements WHERE id = ?`, id).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return Announcement{}, ErrNotFound
+	}
+	if err != nil {
+		return Announcement{}, err
+	}
+	var a Announcement
+	err = json.Unmarshal([]byte(raw), &a)
+	a.ID = id
+	return a, err
+}
+
+func (s *sqliteStore) ListAnnouncements() ([]Announcement, error) {
+	rows, err := s.db.Query(`SELECT id, data FROM announcements ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Announcement
+	for rows.Next() {
+		var id int
+		var raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		var a Announcement
+		if err := json.Unmarshal([]byte(raw), &a); err != nil {
+			return nil, err
+		}
+		a.ID = id
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+func (s *sqliteStore) CreateAnnouncement(a Announcement) (Announcement, error) {
+	raw, err := json.Marshal(a)
+	if err != nil {
+		return Announcement{}, err
+	}
+	res, err := s.db.Exec(`INSERT INTO announcements (data) VALUES (?)`, string(raw))
+	if err != nil {
+		return Announcement{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Announcement{}, err
+	}
+	a.ID = int(id)
+	return a, nil
+}
+
+func (s *sqliteStore) GetProject(id int) (Project, error) {
+	var raw string
+	err := s.db.QueryRow(`SELECT data FROM projects WHERE id = ?`, id).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return Project{}, ErrNotFound
+	}
+	if err != nil {
+		return Project{}, err
+	}
+	var p Project
+	err = json.Unmarshal([]byte(raw), &p)
+	p.ID = id
+	return p, err
+}
+
+// ListProjects 的成員名單存在 JSON 欄內，因此在 Go 端過濾
+func (s *sqliteStore) ListProjects(username string) ([]Project, error) {
+	rows, err := s.db.Query(`SELECT id, data FROM projects ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Project
+	for rows.Next() {
+		var id int
+		var raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		var p Project
+		if err := json.Unmarshal([]byte(raw), &p); err != nil {
+			return nil, err
+		}
+		p.ID = id
+		if p.HasMember(username) {
+			list = append(list, p)
+		}
+	}
+	return list, rows.Err()
+}
+
+func (s *sqliteStore) CreateProject(p Project) (Project, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return Project{}, err
+	}
+	res, err := s.db.Exec(`INSERT INTO projects (data) VALUES (?)`, string(raw))
+	if err != nil {
+		return Project{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Project{}, err
+	}
+	p.ID = int(id)
+	return p, nil
+}
+
+func (s *sqliteStore) ModifyProject(id int, fn func(*Project) error) (Project, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Project{}, err
+	}
+	defer tx.Rollback()
+
+	var raw string
+	err = tx.QueryRow(`SELECT data FROM projects WHERE id = ?`, id).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return Project{}, ErrNotFound
+	}
+	if err != nil {
+		return Project{}, err
+	}
+	var p Project
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		return Project{}, err
+	}
+	if err := fn(&p); err != nil {
+		return Project{}, err
+	}
+	p.ID = id
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return Project{}, err
+	}
+	if _, err := tx.Exec(`UPDATE projects SET data = ? WHERE id = ?`, string(data), id); err != nil {
+		return Project{}, err
+	}
+	return p, tx.Commit()
+}
+
+func (s *sqliteStore) ListSessions() ([]Session, error) {
+	rows, err := s.db.Query(`SELECT data FROM sessions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Session
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var sess Session
+		if err := json.Unmarshal([]byte(raw), &sess); err != nil {
+			return nil, err
+		}
+		list = append(list, sess)
+	}
+	return list, rows.Err()
+}
+
+// sqlTaskTrashed 判斷 data 欄的任務是否在垃圾桶裡：DeletedAt 的零值序列化成 0001-01-01T00:00:00Z，
+// 其他時間在字串上都比它大；舊資料沒有這個欄位時視為不在垃圾桶
+const sqlTaskTrashed = `COALESCE(json_extract(data, '$.deleted_at'), '') > '0001-01-01T00:00:00Z'`
+
+// sqlTaskCleanable 是可以從資料用量頁清除筆記與留言的任務：自己的、不屬於專案的已完成任務
+const sqlTaskCleanable = `json_extract(data, '$.completed') AND COALESCE(json_extract(data, '$.project_id'), 0) = 0`
+
+// DataUsage 以 SQLite 的 JSON 函式在資料庫內加總，筆記是 base64，字元數就是位元組數
+func (s *sqliteStore) DataUsage(username string) (DataUsage, error) {
+	var usage DataUsage
+	err := s.db.QueryRow(`SELECT
+		COALESCE(SUM(NOT trashed), 0),
+		COALESCE(SUM(NOT trashed AND completed), 0),
+		COALESCE(SUM(trashed), 0),
+		COALESCE(SUM(NOT trashed AND note > 0), 0),
+		COALESCE(SUM(CASE WHEN NOT trashed THEN note END), 0),
+		COALESCE(SUM(CASE WHEN NOT trashed AND cleanable THEN note END), 0)
+		FROM (SELECT `+sqlTaskTrashed+` AS trashed,
+			COALESCE(json_extract(data, '$.completed'), 0) AS completed,
+			`+sqlTaskCleanable+` AS cleanable,
+			length(COALESCE(json_extract(data, '$.encrypted_note'), '')) AS note
+			FROM tasks WHERE username = ?)`, username).Scan(
+		&usage.Tasks, &usage.CompletedTasks, &usage.TrashedTasks,
+		&usage.NoteTasks, &usage.NoteBytes, &usage.CleanableNoteBytes)
+	if err != nil {
+		return DataUsage{}, err
+	}
+
+	err = s.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(`+sqlTaskCleanable+`), 0)
+		FROM tasks, json_each(tasks.data, '$.activity') AS a
+		WHERE username = ? AND NOT `+sqlTaskTrashed+` AND json_extract(a.value, '$.kind') = ?`,
+		username, ActivityComment).Scan(&usage.Comments, &usage.CleanableComments)
+	if err != nil {
+		return DataUsage{}, err
+	}
+
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE json_extract(data, '$.username') = ?`,
+		username).Scan(&usage.Sessions)
+	if err != nil {
+		return DataUsage{}, err
+	}
+	return usage, nil
+}
+
+func (s *sqliteStore) SaveSession(sess Session) error {
+	raw, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO sessions (id, data) VALUES (?, ?)
+		ON CONFLICT(id) DO UPDATE SET data = excluded.data`, sess.ID, string(raw))
+	return err
+}
+
+func (s *sqliteStore) DeleteSession(id string) error {
+	res, err := s.db.Exec(`DELETE FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+// decodeTask 以資料列的 id 為準，data 欄內的 id 可能是寫入前的 0
+func decodeTask(id int, raw string) (Task, error) {
+	var task Task
+	if err := json.Unmarshal([]byte(raw), &task); err != nil {
+		return Task{}, err
+	}
+	task.ID = id
+	return task, nil
+}
+
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
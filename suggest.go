@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- 輸入建議 ---
+//
+// /api/v1/suggest?q= 給新增與搜尋欄位的自動完成用：回傳描述含有查詢詞的任務與符合的標籤。
+// 直接查搜尋索引（見 search.go），不讀儲存層，每打一個字查一次也夠快；前端仍會等停止輸入一下才送出。
+// 未完成的、以查詢詞開頭的排前面；查詢詞開頭的 # 會去掉，方便直接打標籤
+
+const (
+	maxSuggestions   = 8
+	maxSuggestLength = 100 // 查詢詞上限（字元），更長的不可能是在打字
+)
+
+type taskSuggestion struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+	Completed   bool   `json:"completed"`
+}
+
+type suggestResponse struct {
+	Query string           `json:"query"`
+	Tasks []taskSuggestion `json:"tasks"`
+	Tags  []string         `json:"tags"`
+}
+
+// Suggest 回傳 username 的任務中描述含有所有查詢詞的任務，以及含有最後一個查詢詞的標籤，各最多 limit 個
+func (idx *searchIndex) Suggest(username, query string, limit int) ([]taskSuggestion, []string) {
+	query = strings.TrimPrefix(strings.TrimSpace(query), "#")
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	ids := idx.Search(username, query)
+	last, prefix := terms[len(terms)-1], strings.Join(terms, " ")
+
+	idx.mu.RLock()
+	var tasks []taskSuggestion
+	startsWith := make(map[int]bool)
+	tagCount := make(map[string]int)
+	for _, id := range ids {
+		doc, ok := idx.docs[id]
+		if !ok {
+			continue
+		}
+		desc := string(foldRunes(doc.description))
+		match := true
+		for _, term := range terms {
+			if !strings.Contains(desc, term) {
+				match = false
+				break
+			}
+		}
+		if match {
+			tasks = append(tasks, taskSuggestion{id, doc.description, doc.completed})
+			startsWith[id] = strings.HasPrefix(desc, prefix)
+		}
+		for _, tag := range doc.tags {
+			if strings.Contains(string(foldRunes(tag)), last) {
+				tagCount[tag]++
+			}
+		}
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.Completed != b.Completed {
+			return !a.Completed
+		}
+		if startsWith[a.ID] != startsWith[b.ID] {
+			return startsWith[a.ID]
+		}
+		return a.ID > b.ID // 新的任務排前面
+	})
+	// 同樣描述的任務（例如每週重複的）只留一個
+	seen := make(map[string]bool)
+	unique := tasks[:0]
+	for _, t := range tasks {
+		if !seen[t.Description] {
+			seen[t.Description] = true
+			unique = append(unique, t)
+		}
+	}
+	tasks = unique
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+
+	tags := make([]string, 0, len(tagCount))
+	for tag := range tagCount {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		pi := strings.HasPrefix(string(foldRunes(tags[i])), last)
+		pj := strings.HasPrefix(string(foldRunes(tags[j])), last)
+		if pi != pj {
+			return pi
+		}
+		if tagCount[tags[i]] != tagCount[tags[j]] {
+			return tagCount[tags[i]] > tagCount[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tasks, tags
+}
+
+func (a *App) apiSuggestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	username := a.getUsername(r)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if utf8.RuneCountInString(query) > maxSuggestLength {
+		writeAPIError(w, http.StatusBadRequest, "查詢詞過長")
+		return
+	}
+	if err := a.taskIndex.ensureLoaded(username); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "讀取任務失敗")
+		return
+	}
+	resp := suggestResponse{Query: query, Tasks: []taskSuggestion{}, Tags: []string{}}
+	tasks, tags := a.taskIndex.Suggest(username, query, maxSuggestions)
+	resp.Tasks = append(resp.Tasks, tasks...)
+	resp.Tags = append(resp.Tags, tags...)
+	w.Header().Set("Cache-Control", "private, max-age=5")
+	writeJSON(w, http.StatusOK, resp)
+}
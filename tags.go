@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- 標籤 ---
+
+const (
+	maxTagsPerTask  = 10
+	maxTagLength    = 30 // 以字元計
+	tagFilterPrefix = "tag:"
+)
+
+// TagCount 是標籤雲的一個項目
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// normalizeTags 整理使用者輸入的標籤：去掉前後空白與開頭的 #，
+// 不分大小寫去除重複，超過長度或數量上限的部分丟棄
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] || utf8.RuneCountInString(tag) > maxTagLength {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+		if len(result) == maxTagsPerTask {
+			break
+		}
+	}
+	return result
+}
+
+// parseTags 解析表單的標籤欄位，半形、全形逗號或空白都可以當分隔
+func parseTags(raw string) []string {
+	return normalizeTags(strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || r == ' ' || r == '\t'
+	}))
+}
+
+func (t Task) HasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if strings.EqualFold(tt, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectTags 統計所有任務用到的標籤，依使用次數由多到少排列
+func collectTags(tasks []Task) []TagCount {
+	counts := make(map[string]int)
+	names := make(map[string]string) // 以第一次出現的寫法顯示
+	for _, task := range tasks {
+		for _, tag := range task.Tags {
+			key := strings.ToLower(tag)
+			if _, ok := names[key]; !ok {
+				names[key] = tag
+			}
+			counts[key]++
+		}
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for key, n := range counts {
+		result = append(result, TagCount{Name: names[key], Count: n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// --- 任務快取 ---
+//
+// 資料量很大時（-store sqlite），啟動時只載入使用者與 session，任務在第一次用到時
+// 才依使用者整批從資料庫讀進來，放進 LRU 快取；快取的估計大小超過 -task-cache-mb 時，
+// 丟掉最久沒用到的使用者。搜尋索引同樣在使用者第一次搜尋時才建立（見 search.go）。
+// 寫入一律直接寫進資料庫，並讓受影響使用者的快取失效，下次讀取時重新載入。
+// JSON 與事件紀錄的儲存層本來就把所有資料放在記憶體，不需要這一層
+
+// taskOverhead 是估計大小時每個任務固定的部分（時間、ID、旗標與 slice 標頭）
+const taskOverhead = 256
+
+type taskCacheEntry struct {
+	username string
+	tasks    []Task
+	size     int
+}
+
+type cachedStore struct {
+	Store
+
+	mu      sync.Mutex
+	limit   int // 位元組
+	used    int
+	order   *list.List // 最近用到的在前面
+	entries map[string]*list.Element
+	owners  map[int]string // 快取中的任務 ID → 所屬使用者
+	gen     int            // 每次失效加一，載入途中有寫入時不放進快取
+
+	hits, misses, evictions int
+}
+
+var taskCache *cachedStore
+
+// withTaskCache 以 limitMB 為上限包住儲存層；limitMB 為 0 時不快取，每次都讀資料庫
+func withTaskCache(s Store, limitMB int) Store {
+	c := &cachedStore{
+		Store:   s,
+		limit:   limitMB << 20,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		owners:  make(map[int]string),
+	}
+	taskCache = c
+	return c
+}
+
+// taskSize 粗估任務在記憶體中佔用的位元組
+func taskSize(t Task) int {
+	n := taskOverhead + len(t.Description) + len(t.Username) + len(t.CreatedBy) + len(t.EncryptedNote)
+	for _, tag := range t.Tags {
+		n += len(tag) + 16
+	}
+	for _, item := range t.Checklist {
+		n += len(item.Text) + 32
+	}
+	for _, a := range t.Activity {
+		n += len(a.User) + len(a.Kind) + len(a.Text) + 64
+	}
+	n += len(t.TimeEntries) * 48
+	return n
+}
+
+func (c *cachedStore) ListTasks(username string) ([]Task, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[username]; ok {
+		c.order.MoveToFront(el)
+		c.hits++
+		tasks := cloneTasks(el.Value.(*taskCacheEntry).tasks)
+		c.mu.Unlock()
+		return tasks, nil
+	}
+	c.misses++
+	gen := c.gen
+	c.mu.Unlock()
+
+	tasks, err := c.Store.ListTasks(username)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen == c.gen {
+		c.insertLocked(username, tasks)
+	}
+	return cloneTasks(tasks), nil
+}
+
+// cloneTasks 複製快取裡的任務再交出去，呼叫端改 Tags、BlockedBy 等 slice 時不會動到快取
+func cloneTasks(tasks []Task) []Task {
+	if tasks == nil {
+		return nil
+	}
+	out := make([]Task, len(tasks))
+	for i, t := range tasks {
+		out[i] = t.clone()
+	}
+	return out
+}
+
+// insertLocked 放進快取並淘汰最久沒用到的使用者；單一使用者就超過上限時不快取
+func (c *cachedStore) insertLocked(username string, tasks []Task) {
+	entry := &taskCacheEntry{username: username, tasks: tasks}
+	for _, t := range tasks {
+		entry.size += taskSize(t)
+	}
+	if entry.size > c.limit {
+		return
+	}
+	if _, ok := c.entries[username]; ok {
+		c.removeLocked(username)
+	}
+	c.entries[username] = c.order.PushFront(entry)
+	c.used += entry.size
+	for _, t := range tasks {
+		c.owners[t.ID] = username
+	}
+	for c.used > c.limit {
+		oldest := c.order.Back().Value.(*taskCacheEntry)
+		c.removeLocked(oldest.username)
+		c.evictions++
+	}
+}
+
+func (c *cachedStore) removeLocked(username string) {
+	el, ok := c.entries[username]
+	if !ok {
+		return
+	}
+	entry := el.Value.(*taskCacheEntry)
+	for _, t := range entry.tasks {
+		if c.owners[t.ID] == username {
+			delete(c.owners, t.ID)
+		}
+	}
+	c.used -= entry.size
+	c.order.Remove(el)
+	delete(c.entries, username)
+}
+
+// invalidate 讓 ids 目前所屬的使用者與 usernames 的快取失效；
+// 任務換了負責人時，原本與新的負責人都要重新載入
+func (c *cachedStore) invalidate(ids []int, usernames ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, id := range ids {
+		if owner, ok := c.owners[id]; ok {
+			c.removeLocked(owner)
+		}
+	}
+	for _, name := range usernames {
+		c.removeLocked(name)
+	}
+}
+
+// GetTask 先找快取，沒有時直接讀資料庫，不為了單一任務載入整個使用者
+func (c *cachedStore) GetTask(id int) (Task, error) {
+	c.mu.Lock()
+	if owner, ok := c.owners[id]; ok {
+		el := c.entries[owner]
+		for _, t := range el.Value.(*taskCacheEntry).tasks {
+			if t.ID == id {
+				c.order.MoveToFront(el)
+				c.hits++
+				c.mu.Unlock()
+				return t.clone(), nil
+			}
+		}
+	}
+	c.mu.Unlock()
+	return c.Store.GetTask(id)
+}
+
+func (c *cachedStore) CreateTask(task Task) (Task, error) {
+	task, err := c.Store.CreateTask(task)
+	if err == nil {
+		c.invalidate(nil, task.Username)
+	}
+	return task, err
+}
+
+func (c *cachedStore) CreateTasks(tasks []Task) ([]Task, error) {
+	tasks, err := c.Store.CreateTasks(tasks)
+	if err == nil {
+		names := make([]string, 0, len(tasks))
+		for _, t := range tasks {
+			names = append(names, t.Username)
+		}
+		c.invalidate(nil, names...)
+	}
+	return tasks, err
+}
+
+// UpdateTask 和 ModifyTask 一樣寫入後才失效，寫入途中讀進來的舊資料不會留在快取
+func (c *cachedStore) UpdateTask(task Task) error {
+	err := c.Store.UpdateTask(task)
+	c.invalidate([]int{task.ID}, task.Username)
+	return err
+}
+
+func (c *cachedStore) ModifyTask(id int, fn func(*Task) error) (Task, error) {
+	task, err := c.Store.ModifyTask(id, fn)
+	c.invalidate([]int{id}, task.Username)
+	return task, err
+}
+
+func (c *cachedStore) ModifyTasks(ids []int, fn func(*Task) error) ([]Task, error) {
+	tasks, err := c.Store.ModifyTasks(ids, fn)
+	names := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		names = append(names, t.Username)
+	}
+	c.invalidate(ids, names...)
+	return tasks, err
+}
+
+func (c *cachedStore) RestoreTask(id int) (Task, error) {
+	task, err := c.Store.RestoreTask(id)
+	if err == nil {
+		c.invalidate(nil, task.Username)
+	}
+	return task, err
+}
+
+func (c *cachedStore) DeleteTask(id int) error {
+	err := c.Store.DeleteTask(id)
+	c.invalidate([]int{id})
+	return err
+}
+
+// queryTaskCache 是管理主控台的快取狀態；快取是整個行程共用的，和 a 無關
+func (*App) queryTaskCache() (consoleResult, error) {
+	result := consoleResult{Columns: []string{"項目", "數值"}}
+	if taskCache == nil {
+		result.Rows = append(result.Rows, []string{"狀態", "未啟用（目前的儲存後端已把所有任務放在記憶體）"})
+		return result, nil
+	}
+	c := taskCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tasks := len(c.owners)
+	ratio := "—"
+	if total := c.hits + c.misses; total > 0 {
+		ratio = fmt.Sprintf("%.1f%%", float64(c.hits)*100/float64(total))
+	}
+	result.Rows = [][]string{
+		{"快取中的使用者", strconv.Itoa(len(c.entries))},
+		{"快取中的任務", strconv.Itoa(tasks)},
+		{"估計大小", fmt.Sprintf("%.1f MB / %d MB", float64(c.used)/(1<<20), c.limit>>20)},
+		{"命中／未命中", fmt.Sprintf("%d／%d（命中率 %s）", c.hits, c.misses, ratio)},
+		{"淘汰次數", strconv.Itoa(c.evictions)},
+	}
+	return result, nil
+}
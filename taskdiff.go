@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// --- 匯入差異與逐項確認 ---
+//
+// 匯入檔和現有任務比對後分成新增、更新、刪除三類：描述與到期時間相同的視為同一個任務，
+// 找不到時再以描述（不分大小寫）比對，欄位有差異的列為更新。mode=sync 時，
+// 檔案中沒有的個人任務列為刪除（移到垃圾桶）；mode=merge 只新增與更新。
+// /api/v1/tasks/diff 回傳比對結果但不寫入；/import 選擇檢視差異時在 /import/review 左右並排顯示，
+// 每一項都可以接受或略過，送出時重新比對一次，只套用勾選的項目
+
+const (
+	diffModeMerge = "merge"
+	diffModeSync  = "sync"
+)
+
+// diffItem 是一項差異；Key 是確認表單用的識別，例如 add-3、update-12、delete-40
+type diffItem struct {
+	Key    string      `json:"key"`
+	Kind   string      `json:"kind"` // add、update 或 delete
+	Line   int         `json:"line,omitempty"`
+	TaskID int         `json:"task_id,omitempty"`
+	Before *taskRecord `json:"before,omitempty"`
+	After  *taskRecord `json:"after,omitempty"`
+	Fields []string    `json:"fields,omitempty"` // update 時有差異的欄位，名稱同匯出檔
+
+	task Task // add 與 update 時解析好的匯入任務
+}
+
+type taskDiff struct {
+	Mode      string      `json:"mode"`
+	Adds      []diffItem  `json:"adds"`
+	Updates   []diffItem  `json:"updates"`
+	Deletes   []diffItem  `json:"deletes"`
+	Unchanged int         `json:"unchanged"`
+	Problems  problemList `json:"problems,omitempty"`
+}
+
+// problemList 是匯入時各列的問題；API 回應裡是中文訊息
+type problemList []error
+
+func (p problemList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(localMessages(LocaleZhTW, p))
+}
+
+// Empty 回報是否沒有任何需要確認的項目
+func (d taskDiff) Empty() bool {
+	return len(d.Adds)+len(d.Updates)+len(d.Deletes) == 0
+}
+
+// diffFields 比較匯出檔中可以匯入的欄位，回傳不同的欄位名稱
+func diffFields(before, after taskRecord) []string {
+	var fields []string
+	if before.DueAt != after.DueAt {
+		fields = append(fields, "due_at")
+	}
+	if before.Status != after.Status {
+		fields = append(fields, "status")
+	}
+	if before.Priority != after.Priority {
+		fields = append(fields, "priority")
+	}
+	if strings.Join(before.Tags, ",") != strings.Join(after.Tags, ",") {
+		fields = append(fields, "tags")
+	}
+	if before.Recurrence != after.Recurrence {
+		fields = append(fields, "recurrence")
+	}
+	return fields
+}
+
+// diffTasks 比對匯入的資料與 username 的個人任務；專案任務與封存的任務不在比對範圍內
+func (a *App) diffTasks(username string, records []numberedRecord, unit, mode string, now time.Time) (taskDiff, error) {
+	existing, err := a.store.ListTasks(username)
+	if err != nil {
+		return taskDiff{}, err
+	}
+	var candidates []Task
+	for _, t := range existing {
+		if t.Username == username && t.ProjectID == 0 && !t.Archived {
+			candidates = append(candidates, t)
+		}
+	}
+	matched := make(map[int]bool)
+	match := func(same func(Task) bool) (Task, bool) {
+		for _, t := range candidates {
+			if !matched[t.ID] && same(t) {
+				matched[t.ID] = true
+				return t, true
+			}
+		}
+		return Task{}, false
+	}
+
+	d := taskDiff{Mode: mode, Adds: []diffItem{}, Updates: []diffItem{}, Deletes: []diffItem{}}
+	for _, rec := range records {
+		task, err := rec.toTask(username, now)
+		if err != nil {
+			d.Problems = append(d.Problems, lineProblem(unit, rec.Line, err, "資料不正確"))
+			continue
+		}
+		after := toRecord(task)
+		key := dedupKey(task.Description, task.DueAt)
+		current, ok := match(func(t Task) bool { return dedupKey(t.Description, t.DueAt) == key })
+		if !ok {
+			current, ok = match(func(t Task) bool {
+				return strings.EqualFold(strings.TrimSpace(t.Description), task.Description)
+			})
+		}
+		if !ok {
+			d.Adds = append(d.Adds, diffItem{Key: fmt.Sprintf("add-%d", rec.Line), Kind: "add", Line: rec.Line, After: &after, task: task})
+			continue
+		}
+		before := toRecord(current)
+		fields := diffFields(before, after)
+		if len(fields) == 0 {
+			d.Unchanged++
+			continue
+		}
+		d.Updates = append(d.Updates, diffItem{
+			Key: fmt.Sprintf("update-%d", current.ID), Kind: "update", Line: rec.Line, TaskID: current.ID,
+			Before: &before, After: &after, Fields: fields, task: task,
+		})
+	}
+
+	if mode == diffModeSync {
+		for _, t := range candidates {
+			if !matched[t.ID] {
+				before := toRecord(t)
+				d.Deletes = append(d.Deletes, diffItem{Key: fmt.Sprintf("delete-%d", t.ID), Kind: "delete", TaskID: t.ID, Before: &before})
+			}
+		}
+	}
+	return d, nil
+}
+
+// applyTaskDiff 只套用 accepted 裡的項目，回傳實際套用的數量
+func (a *App) applyTaskDiff(username string, d taskDiff, accepted map[string]bool, now time.Time) (added, updated, deleted int, err error) {
+	var adds []Task
+	for _, item := range d.Adds {
+		if accepted[item.Key] {
+			adds = append(adds, item.task)
+		}
+	}
+	if len(adds) > 0 {
+		created, err := a.store.CreateTasks(adds)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		added = len(created)
+	}
+
+	for _, item := range d.Updates {
+		if !accepted[item.Key] {
+			continue
+		}
+		incoming := item.task
+		_, err := a.store.ModifyTask(item.TaskID, func(t *Task) error {
+			if t.Username != username || t.Trashed() {
+				return ErrNotFound
+			}
+			before := *t
+			t.DueAt = incoming.DueAt
+			t.AllDay = incoming.AllDay
+			t.Priority = incoming.Priority
+			t.Tags = incoming.Tags
+			t.Recurrence = incoming.Recurrence
+			if t.EffectiveStatus() != incoming.EffectiveStatus() {
+				t.setStatus(incoming.EffectiveStatus(), now)
+				if incoming.Completed && !incoming.CompletedAt.IsZero() {
+					t.CompletedAt = incoming.CompletedAt
+				}
+			}
+			t.recordEdit(username, before, now)
+			return nil
+		})
+		if err == ErrNotFound {
+			continue // 審閱期間被刪除或轉手的任務直接略過
+		}
+		if err != nil {
+			return added, updated, deleted, err
+		}
+		updated++
+	}
+
+	for _, item := range d.Deletes {
+		if !accepted[item.Key] {
+			continue
+		}
+		if err := a.trashTask(item.TaskID, now); err != nil && err != ErrNotFound {
+			return added, updated, deleted, err
+		}
+		deleted++
+	}
+	return added, updated, deleted, nil
+}
+
+// reviewImport 讀取上傳的檔案並顯示審閱頁，還沒有寫入任何資料
+func (a *App) reviewImport(w http.ResponseWriter, r *http.Request, username, mode string) {
+	records, problems, unit, err := readImportFile(r)
+	if err == nil && len(records) == 0 {
+		err = invalidInput("檔案中沒有任務")
+	}
+	var d taskDiff
+	if err == nil {
+		d, err = a.diffTasks(username, records, unit, mode, time.Now())
+	}
+	if err != nil {
+		a.flashError(r, err, "讀取檔案失敗")
+		http.Redirect(w, r, "/import", http.StatusSeeOther)
+		return
+	}
+	d.Problems = append(problems, d.Problems...)
+	encoded, _ := json.Marshal(records)
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+		"Diff":      d,
+		"Problems":  localMessages(a.requestLocale(r), d.Problems),
+		"Sections":  diffSections(d),
+		"Records":   string(encoded),
+		"Unit":      unit,
+		"Sync":      mode == diffModeSync,
+	}
+	t := a.localize(r, a.pages.page("import-review"))
+	t.Execute(w, data)
+}
+
+// diffPane 是審閱頁左右兩欄的其中一欄，Fields 是要標示顏色的欄位
+type diffPane struct {
+	taskRecord
+	Fields []string
+}
+
+func (p diffPane) Changed(field string) bool {
+	return containsString(p.Fields, field)
+}
+
+// diffSection 是審閱頁上的一類變更，沒有項目的類別不顯示
+type diffSection struct {
+	Title string
+	Items []diffRow
+}
+
+type diffRow struct {
+	Key           string
+	Line          int
+	Before, After *diffPane
+}
+
+func diffSections(d taskDiff) []diffSection {
+	pane := func(rec *taskRecord, fields []string) *diffPane {
+		if rec == nil {
+			return nil
+		}
+		return &diffPane{taskRecord: *rec, Fields: fields}
+	}
+	var sections []diffSection
+	for _, s := range []struct {
+		title string
+		items []diffItem
+	}{
+		{"➕ 新增", d.Adds},
+		{"✏️ 更新", d.Updates},
+		{"🗑 刪除（移到垃圾桶）", d.Deletes},
+	} {
+		if len(s.items) == 0 {
+			continue
+		}
+		section := diffSection{Title: s.title}
+		for _, item := range s.items {
+			section.Items = append(section.Items, diffRow{
+				Key:    item.Key,
+				Line:   item.Line,
+				Before: pane(item.Before, item.Fields),
+				After:  pane(item.After, item.Fields),
+			})
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// importReviewHandler 是審閱頁送出的 /import/review，依勾選的項目寫入
+func (a *App) importReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/import", http.StatusSeeOther)
+		return
+	}
+	username := a.getUsername(r)
+	mode := r.FormValue("mode")
+	if mode != diffModeSync {
+		mode = diffModeMerge
+	}
+	var records []numberedRecord
+	if err := json.Unmarshal([]byte(r.FormValue("records")), &records); err != nil || len(records) > maxTaskImportSize {
+		a.flashError(r, invalidInput("審閱資料不正確，請重新上傳檔案"), "")
+		http.Redirect(w, r, "/import", http.StatusSeeOther)
+		return
+	}
+	accepted := make(map[string]bool)
+	for _, key := range r.Form["accept"] {
+		accepted[key] = true
+	}
+
+	now := time.Now()
+	d, err := a.diffTasks(username, records, r.FormValue("unit"), mode, now)
+	if err != nil {
+		a.flashError(r, err, "讀取任務失敗")
+		http.Redirect(w, r, "/import", http.StatusSeeOther)
+		return
+	}
+	added, updated, deleted, err := a.applyTaskDiff(username, d, accepted, now)
+	if err != nil {
+		a.flashError(r, displayError(err, "套用途中失敗"), "")
+		a.flashSuccess(r, "已新增 %d、更新 %d、刪除 %d 個任務", added, updated, deleted)
+		http.Redirect(w, r, "/import", http.StatusSeeOther)
+		return
+	}
+	a.flashSuccess(r, "已新增 %d、更新 %d、刪除 %d 個任務", added, updated, deleted)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// apiTaskDiff：POST /api/v1/tasks/diff?mode=merge|sync，內容是與 JSON 匯出檔相同的任務陣列，
+// 只回傳比對結果，不寫入
+func (a *App) apiTaskDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAPIError(w, http.StatusMethodNotAllowed, "不支援的方法")
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	switch mode {
+	case "":
+		mode = diffModeMerge
+	case diffModeMerge, diffModeSync:
+	default:
+		writeAPIError(w, http.StatusBadRequest, "mode 必須是 merge 或 sync")
+		return
+	}
+	var list []taskRecord
+	if !readJSON(w, r, &list) {
+		return
+	}
+	if len(list) > maxTaskImportSize {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("一次最多比對 %d 筆任務", maxTaskImportSize))
+		return
+	}
+	records := make([]numberedRecord, len(list))
+	for i, rec := range list {
+		records[i] = numberedRecord{Line: i + 1, taskRecord: rec}
+	}
+	d, err := a.diffTasks(a.getUsername(r), records, "筆", mode, time.Now())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "讀取任務失敗")
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
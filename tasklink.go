@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// --- 任務頁與深層連結 ---
+//
+// 每個任務都有固定的網址 /task/{id}，提醒信、摘要信、回顧信、推播、桌面通知與行事曆訂閱
+// 都附上這個連結，網址的前綴依 -base-url 決定。未登入時點開會先導到登入頁，登入後回到任務頁。
+// 使用者可在設定頁開啟「顯示任務編號」，清單與通知裡的任務前面會加上 #編號
+
+// taskPath 是任務頁的站內路徑
+func taskPath(id int) string {
+	return "/task/" + strconv.Itoa(id)
+}
+
+// taskURL 是任務頁的完整網址，給站外的信件與行事曆使用
+func taskURL(id int) string {
+	return publicBaseURL + taskPath(id)
+}
+
+// taskLabel 是通知裡任務的名稱，使用者開啟任務編號時加上 #編號
+func taskLabel(user User, t Task) string {
+	if user.ShowTaskIDs {
+		return "#" + strconv.Itoa(t.ID) + " " + t.Description
+	}
+	return t.Description
+}
+
+// viewableTask 取出 username 看得到的任務：自己負責的、分享給自己的，或所屬專案裡沒有設成私人的
+func (a *App) viewableTask(id int, username string) (Task, error) {
+	task, err := a.store.GetTask(id)
+	if err != nil {
+		return Task{}, err
+	}
+	if task.canEdit(username) {
+		return task, nil
+	}
+	if _, err := a.taskProject(id, username); err != nil {
+		return Task{}, ErrNotFound
+	}
+	return task, nil
+}
+
+func (a *App) taskPageHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/task/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	task, err := a.viewableTask(id, username)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	projectName := ""
+	if task.ProjectID != 0 {
+		if p, err := a.store.GetProject(task.ProjectID); err == nil {
+			projectName = p.Name
+		}
+	}
+	// 動態由新到舊，最後一筆是建立
+	history := make([]TaskActivity, 0, len(task.Activity)+1)
+	for i := len(task.Activity) - 1; i >= 0; i-- {
+		history = append(history, task.Activity[i])
+	}
+	creator := task.CreatedBy
+	if creator == "" {
+		creator = task.Username
+	}
+	history = append(history, TaskActivity{Time: task.CreatedAt, User: creator, Kind: "created"})
+
+	user, _ := a.store.GetUser(username)
+	data := map[string]interface{}{
+		"Username":    username,
+		"Task":        task,
+		"Own":         task.Username == username,
+		"CanEdit":     task.canEdit(username),
+		"SharedIn":    task.Username != username && task.isSharedWith(username),
+		"ShowIDs":     user.ShowTaskIDs,
+		"ProjectName": projectName,
+		"Blockers":    a.blockersOf(task, username),
+		"History":     history,
+		"MaxComment":  maxCommentLength,
+		"Nonce":       newNonce(username),
+		"CSRFToken":   a.sessions.CSRFToken(r),
+		"Flashes":     a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("task"))
+	t.Execute(w, data)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 老師模式 ---
+//
+// 老師維護一份學生名單，派發的作業（Kind 為 assignment 的公告）會在每位學生的清單裡各建立一份任務；
+// 完成情形以「學生 × 作業」的表格呈現，準時與否以作業本身的截止時間判斷，學生改了自己副本的時間也不影響
+
+// 作業在某位學生身上的狀態
+const (
+	SubmissionOnTime  = "ontime"
+	SubmissionLate    = "late"
+	SubmissionPending = "pending" // 未完成、尚未截止
+	SubmissionMissing = "missing" // 未完成、已過截止時間
+	SubmissionDeleted = "deleted" // 學生刪除了自己的副本
+	SubmissionNone    = ""        // 派發時不在名單上
+)
+
+type submissionCell struct {
+	State       string
+	CompletedAt time.Time
+}
+
+type assignmentRow struct {
+	Student  string
+	Cells    []submissionCell // 與 assignmentMatrix.Assignments 同順序
+	Assigned int              // 實際收到的作業數
+	Done     int
+}
+
+type assignmentMatrix struct {
+	Assignments []Announcement // 依截止時間由舊到新
+	Done        []int          // 每份作業已完成的人數
+	Rows        []assignmentRow
+}
+
+func (a *App) isTeacher(username string) bool {
+	user, err := a.store.GetUser(username)
+	return err == nil && (user.Role == RoleTeacher || user.Role == RoleAdmin)
+}
+
+func (a *App) requireTeacher(next http.HandlerFunc) http.HandlerFunc {
+	return a.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !a.isTeacher(a.getUsername(r)) {
+			http.Error(w, "需要老師權限", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+func submissionState(a Announcement, task Task, ok bool, now time.Time) submissionCell {
+	switch {
+	case !ok:
+		return submissionCell{State: SubmissionDeleted}
+	case task.Completed && !task.CompletedAt.After(a.DueAt):
+		return submissionCell{State: SubmissionOnTime, CompletedAt: task.CompletedAt}
+	case task.Completed:
+		return submissionCell{State: SubmissionLate, CompletedAt: task.CompletedAt}
+	case now.After(a.DueAt):
+		return submissionCell{State: SubmissionMissing}
+	default:
+		return submissionCell{State: SubmissionPending}
+	}
+}
+
+// buildAssignmentMatrix 彙整 teacher 派發的所有作業；列出名單上的學生，
+// 以及曾收到作業但已不在名單上的學生
+func (a *App) buildAssignmentMatrix(teacher User, now time.Time) (assignmentMatrix, error) {
+	var m assignmentMatrix
+	list, err := a.store.ListAnnouncements()
+	if err != nil {
+		return m, err
+	}
+	copies, err := a.announcementCopies()
+	if err != nil {
+		return m, err
+	}
+
+	students := append([]string(nil), teacher.Roster...)
+	seen := make(map[string]bool)
+	for _, s := range students {
+		seen[s] = true
+	}
+	for _, asg := range list {
+		if asg.Kind != AnnouncementKindAssignment || asg.CreatedBy != teacher.Username {
+			continue
+		}
+		m.Assignments = append(m.Assignments, asg)
+		for _, s := range asg.Recipients {
+			if !seen[s] {
+				seen[s] = true
+				students = append(students, s)
+			}
+		}
+	}
+	sort.SliceStable(m.Assignments, func(i, j int) bool { return m.Assignments[i].DueAt.Before(m.Assignments[j].DueAt) })
+
+	m.Done = make([]int, len(m.Assignments))
+	for _, student := range students {
+		row := assignmentRow{Student: student}
+		for i, asg := range m.Assignments {
+			cell := submissionCell{State: SubmissionNone}
+			for _, r := range asg.Recipients {
+				if r == student {
+					task, ok := copies[asg.ID][student]
+					cell = submissionState(asg, task, ok, now)
+					break
+				}
+			}
+			if cell.State != SubmissionNone {
+				row.Assigned++
+			}
+			if cell.State == SubmissionOnTime || cell.State == SubmissionLate {
+				row.Done++
+				m.Done[i]++
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		m.Rows = append(m.Rows, row)
+	}
+	return m, nil
+}
+
+func (a *App) teacherHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	teacher, err := a.store.GetUser(username)
+	if err != nil {
+		http.Error(w, "讀取帳號失敗", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == "POST" {
+		switch r.FormValue("action") {
+		case "roster":
+			a.updateRoster(r, teacher)
+		case "assign":
+			a.assignHomework(r, teacher)
+		}
+		http.Redirect(w, r, "/teacher", http.StatusSeeOther)
+		return
+	}
+
+	matrix, err := a.buildAssignmentMatrix(teacher, time.Now())
+	if err != nil {
+		http.Error(w, "讀取作業失敗", http.StatusInternalServerError)
+		return
+	}
+	data := map[string]interface{}{
+		"Username":  username,
+		"Roster":    strings.Join(teacher.Roster, "\n"),
+		"Matrix":    matrix,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("teacher"))
+	t.Execute(w, data)
+}
+
+func (a *App) updateRoster(r *http.Request, teacher User) {
+	students, err := a.parseMembers(r.FormValue("roster"))
+	if err != nil {
+		a.flashError(r, err, "")
+		return
+	}
+	roster := students[:0]
+	for _, s := range students {
+		if s != teacher.Username {
+			roster = append(roster, s)
+		}
+	}
+	_, err = a.store.ModifyUser(teacher.Username, func(u *User) error {
+		u.Roster = roster
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "儲存名單失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "學生名單已更新")
+}
+
+func (a *App) assignHomework(r *http.Request, teacher User) {
+	desc := strings.TrimSpace(r.FormValue("description"))
+	dueAt, err := parseDueTime(r.FormValue("due_at"))
+	descErr := checkDescription(desc)
+	switch {
+	case descErr != nil:
+		err = descErr
+	case err != nil: // parseDueTime 的錯誤已可直接顯示
+	case len(teacher.Roster) == 0:
+		err = invalidInput("請先設定學生名單")
+	}
+	if err != nil {
+		a.flashError(r, err, "")
+		return
+	}
+
+	asg := Announcement{
+		Description: desc,
+		DueAt:       dueAt,
+		CreatedAt:   time.Now(),
+		CreatedBy:   teacher.Username,
+		Recipients:  teacher.Roster,
+		Kind:        AnnouncementKindAssignment,
+	}
+	if _, err := a.distributeAnnouncement(asg); err != nil {
+		a.flashError(r, err, "派發作業失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "作業已派發給 %s", strings.Join(teacher.Roster, a.tr(r, "、")))
+}
+
+// submissionText 是匯出 CSV 時每一格的內容
+func submissionText(c submissionCell) string {
+	switch c.State {
+	case SubmissionOnTime:
+		return "準時 " + c.CompletedAt.Format("2006-01-02 15:04")
+	case SubmissionLate:
+		return "遲交 " + c.CompletedAt.Format("2006-01-02 15:04")
+	case SubmissionPending:
+		return "未完成"
+	case SubmissionMissing:
+		return "逾期未交"
+	case SubmissionDeleted:
+		return "已刪除"
+	}
+	return ""
+}
+
+func (a *App) teacherExportHandler(w http.ResponseWriter, r *http.Request) {
+	teacher, err := a.store.GetUser(a.getUsername(r))
+	if err != nil {
+		http.Error(w, "讀取帳號失敗", http.StatusInternalServerError)
+		return
+	}
+	matrix, err := a.buildAssignmentMatrix(teacher, time.Now())
+	if err != nil {
+		http.Error(w, "讀取作業失敗", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="assignments-`+time.Now().Format("20060102")+`.csv"`)
+	io.WriteString(w, csvBOM)
+	cw := csv.NewWriter(w)
+	header := []string{"學生"}
+	for _, asg := range matrix.Assignments {
+		header = append(header, csvSafe(asg.Description)+"（截止 "+asg.DueAt.Format("2006-01-02 15:04")+"）")
+	}
+	cw.Write(append(header, "完成數"))
+	for _, row := range matrix.Rows {
+		record := []string{csvSafe(row.Student)}
+		for _, c := range row.Cells {
+			record = append(record, submissionText(c))
+		}
+		record = append(record, strconv.Itoa(row.Done)+"/"+strconv.Itoa(row.Assigned))
+		cw.Write(record)
+	}
+	cw.Flush()
+}
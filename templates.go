@@ -0,0 +1,152 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- 頁面模板 ---
+//
+// 頁面模板是 templates/ 底下的 .html 檔，編譯時以 embed 打包進執行檔；各頁共用的片段放在 templates/partials/，
+// 每一頁都可以用 {{template "flash" .Flashes}}、{{template "logout" $.CSRFToken}}、
+// {{template "countdown" .Username}}、{{template "clipstyle"}}、{{template "allday"}}、{{template "suggest"}} 引用。
+// 啟動時全部解析一次，之後每個請求只 Clone 一份、換上這個請求的語系與日期格式（localize）再執行。
+// -templates DIR 裡有同名檔案（例如 DIR/list.html、DIR/partials/flash.html）時用它取代內建的版本，
+// 自行架設的人只要複製想改的檔案；-dev-templates 每個請求都重新讀檔解析，改完重新整理就看得到
+// （沒有指定 -templates 時讀工作目錄的 templates/）。模板有錯時啟動就會失敗，開發模式則在頁面上顯示錯誤
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+var (
+	templateDir    string // -templates
+	templateReload bool   // -dev-templates
+)
+
+// templatePartials 是每一頁都會一起解析的共用片段
+var templatePartials = []string{"flash", "logout", "countdown", "clipstyle", "allday", "suggest"}
+
+// templateSet 是一組頁面模板：啟動時解析好的頁面，加上覆寫目錄與開發模式的設定
+type templateSet struct {
+	dir     string
+	reload  bool
+	pages   map[string]*template.Template // 只讀不寫，也從不直接執行，每次使用都 Clone
+	timeout string                        // 處理逾時時的 503 頁面，由 http.TimeoutHandler 原樣送出
+}
+
+// templateFuncs 是所有模板共用的函式。語系與日期格式先以中文、預設格式掛上讓模板可以解析，
+// 執行前由 localize 換成這個請求的設定；hl、hasMatch、daysLeft 依請求而定，由搜尋頁與垃圾桶換掉
+func templateFuncs() template.FuncMap {
+	funcs := template.FuncMap{
+		"now":           time.Now,
+		"join":          strings.Join,
+		"bytes":         formatBytes,
+		"prio":          effectivePriority,
+		"providerLabel": oauthProviderLabel,
+		"hl":            func(text string) template.HTML { return template.HTML(template.HTMLEscapeString(text)) },
+		"hasMatch":      func(string) bool { return false },
+		"daysLeft":      func(Task) int { return 0 },
+		"countdowns":    func(string) []Task { return nil },
+	}
+	for _, m := range []template.FuncMap{countdownFuncs, clipFuncs, DatePrefs{}.Funcs(), i18nFuncs(LocaleZhTW)} {
+		for name, fn := range m {
+			funcs[name] = fn
+		}
+	}
+	return funcs
+}
+
+// source 讀取 name（例如 "list"、"partials/flash"）的內容，覆寫目錄裡有就用它的
+func (s *templateSet) source(name string) (string, error) {
+	file := name + ".html"
+	dir := s.dir
+	if dir == "" && s.reload {
+		dir = "templates"
+	}
+	if dir != "" {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err == nil {
+			return string(b), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	b, err := embeddedTemplates.ReadFile(path.Join("templates", file))
+	return string(b), err
+}
+
+// parse 解析 name 與所有共用片段
+func (s *templateSet) parse(name string) (*template.Template, error) {
+	t := template.New(name).Funcs(templateFuncs())
+	for _, partial := range templatePartials {
+		src, err := s.source("partials/" + partial)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.New(partial).Parse(src); err != nil {
+			return nil, err
+		}
+	}
+	src, err := s.source(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.Parse(src)
+}
+
+// newTemplateSet 解析所有頁面，有錯就回傳錯誤讓啟動失敗；頁面清單以內建的檔案為準，覆寫目錄裡多出來的檔案不會用到
+func newTemplateSet(dir string, reload bool) (*templateSet, error) {
+	files, err := fs.Glob(embeddedTemplates, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+	s := &templateSet{dir: dir, reload: reload, pages: make(map[string]*template.Template, len(files))}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".html")
+		t, err := s.parse(name)
+		if err != nil {
+			return nil, fmt.Errorf("模板 %s 有錯：%v", name, err)
+		}
+		s.pages[name] = t
+	}
+
+	var b strings.Builder
+	if err := s.page("timeout").Execute(&b, nil); err != nil {
+		return nil, fmt.Errorf("模板 timeout 有錯：%v", err)
+	}
+	s.timeout = b.String()
+	return s, nil
+}
+
+// page 回傳 name 的模板副本，呼叫端可以再掛上這個請求的函式後執行。
+// 開發模式每次重新解析；解析失敗時回傳顯示錯誤訊息的頁面，不會讓 handler 拿到 nil
+func (s *templateSet) page(name string) *template.Template {
+	var t *template.Template
+	var err error
+	if master, ok := s.pages[name]; ok && !s.reload {
+		t, err = master.Clone()
+	} else {
+		t, err = s.parse(name)
+	}
+	if err != nil {
+		log.Printf("模板 %s 有錯：%v", name, err)
+		return templateErrorPage(err)
+	}
+	return t
+}
+
+func templateErrorPage(err error) *template.Template {
+	msg := err.Error()
+	return template.Must(template.New("template-error").Funcs(template.FuncMap{"templateError": func() string { return msg }}).
+		Parse(`<!DOCTYPE html><html><body><h1>模板有錯</h1><pre>{{templateError}}</pre></body></html>`))
+}
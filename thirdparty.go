@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 從 Todoist／Trello 匯入 ---
+//
+// /import/service 接受 Todoist 專案「匯出為範本」的 CSV 與 Trello 看板「匯出 JSON」，
+// 先顯示預覽，確認後才新增；重複的任務（描述與到期時間相同）和 /add/markdown 一樣略過。
+// Todoist：CONTENT 是任務，裡面的 @標籤 轉成標籤，PRIORITY 1～3 對應高／中／低，
+// INDENT 大於 1 的任務成為上一個任務的子項目，DATE 是 every day／week／month／weekday 時設成重複任務。
+// Trello：卡片是任務，due 與 dueComplete 是到期時間與完成狀態，標籤的名稱（沒有名稱時用顏色）轉成標籤，
+// 檢查清單成為子項目；封存的卡片與封存列表裡的卡片不匯入。
+// 沒有到期日的任務用表單上指定的預設到期時間。預覽頁把解析結果放在隱藏欄位送回，確認時每一筆重新檢查
+
+const (
+	sourceTodoist = "todoist"
+	sourceTrello  = "trello"
+)
+
+var todoistLabel = regexp.MustCompile(`(^|\s)@([^\s@]+)`)
+
+// todoistDateLayouts 是 Todoist DATE 欄常見的寫法（英文介面），其他寫法先試 parseImportTime
+var todoistDateLayouts = []string{"Jan 2 2006 15:04", "Jan 2 2006", "2 Jan 2006 15:04", "2 Jan 2006"}
+
+// serviceRecord 是從其他服務讀到的一筆任務，欄位對應到 taskRecord，另外帶著子項目
+type serviceRecord struct {
+	Line int `json:"line"`
+	taskRecord
+	Checklist []ChecklistItem `json:"checklist,omitempty"`
+}
+
+// toTask 檢查一筆資料並轉成任務，子項目沿用 addChecklistItem 的上限
+func (rec serviceRecord) toTask(username string, now time.Time) (Task, error) {
+	task, err := rec.taskRecord.toTask(username, now)
+	if err != nil {
+		return Task{}, err
+	}
+	for _, item := range rec.Checklist {
+		text := obsidianText(item.Text)
+		if text == "" {
+			continue
+		}
+		if err := task.addChecklistItem(text); err != nil {
+			return Task{}, err
+		}
+		task.Checklist[len(task.Checklist)-1].Done = item.Done
+	}
+	return task, nil
+}
+
+func serviceName(source string) string {
+	if source == sourceTrello {
+		return "Trello"
+	}
+	return "Todoist"
+}
+
+// serviceTag 把標籤名稱裡的空白換成 -，標籤不能有空白
+func serviceTag(name string) string {
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// todoistPriority 對應 Todoist 的 p1～p4；p4（不設定）與看不懂的值都是中
+func todoistPriority(s string) string {
+	switch strings.TrimSpace(s) {
+	case "1":
+		return PriorityHigh
+	case "3":
+		return PriorityLow
+	}
+	return PriorityMedium
+}
+
+// todoistRecurrence 把 every day 之類的 DATE 轉成重複規則，其他寫法回傳 RecurNone
+func todoistRecurrence(date string) string {
+	switch strings.Join(strings.Fields(strings.ToLower(date)), " ") {
+	case "every day", "daily":
+		return RecurDaily
+	case "every week", "weekly":
+		return RecurWeekly
+	case "every month", "monthly":
+		return RecurMonthly
+	case "every weekday", "every workday":
+		return RecurWeekdays
+	}
+	return RecurNone
+}
+
+// todoistDue 把 DATE 轉成匯入用的到期時間；只有日期時照樣只寫日期，匯入成全天任務
+func todoistDue(date string) (string, bool) {
+	if t, err := parseImportTime(date); err == nil {
+		if isDateOnly(date) {
+			return t.Format(dateInputFormat), true
+		}
+		return formatExportTime(t), true
+	}
+	for _, layout := range todoistDateLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(date), time.Local); err == nil {
+			if !strings.Contains(layout, "15:04") {
+				return t.Format(dateInputFormat), true
+			}
+			return formatExportTime(t), true
+		}
+	}
+	return "", false
+}
+
+// parseTodoistCSV 讀取 Todoist 的 CSV；section、note 等不是任務的列略過，數量以 problems 回報
+func parseTodoistCSV(r io.Reader, defaultDue time.Time) ([]serviceRecord, []error, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, invalidInput("CSV 格式錯誤：%v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, csvBOM)))] = i
+	}
+	_, hasType := columns["TYPE"]
+	_, hasContent := columns["CONTENT"]
+	if !hasType || !hasContent {
+		return nil, nil, invalidInput("不是 Todoist 匯出的 CSV：找不到 TYPE 與 CONTENT 欄")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var records []serviceRecord
+	var problems []error
+	other := 0
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, invalidInput("CSV 格式錯誤：%v", err)
+		}
+		switch strings.ToLower(field(record, "TYPE")) {
+		case "task":
+		case "":
+			continue
+		default:
+			other++
+			continue
+		}
+
+		content := field(record, "CONTENT")
+		var tags []string
+		for _, m := range todoistLabel.FindAllStringSubmatch(content, -1) {
+			tags = append(tags, m[2])
+		}
+		content = obsidianText(todoistLabel.ReplaceAllString(content, " "))
+
+		if indent, _ := strconv.Atoi(field(record, "INDENT")); indent > 1 {
+			if len(records) == 0 {
+				problems = append(problems, invalidInput("第 %d 列：子任務前面沒有上層任務，已略過", line))
+				continue
+			}
+			parent := &records[len(records)-1]
+			parent.Checklist = append(parent.Checklist, ChecklistItem{Text: content})
+			continue
+		}
+		if len(records) == maxTaskImportSize {
+			return nil, nil, invalidInput("一次最多匯入 %d 筆任務", maxTaskImportSize)
+		}
+
+		rec := serviceRecord{Line: line, taskRecord: taskRecord{
+			Description: content,
+			DueAt:       formatExportTime(defaultDue),
+			Priority:    todoistPriority(field(record, "PRIORITY")),
+			Tags:        tags,
+		}}
+		if date := field(record, "DATE"); date != "" {
+			if rule := todoistRecurrence(date); rule != RecurNone {
+				rec.Recurrence = rule
+			} else if due, ok := todoistDue(date); ok {
+				rec.DueAt = due
+			} else {
+				problems = append(problems, invalidInput("第 %d 列：看不懂到期日「%s」，改用預設的到期時間", line, date))
+			}
+		}
+		records = append(records, rec)
+	}
+	if other > 0 {
+		problems = append(problems, invalidInput("略過 %d 列不是任務的資料（區段或留言）", other))
+	}
+	return records, problems, nil
+}
+
+// trelloBoard 是 Trello 看板匯出檔裡用得到的部分
+type trelloBoard struct {
+	Cards []struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		IDList      string `json:"idList"`
+		Due         string `json:"due"`
+		DueComplete bool   `json:"dueComplete"`
+		Closed      bool   `json:"closed"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+	} `json:"cards"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Checklists []struct {
+		IDCard     string  `json:"idCard"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+}
+
+// parseTrelloJSON 讀取 Trello 看板的匯出檔；封存的卡片不匯入，數量以 problems 回報
+func parseTrelloJSON(r io.Reader, defaultDue time.Time) ([]serviceRecord, []error, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil || (board.Cards == nil && board.Lists == nil) {
+		return nil, nil, invalidInput("不是 Trello 看板匯出的 JSON")
+	}
+	closedLists := make(map[string]bool)
+	for _, l := range board.Lists {
+		closedLists[l.ID] = l.Closed
+	}
+	sort.SliceStable(board.Checklists, func(i, j int) bool { return board.Checklists[i].Pos < board.Checklists[j].Pos })
+	items := make(map[string][]ChecklistItem)
+	for _, cl := range board.Checklists {
+		checkItems := cl.CheckItems
+		sort.SliceStable(checkItems, func(i, j int) bool { return checkItems[i].Pos < checkItems[j].Pos })
+		for _, item := range checkItems {
+			items[cl.IDCard] = append(items[cl.IDCard], ChecklistItem{Text: item.Name, Done: item.State == "complete"})
+		}
+	}
+
+	var records []serviceRecord
+	var problems []error
+	archived := 0
+	for i, card := range board.Cards {
+		if card.Closed || closedLists[card.IDList] {
+			archived++
+			continue
+		}
+		if len(records) == maxTaskImportSize {
+			return nil, nil, invalidInput("一次最多匯入 %d 筆任務", maxTaskImportSize)
+		}
+		rec := serviceRecord{Line: i + 1, Checklist: items[card.ID], taskRecord: taskRecord{
+			Description: obsidianText(card.Name),
+			DueAt:       formatExportTime(defaultDue),
+			Completed:   card.DueComplete,
+		}}
+		for _, label := range card.Labels {
+			name := label.Name
+			if name == "" {
+				name = label.Color
+			}
+			rec.Tags = append(rec.Tags, serviceTag(name))
+		}
+		if card.Due != "" {
+			due, err := time.Parse(time.RFC3339, card.Due)
+			if err != nil {
+				problems = append(problems, invalidInput("第 %d 筆：看不懂到期日「%s」，改用預設的到期時間", i+1, card.Due))
+			} else {
+				rec.DueAt = formatExportTime(due.Local())
+			}
+		}
+		records = append(records, rec)
+	}
+	if archived > 0 {
+		problems = append(problems, invalidInput("略過 %d 張封存的卡片", archived))
+	}
+	return records, problems, nil
+}
+
+// readServiceFile 依 source 解析上傳的檔案；unit 是回報錯誤位置用的「列」或「筆」
+func readServiceFile(r *http.Request, source string, defaultDue time.Time) (records []serviceRecord, problems []error, unit string, err error) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, nil, "", invalidInput("請選擇要匯入的檔案")
+	}
+	defer file.Close()
+	if source == sourceTrello {
+		records, problems, err = parseTrelloJSON(file, defaultDue)
+		return records, problems, "筆", err
+	}
+	records, problems, err = parseTodoistCSV(file, defaultDue)
+	return records, problems, "列", err
+}
+
+// previewServiceRecords 把每一筆轉成任務並標出重複的任務，有問題的一筆加進 problems 後略過
+func (a *App) previewServiceRecords(records []serviceRecord, unit, username string, now time.Time) ([]markdownTask, []error, error) {
+	var tasks []markdownTask
+	var problems []error
+	for _, rec := range records {
+		task, err := rec.toTask(username, now)
+		if err != nil {
+			problems = append(problems, lineProblem(unit, rec.Line, err, "資料不正確"))
+			continue
+		}
+		tasks = append(tasks, markdownTask{Task: task, Line: rec.Line})
+	}
+	if err := a.markDuplicates(tasks, username); err != nil {
+		return nil, nil, err
+	}
+	return tasks, problems, nil
+}
+
+func (a *App) serviceImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/import", http.StatusSeeOther)
+		return
+	}
+	username := a.getUsername(r)
+	now := time.Now()
+	source := r.FormValue("source")
+	if source != sourceTrello {
+		source = sourceTodoist
+	}
+	unit := "列"
+	if source == sourceTrello {
+		unit = "筆"
+	}
+
+	if r.FormValue("action") == "create" {
+		var records []serviceRecord
+		if err := json.Unmarshal([]byte(r.FormValue("records")), &records); err != nil || len(records) > maxTaskImportSize {
+			a.flashError(r, invalidInput("預覽資料不正確，請重新上傳檔案"), "")
+			http.Redirect(w, r, "/import", http.StatusSeeOther)
+			return
+		}
+		tasks, problems, err := a.previewServiceRecords(records, unit, username, now)
+		if err != nil {
+			a.flashError(r, err, "讀取任務失敗")
+			http.Redirect(w, r, "/import", http.StatusSeeOther)
+			return
+		}
+		a.createPreviewedTasks(r, username, serviceName(source), tasks, problems)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	defaultDue, err := markdownDefaultDue(r, now)
+	var records []serviceRecord
+	var problems []error
+	if err == nil {
+		records, problems, unit, err = readServiceFile(r, source, defaultDue)
+	}
+	if err == nil && len(records) == 0 {
+		err = invalidInput("檔案中沒有任務")
+	}
+	var tasks []markdownTask
+	var invalid []error
+	if err == nil {
+		tasks, invalid, err = a.previewServiceRecords(records, unit, username, now)
+	}
+	if err != nil {
+		a.flashError(r, err, "讀取檔案失敗")
+		http.Redirect(w, r, "/import", http.StatusSeeOther)
+		return
+	}
+	encoded, _ := json.Marshal(records)
+	fresh := 0
+	for _, t := range tasks {
+		if !t.Duplicate {
+			fresh++
+		}
+	}
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+		"Service":   serviceName(source),
+		"Source":    source,
+		"Unit":      unit,
+		"Preview":   tasks,
+		"Problems":  localMessages(a.requestLocale(r), append(problems, invalid...)),
+		"NewCount":  fresh,
+		"Records":   string(encoded),
+	}
+	t := a.localize(r, a.pages.page("service-import"))
+	t.Execute(w, data)
+}
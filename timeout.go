@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- 請求逾時與慢請求警示 ---
+//
+// 每個請求都有處理時間上限（-handler-timeout），超過時回 503，不讓瀏覽器一直等儲存層；
+// 匯入、管理工作等較重的路徑在 routeTimeouts 另外給上限，/events 是長連線不設上限。
+// 處理時間超過 -slow-request 的請求寫進 log 並通知管理員，同一個路徑每 slowAlertEvery 最多通知一次
+
+const slowAlertEvery = 10 * time.Minute
+
+var (
+	handlerTimeout = 10 * time.Second
+	slowRequest    = 2 * time.Second
+)
+
+// routeTimeouts 依路徑前綴覆寫上限，0 表示不限制
+var routeTimeouts = map[string]time.Duration{
+	"/events":        0,
+	"/import":        time.Minute,
+	"/export":        time.Minute,
+	"/admin/users":   time.Minute,
+	"/admin/console": time.Minute,
+}
+
+// timeoutFor 回傳路徑適用的上限，前綴最長的設定優先
+func timeoutFor(path string) time.Duration {
+	limit, matched := handlerTimeout, ""
+	for prefix, d := range routeTimeouts {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(matched) {
+			limit, matched = d, prefix
+		}
+	}
+	return limit
+}
+
+var timeoutAPIBody, _ = json.Marshal(apiError{Error: "伺服器忙碌中，請稍後再試", Code: "timeout"})
+
+// withTimeouts 包住整個 mux：套上各路徑的處理時間上限，並記錄慢請求
+func (a *App) withTimeouts(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		limit := timeoutFor(r.URL.Path)
+		h := next
+		if limit > 0 {
+			msg := a.pages.timeout
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				msg = string(timeoutAPIBody)
+			}
+			h = http.TimeoutHandler(next, limit, msg)
+		}
+		h.ServeHTTP(w, r)
+
+		elapsed := time.Since(start)
+		if limit == 0 || elapsed < slowRequest {
+			return
+		}
+		timedOut := elapsed >= limit
+		log.Printf("慢請求：%s %s 花了 %s（使用者：%s）", r.Method, r.URL.Path, elapsed.Round(time.Millisecond), a.getUsername(r))
+		go a.alertSlowRequest(r.Method, r.URL.Path, elapsed, timedOut)
+	})
+}
+
+var slowAlerts = struct {
+	sync.Mutex
+	last map[string]time.Time
+}{last: make(map[string]time.Time)}
+
+// alertSlowRequest 通知所有管理員（見 notifyAdmins），同一個路徑每 slowAlertEvery 最多一次
+func (a *App) alertSlowRequest(method, path string, elapsed time.Duration, timedOut bool) {
+	key := method + " " + path
+	now := time.Now()
+	slowAlerts.Lock()
+	if now.Sub(slowAlerts.last[key]) < slowAlertEvery {
+		slowAlerts.Unlock()
+		return
+	}
+	slowAlerts.last[key] = now
+	slowAlerts.Unlock()
+
+	subject := fmt.Sprintf("慢請求警示：%s 花了 %s", key, elapsed.Round(time.Millisecond))
+	if timedOut {
+		subject = fmt.Sprintf("請求逾時：%s 超過 %s 未完成", key, timeoutFor(path))
+	}
+	body := subject + "\n\n可能是資料檔或資料庫的讀寫變慢了，請檢查伺服器的 log 與磁碟狀態。" +
+		fmt.Sprintf("\n同一個路徑 %d 分鐘內不會重複通知。\n", int(slowAlertEvery/time.Minute))
+
+	a.notifyAdmins(subject, body, pushMessage{Title: "🐢 " + subject, Body: "請檢查伺服器的 log 與磁碟狀態", Tag: "slow-request", URL: "/admin/console"})
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// --- 計時 ---
+//
+// 任務可以開始／停止計時，每一段存成一筆 TimeEntry。同一個使用者同時只有一個計時在跑：
+// 開始新的計時會先停掉其他任務的，任務完成時也會自動停止。
+// /stats 依天與專案加總花費的時間，/api/v1/stats 也多了 tracked_minutes
+
+// TimeEntry 是一段計時；End 為零值代表還在計時
+type TimeEntry struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+const (
+	maxTimeEntries = 500
+	timesheetDays  = 14
+)
+
+var (
+	errTimerRunning     = invalidInput("這個任務已經在計時了")
+	errTimerCompleted   = invalidInput("已完成的任務不能計時")
+	errTooManyTimeEntry = invalidInput("每個任務最多 %d 段計時紀錄", maxTimeEntries)
+)
+
+// TimerRunning 回報任務是否正在計時
+func (t Task) TimerRunning() bool {
+	n := len(t.TimeEntries)
+	return n > 0 && t.TimeEntries[n-1].End.IsZero()
+}
+
+// TimerStartedAt 回傳進行中這一段的開始時間，沒有在計時時為零值
+func (t Task) TimerStartedAt() time.Time {
+	if !t.TimerRunning() {
+		return time.Time{}
+	}
+	return t.TimeEntries[len(t.TimeEntries)-1].Start
+}
+
+// TimeSpent 回傳累計花費的時間，進行中的那一段算到現在
+func (t Task) TimeSpent() time.Duration {
+	return t.trackedBetween(time.Time{}, time.Now(), time.Now())
+}
+
+// trackedBetween 回傳落在 [start, end) 之間的計時長度，進行中的那一段算到 now
+func (t Task) trackedBetween(start, end, now time.Time) time.Duration {
+	var total time.Duration
+	for _, e := range t.TimeEntries {
+		from, to := e.Start, e.End
+		if to.IsZero() {
+			to = now
+		}
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		if to.After(from) {
+			total += to.Sub(from)
+		}
+	}
+	return total
+}
+
+func (t *Task) startTimer(now time.Time) error {
+	switch {
+	case t.Completed:
+		return errTimerCompleted
+	case t.TimerRunning():
+		return errTimerRunning
+	case len(t.TimeEntries) >= maxTimeEntries:
+		return errTooManyTimeEntry
+	}
+	t.TimeEntries = append(t.TimeEntries, TimeEntry{Start: now})
+	return nil
+}
+
+// stopTimer 結束進行中的那一段，回傳這段的長度；沒有在計時時回傳 0
+func (t *Task) stopTimer(now time.Time) time.Duration {
+	if !t.TimerRunning() {
+		return 0
+	}
+	e := &t.TimeEntries[len(t.TimeEntries)-1]
+	e.End = now
+	return e.End.Sub(e.Start)
+}
+
+// formatDuration 把時間長度寫成「1 小時 5 分」，不到一分鐘算一分鐘
+func formatDuration(locale string, d time.Duration) string {
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	if minutes < 60 {
+		return tr(locale, "%d 分", minutes)
+	}
+	if minutes%60 == 0 {
+		return tr(locale, "%d 小時", minutes/60)
+	}
+	return tr(locale, "%d 小時 %d 分", minutes/60, minutes%60)
+}
+
+func (a *App) timerStartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	username := a.getUsername(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+	now := time.Now()
+
+	// 先停掉其他任務的計時，同一時間只算一件事
+	if tasks, err := a.store.ListTasks(username); err == nil {
+		for _, t := range tasks {
+			if t.ID != id && t.Username == username && t.TimerRunning() {
+				a.modifyOwnTask(t.ID, username, func(t *Task) error {
+					t.stopTimer(now)
+					return nil
+				})
+			}
+		}
+	}
+
+	task, err := a.modifyOwnTask(id, username, func(t *Task) error {
+		return t.startTimer(now)
+	})
+	if err != nil {
+		a.flashError(r, err, "開始計時失敗，請稍後再試")
+	} else {
+		a.flashSuccess(r, "開始計時「%s」", brief(task.Description))
+	}
+	redirectBack(w, r)
+}
+
+func (a *App) timerStopHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	username := a.getUsername(r)
+	id, ok := a.requireFormID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var spent time.Duration
+	task, err := a.modifyOwnTask(id, username, func(t *Task) error {
+		spent = t.stopTimer(time.Now())
+		return nil
+	})
+	if err != nil {
+		a.flashError(r, err, "停止計時失敗，請稍後再試")
+	} else if spent > 0 {
+		locale := a.requestLocale(r)
+		a.flashSuccess(r, "「%s」這次花了 %s，累計 %s", brief(task.Description), formatDuration(locale, spent), formatDuration(locale, task.TimeSpent()))
+	}
+	redirectBack(w, r)
+}
+
+// timesheetRow 是統計頁上的一列；Percent 是長條圖相對於最大值的寬度
+type timesheetRow struct {
+	Label   string
+	Spent   time.Duration
+	Percent int
+}
+
+func fillPercent(rows []timesheetRow) {
+	var max time.Duration
+	for _, row := range rows {
+		if row.Spent > max {
+			max = row.Spent
+		}
+	}
+	for i := range rows {
+		if max > 0 {
+			rows[i].Percent = int(rows[i].Spent * 100 / max)
+		}
+	}
+}
+
+// statsPageHandler 顯示最近兩週每天與各專案花費的時間、完成的番茄鐘（見 pomodoro.go）與準時完成的比例，只算自己負責的任務
+func (a *App) statsPageHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	now := time.Now()
+	prefs := a.requestDatePrefs(r)
+
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	var mine, own, focused []Task
+	for _, t := range tasks {
+		if t.Username == username {
+			mine = append(mine, t)
+		}
+		if t.Username == username && len(t.TimeEntries) > 0 {
+			own = append(own, t)
+		}
+		if t.Username == username && len(t.Pomodoros) > 0 {
+			focused = append(focused, t)
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from := today.AddDate(0, 0, 1-timesheetDays)
+	var days []timesheetRow
+	var total time.Duration
+	for i := timesheetDays - 1; i >= 0; i-- {
+		start := today.AddDate(0, 0, -i)
+		row := timesheetRow{Label: prefs.Date(start)}
+		for _, t := range own {
+			row.Spent += t.trackedBetween(start, start.AddDate(0, 0, 1), now)
+		}
+		total += row.Spent
+		days = append(days, row)
+	}
+	fillPercent(days)
+
+	names := map[int]string{0: a.tr(r, "個人任務")}
+	if projects, err := a.store.ListProjects(username); err == nil {
+		for _, p := range projects {
+			names[p.ID] = p.Name
+		}
+	}
+	byProject := make(map[int]time.Duration)
+	for _, t := range own {
+		if spent := t.trackedBetween(from, now, now); spent > 0 {
+			byProject[t.ProjectID] += spent
+		}
+	}
+	var projects []timesheetRow
+	for id, spent := range byProject {
+		projects = append(projects, timesheetRow{Label: names[id], Spent: spent})
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Spent > projects[j].Spent })
+	fillPercent(projects)
+
+	var running []Task
+	for _, t := range own {
+		if t.TimerRunning() {
+			running = append(running, t)
+		}
+	}
+
+	pomodoroDays, pomodoroTasks, pomodoroTotal := pomodoroStats(focused, prefs, today, timesheetDays)
+	completion := summarize(mine, from, today.AddDate(0, 0, 1), now)
+
+	data := map[string]interface{}{
+		"Username":      username,
+		"Days":          days,
+		"Projects":      projects,
+		"Total":         total,
+		"Running":       running,
+		"PomodoroDays":  pomodoroDays,
+		"PomodoroTasks": pomodoroTasks,
+		"PomodoroTotal": pomodoroTotal,
+		"Completion":    completion,
+		"Nonce":         newNonce(username),
+		"CSRFToken":     a.sessions.CSRFToken(r),
+		"Flashes":       a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("stats"))
+	t.Execute(w, data)
+}
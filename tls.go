@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- HTTPS ---
+//
+// 直接對外服務時開啟 HTTPS，session cookie 才不會以明文在網路上傳送。兩種方式擇一：
+// -tls-cert 與 -tls-key 指定憑證與私鑰檔（例如 certbot 產生的檔案，更新後送 SIGHUP 重新載入）；
+// 或 -autocert 指定主機名稱，自動向 Let's Encrypt 申請與續約，憑證存在 -autocert-cache 目錄。
+// autocert 需要 golang.org/x/crypto 的 acme 套件，以 go build -tags autocert 編譯時才會包含（見 autocert.go），
+// 預設建置只用到 x/crypto 的 bcrypt（見 password.go）。-http-redirect 另外開一個 HTTP 埠（通常是 :80），把請求轉到 HTTPS；
+// 使用 autocert 時這個埠也回應 Let's Encrypt 的 HTTP-01 驗證。開啟 HTTPS 時 cookie 一律加上 Secure。
+// 部署在會處理 TLS 的反向代理後方時不需要這些設定，照舊使用 -secure-cookies
+
+var (
+	tlsCertFile      string // -tls-cert
+	tlsKeyFile       string // -tls-key
+	autocertHosts    string // -autocert，逗號分隔
+	autocertCache    = "autocert-cache"
+	autocertEmail    string // -autocert-email
+	httpRedirectAddr string // -http-redirect
+)
+
+// autocertManager 由 autocert.go（build tag: autocert）設定，回傳 TLS 設定與處理 HTTP-01 驗證的 handler 包裝；
+// 預設建置為 nil
+var autocertManager func(hosts []string, cacheDir, email string) (*tls.Config, func(http.Handler) http.Handler)
+
+// setupTLS 依啟動參數建立 TLS 設定；沒有開啟 HTTPS 時回傳 nil。
+// 第二個回傳值包住 -http-redirect 的 handler，autocert 用它回應驗證請求
+func setupTLS() (*tls.Config, func(http.Handler) http.Handler, error) {
+	passthrough := func(h http.Handler) http.Handler { return h }
+	switch {
+	case autocertHosts != "" && (tlsCertFile != "" || tlsKeyFile != ""):
+		return nil, nil, errors.New("-autocert 與 -tls-cert/-tls-key 只能擇一")
+	case autocertHosts != "":
+		if autocertManager == nil {
+			return nil, nil, errors.New("此執行檔未包含 autocert，請以 -tags autocert 重新編譯，或改用 -tls-cert/-tls-key")
+		}
+		var hosts []string
+		for _, h := range strings.Split(autocertHosts, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				hosts = append(hosts, h)
+			}
+		}
+		cfg, wrap := autocertManager(hosts, autocertCache, autocertEmail)
+		cfg.MinVersion = tls.VersionTLS12
+		return cfg, wrap, nil
+	case tlsCertFile != "" || tlsKeyFile != "":
+		if tlsCertFile == "" || tlsKeyFile == "" {
+			return nil, nil, errors.New("-tls-cert 與 -tls-key 必須同時指定")
+		}
+		cert, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, passthrough, nil
+	}
+	if httpRedirectAddr != "" {
+		return nil, nil, errors.New("-http-redirect 需要 -tls-cert/-tls-key 或 -autocert")
+	}
+	return nil, nil, nil
+}
+
+// httpsRedirect 把請求轉到同一個主機的 HTTPS；tlsPort 是 HTTPS 的埠號，443 時網址不帶埠號
+func httpsRedirect(tlsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, "缺少 Host", http.StatusBadRequest)
+			return
+		}
+		if tlsPort != "" && tlsPort != "443" {
+			host = net.JoinHostPort(host, tlsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// startHTTPRedirect 在 addr 開始 HTTP 轉址服務；重新啟動時不交接這個埠，由新行程重新監聽
+func startHTTPRedirect(addr string, ln net.Listener, wrap func(http.Handler) http.Handler) error {
+	port := ""
+	if tcp, ok := ln.Addr().(*net.TCPAddr); ok {
+		port = strconv.Itoa(tcp.Port)
+	}
+	redirectLn, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           wrap(httpsRedirect(port)),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       time.Minute,
+	}
+	go func() {
+		if err := srv.Serve(redirectLn); err != nil {
+			log.Printf("HTTP 轉址服務停止：%v", err)
+		}
+	}()
+	return nil
+}
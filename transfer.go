@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 任務匯出／匯入 ---
+//
+// /export?format=csv|json 下載自己的任務（obsidian 格式見 obsidian.go）；/import 上傳同樣格式的檔案。
+// 匯入時逐列檢查，有問題的列以訊息回報並跳過，描述與到期時間相同的任務視為重複不再新增。
+// on_time（是否在期限內完成）與 checklist（子項目進度，例如 2/5）只在匯出時提供給報表使用，匯入時忽略
+
+const (
+	exportTimeFormat  = "2006-01-02 15:04"
+	maxTaskImportSize = maxImportRows * 2
+)
+
+var taskCSVHeader = []string{"description", "due_at", "completed", "completed_at", "priority", "tags", "recurrence", "status", "on_time", "checklist"}
+
+// taskRecord 是匯出／匯入用的一筆任務，CSV 與 JSON 共用，時間一律用 exportTimeFormat
+type taskRecord struct {
+	Description string   `json:"description"`
+	DueAt       string   `json:"due_at"`
+	Completed   bool     `json:"completed"`
+	CompletedAt string   `json:"completed_at,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Recurrence  string   `json:"recurrence,omitempty"`
+	Status      string   `json:"status,omitempty"` // 舊的匯出檔沒有這欄，依 completed 判斷
+
+	// 以下只有匯出；OnTime 在未完成或不知道完成時間時為 nil
+	OnTime    *bool  `json:"on_time,omitempty"`
+	Checklist string `json:"checklist,omitempty"`
+}
+
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(exportTimeFormat)
+}
+
+// parseImportTime 接受匯出格式，以及試算表常見的幾種寫法
+func parseImportTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{exportTimeFormat, "2006-01-02T15:04", "2006/01/02 15:04", time.RFC3339, "2006-01-02", "2006/01/02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrInvalidDueDate
+}
+
+// formatExportDue 是匯出的到期時間，全天任務只寫日期
+func formatExportDue(t Task) string {
+	if t.AllDay {
+		return t.DueAt.Format(dateInputFormat)
+	}
+	return formatExportTime(t.DueAt)
+}
+
+// isDateOnly 回報匯入的到期時間是否只有日期，只有日期的任務匯入成全天任務
+func isDateOnly(s string) bool {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", "2006/01/02"} {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func toRecord(t Task) taskRecord {
+	rec := taskRecord{
+		Description: t.Description,
+		DueAt:       formatExportDue(t),
+		Completed:   t.Completed,
+		CompletedAt: formatExportTime(t.CompletedAt),
+		Priority:    effectivePriority(t.Priority),
+		Tags:        t.Tags,
+		Recurrence:  t.Recurrence,
+		Status:      t.EffectiveStatus(),
+		Checklist:   t.ChecklistProgress(),
+	}
+	if onTime, ok := t.completedOnTime(); ok {
+		rec.OnTime = &onTime
+	}
+	return rec
+}
+
+// toTask 檢查一筆匯入的資料並轉成任務
+func (rec taskRecord) toTask(username string, now time.Time) (Task, error) {
+	desc := strings.TrimSpace(rec.Description)
+	if err := checkDescription(desc); err != nil {
+		return Task{}, err
+	}
+	dueAt, err := parseImportTime(rec.DueAt)
+	if err == nil {
+		err = checkDueAt(dueAt)
+	}
+	if err != nil {
+		return Task{}, err
+	}
+	priority := strings.ToLower(strings.TrimSpace(rec.Priority))
+	if priority == "" {
+		priority = PriorityMedium
+	}
+	if !validPriority(priority) {
+		return Task{}, invalidInput("優先順序「%s」不正確", rec.Priority)
+	}
+	recurrence := strings.ToLower(strings.TrimSpace(rec.Recurrence))
+	if !validRecurrence(recurrence) {
+		return Task{}, invalidInput("重複規則「%s」不正確", rec.Recurrence)
+	}
+	status := strings.ToLower(strings.TrimSpace(rec.Status))
+	if status != "" && !validStatus(status) {
+		return Task{}, invalidInput("狀態「%s」不正確", rec.Status)
+	}
+
+	task := Task{
+		Description: desc,
+		CreatedAt:   now,
+		DueAt:       dueAt,
+		AllDay:      isDateOnly(rec.DueAt),
+		Username:    username,
+		Recurrence:  recurrence,
+		Priority:    priority,
+		Tags:        normalizeTags(rec.Tags),
+	}
+	if status == "" {
+		status = StatusTodo
+		if rec.Completed {
+			status = StatusDone
+		}
+	}
+	task.setStatus(status, now)
+	if task.Completed {
+		if rec.CompletedAt != "" {
+			completedAt, err := parseImportTime(rec.CompletedAt)
+			if err != nil {
+				return Task{}, invalidInput("完成時間格式錯誤")
+			}
+			task.CompletedAt = completedAt
+		}
+	}
+	return task, nil
+}
+
+// dedupKey 以描述與到期時間（到分鐘）判斷重複
+func dedupKey(description string, dueAt time.Time) string {
+	return strings.TrimSpace(description) + "\x00" + dueAt.Format(exportTimeFormat)
+}
+
+func (a *App) exportHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	tasks, err := a.store.ListTasks(username)
+	if err != nil {
+		http.Error(w, "讀取任務失敗", http.StatusInternalServerError)
+		return
+	}
+	filename := "tasks-" + time.Now().Format("20060102")
+
+	switch r.URL.Query().Get("format") {
+	case "json":
+		records := make([]taskRecord, len(tasks))
+		for i, t := range tasks {
+			records[i] = toRecord(t)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(records)
+	case "csv", "":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		io.WriteString(w, csvBOM)
+		cw := csv.NewWriter(w)
+		cw.Write(taskCSVHeader)
+		for _, t := range tasks {
+			rec := toRecord(t)
+			onTime := ""
+			if rec.OnTime != nil {
+				onTime = strconv.FormatBool(*rec.OnTime)
+			}
+			cw.Write([]string{
+				csvSafe(rec.Description),
+				rec.DueAt,
+				strconv.FormatBool(rec.Completed),
+				rec.CompletedAt,
+				rec.Priority,
+				csvSafe(strings.Join(rec.Tags, ",")),
+				rec.Recurrence,
+				rec.Status,
+				onTime,
+				rec.Checklist,
+			})
+		}
+		cw.Flush()
+	case "obsidian":
+		a.exportObsidian(w, tasks, filename)
+	default:
+		http.Error(w, "不支援的格式", http.StatusBadRequest)
+	}
+}
+
+// csvUnescape 還原 csvSafe 加上的單引號，讓匯出的檔案可以原樣匯回
+func csvUnescape(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// numberedRecord 記住資料在檔案中的位置，回報錯誤時使用
+type numberedRecord struct {
+	Line int
+	taskRecord
+}
+
+// parseTaskCSV 依標題列對應欄位，沒有標題列時依匯出的欄位順序讀取
+func parseTaskCSV(r io.Reader) ([]numberedRecord, []error, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	columns := make(map[string]int)
+	for i, name := range taskCSVHeader {
+		columns[name] = i
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var records []numberedRecord
+	var problems []error
+	line := 0
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, invalidInput("CSV 格式錯誤：%v", err)
+		}
+		line++
+		if line == 1 && len(record) > 0 {
+			record[0] = strings.TrimPrefix(record[0], csvBOM)
+			if strings.EqualFold(strings.TrimSpace(record[0]), "description") {
+				columns = make(map[string]int)
+				for i, name := range record {
+					columns[strings.ToLower(strings.TrimSpace(name))] = i
+				}
+				continue
+			}
+		}
+		if len(records) == maxTaskImportSize {
+			return nil, nil, invalidInput("一次最多匯入 %d 筆任務", maxTaskImportSize)
+		}
+		completed := field(record, "completed")
+		done, err := strconv.ParseBool(completed)
+		if completed != "" && err != nil {
+			problems = append(problems, invalidInput("第 %d 列：completed 欄必須是 true 或 false", line))
+			continue
+		}
+		records = append(records, numberedRecord{Line: line, taskRecord: taskRecord{
+			Description: csvUnescape(field(record, "description")),
+			DueAt:       field(record, "due_at"),
+			Completed:   done,
+			CompletedAt: field(record, "completed_at"),
+			Priority:    field(record, "priority"),
+			Tags:        parseTags(csvUnescape(field(record, "tags"))),
+			Recurrence:  field(record, "recurrence"),
+			Status:      field(record, "status"),
+		}})
+	}
+	return records, problems, nil
+}
+
+func parseTaskJSON(r io.Reader) ([]numberedRecord, error) {
+	var list []taskRecord
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, invalidInput("JSON 格式錯誤：需要任務陣列")
+	}
+	if len(list) > maxTaskImportSize {
+		return nil, invalidInput("一次最多匯入 %d 筆任務", maxTaskImportSize)
+	}
+	records := make([]numberedRecord, len(list))
+	for i, rec := range list {
+		records[i] = numberedRecord{Line: i + 1, taskRecord: rec}
+	}
+	return records, nil
+}
+
+// lineProblem 是匯入時第 line 列（unit 為「筆」時是第幾筆）的問題；err 不是 DomainError 時以 fallback 說明
+func lineProblem(unit string, line int, err error, fallback string) error {
+	if unit == "筆" {
+		return invalidInput("第 %d 筆：%s", line, displayError(err, fallback))
+	}
+	return invalidInput("第 %d 列：%s", line, displayError(err, fallback))
+}
+
+// lineCount 是匯入失敗的數量，單位同 lineProblem
+func lineCount(unit string, n int) error {
+	if unit == "筆" {
+		return invalidInput("%d 筆匯入失敗", n)
+	}
+	return invalidInput("%d 列匯入失敗", n)
+}
+
+// readImportFile 讀取上傳的 CSV 或 JSON 檔；unit 是回報錯誤位置用的「列」或「筆」
+func readImportFile(r *http.Request) (records []numberedRecord, problems []error, unit string, err error) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, nil, "", invalidInput("請選擇要匯入的 CSV 或 JSON 檔")
+	}
+	defer file.Close()
+
+	if strings.EqualFold(path.Ext(header.Filename), ".json") {
+		records, err = parseTaskJSON(file)
+		return records, nil, "筆", err
+	}
+	records, problems, err = parseTaskCSV(file)
+	return records, problems, "列", err
+}
+
+// importTasks 處理上傳的檔案，結果以 flash 訊息回報
+func (a *App) importTasks(r *http.Request, username string) {
+	records, problems, unit, err := readImportFile(r)
+	if err != nil {
+		a.flashError(r, err, "讀取檔案失敗")
+		return
+	}
+
+	existing, err := a.store.ListTasks(username)
+	if err != nil {
+		a.flashError(r, err, "讀取任務失敗")
+		return
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		seen[dedupKey(t.Description, t.DueAt)] = true
+	}
+
+	now := time.Now()
+	created, skipped := 0, 0
+	for _, rec := range records {
+		task, err := rec.toTask(username, now)
+		if err != nil {
+			problems = append(problems, lineProblem(unit, rec.Line, err, "資料不正確"))
+			continue
+		}
+		key := dedupKey(task.Description, task.DueAt)
+		if seen[key] {
+			skipped++
+			continue
+		}
+		if _, err := a.store.CreateTask(task); err != nil {
+			problems = append(problems, lineProblem(unit, rec.Line, err, "新增任務失敗"))
+			continue
+		}
+		seen[key] = true
+		created++
+	}
+
+	if skipped > 0 {
+		a.flashSuccess(r, "已匯入 %d 個任務，略過 %d 個重複任務", created, skipped)
+	} else {
+		a.flashSuccess(r, "已匯入 %d 個任務", created)
+	}
+	if len(problems) > 0 {
+		a.flashError(r, lineCount(unit, len(problems)), "")
+		a.flashDetails(r, FlashError, problems)
+	}
+}
+
+func (a *App) importHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+	if r.Method == "POST" {
+		if mode := r.FormValue("mode"); mode == diffModeMerge || mode == diffModeSync {
+			a.reviewImport(w, r, username, mode)
+			return
+		}
+		a.importTasks(r, username)
+		http.Redirect(w, r, "/import", http.StatusSeeOther)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Username":  username,
+		"Nonce":     newNonce(username),
+		"CSRFToken": a.sessions.CSRFToken(r),
+		"Flashes":   a.sessions.PopFlashes(r),
+		"MaxRows":   maxTaskImportSize,
+		"Today":     time.Now().Format(dateInputFormat),
+	}
+	t := a.localize(r, a.pages.page("import"))
+	t.Execute(w, data)
+}
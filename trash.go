@@ -0,0 +1,163 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// --- 垃圾桶 ---
+//
+// 刪除任務只是設定 DeletedAt 移到垃圾桶，可以在 /trash 復原或永久刪除；
+// 刪除後的 flash 附「復原」按鈕。排程每天清掉放超過 trashRetention 的任務
+
+const trashRetention = 30 * 24 * time.Hour
+
+// Trashed 回報任務是否在垃圾桶裡
+func (t Task) Trashed() bool {
+	return !t.DeletedAt.IsZero()
+}
+
+// trashTask 把任務移到垃圾桶，呼叫前須確認任務屬於目前的使用者
+func (a *App) trashTask(id int, now time.Time) error {
+	_, err := a.store.ModifyTask(id, func(t *Task) error {
+		t.DeletedAt = now
+		return nil
+	})
+	return err
+}
+
+// findTrashed 在使用者的垃圾桶裡找任務，不是自己的一律當作找不到
+func (a *App) findTrashed(username string, id int) (Task, error) {
+	trash, err := a.store.ListTrash(username)
+	if err != nil {
+		return Task{}, err
+	}
+	for _, t := range trash {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return Task{}, ErrNotFound
+}
+
+// flashUndo 顯示刪除成功的訊息並附上復原按鈕；按鈕自帶 nonce 與 CSRF token，
+// 因為 flash 區塊拿不到頁面的資料
+func (a *App) flashUndo(r *http.Request, taskID int, format string, args ...interface{}) {
+	a.sessions.pushFlash(r, Flash{
+		Kind:    FlashSuccess,
+		Message: a.tr(r, format, args...),
+		Undo: &FlashUndo{
+			TaskID:    taskID,
+			Nonce:     newNonce(a.getUsername(r)),
+			CSRFToken: a.sessions.CSRFToken(r),
+		},
+	})
+}
+
+func (a *App) trashHandler(w http.ResponseWriter, r *http.Request) {
+	username := a.getUsername(r)
+
+	if r.Method == "POST" {
+		switch r.FormValue("action") {
+		case "restore":
+			a.restoreFromTrash(r, username)
+		case "delete":
+			a.deleteFromTrash(r, username)
+		case "empty":
+			a.emptyTrash(r, username)
+		}
+		redirectBack(w, r)
+		return
+	}
+
+	trash, err := a.store.ListTrash(username)
+	if err != nil {
+		http.Error(w, "讀取垃圾桶失敗", http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(trash, func(i, j int) bool { return trash[i].DeletedAt.After(trash[j].DeletedAt) })
+
+	now := time.Now()
+	funcMap := template.FuncMap{
+		// daysLeft 是距離自動清除還有幾天，不足一天算一天
+		"daysLeft": func(t Task) int {
+			left := t.DeletedAt.Add(trashRetention).Sub(now)
+			if left <= 0 {
+				return 0
+			}
+			return int((left + 24*time.Hour - 1) / (24 * time.Hour))
+		},
+	}
+	data := map[string]interface{}{
+		"Username":      username,
+		"Tasks":         trash,
+		"RetentionDays": int(trashRetention / (24 * time.Hour)),
+		"Nonce":         newNonce(username),
+		"CSRFToken":     a.sessions.CSRFToken(r),
+		"Flashes":       a.sessions.PopFlashes(r),
+	}
+	t := a.localize(r, a.pages.page("trash")).Funcs(funcMap)
+	t.Execute(w, data)
+}
+
+func (a *App) restoreFromTrash(r *http.Request, username string) {
+	id, err := formID(r, "id")
+	if err != nil {
+		a.flashError(r, err, "")
+		return
+	}
+	if _, err := a.findTrashed(username, id); err != nil {
+		a.flashError(r, err, "復原任務失敗，請稍後再試")
+		return
+	}
+	task, err := a.store.RestoreTask(id)
+	if err != nil {
+		a.flashError(r, err, "復原任務失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "已復原「%s」", brief(task.Description))
+}
+
+func (a *App) deleteFromTrash(r *http.Request, username string) {
+	id, err := formID(r, "id")
+	if err != nil {
+		a.flashError(r, err, "")
+		return
+	}
+	task, err := a.findTrashed(username, id)
+	if err == nil {
+		err = a.store.DeleteTask(id)
+	}
+	if err != nil {
+		a.flashError(r, err, "刪除任務失敗，請稍後再試")
+		return
+	}
+	a.flashSuccess(r, "已永久刪除「%s」", brief(task.Description))
+}
+
+func (a *App) emptyTrash(r *http.Request, username string) {
+	trash, err := a.store.ListTrash(username)
+	if err != nil {
+		a.flashError(r, err, "清空垃圾桶失敗，請稍後再試")
+		return
+	}
+	for _, t := range trash {
+		if err := a.store.DeleteTask(t.ID); err != nil && err != ErrNotFound {
+			a.flashError(r, err, "清空垃圾桶失敗，請稍後再試")
+			return
+		}
+	}
+	a.flashSuccess(r, "已永久刪除 %d 個任務", len(trash))
+}
+
+// purgeTrash 是排程工作，清除放在垃圾桶超過 trashRetention 的任務
+func (a *App) purgeTrash() error {
+	n, err := a.store.PurgeTrash(time.Now().Add(-trashRetention))
+	if n > 0 {
+		log.Printf("垃圾桶清除 %d 個過期任務", n)
+	}
+	return err
+}
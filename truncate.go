@@ -0,0 +1,92 @@
+package main
+
+import (
+	"html/template"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- 任務內容長度與截斷 ---
+//
+// 任務內容最長 maxDescriptionLength 個字（啟動參數 -max-description），超過時拒絕而不是默默截掉，
+// 存進儲存層的永遠是完整內容。顯示時才依位置截斷：每種位置的寬度集中在 clipWidths，
+// Go 程式（flash 訊息、信件主旨、推播）用 clipText／quoted，模板用 withClip 裝上的 clip 與 short。
+// clip 截斷後可以滑過看完整內容，點一下就展開；short 只截斷文字，給月曆格子、標題等放不下展開的地方，
+// 需要時由模板自己在外層加 title
+
+// maxDescriptionLength 是任務內容的字數上限，main 依啟動參數設定
+var maxDescriptionLength = 1000
+
+const (
+	clipMessage  = 40  // flash 訊息與專案通知裡用「」括起來的任務
+	clipSubject  = 50  // 信件主旨與推播標題
+	clipLine     = 100 // 信件與推播內文裡的一行
+	clipList     = 120 // 清單、垃圾桶、封存等一列一個任務的頁面
+	clipCard     = 80  // 看板卡片、公告、標題
+	clipCalendar = 24  // 月曆格子與倒數橫幅
+)
+
+// clipWidths 讓模板以位置名稱指定寬度，例如 {{clip .Description "list"}}
+var clipWidths = map[string]int{
+	"message":  clipMessage,
+	"subject":  clipSubject,
+	"line":     clipLine,
+	"list":     clipList,
+	"card":     clipCard,
+	"calendar": clipCalendar,
+}
+
+// checkDescription 檢查已去掉前後空白的任務內容
+func checkDescription(desc string) error {
+	if desc == "" {
+		return ErrEmptyDescription
+	}
+	if utf8.RuneCountInString(desc) > maxDescriptionLength {
+		return invalidInput("任務內容最多 %d 個字", maxDescriptionLength)
+	}
+	return nil
+}
+
+// clipText 把 s 的空白（含換行）合併成一格後截到 n 個字，截掉時以 … 結尾（… 算在 n 裡）
+func clipText(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
+
+// quoted 是 flash 訊息與通知裡的「任務內容」
+func quoted(desc string) string {
+	return "「" + brief(desc) + "」"
+}
+
+// brief 是放進訊息格式（例如「已復原「%s」」）的任務描述，和 quoted 一樣截短但不加括號
+func brief(desc string) string {
+	return clipText(desc, clipMessage)
+}
+
+func clipWidth(view string) int {
+	if n, ok := clipWidths[view]; ok {
+		return n
+	}
+	return clipList
+}
+
+// clipHTML 沒被截斷時就是原本的文字；截斷時是可以點開的 <details>，滑過摘要會顯示完整內容
+func clipHTML(s, view string) template.HTML {
+	short := clipText(s, clipWidth(view))
+	if short == strings.Join(strings.Fields(s), " ") {
+		return template.HTML(template.HTMLEscapeString(s))
+	}
+	full := template.HTMLEscapeString(s)
+	return template.HTML(`<details class="clip"><summary title="` + full + `">` +
+		template.HTMLEscapeString(short) + `</summary>` + full + `</details>`)
+}
+
+// clipFuncs 是模板裡的 clip 與 short，搭配樣式 {{template "clipstyle"}}
+var clipFuncs = template.FuncMap{
+	"clip":  clipHTML,
+	"short": func(s, view string) string { return clipText(s, clipWidth(view)) },
+}
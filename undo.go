@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// --- 復原與重做 ---
+//
+// 在網頁上新增、刪除、勾選完成與編輯任務時，把這個動作記在 session 的操作紀錄（只放在記憶體），
+// 最多保留 maxUndo 筆；flash 附「復原」按鈕，也可以直接 POST /undo、/redo。
+// 每筆紀錄都有反向操作：新增 ↔ 移到垃圾桶、刪除 ↔ 從垃圾桶復原、完成 ↔ 未完成、編輯 ↔ 改回原本的內容。
+// 套用前先確認任務仍是當時的狀態，之後又被改過（例如在別的分頁或被分享的人修改）就不復原，
+// 以免蓋掉別人的變更。做了新的動作後，重做紀錄清空
+
+const maxUndo = 20
+
+const (
+	undoRestore  = "restore"  // 任務存在（新增或從垃圾桶復原）
+	undoTrash    = "trash"    // 任務移到垃圾桶
+	undoComplete = "complete" // 任務的完成狀態改成 Completed
+	undoEdit     = "edit"     // 任務的內容從 From 改成 To
+)
+
+var ErrUndoConflict = &DomainError{Code: "undo_conflict", Message: "任務之後又被修改過，無法復原", Status: http.StatusConflict}
+
+// undoFields 是編輯頁能改的欄位，編輯的復原只動這些欄位
+type undoFields struct {
+	Description   string    `json:"description"`
+	DueAt         time.Time `json:"due_at"`
+	AllDay        bool      `json:"all_day"`
+	Recurrence    string    `json:"recurrence"`
+	Priority      string    `json:"priority"`
+	Tags          []string  `json:"tags"`
+	EncryptedNote string    `json:"encrypted_note"`
+	BlockedBy     []int     `json:"blocked_by"`
+	Reminders     []int     `json:"reminders"`
+	RemindersSet  bool      `json:"reminders_set"`
+}
+
+func fieldsOf(t Task) undoFields {
+	return undoFields{t.Description, t.DueAt, t.AllDay, t.Recurrence, t.Priority, t.Tags, t.EncryptedNote, t.BlockedBy, t.Reminders, t.RemindersSet}
+}
+
+// equal 以 JSON 比較，時間經過儲存層讀寫後時區表示可能不同
+func (f undoFields) equal(g undoFields) bool {
+	a, _ := json.Marshal(f)
+	b, _ := json.Marshal(g)
+	return string(a) == string(b)
+}
+
+func (f undoFields) apply(t *Task) {
+	t.Description = f.Description
+	t.DueAt = f.DueAt
+	t.AllDay = f.AllDay
+	t.Recurrence = f.Recurrence
+	t.Priority = f.Priority
+	t.Tags = f.Tags
+	t.EncryptedNote = f.EncryptedNote
+	t.BlockedBy = f.BlockedBy
+	t.Reminders = f.Reminders
+	t.RemindersSet = f.RemindersSet
+}
+
+// undoOp 是操作紀錄的一筆：Kind 是已經做過的變更，復原時套用 inverse
+type undoOp struct {
+	Kind      string
+	TaskID    int
+	Label     string // 訊息用，例如「新增「買牛奶」」
+	Completed bool
+	From, To  undoFields
+}
+
+func (op undoOp) inverse() undoOp {
+	inv := op
+	switch op.Kind {
+	case undoRestore:
+		inv.Kind = undoTrash
+	case undoTrash:
+		inv.Kind = undoRestore
+	case undoComplete:
+		inv.Completed = !op.Completed
+	case undoEdit:
+		inv.From, inv.To = op.To, op.From
+	}
+	return inv
+}
+
+// applyUndo 以 username 的身分做 op 描述的變更；任務已經不是 op 之前的狀態時回傳 ErrUndoConflict
+func (a *App) applyUndo(op undoOp, username string, now time.Time) error {
+	switch op.Kind {
+	case undoRestore:
+		if _, err := a.findTrashed(username, op.TaskID); err != nil {
+			return ErrUndoConflict
+		}
+		_, err := a.store.RestoreTask(op.TaskID)
+		return err
+	case undoTrash:
+		t, err := a.store.GetTask(op.TaskID)
+		if err != nil || t.Username != username {
+			return ErrUndoConflict
+		}
+		return a.trashTask(op.TaskID, now)
+	case undoComplete:
+		_, err := a.modifyCompletion(op.TaskID, func(t *Task) error {
+			if !t.canEdit(username) || t.Completed == op.Completed {
+				return ErrUndoConflict
+			}
+			t.setCompletedBy(op.Completed, now, username)
+			return nil
+		})
+		return undoError(err)
+	case undoEdit:
+		_, err := a.store.ModifyTask(op.TaskID, func(t *Task) error {
+			if !t.canEdit(username) || !fieldsOf(*t).equal(op.From) {
+				return ErrUndoConflict
+			}
+			before := *t
+			op.To.apply(t)
+			t.recordEdit(username, before, now)
+			return nil
+		})
+		return undoError(err)
+	}
+	return ErrUndoConflict
+}
+
+// undoError 把「任務已經不在」也當成無法復原
+func undoError(err error) error {
+	if err == ErrNotFound {
+		return ErrUndoConflict
+	}
+	return err
+}
+
+// recordUndo 記下目前 session 剛做完的 op，並清空重做紀錄
+func (m *sessionManager) recordUndo(r *http.Request, op undoOp) {
+	m.withSession(r, func(s *Session) {
+		s.undo = append(s.undo, op)
+		if len(s.undo) > maxUndo {
+			s.undo = s.undo[len(s.undo)-maxUndo:]
+		}
+		s.redo = nil
+	})
+}
+
+// popHistory 取出最後一筆復原（redo 為 false）或重做紀錄
+func (m *sessionManager) popHistory(r *http.Request, redo bool) (undoOp, bool) {
+	var op undoOp
+	var ok bool
+	m.withSession(r, func(s *Session) {
+		stack := &s.undo
+		if redo {
+			stack = &s.redo
+		}
+		if n := len(*stack); n > 0 {
+			op, ok = (*stack)[n-1], true
+			*stack = (*stack)[:n-1]
+		}
+	})
+	return op, ok
+}
+
+// pushHistory 把 op 放回復原（redo 為 false）或重做紀錄，不清空另一邊
+func (m *sessionManager) pushHistory(r *http.Request, op undoOp, redo bool) {
+	m.withSession(r, func(s *Session) {
+		if redo {
+			s.redo = append(s.redo, op)
+		} else {
+			s.undo = append(s.undo, op)
+		}
+	})
+}
+
+func (m *sessionManager) withSession(r *http.Request, fn func(*Session)) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[hashSessionToken(cookie.Value)]; ok {
+		fn(s)
+	}
+}
+
+// flashUndoable 記下 op 並顯示附「復原」按鈕的訊息；op.Label 要先以 a.tr 翻好，復原與重做的訊息直接帶入
+func (a *App) flashUndoable(r *http.Request, op undoOp, format string, args ...interface{}) {
+	a.sessions.recordUndo(r, op)
+	a.pushHistoryFlash(r, a.tr(r, format, args...), "/undo")
+}
+
+func (a *App) pushHistoryFlash(r *http.Request, message, action string) {
+	a.sessions.pushFlash(r, Flash{
+		Kind:    FlashSuccess,
+		Message: message,
+		Undo: &FlashUndo{
+			Action:    action,
+			Nonce:     newNonce(a.getUsername(r)),
+			CSRFToken: a.sessions.CSRFToken(r),
+		},
+	})
+}
+
+// undoHandler 處理 /undo 與 /redo
+func (a *App) undoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "不支援的方法", http.StatusMethodNotAllowed)
+		return
+	}
+	redo := r.URL.Path == "/redo"
+	op, ok := a.sessions.popHistory(r, redo)
+	switch {
+	case !ok && redo:
+		a.flashError(r, invalidInput("沒有可以重做的動作"), "")
+	case !ok:
+		a.flashError(r, invalidInput("沒有可以復原的動作"), "")
+	case redo:
+		if err := a.applyUndo(op, a.getUsername(r), time.Now()); err != nil {
+			a.flashError(r, err, "重做失敗，請稍後再試")
+			break
+		}
+		a.sessions.pushHistory(r, op, false)
+		a.pushHistoryFlash(r, a.tr(r, "已重做：%s", op.Label), "/undo")
+	default:
+		if err := a.applyUndo(op.inverse(), a.getUsername(r), time.Now()); err != nil {
+			a.flashError(r, err, "復原失敗，請稍後再試")
+			break
+		}
+		a.sessions.pushHistory(r, op, true)
+		a.pushHistoryFlash(r, a.tr(r, "已復原：%s", op.Label), "/redo")
+	}
+	redirectBack(w, r)
+}
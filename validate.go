@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 輸入驗證 ---
+//
+// 表單與 API 送來的編號、到期時間都先在這裡檢查，解析失敗或不合理時回傳 DomainError，
+// 不再把解析失敗得到的零值（編號 0、西元 1 年）當成正常資料繼續處理。
+// HTML 表單以 flashError 顯示訊息後導回原頁，API 以 writeDomainError 回傳 code 與訊息
+
+// 到期時間的合理範圍；上限與表單的 max="9999-12-31" 一致，下限擋掉少打一位數的年份（0202 年）
+const (
+	minDueYear = 1970
+	maxDueYear = 9999
+)
+
+var ErrInvalidID = &DomainError{Code: "invalid_id", Message: "編號格式錯誤", Status: http.StatusBadRequest}
+
+// parseID 解析任務、子項目等的編號，必須是正整數
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidID
+	}
+	return id, nil
+}
+
+// formID 讀出表單欄位 name 的編號
+func formID(r *http.Request, name string) (int, error) {
+	return parseID(r.FormValue(name))
+}
+
+// requireFormID 讀出表單欄位 name 的編號；格式錯誤時以 flash 顯示並導回原頁，回傳 false
+func (a *App) requireFormID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := formID(r, name)
+	if err != nil {
+		a.flashError(r, err, "")
+		redirectBack(w, r)
+		return 0, false
+	}
+	return id, true
+}
+
+// checkDueAt 檢查到期時間是否在合理範圍內
+func checkDueAt(t time.Time) error {
+	if t.IsZero() {
+		return ErrInvalidDueDate
+	}
+	if y := t.Year(); y < minDueYear || y > maxDueYear {
+		return invalidInput("到期時間必須在 %d 年到 %d 年之間", minDueYear, maxDueYear)
+	}
+	return nil
+}
+
+// parseDueTime 解析 datetime-local 表單欄位並檢查範圍
+func parseDueTime(s string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02T15:04", s, time.Local)
+	if err != nil {
+		return time.Time{}, ErrInvalidDueDate
+	}
+	return t, checkDueAt(t)
+}
+
+// jsonInputError 把 JSON 解析錯誤轉成可以回給用戶端的錯誤；時間欄位格式錯誤時是 ErrInvalidDueDate
+func jsonInputError(err error) error {
+	var pe *time.ParseError
+	if errors.As(err, &pe) {
+		return ErrInvalidDueDate
+	}
+	return &DomainError{Code: "invalid_json", Message: "請求格式錯誤", Status: http.StatusBadRequest}
+}